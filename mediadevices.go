@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/pion/mediadevices/pkg/driver"
+	"github.com/pion/mediadevices/pkg/frame"
 	"github.com/pion/mediadevices/pkg/prop"
 )
 
@@ -129,6 +130,11 @@ func selectBestDriver(filter driver.FilterFn, constraints MediaTrackConstraints)
 		priority := float64(d.Info().Priority)
 		for _, p := range props {
 			foundPropertiesLog = append(foundPropertiesLog, p.String())
+			// Tracks in formats which can't be decoded can only be read in their native codec,
+			// so they are only selected when explicitly requested
+			if frame.IsEncodedOnly(p.FrameFormat) && constraints.FrameFormat == nil {
+				continue
+			}
 			fitnessDist, ok := constraints.MediaConstraints.FitnessDistance(p)
 			if !ok {
 				continue
@@ -0,0 +1,59 @@
+// Package clock abstracts the time functions used by drivers and transforms,
+// so that tests can control the time with a Manual clock instead of waiting.
+package clock
+
+import (
+	"time"
+)
+
+// Clock tells the time, and waits for durations to elapse.
+type Clock interface {
+	// Now returns the current time
+	Now() time.Time
+	// Sleep blocks until d elapsed
+	Sleep(d time.Duration)
+	// After returns a channel receiving the time once d elapsed
+	After(d time.Duration) <-chan time.Time
+	// NewTicker returns a ticker sending the time every d, which must be
+	// positive. Like time.Ticker, ticks are dropped for slow receivers.
+	NewTicker(d time.Duration) Ticker
+}
+
+// Ticker sends the time at regular intervals.
+type Ticker interface {
+	// C returns the channel receiving the ticks
+	C() <-chan time.Time
+	// Stop turns off the ticker
+	Stop()
+}
+
+// New returns a clock using the time package.
+func New() Clock {
+	return realClock{}
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) Sleep(d time.Duration) {
+	time.Sleep(d)
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+func (realClock) NewTicker(d time.Duration) Ticker {
+	return &realTicker{time.NewTicker(d)}
+}
+
+type realTicker struct {
+	*time.Ticker
+}
+
+func (t *realTicker) C() <-chan time.Time {
+	return t.Ticker.C
+}
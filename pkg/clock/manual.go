@@ -0,0 +1,172 @@
+package clock
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Manual is a clock whose time only changes when Add or Set is called, which
+// makes the tests using it deterministic. Sleeps, timers and tickers fire in
+// order of their deadlines while the time is moved forward.
+type Manual struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []*waiter
+	// changed is closed and replaced when the waiters change, for BlockUntil
+	changed chan struct{}
+}
+
+// waiter is a pending sleep, timer or ticker
+type waiter struct {
+	deadline time.Time
+	c        chan time.Time
+	// period is positive for tickers
+	period time.Duration
+}
+
+// NewManual returns a manual clock set to start.
+func NewManual(start time.Time) *Manual {
+	return &Manual{
+		now:     start,
+		changed: make(chan struct{}),
+	}
+}
+
+// Now returns the time of the clock.
+func (m *Manual) Now() time.Time {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.now
+}
+
+// Sleep blocks until the clock is moved forward by d.
+func (m *Manual) Sleep(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	<-m.After(d)
+}
+
+// After returns a channel receiving the time once the clock is moved forward
+// by d.
+func (m *Manual) After(d time.Duration) <-chan time.Time {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c := make(chan time.Time, 1)
+	if d <= 0 {
+		c <- m.now
+		return c
+	}
+	m.add(&waiter{deadline: m.now.Add(d), c: c})
+	return c
+}
+
+// NewTicker returns a ticker firing every d, when the clock is moved forward.
+func (m *Manual) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("non-positive interval for NewTicker")
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	w := &waiter{deadline: m.now.Add(d), c: make(chan time.Time, 1), period: d}
+	m.add(w)
+	return &manualTicker{clock: m, waiter: w}
+}
+
+// Add moves the clock forward by d, firing the waiters whose deadline is
+// reached.
+func (m *Manual) Add(d time.Duration) {
+	m.mu.Lock()
+	m.advance(m.now.Add(d))
+	m.mu.Unlock()
+}
+
+// Set moves the clock to t. The clock can't go backward, earlier times are
+// ignored.
+func (m *Manual) Set(t time.Time) {
+	m.mu.Lock()
+	m.advance(t)
+	m.mu.Unlock()
+}
+
+// BlockUntil blocks until at least n sleeps, timers or tickers are waiting
+// for the clock, e.g. to make sure that a goroutine is sleeping before moving
+// the clock forward.
+func (m *Manual) BlockUntil(n int) {
+	for {
+		m.mu.Lock()
+		count := len(m.waiters)
+		changed := m.changed
+		m.mu.Unlock()
+		if count >= n {
+			return
+		}
+		<-changed
+	}
+}
+
+func (m *Manual) add(w *waiter) {
+	m.waiters = append(m.waiters, w)
+	m.notify()
+}
+
+func (m *Manual) remove(w *waiter) {
+	for i, other := range m.waiters {
+		if other == w {
+			m.waiters = append(m.waiters[:i], m.waiters[i+1:]...)
+			m.notify()
+			return
+		}
+	}
+}
+
+func (m *Manual) notify() {
+	close(m.changed)
+	m.changed = make(chan struct{})
+}
+
+// advance fires the waiters until t in order of their deadlines
+func (m *Manual) advance(t time.Time) {
+	for {
+		sort.SliceStable(m.waiters, func(i, j int) bool {
+			return m.waiters[i].deadline.Before(m.waiters[j].deadline)
+		})
+		if len(m.waiters) == 0 || m.waiters[0].deadline.After(t) {
+			break
+		}
+
+		w := m.waiters[0]
+		m.now = w.deadline
+		select {
+		case w.c <- m.now:
+		default:
+			// the tick is dropped, like time.Ticker does
+		}
+		if w.period > 0 {
+			w.deadline = w.deadline.Add(w.period)
+		} else {
+			m.remove(w)
+		}
+	}
+	if t.After(m.now) {
+		m.now = t
+	}
+}
+
+type manualTicker struct {
+	clock  *Manual
+	waiter *waiter
+}
+
+func (t *manualTicker) C() <-chan time.Time {
+	return t.waiter.c
+}
+
+func (t *manualTicker) Stop() {
+	t.clock.mu.Lock()
+	t.clock.remove(t.waiter)
+	t.clock.mu.Unlock()
+}
@@ -0,0 +1,89 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestManual(t *testing.T) {
+	start := time.Unix(1000, 0)
+	m := NewManual(start)
+
+	ticker := m.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	after := m.After(25 * time.Millisecond)
+
+	expectTick := func(expected time.Time) {
+		t.Helper()
+		select {
+		case tick := <-ticker.C():
+			if !tick.Equal(expected) {
+				t.Errorf("Expected a tick at %v, got %v", expected, tick)
+			}
+		default:
+			t.Errorf("Expected a tick at %v", expected)
+		}
+	}
+
+	m.Add(5 * time.Millisecond)
+	if now := m.Now(); !now.Equal(start.Add(5 * time.Millisecond)) {
+		t.Errorf("Unexpected time %v", now)
+	}
+	select {
+	case <-ticker.C():
+		t.Fatal("Unexpected tick")
+	default:
+	}
+
+	m.Add(5 * time.Millisecond)
+	expectTick(start.Add(10 * time.Millisecond))
+
+	// the ticks of a slow receiver are dropped
+	m.Add(20 * time.Millisecond)
+	expectTick(start.Add(20 * time.Millisecond))
+	select {
+	case <-ticker.C():
+		t.Fatal("Unexpected tick")
+	default:
+	}
+
+	select {
+	case fired := <-after:
+		if expected := start.Add(25 * time.Millisecond); !fired.Equal(expected) {
+			t.Errorf("Expected %v, got %v", expected, fired)
+		}
+	default:
+		t.Error("Expected After to fire")
+	}
+
+	// Set can't move the clock backward
+	m.Set(start)
+	if now := m.Now(); !now.Equal(start.Add(30 * time.Millisecond)) {
+		t.Errorf("Unexpected time %v", now)
+	}
+}
+
+func TestManualSleep(t *testing.T) {
+	m := NewManual(time.Unix(0, 0))
+
+	done := make(chan struct{})
+	go func() {
+		m.Sleep(time.Second)
+		close(done)
+	}()
+
+	m.BlockUntil(1)
+	m.Add(999 * time.Millisecond)
+	select {
+	case <-done:
+		t.Fatal("Sleep returned too early")
+	default:
+	}
+
+	m.Add(time.Millisecond)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Sleep didn't return")
+	}
+}
@@ -145,6 +145,15 @@ type BitRateController interface {
 	SetBitRate(int) error
 }
 
+// VoiceActivityController is a interface representing an audio encoder which can stop transmitting
+// while nobody speaks, e.g. Opus with DTX enabled
+type VoiceActivityController interface {
+	EncoderController
+	// SetVoiceActivity reports whether someone is speaking in the next chunks read by the encoder.
+	// While nobody speaks, the encoder can return empty buffers, which aren't to be transmitted.
+	SetVoiceActivity(speaking bool) error
+}
+
 // BaseParams represents an codec's encoding properties
 type BaseParams struct {
 	// Target bitrate in bps.
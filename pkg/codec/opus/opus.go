@@ -20,6 +20,11 @@ int pion_set_encoder_bitrate(OpusEncoder *e, opus_int32 bitrate)
 {
 	return opus_encoder_ctl(e, OPUS_SET_BITRATE(bitrate));
 }
+
+int pion_set_encoder_dtx(OpusEncoder *e, opus_int32 dtx)
+{
+	return opus_encoder_ctl(e, OPUS_SET_DTX(dtx));
+}
 */
 import "C"
 
@@ -27,8 +32,12 @@ type encoder struct {
 	inBuff wave.Audio
 	reader audio.Reader
 	engine *C.OpusEncoder
+	dtx    bool
 
-	mu sync.Mutex
+	mu       sync.Mutex
+	silent   bool
+	silence  []int16
+	silenceF []float32
 }
 
 func newEncoder(r audio.Reader, p prop.Media, params Params) (codec.ReadCloser, error) {
@@ -67,6 +76,7 @@ func newEncoder(r audio.Reader, p prop.Media, params Params) (codec.ReadCloser,
 	e := encoder{
 		engine: engine,
 		reader: rMix(rBuf(r)),
+		dtx:    params.DTX,
 	}
 
 	err := e.SetBitRate(params.BitRate)
@@ -74,6 +84,10 @@ func newEncoder(r audio.Reader, p prop.Media, params Params) (codec.ReadCloser,
 		e.Close()
 		return nil, err
 	}
+	if params.DTX && C.pion_set_encoder_dtx(engine, 1) != C.OPUS_OK {
+		e.Close()
+		return nil, errors.New("failed to enable encoder's DTX")
+	}
 	return &e, nil
 }
 
@@ -93,17 +107,32 @@ func (e *encoder) Read() ([]byte, func(), error) {
 	var n C.opus_int32
 	switch b := buff.(type) {
 	case *wave.Int16Interleaved:
+		data := b.Data
+		if e.silent {
+			// the silence lets the encoder stop transmitting
+			if len(e.silence) < len(data) {
+				e.silence = make([]int16, len(data))
+			}
+			data = e.silence
+		}
 		n = C.opus_encode(
 			e.engine,
-			(*C.opus_int16)(&b.Data[0]),
+			(*C.opus_int16)(&data[0]),
 			C.int(b.ChunkInfo().Len),
 			(*C.uchar)(&encoded[0]),
 			C.opus_int32(cap(encoded)),
 		)
 	case *wave.Float32Interleaved:
+		data := b.Data
+		if e.silent {
+			if len(e.silenceF) < len(data) {
+				e.silenceF = make([]float32, len(data))
+			}
+			data = e.silenceF
+		}
 		n = C.opus_encode_float(
 			e.engine,
-			(*C.float)(&b.Data[0]),
+			(*C.float)(&data[0]),
 			C.int(b.ChunkInfo().Len),
 			(*C.uchar)(&encoded[0]),
 			C.opus_int32(cap(encoded)),
@@ -113,7 +142,11 @@ func (e *encoder) Read() ([]byte, func(), error) {
 	}
 
 	if n < 0 {
-		err = errors.New("failed to encode")
+		return nil, func() {}, errors.New("failed to encode")
+	}
+	// packets of 2 bytes or less don't need to be transmitted with DTX
+	if e.dtx && n <= 2 {
+		n = 0
 	}
 
 	return encoded[:n:n], func() {}, err
@@ -131,6 +164,15 @@ func (e *encoder) SetBitRate(bitRate int) error {
 	return nil
 }
 
+// SetVoiceActivity implements codec.VoiceActivityController. It only has effect with DTX
+// enabled.
+func (e *encoder) SetVoiceActivity(speaking bool) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.silent = e.dtx && !speaking
+	return nil
+}
+
 func (e *encoder) Controller() codec.EncoderController {
 	return e
 }
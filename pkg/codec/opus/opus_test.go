@@ -1,10 +1,12 @@
 package opus
 
 import (
+	"math"
 	"testing"
 
 	"github.com/pion/mediadevices/pkg/codec"
 	"github.com/pion/mediadevices/pkg/codec/internal/codectest"
+	"github.com/pion/mediadevices/pkg/io/audio"
 	"github.com/pion/mediadevices/pkg/prop"
 	"github.com/pion/mediadevices/pkg/wave"
 )
@@ -16,6 +18,13 @@ func TestShouldImplementBitRateControl(t *testing.T) {
 	}
 }
 
+func TestShouldImplementVoiceActivityControl(t *testing.T) {
+	e := &encoder{}
+	if _, ok := e.Controller().(codec.VoiceActivityController); !ok {
+		t.Error()
+	}
+}
+
 func TestShouldImplementKeyFrameControl(t *testing.T) {
 	t.SkipNow() // TODO: Implement key frame control
 
@@ -77,3 +86,69 @@ func TestEncoder(t *testing.T) {
 		)
 	})
 }
+
+func TestDTX(t *testing.T) {
+	testCases := map[string]struct {
+		dtx      bool
+		speaking bool
+		sent     bool
+	}{
+		"Speaking": {
+			dtx:      true,
+			speaking: true,
+			sent:     true,
+		},
+		"Silent": {
+			dtx:      true,
+			speaking: false,
+			sent:     false,
+		},
+		"Disabled": {
+			dtx:      false,
+			speaking: false,
+			sent:     true,
+		},
+	}
+	for name, c := range testCases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			var n int
+			r := audio.ReaderFunc(func() (wave.Audio, func(), error) {
+				chunk := wave.NewInt16Interleaved(wave.ChunkInfo{Len: 960, Channels: 1, SamplingRate: 48000})
+				for i := range chunk.Data {
+					chunk.Data[i] = int16(8000 * math.Sin(2*math.Pi*440*float64(n+i)/48000))
+				}
+				n += 960
+				return chunk, func() {}, nil
+			})
+			p, err := NewParams()
+			if err != nil {
+				t.Fatal(err)
+			}
+			p.DTX = c.dtx
+			e, err := p.BuildAudioEncoder(r, prop.Media{Audio: prop.Audio{SampleRate: 48000, ChannelCount: 1}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer e.Close()
+			if err := e.Controller().(codec.VoiceActivityController).SetVoiceActivity(c.speaking); err != nil {
+				t.Fatal(err)
+			}
+
+			// the encoder enters DTX after 200ms of silence
+			var empty int
+			for i := 0; i < 20; i++ {
+				data, _, err := e.Read()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(data) == 0 {
+					empty++
+				}
+			}
+			if sent := empty == 0; sent != c.sent {
+				t.Errorf("Expected all the frames to be sent: %v, got %d empty frames", c.sent, empty)
+			}
+		})
+	}
+}
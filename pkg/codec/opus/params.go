@@ -50,6 +50,11 @@ type Params struct {
 
 	// Expected latency of the codec.
 	Latency Latency
+
+	// DTX enables the discontinuous transmission: while nobody speaks, as reported by
+	// the track's voice activity, the encoder only returns a comfort noise frame every
+	// 400ms, and empty frames otherwise.
+	DTX bool
 }
 
 // NewParams returns default opus codec specific parameters.
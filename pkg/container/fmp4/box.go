@@ -0,0 +1,71 @@
+package fmp4
+
+import (
+	"encoding/binary"
+)
+
+// boxBuffer is a helper to serialize ISO BMFF boxes. Box sizes are patched
+// once the box contents have been written.
+type boxBuffer struct {
+	b []byte
+}
+
+func (b *boxBuffer) u8(v uint8) {
+	b.b = append(b.b, v)
+}
+
+func (b *boxBuffer) u16(v uint16) {
+	b.b = append(b.b, byte(v>>8), byte(v))
+}
+
+func (b *boxBuffer) u24(v uint32) {
+	b.b = append(b.b, byte(v>>16), byte(v>>8), byte(v))
+}
+
+func (b *boxBuffer) u32(v uint32) {
+	b.b = append(b.b, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+func (b *boxBuffer) u64(v uint64) {
+	b.u32(uint32(v >> 32))
+	b.u32(uint32(v))
+}
+
+func (b *boxBuffer) zeros(n int) {
+	for i := 0; i < n; i++ {
+		b.b = append(b.b, 0)
+	}
+}
+
+func (b *boxBuffer) bytes(v []byte) {
+	b.b = append(b.b, v...)
+}
+
+func (b *boxBuffer) str(v string) {
+	b.b = append(b.b, v...)
+}
+
+// box writes a box with the given type, contents are written by f.
+func (b *boxBuffer) box(typ string, f func()) {
+	offset := len(b.b)
+	b.u32(0)
+	b.str(typ)
+	f()
+	binary.BigEndian.PutUint32(b.b[offset:], uint32(len(b.b)-offset))
+}
+
+// fullBox writes a box with version and flags header, contents are written by f.
+func (b *boxBuffer) fullBox(typ string, version uint8, flags uint32, f func()) {
+	b.box(typ, func() {
+		b.u8(version)
+		b.u24(flags)
+		f()
+	})
+}
+
+// matrix writes the unity transformation matrix used by mvhd and tkhd.
+func (b *boxBuffer) matrix() {
+	for _, v := range []uint32{0x00010000, 0, 0, 0, 0x00010000, 0, 0, 0, 0x40000000} {
+		b.u32(v)
+	}
+}
@@ -0,0 +1,241 @@
+package fmp4
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/pion/mediadevices/pkg/container/h264"
+)
+
+var errInvalidVP9Frame = errors.New("fmp4: invalid vp9 frame")
+
+// avcConfig is the decoder configuration of an H.264 track, extracted from
+// the in-band parameter sets.
+type avcConfig struct {
+	sps, pps []byte
+	info     *h264.SPS
+}
+
+// writeAVCC writes AVCDecoderConfigurationRecord.
+// Reference: ISO/IEC 14496-15 5.3.3.1
+func (c *avcConfig) writeAVCC(b *boxBuffer) {
+	b.box("avcC", func() {
+		b.u8(1) // configurationVersion
+		b.u8(c.info.ProfileIDC)
+		b.u8(c.info.ConstraintFlags)
+		b.u8(c.info.LevelIDC)
+		b.u8(0xFC | 3) // lengthSizeMinusOne
+		b.u8(0xE0 | 1) // numOfSequenceParameterSets
+		b.u16(uint16(len(c.sps)))
+		b.bytes(c.sps)
+		b.u8(1) // numOfPictureParameterSets
+		b.u16(uint16(len(c.pps)))
+		b.bytes(c.pps)
+
+		switch c.info.ProfileIDC {
+		case 100, 110, 122, 144:
+			b.u8(0xFC | uint8(c.info.ChromaFormatIDC))
+			b.u8(0xF8 | uint8(c.info.BitDepthLumaMinus8))
+			b.u8(0xF8 | uint8(c.info.BitDepthChromaMinus8))
+			b.u8(0) // numOfSequenceParameterSetExt
+		}
+	})
+}
+
+// toAVCSample converts an Annex-B access unit to a length prefixed sample.
+// Parameter sets and access unit delimiters are moved out of band. changed
+// reports whether the parameter sets differ from the ones of c, they are only
+// stored in c when update is true.
+func (c *avcConfig) toAVCSample(frame []byte, update bool) (sample []byte, keyFrame, changed bool) {
+	for _, nalu := range h264.SplitNALUs(frame) {
+		switch h264.NALUType(nalu) {
+		case h264.NALUTypeSPS:
+			if bytes.Equal(nalu, c.sps) {
+				continue
+			}
+			if info, err := h264.ParseSPS(nalu); err == nil {
+				changed = true
+				if update {
+					c.sps = append(c.sps[:0], nalu...)
+					c.info = info
+				}
+			}
+			continue
+		case h264.NALUTypePPS:
+			if !bytes.Equal(nalu, c.pps) {
+				changed = true
+				if update {
+					c.pps = append(c.pps[:0], nalu...)
+				}
+			}
+			continue
+		case h264.NALUTypeAUD:
+			continue
+		case h264.NALUTypeIDR:
+			keyFrame = true
+		}
+		n := len(nalu)
+		sample = append(sample, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+		sample = append(sample, nalu...)
+	}
+	return sample, keyFrame, changed
+}
+
+func (c *avcConfig) ready() bool {
+	return c.info != nil && len(c.pps) > 0
+}
+
+// vp9Config is the decoder configuration of a VP9 track, extracted from the
+// uncompressed header of a key frame.
+type vp9Config struct {
+	profile           uint8
+	bitDepth          uint8
+	chromaSubsampling uint8
+	fullRange         uint8
+	matrix            uint8
+	width, height     int
+}
+
+// VP9 levels by maximum luma picture size.
+// Reference: https://www.webmproject.org/vp9/levels/
+var vp9Levels = []struct {
+	level   uint8
+	maxSize int
+}{
+	{10, 36864},
+	{11, 73728},
+	{20, 122880},
+	{21, 245760},
+	{30, 552960},
+	{31, 983040},
+	{40, 2228224},
+	{50, 8912896},
+	{60, 35651584},
+}
+
+func (c *vp9Config) level() uint8 {
+	size := c.width * c.height
+	for _, l := range vp9Levels {
+		if size <= l.maxSize {
+			return l.level
+		}
+	}
+	return 62
+}
+
+// writeVPCC writes VPCodecConfigurationRecord.
+// Reference: https://www.webmproject.org/vp9/mp4/
+func (c *vp9Config) writeVPCC(b *boxBuffer) {
+	b.fullBox("vpcC", 1, 0, func() {
+		b.u8(c.profile)
+		b.u8(c.level())
+		b.u8(c.bitDepth<<4 | c.chromaSubsampling<<1 | c.fullRange)
+		b.u8(2) // colourPrimaries: unspecified
+		b.u8(2) // transferCharacteristics: unspecified
+		b.u8(c.matrix)
+		b.u16(0) // codecInitializationDataSize
+	})
+}
+
+// parseVP9Frame parses the uncompressed header of a VP9 frame. The
+// configuration is returned only for key frames.
+// Reference: VP9 Bitstream Specification 6.2
+func parseVP9Frame(frame []byte) (keyFrame bool, config *vp9Config, err error) {
+	r := &bitReader{buf: frame}
+	if r.u(2) != 2 {
+		return false, nil, errInvalidVP9Frame
+	}
+	profileLow := r.u(1)
+	profile := uint8(r.u(1)<<1 | profileLow)
+	if profile == 3 {
+		r.u(1) // reserved_zero
+	}
+	if r.u(1) == 1 {
+		// show_existing_frame
+		return false, nil, r.err
+	}
+	if r.u(1) != 0 {
+		// Inter frame
+		return false, nil, r.err
+	}
+	r.u(2) // show_frame, error_resilient_mode
+	if r.u(24) != 0x498342 {
+		return false, nil, errInvalidVP9Frame
+	}
+
+	config = &vp9Config{profile: profile, bitDepth: 8}
+	if profile >= 2 {
+		config.bitDepth = 10
+		if r.u(1) == 1 {
+			config.bitDepth = 12
+		}
+	}
+
+	subsamplingX, subsamplingY := uint32(1), uint32(1)
+	colorSpace := r.u(3)
+	if colorSpace != 7 {
+		config.fullRange = uint8(r.u(1))
+		if profile == 1 || profile == 3 {
+			subsamplingX = r.u(1)
+			subsamplingY = r.u(1)
+			r.u(1) // reserved_zero
+		}
+	} else {
+		// sRGB
+		config.fullRange = 1
+		if profile == 1 || profile == 3 {
+			subsamplingX, subsamplingY = 0, 0
+			r.u(1) // reserved_zero
+		}
+	}
+
+	switch {
+	case subsamplingX == 1 && subsamplingY == 1:
+		config.chromaSubsampling = 1 // 4:2:0 colocated with luma
+	case subsamplingX == 1:
+		config.chromaSubsampling = 2 // 4:2:2
+	default:
+		config.chromaSubsampling = 3 // 4:4:4
+	}
+
+	switch colorSpace {
+	case 1:
+		config.matrix = 6 // BT.601
+	case 2:
+		config.matrix = 1 // BT.709
+	case 5:
+		config.matrix = 9 // BT.2020
+	case 7:
+		config.matrix = 0 // identity (RGB)
+	default:
+		config.matrix = 2 // unspecified
+	}
+
+	config.width = int(r.u(16)) + 1
+	config.height = int(r.u(16)) + 1
+	if r.err != nil {
+		return false, nil, r.err
+	}
+
+	return true, config, nil
+}
+
+type bitReader struct {
+	buf []byte
+	pos int
+	err error
+}
+
+func (r *bitReader) u(n int) uint32 {
+	var v uint32
+	for i := 0; i < n; i++ {
+		if r.pos >= len(r.buf)*8 {
+			r.err = errInvalidVP9Frame
+			return 0
+		}
+		bit := (r.buf[r.pos/8] >> (7 - uint(r.pos%8))) & 1
+		v = v<<1 | uint32(bit)
+		r.pos++
+	}
+	return v
+}
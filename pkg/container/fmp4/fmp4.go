@@ -0,0 +1,607 @@
+// Package fmp4 implements a fragmented MP4 (CMAF) writer for encoded media.
+//
+// The writer only appends to the underlying io.Writer, so it can be used to
+// record a file as well as to stream to an HTTP response or a MediaSource:
+//
+//	w, _ := fmp4.NewWriter(out, fmp4.Track{MimeType: webrtc.MimeTypeH264, ClockRate: 90000})
+//	for {
+//		buf, release, err := encodedReader.Read()
+//		if err != nil {
+//			break
+//		}
+//		w.WriteSample(0, buf.Data, buf.Samples)
+//		release()
+//	}
+//	w.Close()
+package fmp4
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/pion/webrtc/v3"
+)
+
+const (
+	// fragmentDuration is the duration of fragments in seconds when the stream has no video track.
+	fragmentDuration = 1
+	// defaultFrameRate is used to estimate the duration of the last video sample.
+	defaultFrameRate = 30
+	// defaultPreSkip is the encoder delay of libopus, in samples at 48 kHz.
+	defaultPreSkip = 312
+
+	sampleFlagsSync    = 0x02000000 // sample_depends_on=2
+	sampleFlagsNonSync = 0x01010000 // sample_depends_on=1, sample_is_non_sync_sample=1
+)
+
+var (
+	errNoTracks         = errors.New("fmp4: at least one track is required")
+	errInvalidTrack     = errors.New("fmp4: invalid track index")
+	errInvalidClockRate = errors.New("fmp4: clock rate must be greater than 0")
+	errClosed           = errors.New("fmp4: writer is closed")
+	errConfigChanged    = errors.New("fmp4: decoder configuration changed after the initialization segment")
+)
+
+// Track describes a single track of the stream.
+type Track struct {
+	// MimeType is the codec of the track. Supported values are
+	// webrtc.MimeTypeH264, webrtc.MimeTypeVP9 and webrtc.MimeTypeOpus.
+	MimeType string
+	// ClockRate is the number of sample ticks per second, which is used as
+	// the track time scale. It should match the codec's RTP clock rate.
+	ClockRate uint32
+	// ChannelCount is the number of audio channels. Only used by audio tracks.
+	ChannelCount int
+	// PreSkip is the number of samples, at 48 kHz, to discard from the decoder
+	// output at the start of an Opus track. When zero, the default libopus
+	// encoder delay is used.
+	PreSkip uint16
+}
+
+type codecType int
+
+const (
+	codecH264 codecType = iota + 1
+	codecVP9
+	codecOpus
+)
+
+type sample struct {
+	data     []byte
+	duration uint32
+	keyFrame bool
+}
+
+type track struct {
+	Track
+	id      uint32
+	codec   codecType
+	avc     avcConfig
+	vp9     *vp9Config
+	started bool
+
+	decodeTime   uint64
+	lastDuration uint32
+	pending      *sample
+	samples      []sample
+}
+
+func (t *track) isVideo() bool {
+	return t.codec != codecOpus
+}
+
+func (t *track) configured() bool {
+	switch t.codec {
+	case codecH264:
+		return t.avc.ready()
+	case codecVP9:
+		return t.vp9 != nil
+	}
+	return true
+}
+
+func (t *track) size() (int, int) {
+	switch t.codec {
+	case codecH264:
+		return t.avc.info.Width, t.avc.info.Height
+	case codecVP9:
+		return t.vp9.width, t.vp9.height
+	}
+	return 0, 0
+}
+
+func (t *track) fragmentDuration() uint64 {
+	var d uint64
+	for _, s := range t.samples {
+		d += uint64(s.duration)
+	}
+	return d
+}
+
+// end returns the decode time of the pending sample, in the track's clock rate.
+func (t *track) end() uint64 {
+	return t.decodeTime + t.fragmentDuration()
+}
+
+// Writer writes samples of one or more tracks as a fragmented MP4 stream.
+// The initialization segment is written as soon as the decoder configuration
+// of every track is known. Samples which are written before that are dropped,
+// and a sample changing the decoder configuration after that is rejected.
+// A new fragment is started on every key frame of the first video track, or
+// every second if there is no video track.
+type Writer struct {
+	w           io.Writer
+	mu          sync.Mutex
+	tracks      []*track
+	primary     *track
+	initialized bool
+	closed      bool
+	sequence    uint32
+}
+
+// NewWriter creates a new fragmented MP4 writer with the given tracks. Track
+// indexes given to WriteSample follow the order of tracks.
+func NewWriter(w io.Writer, tracks ...Track) (*Writer, error) {
+	if len(tracks) == 0 {
+		return nil, errNoTracks
+	}
+
+	writer := &Writer{w: w}
+	for i, t := range tracks {
+		if t.ClockRate == 0 {
+			return nil, errInvalidClockRate
+		}
+
+		tr := &track{Track: t, id: uint32(i + 1)}
+		switch strings.ToLower(t.MimeType) {
+		case strings.ToLower(webrtc.MimeTypeH264):
+			tr.codec = codecH264
+		case strings.ToLower(webrtc.MimeTypeVP9):
+			tr.codec = codecVP9
+		case strings.ToLower(webrtc.MimeTypeOpus):
+			tr.codec = codecOpus
+			if tr.ChannelCount == 0 {
+				tr.ChannelCount = 2
+			}
+			if tr.PreSkip == 0 {
+				tr.PreSkip = defaultPreSkip
+			}
+		default:
+			return nil, fmt.Errorf("fmp4: unsupported codec %s", t.MimeType)
+		}
+
+		if writer.primary == nil && tr.isVideo() {
+			writer.primary = tr
+		}
+		writer.tracks = append(writer.tracks, tr)
+	}
+	if writer.primary == nil {
+		writer.primary = writer.tracks[0]
+	}
+
+	return writer, nil
+}
+
+// WriteSample writes an encoded frame to the track at the given index. samples
+// is the number of ticks, in the track's clock rate, elapsed since the previous
+// sample of the same track, i.e. EncodedBuffer.Samples. The tracks share the
+// same timeline, which starts at the first sample written: the first sample of
+// the other tracks starts at the time of the last sample written. Empty samples,
+// e.g. access units only carrying parameter sets, aren't written, their duration
+// is added to the previous sample. frame is copied, so it can be reused right
+// after the call.
+func (w *Writer) WriteSample(trackIndex int, frame []byte, samples uint32) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return errClosed
+	}
+	if trackIndex < 0 || trackIndex >= len(w.tracks) {
+		return errInvalidTrack
+	}
+	t := w.tracks[trackIndex]
+
+	s := sample{keyFrame: true}
+	switch t.codec {
+	case codecH264:
+		var changed bool
+		s.data, s.keyFrame, changed = t.avc.toAVCSample(frame, !w.initialized)
+		if changed && w.initialized {
+			return errConfigChanged
+		}
+	case codecVP9:
+		keyFrame, config, err := parseVP9Frame(frame)
+		if err != nil {
+			return err
+		}
+		if config != nil {
+			switch {
+			case t.vp9 == nil:
+				t.vp9 = config
+			case *config != *t.vp9 && w.initialized:
+				return errConfigChanged
+			}
+		}
+		s.keyFrame = keyFrame
+		s.data = append([]byte(nil), frame...)
+	default:
+		s.data = append([]byte(nil), frame...)
+	}
+
+	if !w.initialized {
+		for _, other := range w.tracks {
+			if !other.configured() {
+				return nil
+			}
+		}
+		if err := w.writeInit(); err != nil {
+			return err
+		}
+		w.initialized = true
+	}
+
+	if len(s.data) == 0 {
+		if t.pending != nil {
+			t.pending.duration += samples
+		}
+		return nil
+	}
+
+	// Every video track has to start with a key frame
+	if !t.started {
+		if !s.keyFrame {
+			return nil
+		}
+		t.decodeTime = w.elapsed(t.ClockRate)
+		t.started = true
+	}
+
+	if t.pending != nil {
+		t.pending.duration += samples
+		t.lastDuration = t.pending.duration
+		t.samples = append(t.samples, *t.pending)
+		t.pending = nil
+	}
+
+	if t == w.primary {
+		var cut bool
+		if t.isVideo() {
+			cut = s.keyFrame
+		} else {
+			cut = t.fragmentDuration() >= uint64(t.ClockRate)*fragmentDuration
+		}
+		if cut {
+			if err := w.writeFragment(); err != nil {
+				return err
+			}
+		}
+	}
+
+	t.pending = &s
+	return nil
+}
+
+// elapsed returns the time of the last sample written since the first one, in
+// clockRate.
+func (w *Writer) elapsed(clockRate uint32) uint64 {
+	var elapsed uint64
+	for _, t := range w.tracks {
+		if !t.started {
+			continue
+		}
+		if d := t.end() * uint64(clockRate) / uint64(t.ClockRate); d > elapsed {
+			elapsed = d
+		}
+	}
+	return elapsed
+}
+
+// Close writes all the pending samples. It doesn't close the underlying writer.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+
+	if !w.initialized {
+		return nil
+	}
+
+	for _, t := range w.tracks {
+		if t.pending == nil {
+			continue
+		}
+		if t.pending.duration == 0 {
+			t.pending.duration = t.lastDuration
+		}
+		if t.pending.duration == 0 && t.isVideo() {
+			t.pending.duration = t.ClockRate / defaultFrameRate
+		}
+		t.samples = append(t.samples, *t.pending)
+		t.pending = nil
+	}
+
+	return w.writeFragment()
+}
+
+func (w *Writer) write(b []byte) error {
+	if _, err := w.w.Write(b); err != nil {
+		return err
+	}
+
+	// Push the data to the client if the writer is an http.ResponseWriter
+	if f, ok := w.w.(interface{ Flush() }); ok {
+		f.Flush()
+	}
+	return nil
+}
+
+// writeInit writes the initialization segment, ftyp and moov boxes.
+func (w *Writer) writeInit() error {
+	b := &boxBuffer{}
+
+	b.box("ftyp", func() {
+		b.str("iso5") // major_brand
+		b.u32(0x200)  // minor_version
+		// compatible_brands
+		b.str("iso5")
+		b.str("iso6")
+		b.str("mp41")
+	})
+
+	b.box("moov", func() {
+		b.fullBox("mvhd", 0, 0, func() {
+			b.u32(0)          // creation_time
+			b.u32(0)          // modification_time
+			b.u32(1000)       // timescale
+			b.u32(0)          // duration
+			b.u32(0x00010000) // rate
+			b.u16(0x0100)     // volume
+			b.zeros(10)       // reserved
+			b.matrix()
+			b.zeros(24) // pre_defined
+			// next_track_ID
+			b.u32(uint32(len(w.tracks) + 1))
+		})
+
+		for _, t := range w.tracks {
+			w.writeTrak(b, t)
+		}
+
+		b.box("mvex", func() {
+			for _, t := range w.tracks {
+				b.fullBox("trex", 0, 0, func() {
+					b.u32(t.id)
+					b.u32(1) // default_sample_description_index
+					b.u32(0) // default_sample_duration
+					b.u32(0) // default_sample_size
+					b.u32(0) // default_sample_flags
+				})
+			}
+		})
+	})
+
+	return w.write(b.b)
+}
+
+func (w *Writer) writeTrak(b *boxBuffer, t *track) {
+	width, height := t.size()
+
+	b.box("trak", func() {
+		// flags: track_enabled | track_in_movie
+		b.fullBox("tkhd", 0, 3, func() {
+			b.u32(0) // creation_time
+			b.u32(0) // modification_time
+			b.u32(t.id)
+			b.u32(0)   // reserved
+			b.u32(0)   // duration
+			b.zeros(8) // reserved
+			b.u16(0)   // layer
+			b.u16(0)   // alternate_group
+			// volume
+			if t.isVideo() {
+				b.u16(0)
+			} else {
+				b.u16(0x0100)
+			}
+			b.u16(0) // reserved
+			b.matrix()
+			b.u32(uint32(width) << 16)
+			b.u32(uint32(height) << 16)
+		})
+
+		b.box("mdia", func() {
+			b.fullBox("mdhd", 0, 0, func() {
+				b.u32(0) // creation_time
+				b.u32(0) // modification_time
+				b.u32(t.ClockRate)
+				b.u32(0)      // duration
+				b.u16(0x55C4) // language: und
+				b.u16(0)      // pre_defined
+			})
+
+			b.fullBox("hdlr", 0, 0, func() {
+				b.u32(0) // pre_defined
+				if t.isVideo() {
+					b.str("vide")
+				} else {
+					b.str("soun")
+				}
+				b.zeros(12) // reserved
+				if t.isVideo() {
+					b.str("VideoHandler\x00")
+				} else {
+					b.str("SoundHandler\x00")
+				}
+			})
+
+			b.box("minf", func() {
+				if t.isVideo() {
+					b.fullBox("vmhd", 0, 1, func() {
+						b.zeros(8) // graphicsmode, opcolor
+					})
+				} else {
+					b.fullBox("smhd", 0, 0, func() {
+						b.zeros(4) // balance, reserved
+					})
+				}
+
+				b.box("dinf", func() {
+					b.fullBox("dref", 0, 0, func() {
+						b.u32(1) // entry_count
+						b.fullBox("url ", 0, 1, func() {})
+					})
+				})
+
+				b.box("stbl", func() {
+					b.fullBox("stsd", 0, 0, func() {
+						b.u32(1) // entry_count
+						w.writeSampleEntry(b, t, width, height)
+					})
+					b.fullBox("stts", 0, 0, func() { b.u32(0) })
+					b.fullBox("stsc", 0, 0, func() { b.u32(0) })
+					b.fullBox("stsz", 0, 0, func() {
+						b.u32(0) // sample_size
+						b.u32(0) // sample_count
+					})
+					b.fullBox("stco", 0, 0, func() { b.u32(0) })
+				})
+			})
+		})
+	})
+}
+
+func (w *Writer) writeSampleEntry(b *boxBuffer, t *track, width, height int) {
+	visualSampleEntry := func(typ string, config func()) {
+		b.box(typ, func() {
+			b.zeros(6)  // reserved
+			b.u16(1)    // data_reference_index
+			b.zeros(16) // pre_defined, reserved
+			b.u16(uint16(width))
+			b.u16(uint16(height))
+			b.u32(0x00480000) // horizresolution: 72 dpi
+			b.u32(0x00480000) // vertresolution: 72 dpi
+			b.u32(0)          // reserved
+			b.u16(1)          // frame_count
+			b.zeros(32)       // compressorname
+			b.u16(0x0018)     // depth
+			b.u16(0xFFFF)     // pre_defined
+			config()
+		})
+	}
+
+	switch t.codec {
+	case codecH264:
+		visualSampleEntry("avc1", func() { t.avc.writeAVCC(b) })
+	case codecVP9:
+		visualSampleEntry("vp09", func() { t.vp9.writeVPCC(b) })
+	case codecOpus:
+		b.box("Opus", func() {
+			b.zeros(6) // reserved
+			b.u16(1)   // data_reference_index
+			b.zeros(8) // reserved
+			b.u16(uint16(t.ChannelCount))
+			b.u16(16) // samplesize
+			b.u16(0)  // pre_defined
+			b.u16(0)  // reserved
+			b.u32(48000 << 16)
+
+			// Reference: https://opus-codec.org/docs/opus_in_isobmff.html
+			b.box("dOps", func() {
+				b.u8(0) // Version
+				b.u8(uint8(t.ChannelCount))
+				b.u16(t.PreSkip)
+				b.u32(48000) // InputSampleRate
+				b.u16(0)     // OutputGain
+				b.u8(0)      // ChannelMappingFamily
+			})
+		})
+	}
+}
+
+// writeFragment writes all the queued samples as a moof and mdat pair.
+func (w *Writer) writeFragment() error {
+	var tracks []*track
+	var mdatSize int
+	for _, t := range w.tracks {
+		if len(t.samples) == 0 {
+			continue
+		}
+		tracks = append(tracks, t)
+		for _, s := range t.samples {
+			mdatSize += len(s.data)
+		}
+	}
+	if len(tracks) == 0 {
+		return nil
+	}
+
+	w.sequence++
+	b := &boxBuffer{}
+	var dataOffsetPos []int
+
+	b.box("moof", func() {
+		b.fullBox("mfhd", 0, 0, func() {
+			b.u32(w.sequence)
+		})
+
+		for _, t := range tracks {
+			b.box("traf", func() {
+				// flags: default-base-is-moof
+				b.fullBox("tfhd", 0, 0x020000, func() {
+					b.u32(t.id)
+				})
+				b.fullBox("tfdt", 1, 0, func() {
+					b.u64(t.decodeTime)
+				})
+				// flags: data-offset, sample-duration, sample-size, sample-flags
+				b.fullBox("trun", 0, 0x000701, func() {
+					b.u32(uint32(len(t.samples)))
+					dataOffsetPos = append(dataOffsetPos, len(b.b))
+					b.u32(0) // data_offset
+					for _, s := range t.samples {
+						b.u32(s.duration)
+						b.u32(uint32(len(s.data)))
+						if s.keyFrame {
+							b.u32(sampleFlagsSync)
+						} else {
+							b.u32(sampleFlagsNonSync)
+						}
+					}
+				})
+			})
+		}
+	})
+
+	// Data offsets are relative to the beginning of moof
+	offset := len(b.b) + 8
+	for i, t := range tracks {
+		pos := dataOffsetPos[i]
+		b.b[pos] = byte(offset >> 24)
+		b.b[pos+1] = byte(offset >> 16)
+		b.b[pos+2] = byte(offset >> 8)
+		b.b[pos+3] = byte(offset)
+		for _, s := range t.samples {
+			offset += len(s.data)
+		}
+	}
+
+	b.u32(uint32(mdatSize + 8))
+	b.str("mdat")
+	for _, t := range tracks {
+		for _, s := range t.samples {
+			b.bytes(s.data)
+		}
+		t.decodeTime += t.fragmentDuration()
+		t.samples = t.samples[:0]
+	}
+
+	return w.write(b.b)
+}
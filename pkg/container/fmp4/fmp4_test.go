@@ -0,0 +1,415 @@
+package fmp4
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/pion/webrtc/v3"
+)
+
+var (
+	// 640x480 baseline profile SPS
+	testSPS = []byte{0x67, 0x42, 0xc0, 0x1e, 0xda, 0x02, 0x80, 0xf6, 0x40}
+	testPPS = []byte{0x68, 0xce, 0x38, 0x80}
+)
+
+func testH264Frame(keyFrame bool) []byte {
+	var frame []byte
+	if keyFrame {
+		frame = append(frame, 0, 0, 0, 1)
+		frame = append(frame, testSPS...)
+		frame = append(frame, 0, 0, 0, 1)
+		frame = append(frame, testPPS...)
+		frame = append(frame, 0, 0, 0, 1, 0x65, 0x88, 0x84)
+	} else {
+		frame = append(frame, 0, 0, 0, 1, 0x41, 0x9a, 0x02)
+	}
+	return frame
+}
+
+type testBox struct {
+	typ     string
+	payload []byte
+}
+
+func parseBoxes(t *testing.T, b []byte) []testBox {
+	var boxes []testBox
+	for len(b) > 0 {
+		if len(b) < 8 {
+			t.Fatalf("Truncated box header: %v", b)
+		}
+		size := int(binary.BigEndian.Uint32(b))
+		if size < 8 || size > len(b) {
+			t.Fatalf("Invalid box size %d, remaining %d", size, len(b))
+		}
+		boxes = append(boxes, testBox{typ: string(b[4:8]), payload: b[8:size]})
+		b = b[size:]
+	}
+	return boxes
+}
+
+func findBox(t *testing.T, b []byte, path ...string) []byte {
+	for _, typ := range path {
+		var found bool
+		for _, box := range parseBoxes(t, b) {
+			if box.typ == typ {
+				b = box.payload
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Fatalf("Box %s not found", typ)
+		}
+	}
+	return b
+}
+
+func boxTypes(boxes []testBox) []string {
+	var types []string
+	for _, box := range boxes {
+		types = append(types, box.typ)
+	}
+	return types
+}
+
+func TestWriterH264AndOpus(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf,
+		Track{MimeType: webrtc.MimeTypeH264, ClockRate: 90000},
+		Track{MimeType: webrtc.MimeTypeOpus, ClockRate: 48000, ChannelCount: 2},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Samples before the first key frame are dropped
+	if err := w.WriteSample(0, testH264Frame(false), 3000); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WriteSample(1, []byte{0xfc, 1}, 960); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != 0 {
+		t.Fatal("Expected nothing to be written before the first key frame")
+	}
+
+	// 2 GOPs of 3 frames, 2 audio packets per video frame
+	for i := 0; i < 6; i++ {
+		if err := w.WriteSample(0, testH264Frame(i%3 == 0), 3000); err != nil {
+			t.Fatal(err)
+		}
+		for j := 0; j < 2; j++ {
+			if err := w.WriteSample(1, []byte{0xfc, byte(i), byte(j)}, 960); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.WriteSample(0, testH264Frame(true), 3000); err != errClosed {
+		t.Errorf("Expected %v after close, got %v", errClosed, err)
+	}
+
+	boxes := parseBoxes(t, buf.Bytes())
+	expectedTypes := []string{"ftyp", "moov", "moof", "mdat", "moof", "mdat"}
+	types := boxTypes(boxes)
+	if len(types) != len(expectedTypes) {
+		t.Fatalf("Expected boxes %v, got %v", expectedTypes, types)
+	}
+	for i := range types {
+		if types[i] != expectedTypes[i] {
+			t.Fatalf("Expected boxes %v, got %v", expectedTypes, types)
+		}
+	}
+
+	moov := boxes[1].payload
+	avcC := findBox(t, moov, "trak", "mdia", "minf", "stbl", "stsd")[8+8+78:]
+	avcC = findBox(t, avcC, "avcC")
+	if avcC[1] != 66 || avcC[3] != 30 {
+		t.Errorf("Unexpected avcC profile/level: %v", avcC[:4])
+	}
+	tkhd := findBox(t, moov, "trak", "tkhd")
+	if width, height := binary.BigEndian.Uint32(tkhd[76:])>>16, binary.BigEndian.Uint32(tkhd[80:])>>16; width != 640 || height != 480 {
+		t.Errorf("Expected 640x480, got %dx%d", width, height)
+	}
+
+	// First fragment contains the first GOP, and the audio written during it
+	type trafInfo struct {
+		trackID    uint32
+		decodeTime uint64
+		count      uint32
+		durations  []uint32
+	}
+	parseMoof := func(moof []byte) []trafInfo {
+		var infos []trafInfo
+		for _, box := range parseBoxes(t, moof) {
+			if box.typ != "traf" {
+				continue
+			}
+			tfhd := findBox(t, box.payload, "tfhd")
+			tfdt := findBox(t, box.payload, "tfdt")
+			trun := findBox(t, box.payload, "trun")
+			info := trafInfo{
+				trackID:    binary.BigEndian.Uint32(tfhd[4:]),
+				decodeTime: binary.BigEndian.Uint64(tfdt[4:]),
+				count:      binary.BigEndian.Uint32(trun[4:]),
+			}
+			for i := uint32(0); i < info.count; i++ {
+				info.durations = append(info.durations, binary.BigEndian.Uint32(trun[12+i*12:]))
+			}
+			infos = append(infos, info)
+		}
+		return infos
+	}
+
+	first := parseMoof(boxes[2].payload)
+	second := parseMoof(boxes[4].payload)
+	if len(first) != 2 || len(second) != 2 {
+		t.Fatalf("Expected 2 tracks in each fragment, got %d and %d", len(first), len(second))
+	}
+
+	if first[0].trackID != 1 || first[0].count != 3 || first[0].decodeTime != 0 {
+		t.Errorf("Unexpected first video fragment: %+v", first[0])
+	}
+	if second[0].count != 3 || second[0].decodeTime != 9000 {
+		t.Errorf("Unexpected second video fragment: %+v", second[0])
+	}
+	for _, d := range append(first[0].durations, second[0].durations...) {
+		if d != 3000 {
+			t.Errorf("Expected video sample duration 3000, got %d", d)
+		}
+	}
+
+	audioSamples := first[1].count + second[1].count
+	if audioSamples != 12 {
+		t.Errorf("Expected 12 audio samples, got %d", audioSamples)
+	}
+	if second[1].decodeTime != uint64(first[1].count)*960 {
+		t.Errorf("Expected audio decode time %d, got %d", first[1].count*960, second[1].decodeTime)
+	}
+
+	// Samples are stored length prefixed without parameter sets
+	mdat := boxes[3].payload
+	if n := binary.BigEndian.Uint32(mdat); n != 3 || mdat[4] != 0x65 {
+		t.Errorf("Expected length prefixed IDR slice, got %v", mdat[:8])
+	}
+}
+
+func TestWriterSharedTimeline(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf,
+		Track{MimeType: webrtc.MimeTypeH264, ClockRate: 90000},
+		Track{MimeType: webrtc.MimeTypeOpus, ClockRate: 48000, ChannelCount: 2},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parameterSets := append(append([]byte{0, 0, 0, 1}, testSPS...), append([]byte{0, 0, 0, 1}, testPPS...)...)
+
+	// The parameter sets initialize the stream, the audio starts before the
+	// first key frame
+	if err := w.WriteSample(0, parameterSets, 3000); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		if err := w.WriteSample(1, []byte{0xfc, byte(i)}, 960); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The empty access unit isn't written, and its duration is kept
+	for i, frame := range [][]byte{testH264Frame(true), parameterSets, testH264Frame(false), testH264Frame(false)} {
+		duration := uint32(3000)
+		if i > 0 {
+			duration = 1500
+		}
+		if err := w.WriteSample(0, frame, duration); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var trafs [][]byte
+	for _, box := range parseBoxes(t, buf.Bytes()) {
+		if box.typ != "moof" {
+			continue
+		}
+		for _, traf := range parseBoxes(t, box.payload) {
+			if traf.typ == "traf" {
+				trafs = append(trafs, traf.payload)
+			}
+		}
+	}
+	// The audio written before the key frame, then the video and the last
+	// audio sample
+	if len(trafs) != 3 {
+		t.Fatalf("Expected 3 track fragments, got %d", len(trafs))
+	}
+	testCases := map[string]struct {
+		traf       []byte
+		trackID    uint32
+		decodeTime uint64
+		durations  []uint32
+	}{
+		"Audio": {traf: trafs[0], trackID: 2, decodeTime: 0, durations: []uint32{960, 960, 960, 960}},
+		// The video starts with the fifth audio sample, at 4*960/48000 s
+		"Video":     {traf: trafs[1], trackID: 1, decodeTime: 7200, durations: []uint32{3000, 1500, 1500}},
+		"LastAudio": {traf: trafs[2], trackID: 2, decodeTime: 3840, durations: []uint32{960}},
+	}
+	for name, c := range testCases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			if id := binary.BigEndian.Uint32(findBox(t, c.traf, "tfhd")[4:]); id != c.trackID {
+				t.Errorf("Expected track %d, got %d", c.trackID, id)
+			}
+			if d := binary.BigEndian.Uint64(findBox(t, c.traf, "tfdt")[4:]); d != c.decodeTime {
+				t.Errorf("Expected decode time %d, got %d", c.decodeTime, d)
+			}
+			trun := findBox(t, c.traf, "trun")
+			var durations []uint32
+			for i := uint32(0); i < binary.BigEndian.Uint32(trun[4:]); i++ {
+				durations = append(durations, binary.BigEndian.Uint32(trun[12+i*12:]))
+			}
+			if !reflect.DeepEqual(durations, c.durations) {
+				t.Errorf("Expected durations %v, got %v", c.durations, durations)
+			}
+		})
+	}
+}
+
+func TestWriterVP9(t *testing.T) {
+	// Key frame header: profile 0, BT.709, 320x240
+	keyFrame := []byte{0x82, 0x49, 0x83, 0x42, 0x40, 0x13, 0xf0, 0x0e, 0xf0}
+	interFrame := []byte{0x86, 0x00}
+
+	rec := httptest.NewRecorder()
+	w, err := NewWriter(rec, Track{MimeType: webrtc.MimeTypeVP9, ClockRate: 90000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, frame := range [][]byte{interFrame, keyFrame, interFrame, keyFrame} {
+		if err := w.WriteSample(0, frame, 3000); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !rec.Flushed {
+		t.Error("Expected http response to be flushed")
+	}
+
+	boxes := parseBoxes(t, rec.Body.Bytes())
+	if types := boxTypes(boxes); len(types) != 6 {
+		t.Fatalf("Expected 2 fragments, got %v", types)
+	}
+	vpcC := findBox(t, boxes[1].payload, "trak", "mdia", "minf", "stbl", "stsd")[8+8+78:]
+	vpcC = findBox(t, vpcC, "vpcC")
+	if profile, level := vpcC[4], vpcC[5]; profile != 0 || level != 20 {
+		t.Errorf("Unexpected vpcC profile/level: %d/%d", profile, level)
+	}
+	if bitDepth := vpcC[6] >> 4; bitDepth != 8 {
+		t.Errorf("Expected bit depth 8, got %d", bitDepth)
+	}
+	if matrix := vpcC[9]; matrix != 1 {
+		t.Errorf("Expected BT.709 matrix, got %d", matrix)
+	}
+}
+
+func TestWriterOpusPreSkip(t *testing.T) {
+	testCases := map[string]struct {
+		preSkip  uint16
+		expected uint16
+	}{
+		"Default": {
+			expected: defaultPreSkip,
+		},
+		"Custom": {
+			preSkip:  120,
+			expected: 120,
+		},
+	}
+	for name, c := range testCases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w, err := NewWriter(&buf, Track{MimeType: webrtc.MimeTypeOpus, ClockRate: 48000, PreSkip: c.preSkip})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := w.WriteSample(0, []byte{0xfc, 0}, 960); err != nil {
+				t.Fatal(err)
+			}
+
+			moov := parseBoxes(t, buf.Bytes())[1].payload
+			opus := findBox(t, findBox(t, moov, "trak", "mdia", "minf", "stbl", "stsd")[8:], "Opus")
+			dOps := findBox(t, opus[28:], "dOps")
+			if preSkip := binary.BigEndian.Uint16(dOps[2:]); preSkip != c.expected {
+				t.Errorf("Expected pre-skip %d, got %d", c.expected, preSkip)
+			}
+		})
+	}
+}
+
+func TestWriterConfigChanged(t *testing.T) {
+	testCases := map[string]struct {
+		sps, pps []byte
+	}{
+		"SPS": {
+			// 640x480 baseline profile SPS, level 3.1
+			sps: []byte{0x67, 0x42, 0xc0, 0x1f, 0xda, 0x02, 0x80, 0xf6, 0x40},
+			pps: testPPS,
+		},
+		"PPS": {
+			sps: testSPS,
+			pps: []byte{0x68, 0xce, 0x3c, 0x80},
+		},
+	}
+	for name, c := range testCases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			w, err := NewWriter(&bytes.Buffer{}, Track{MimeType: webrtc.MimeTypeH264, ClockRate: 90000})
+			if err != nil {
+				t.Fatal(err)
+			}
+			// The same parameter sets can be repeated
+			for i := 0; i < 2; i++ {
+				if err := w.WriteSample(0, testH264Frame(true), 3000); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			var frame []byte
+			frame = append(frame, 0, 0, 0, 1)
+			frame = append(frame, c.sps...)
+			frame = append(frame, 0, 0, 0, 1)
+			frame = append(frame, c.pps...)
+			frame = append(frame, 0, 0, 0, 1, 0x65, 0x88, 0x84)
+			if err := w.WriteSample(0, frame, 3000); err != errConfigChanged {
+				t.Errorf("Expected %v, got %v", errConfigChanged, err)
+			}
+			if err := w.WriteSample(0, frame, 3000); err != errConfigChanged {
+				t.Errorf("Expected %v on the next sample, got %v", errConfigChanged, err)
+			}
+		})
+	}
+}
+
+func TestNewWriterErrors(t *testing.T) {
+	if _, err := NewWriter(&bytes.Buffer{}); err != errNoTracks {
+		t.Errorf("Expected %v, got %v", errNoTracks, err)
+	}
+	if _, err := NewWriter(&bytes.Buffer{}, Track{MimeType: webrtc.MimeTypeVP8, ClockRate: 90000}); err == nil {
+		t.Error("Expected unsupported codec error")
+	}
+	if _, err := NewWriter(&bytes.Buffer{}, Track{MimeType: webrtc.MimeTypeH264}); err != errInvalidClockRate {
+		t.Errorf("Expected %v, got %v", errInvalidClockRate, err)
+	}
+}
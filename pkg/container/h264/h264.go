@@ -0,0 +1,268 @@
+// Package h264 implements parsing helpers for H.264 Annex-B byte streams.
+package h264
+
+import (
+	"errors"
+)
+
+// NAL unit types used by the containers.
+const (
+	NALUTypeSlice  = 1
+	NALUTypeIDR    = 5
+	NALUTypeSEI    = 6
+	NALUTypeSPS    = 7
+	NALUTypePPS    = 8
+	NALUTypeAUD    = 9
+	NALUTypeFiller = 12
+	naluTypeMask   = 0x1F
+	minSPSLen      = 4
+)
+
+var (
+	errInvalidSPS = errors.New("h264: invalid sequence parameter set")
+)
+
+// NALUType returns the type of the given NAL unit.
+func NALUType(nalu []byte) int {
+	if len(nalu) == 0 {
+		return 0
+	}
+	return int(nalu[0] & naluTypeMask)
+}
+
+// SplitNALUs splits an Annex-B byte stream into NAL units. The returned
+// slices share the memory with b and don't contain the start codes.
+func SplitNALUs(b []byte) [][]byte {
+	var nalus [][]byte
+
+	start := -1
+	zeros := 0
+	for i := 0; i < len(b); i++ {
+		switch {
+		case b[i] == 0:
+			zeros++
+			continue
+		case b[i] == 1 && zeros >= 2:
+			if start >= 0 {
+				nalus = appendNALU(nalus, b[start:i-zeros])
+			}
+			start = i + 1
+		}
+		zeros = 0
+	}
+
+	if start >= 0 {
+		nalus = appendNALU(nalus, b[start:])
+	} else if len(b) > 0 {
+		// Not an Annex-B stream, treat the whole buffer as a single NAL unit
+		nalus = appendNALU(nalus, b)
+	}
+
+	return nalus
+}
+
+func appendNALU(nalus [][]byte, nalu []byte) [][]byte {
+	// Trailing zero bytes belong to the next start code
+	for len(nalu) > 0 && nalu[len(nalu)-1] == 0 {
+		nalu = nalu[:len(nalu)-1]
+	}
+	if len(nalu) == 0 {
+		return nalus
+	}
+	return append(nalus, nalu)
+}
+
+// IsKeyFrame reports whether the given Annex-B access unit contains an IDR slice.
+func IsKeyFrame(b []byte) bool {
+	for _, nalu := range SplitNALUs(b) {
+		if NALUType(nalu) == NALUTypeIDR {
+			return true
+		}
+	}
+	return false
+}
+
+// SPS contains the fields of a sequence parameter set that are needed to
+// describe the stream in a container.
+type SPS struct {
+	ProfileIDC           uint8
+	ConstraintFlags      uint8
+	LevelIDC             uint8
+	ChromaFormatIDC      uint32
+	BitDepthLumaMinus8   uint32
+	BitDepthChromaMinus8 uint32
+	Width, Height        int
+}
+
+// ParseSPS parses a sequence parameter set NAL unit, including its NAL header.
+func ParseSPS(nalu []byte) (*SPS, error) {
+	if len(nalu) < minSPSLen || NALUType(nalu) != NALUTypeSPS {
+		return nil, errInvalidSPS
+	}
+
+	sps := &SPS{
+		ProfileIDC:      nalu[1],
+		ConstraintFlags: nalu[2],
+		LevelIDC:        nalu[3],
+		ChromaFormatIDC: 1,
+	}
+
+	r := &bitReader{buf: removeEmulationPrevention(nalu[4:])}
+	r.ue() // seq_parameter_set_id
+
+	switch sps.ProfileIDC {
+	case 100, 110, 122, 244, 44, 83, 86, 118, 128, 138, 139, 134, 135:
+		sps.ChromaFormatIDC = r.ue()
+		if sps.ChromaFormatIDC == 3 {
+			r.u(1) // separate_colour_plane_flag
+		}
+		sps.BitDepthLumaMinus8 = r.ue()
+		sps.BitDepthChromaMinus8 = r.ue()
+		r.u(1) // qpprime_y_zero_transform_bypass_flag
+		// seq_scaling_matrix_present_flag
+		if r.u(1) == 1 {
+			n := 8
+			if sps.ChromaFormatIDC == 3 {
+				n = 12
+			}
+			for i := 0; i < n; i++ {
+				if r.u(1) == 0 {
+					continue
+				}
+				size := 16
+				if i >= 6 {
+					size = 64
+				}
+				r.skipScalingList(size)
+			}
+		}
+	}
+
+	r.ue() // log2_max_frame_num_minus4
+	// pic_order_cnt_type
+	switch r.ue() {
+	case 0:
+		r.ue() // log2_max_pic_order_cnt_lsb_minus4
+	case 1:
+		r.u(1) // delta_pic_order_always_zero_flag
+		r.se() // offset_for_non_ref_pic
+		r.se() // offset_for_top_to_bottom_field
+		n := r.ue()
+		for i := uint32(0); i < n && r.err == nil; i++ {
+			r.se() // offset_for_ref_frame
+		}
+	}
+	r.ue() // max_num_ref_frames
+	r.u(1) // gaps_in_frame_num_value_allowed_flag
+
+	widthInMbs := int(r.ue()) + 1
+	heightInMapUnits := int(r.ue()) + 1
+	frameMbsOnly := int(r.u(1))
+	if frameMbsOnly == 0 {
+		r.u(1) // mb_adaptive_frame_field_flag
+	}
+	r.u(1) // direct_8x8_inference_flag
+
+	var cropLeft, cropRight, cropTop, cropBottom int
+	if r.u(1) == 1 {
+		cropLeft = int(r.ue())
+		cropRight = int(r.ue())
+		cropTop = int(r.ue())
+		cropBottom = int(r.ue())
+	}
+
+	if r.err != nil {
+		return nil, errInvalidSPS
+	}
+
+	cropUnitX, cropUnitY := 1, 2-frameMbsOnly
+	switch sps.ChromaFormatIDC {
+	case 1:
+		cropUnitX, cropUnitY = 2, 2*(2-frameMbsOnly)
+	case 2:
+		cropUnitX = 2
+	}
+
+	sps.Width = widthInMbs*16 - cropUnitX*(cropLeft+cropRight)
+	sps.Height = (2-frameMbsOnly)*heightInMapUnits*16 - cropUnitY*(cropTop+cropBottom)
+	if sps.Width <= 0 || sps.Height <= 0 {
+		return nil, errInvalidSPS
+	}
+
+	return sps, nil
+}
+
+// removeEmulationPrevention converts NAL unit payload to RBSP by removing
+// emulation_prevention_three_byte.
+func removeEmulationPrevention(b []byte) []byte {
+	rbsp := make([]byte, 0, len(b))
+	zeros := 0
+	for _, v := range b {
+		if zeros >= 2 && v == 3 {
+			zeros = 0
+			continue
+		}
+		if v == 0 {
+			zeros++
+		} else {
+			zeros = 0
+		}
+		rbsp = append(rbsp, v)
+	}
+	return rbsp
+}
+
+type bitReader struct {
+	buf []byte
+	pos int
+	err error
+}
+
+var errBitReaderEOF = errors.New("h264: unexpected end of bitstream")
+
+func (r *bitReader) u(n int) uint32 {
+	var v uint32
+	for i := 0; i < n; i++ {
+		if r.pos >= len(r.buf)*8 {
+			r.err = errBitReaderEOF
+			return 0
+		}
+		bit := (r.buf[r.pos/8] >> (7 - uint(r.pos%8))) & 1
+		v = v<<1 | uint32(bit)
+		r.pos++
+	}
+	return v
+}
+
+func (r *bitReader) ue() uint32 {
+	zeros := 0
+	for r.u(1) == 0 {
+		if r.err != nil || zeros >= 32 {
+			r.err = errBitReaderEOF
+			return 0
+		}
+		zeros++
+	}
+	return (1<<uint(zeros) - 1) + r.u(zeros)
+}
+
+func (r *bitReader) se() int32 {
+	v := r.ue()
+	if v%2 == 0 {
+		return -int32(v / 2)
+	}
+	return int32((v + 1) / 2)
+}
+
+func (r *bitReader) skipScalingList(size int) {
+	last, next := int32(8), int32(8)
+	for i := 0; i < size && r.err == nil; i++ {
+		if next != 0 {
+			delta := r.se()
+			next = (last + delta + 256) % 256
+		}
+		if next != 0 {
+			last = next
+		}
+	}
+}
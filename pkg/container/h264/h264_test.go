@@ -0,0 +1,107 @@
+package h264
+
+import (
+	"bytes"
+	"testing"
+)
+
+// 640x480 baseline profile SPS
+var testSPS = []byte{0x67, 0x42, 0xc0, 0x1e, 0xda, 0x02, 0x80, 0xf6, 0x40}
+
+// 1920x1080 high profile SPS with frame cropping
+var testSPSHigh = []byte{0x67, 0x64, 0x00, 0x28, 0xac, 0xd9, 0x40, 0x78, 0x02, 0x27, 0xe5, 0x40}
+
+func TestSplitNALUs(t *testing.T) {
+	testCases := map[string]struct {
+		input    []byte
+		expected [][]byte
+	}{
+		"ThreeByteStartCode": {
+			input:    []byte{0, 0, 1, 0x67, 1, 2, 0, 0, 1, 0x68, 3},
+			expected: [][]byte{{0x67, 1, 2}, {0x68, 3}},
+		},
+		"FourByteStartCode": {
+			input:    []byte{0, 0, 0, 1, 0x65, 1, 0, 0, 0, 1, 0x41, 2, 0},
+			expected: [][]byte{{0x65, 1}, {0x41, 2}},
+		},
+		"NoStartCode": {
+			input:    []byte{0x65, 1, 2},
+			expected: [][]byte{{0x65, 1, 2}},
+		},
+		"Empty": {
+			input:    []byte{},
+			expected: nil,
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			nalus := SplitNALUs(testCase.input)
+			if len(nalus) != len(testCase.expected) {
+				t.Fatalf("Expected %d NAL units, got %d", len(testCase.expected), len(nalus))
+			}
+			for i := range nalus {
+				if !bytes.Equal(nalus[i], testCase.expected[i]) {
+					t.Errorf("Expected NAL unit %d to be %v, got %v", i, testCase.expected[i], nalus[i])
+				}
+			}
+		})
+	}
+}
+
+func TestIsKeyFrame(t *testing.T) {
+	if !IsKeyFrame([]byte{0, 0, 0, 1, 0x67, 1, 0, 0, 0, 1, 0x68, 1, 0, 0, 0, 1, 0x65, 1}) {
+		t.Error("Expected IDR access unit to be a key frame")
+	}
+	if IsKeyFrame([]byte{0, 0, 0, 1, 0x41, 1}) {
+		t.Error("Expected non-IDR access unit not to be a key frame")
+	}
+}
+
+func TestParseSPS(t *testing.T) {
+	testCases := map[string]struct {
+		sps           []byte
+		profile       uint8
+		level         uint8
+		width, height int
+	}{
+		"Baseline": {
+			sps:     testSPS,
+			profile: 66,
+			level:   30,
+			width:   640,
+			height:  480,
+		},
+		"High": {
+			sps:     testSPSHigh,
+			profile: 100,
+			level:   40,
+			width:   1920,
+			height:  1080,
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			sps, err := ParseSPS(testCase.sps)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sps.ProfileIDC != testCase.profile {
+				t.Errorf("Expected profile %d, got %d", testCase.profile, sps.ProfileIDC)
+			}
+			if sps.LevelIDC != testCase.level {
+				t.Errorf("Expected level %d, got %d", testCase.level, sps.LevelIDC)
+			}
+			if sps.Width != testCase.width || sps.Height != testCase.height {
+				t.Errorf("Expected %dx%d, got %dx%d", testCase.width, testCase.height, sps.Width, sps.Height)
+			}
+		})
+	}
+
+	if _, err := ParseSPS([]byte{0x68, 0, 0, 0}); err == nil {
+		t.Error("Expected error when parsing a non-SPS NAL unit")
+	}
+}
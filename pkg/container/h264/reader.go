@@ -0,0 +1,121 @@
+package h264
+
+import (
+	"bufio"
+	"io"
+)
+
+// Reader reads access units from an H.264 Annex-B byte stream.
+type Reader struct {
+	r       *bufio.Reader
+	synced  bool
+	pending []byte
+}
+
+// NewReader creates a new Annex-B reader. Any data before the first start
+// code is ignored.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: bufio.NewReader(r)}
+}
+
+// ReadAccessUnit reads the NAL units of the next access unit. The access unit
+// is returned in Annex-B format with 4 bytes start codes. io.EOF is returned
+// when there are no more access units.
+func (r *Reader) ReadAccessUnit() ([]byte, error) {
+	var au []byte
+	var hasVCL bool
+
+	for {
+		nalu := r.pending
+		r.pending = nil
+		if nalu == nil {
+			var err error
+			nalu, err = r.readNALU()
+			if err == io.EOF {
+				if len(au) > 0 {
+					return au, nil
+				}
+				return nil, io.EOF
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if hasVCL && startsAccessUnit(nalu) {
+			r.pending = nalu
+			return au, nil
+		}
+
+		au = append(au, 0, 0, 0, 1)
+		au = append(au, nalu...)
+		if isVCL(nalu) {
+			hasVCL = true
+		}
+	}
+}
+
+func isVCL(nalu []byte) bool {
+	t := NALUType(nalu)
+	return t == NALUTypeSlice || t == NALUTypeIDR
+}
+
+// startsAccessUnit reports whether the NAL unit is the first one of a new access
+// unit, given that the current access unit already contains a slice.
+// Reference: ITU-T H.264 7.4.1.2.3
+func startsAccessUnit(nalu []byte) bool {
+	switch t := NALUType(nalu); {
+	case t == NALUTypeSEI, t == NALUTypeSPS, t == NALUTypePPS, t == NALUTypeAUD, t >= 14 && t <= 18:
+		return true
+	case t == NALUTypeSlice, t == NALUTypeIDR:
+		// first_mb_in_slice is 0, i.e. the first slice of a picture
+		return len(nalu) > 1 && nalu[1]&0x80 != 0
+	}
+	return false
+}
+
+// readNALU reads bytes until the next start code, and returns them without
+// the trailing zeros.
+func (r *Reader) readNALU() ([]byte, error) {
+	var nalu []byte
+	zeros := 0
+
+	for {
+		b, err := r.r.ReadByte()
+		if err == io.EOF {
+			for len(nalu) > 0 && nalu[len(nalu)-1] == 0 {
+				nalu = nalu[:len(nalu)-1]
+			}
+			if !r.synced || len(nalu) == 0 {
+				return nil, io.EOF
+			}
+			return nalu, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if b == 1 && zeros >= 2 {
+			if !r.synced {
+				r.synced = true
+				nalu = nalu[:0]
+				zeros = 0
+				continue
+			}
+			nalu = nalu[:len(nalu)-zeros]
+			if len(nalu) == 0 {
+				// Consecutive start codes
+				zeros = 0
+				continue
+			}
+			return nalu, nil
+		}
+
+		if b == 0 {
+			zeros++
+		} else {
+			zeros = 0
+		}
+		nalu = append(nalu, b)
+	}
+}
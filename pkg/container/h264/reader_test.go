@@ -0,0 +1,57 @@
+package h264
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestReader(t *testing.T) {
+	var stream []byte
+	// Leading garbage is skipped
+	stream = append(stream, 0xff, 0xfe)
+	// AUD, SPS, PPS and IDR with 2 slices
+	stream = append(stream, 0, 0, 0, 1, 0x09, 0xf0)
+	stream = append(stream, 0, 0, 1)
+	stream = append(stream, testSPS...)
+	stream = append(stream, 0, 0, 0, 1, 0x68, 0xce, 0x38, 0x80)
+	stream = append(stream, 0, 0, 1, 0x65, 0x88, 0x84)
+	stream = append(stream, 0, 0, 1, 0x65, 0x08, 0x84)
+	// Non IDR slices without AUD
+	stream = append(stream, 0, 0, 0, 1, 0x41, 0x9a, 0x02)
+	stream = append(stream, 0, 0, 0, 1, 0x41, 0x9a, 0x04, 0, 0)
+
+	expected := [][]byte{
+		{
+			0, 0, 0, 1, 0x09, 0xf0,
+			0, 0, 0, 1, 0x67, 0x42, 0xc0, 0x1e, 0xda, 0x02, 0x80, 0xf6, 0x40,
+			0, 0, 0, 1, 0x68, 0xce, 0x38, 0x80,
+			0, 0, 0, 1, 0x65, 0x88, 0x84,
+			0, 0, 0, 1, 0x65, 0x08, 0x84,
+		},
+		{0, 0, 0, 1, 0x41, 0x9a, 0x02},
+		{0, 0, 0, 1, 0x41, 0x9a, 0x04},
+	}
+
+	r := NewReader(bytes.NewReader(stream))
+	for i, e := range expected {
+		au, err := r.ReadAccessUnit()
+		if err != nil {
+			t.Fatalf("Access unit %d: %v", i, err)
+		}
+		if !bytes.Equal(au, e) {
+			t.Errorf("Access unit %d: expected %v, got %v", i, e, au)
+		}
+	}
+
+	if _, err := r.ReadAccessUnit(); err != io.EOF {
+		t.Errorf("Expected %v, got %v", io.EOF, err)
+	}
+}
+
+func TestReaderNoStartCode(t *testing.T) {
+	r := NewReader(bytes.NewReader([]byte{0x65, 1, 2}))
+	if _, err := r.ReadAccessUnit(); err != io.EOF {
+		t.Errorf("Expected %v, got %v", io.EOF, err)
+	}
+}
@@ -0,0 +1,237 @@
+// Package ivf implements reading and writing of IVF files, the simple container
+// used by libvpx for VP8 and VP9 streams.
+package ivf
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/bits"
+	"time"
+)
+
+const (
+	fileHeaderSize  = 32
+	frameHeaderSize = 12
+	signature       = "DKIF"
+
+	// FourCCVP8 is the FourCC of VP8 streams
+	FourCCVP8 = "VP80"
+	// FourCCVP9 is the FourCC of VP9 streams
+	FourCCVP9 = "VP90"
+
+	// defaultTimebaseDenominator matches the RTP clock rate of video codecs, so
+	// that EncodedBuffer.Samples can be used as timestamps without scaling.
+	defaultTimebaseDenominator = 90000
+
+	// maxFrameSize limits the size of the frames, so that a corrupted file
+	// doesn't allocate unbounded memory
+	maxFrameSize = 64 << 20
+)
+
+var (
+	errInvalidSignature = errors.New("ivf: invalid signature")
+	errInvalidFourCC    = errors.New("ivf: fourcc must be 4 characters long")
+	errInvalidTimebase  = errors.New("ivf: invalid timebase")
+	errFrameTooLarge    = errors.New("ivf: frame too large")
+)
+
+// Header is the IVF file header.
+type Header struct {
+	// FourCC is the codec of the stream, e.g. FourCCVP8 or FourCCVP9
+	FourCC string
+	Width  uint16
+	Height uint16
+	// TimebaseDenominator and TimebaseNumerator define the unit of the frame
+	// timestamps in seconds, i.e. TimebaseNumerator/TimebaseDenominator.
+	// When both are zero, the writer uses 1/90000.
+	TimebaseDenominator uint32
+	TimebaseNumerator   uint32
+	// FrameCount is the number of frames in the file. It may be zero when
+	// the file was written to a non-seekable stream.
+	FrameCount uint32
+}
+
+// Duration converts a timestamp in the header's timebase to time.Duration. The
+// timestamps beyond the range of time.Duration are clamped to its maximum.
+func (h *Header) Duration(timestamp uint64) time.Duration {
+	if h.TimebaseDenominator == 0 {
+		return 0
+	}
+	// the product is computed on 128 bits to avoid overflowing
+	hi, lo := bits.Mul64(timestamp, uint64(h.TimebaseNumerator))
+	if hi >= uint64(h.TimebaseDenominator) {
+		return math.MaxInt64
+	}
+	seconds, rest := bits.Div64(hi, lo, uint64(h.TimebaseDenominator))
+	if seconds > uint64(math.MaxInt64/time.Second)-1 {
+		return math.MaxInt64
+	}
+	return time.Duration(seconds)*time.Second +
+		time.Duration(rest)*time.Second/time.Duration(h.TimebaseDenominator)
+}
+
+// Writer writes encoded frames to an IVF file.
+type Writer struct {
+	w         io.Writer
+	header    Header
+	timestamp uint64
+	count     uint32
+}
+
+// NewWriter writes the file header to w, and returns a writer to write frames.
+func NewWriter(w io.Writer, header Header) (*Writer, error) {
+	if len(header.FourCC) != 4 {
+		return nil, errInvalidFourCC
+	}
+	if header.TimebaseDenominator == 0 && header.TimebaseNumerator == 0 {
+		header.TimebaseDenominator = defaultTimebaseDenominator
+		header.TimebaseNumerator = 1
+	}
+	if header.TimebaseDenominator == 0 || header.TimebaseNumerator == 0 {
+		return nil, errInvalidTimebase
+	}
+
+	writer := &Writer{w: w, header: header}
+	if _, err := w.Write(writer.fileHeader()); err != nil {
+		return nil, err
+	}
+
+	return writer, nil
+}
+
+func (w *Writer) fileHeader() []byte {
+	b := make([]byte, fileHeaderSize)
+	copy(b[0:], signature)
+	binary.LittleEndian.PutUint16(b[4:], 0) // version
+	binary.LittleEndian.PutUint16(b[6:], fileHeaderSize)
+	copy(b[8:], w.header.FourCC)
+	binary.LittleEndian.PutUint16(b[12:], w.header.Width)
+	binary.LittleEndian.PutUint16(b[14:], w.header.Height)
+	binary.LittleEndian.PutUint32(b[16:], w.header.TimebaseDenominator)
+	binary.LittleEndian.PutUint32(b[20:], w.header.TimebaseNumerator)
+	binary.LittleEndian.PutUint32(b[24:], w.header.FrameCount)
+	return b
+}
+
+// WriteFrame writes an encoded frame. samples is the number of ticks, in the
+// timebase of the file, elapsed since the previous frame, i.e. EncodedBuffer.Samples
+// when the timebase is the codec's clock rate. The first frame is always written
+// with a zero timestamp.
+func (w *Writer) WriteFrame(frame []byte, samples uint32) error {
+	if w.count > 0 {
+		w.timestamp += uint64(samples)
+	}
+
+	var header [frameHeaderSize]byte
+	binary.LittleEndian.PutUint32(header[0:], uint32(len(frame)))
+	binary.LittleEndian.PutUint64(header[4:], w.timestamp)
+	if _, err := w.w.Write(header[:]); err != nil {
+		return err
+	}
+	if _, err := w.w.Write(frame); err != nil {
+		return err
+	}
+
+	w.count++
+	return nil
+}
+
+// Close updates the frame count in the file header if the underlying writer
+// is seekable. It doesn't close the underlying writer.
+func (w *Writer) Close() error {
+	seeker, ok := w.w.(io.WriteSeeker)
+	if !ok {
+		return nil
+	}
+
+	w.header.FrameCount = w.count
+	end, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := seeker.Write(w.fileHeader()); err != nil {
+		return err
+	}
+	_, err = seeker.Seek(end, io.SeekStart)
+	return err
+}
+
+// Reader reads encoded frames from an IVF file.
+type Reader struct {
+	r      io.Reader
+	header Header
+}
+
+// NewReader reads the file header from r, and returns a reader to read frames.
+func NewReader(r io.Reader) (*Reader, error) {
+	b := make([]byte, fileHeaderSize)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, err
+	}
+	if string(b[0:4]) != signature {
+		return nil, errInvalidSignature
+	}
+
+	headerSize := int(binary.LittleEndian.Uint16(b[6:]))
+	if headerSize < fileHeaderSize {
+		return nil, fmt.Errorf("ivf: invalid header size %d", headerSize)
+	}
+	if headerSize > fileHeaderSize {
+		// Skip unknown header extension
+		if _, err := io.CopyN(io.Discard, r, int64(headerSize-fileHeaderSize)); err != nil {
+			return nil, err
+		}
+	}
+
+	header := Header{
+		FourCC:              string(b[8:12]),
+		Width:               binary.LittleEndian.Uint16(b[12:]),
+		Height:              binary.LittleEndian.Uint16(b[14:]),
+		TimebaseDenominator: binary.LittleEndian.Uint32(b[16:]),
+		TimebaseNumerator:   binary.LittleEndian.Uint32(b[20:]),
+		FrameCount:          binary.LittleEndian.Uint32(b[24:]),
+	}
+	if header.TimebaseDenominator == 0 || header.TimebaseNumerator == 0 {
+		return nil, errInvalidTimebase
+	}
+
+	return &Reader{r: r, header: header}, nil
+}
+
+// Header returns the file header.
+func (r *Reader) Header() Header {
+	return r.header
+}
+
+// ReadFrame reads the next frame and its presentation timestamp. io.EOF is
+// returned when there are no more frames.
+func (r *Reader) ReadFrame() (frame []byte, timestamp time.Duration, err error) {
+	var header [frameHeaderSize]byte
+	if _, err := io.ReadFull(r.r, header[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			err = io.EOF
+		}
+		return nil, 0, err
+	}
+
+	size := binary.LittleEndian.Uint32(header[0:])
+	pts := binary.LittleEndian.Uint64(header[4:])
+	if size > maxFrameSize {
+		return nil, 0, errFrameTooLarge
+	}
+	frame = make([]byte, size)
+	if _, err := io.ReadFull(r.r, frame); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			err = io.EOF
+		}
+		return nil, 0, err
+	}
+
+	return frame, r.header.Duration(pts), nil
+}
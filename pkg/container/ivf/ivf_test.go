@@ -0,0 +1,170 @@
+package ivf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWriterReader(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, Header{FourCC: FourCCVP8, Width: 640, Height: 480})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	frames := [][]byte{{0x10, 0x02}, {0x11}, {0x12, 0x13, 0x14}}
+	samples := []uint32{1234, 3000, 4500}
+	for i, frame := range frames {
+		if err := w.WriteFrame(frame, samples[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := NewReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := r.Header()
+	if header.FourCC != FourCCVP8 || header.Width != 640 || header.Height != 480 {
+		t.Errorf("Unexpected header: %+v", header)
+	}
+	if header.TimebaseDenominator != 90000 || header.TimebaseNumerator != 1 {
+		t.Errorf("Expected 1/90000 timebase, got %d/%d", header.TimebaseNumerator, header.TimebaseDenominator)
+	}
+
+	// The first frame starts at zero regardless of its samples
+	timestamps := []time.Duration{0, time.Second / 30, time.Second/30 + time.Second/20}
+	for i, expected := range frames {
+		frame, timestamp, err := r.ReadFrame()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(frame, expected) {
+			t.Errorf("Frame %d doesn't match", i)
+		}
+		if timestamp != timestamps[i] {
+			t.Errorf("Expected frame %d timestamp %v, got %v", i, timestamps[i], timestamp)
+		}
+	}
+	if _, _, err := r.ReadFrame(); err != io.EOF {
+		t.Errorf("Expected %v, got %v", io.EOF, err)
+	}
+}
+
+func TestWriterFrameCount(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "test.ivf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	w, err := NewWriter(f, Header{FourCC: FourCCVP9, TimebaseDenominator: 30, TimebaseNumerator: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		if err := w.WriteFrame([]byte{byte(i)}, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count := r.Header().FrameCount; count != 5 {
+		t.Errorf("Expected frame count 5, got %d", count)
+	}
+	for i := 0; i < 5; i++ {
+		_, timestamp, err := r.ReadFrame()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected := time.Duration(i) * time.Second / 30; timestamp != expected {
+			t.Errorf("Expected timestamp %v, got %v", expected, timestamp)
+		}
+	}
+}
+
+func TestNewReaderInvalid(t *testing.T) {
+	if _, err := NewReader(bytes.NewReader(make([]byte, fileHeaderSize))); err != errInvalidSignature {
+		t.Errorf("Expected %v, got %v", errInvalidSignature, err)
+	}
+	if _, err := NewWriter(&bytes.Buffer{}, Header{FourCC: "VP8"}); err != errInvalidFourCC {
+		t.Errorf("Expected %v, got %v", errInvalidFourCC, err)
+	}
+}
+
+func TestReadFrameTooLarge(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, Header{FourCC: FourCCVP8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	header := make([]byte, frameHeaderSize)
+	binary.LittleEndian.PutUint32(header, maxFrameSize+1)
+	buf.Write(header)
+
+	r, err := NewReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := r.ReadFrame(); err != errFrameTooLarge {
+		t.Errorf("Expected %v, got %v", errFrameTooLarge, err)
+	}
+}
+
+func TestHeaderDuration(t *testing.T) {
+	testCases := map[string]struct {
+		header    Header
+		timestamp uint64
+		expected  time.Duration
+	}{
+		"90kHz": {
+			header:    Header{TimebaseDenominator: 90000, TimebaseNumerator: 1},
+			timestamp: 135000,
+			expected:  1500 * time.Millisecond,
+		},
+		"LargeProduct": {
+			header:    Header{TimebaseDenominator: 3000000000, TimebaseNumerator: 3000000000},
+			timestamp: 1 << 33,
+			expected:  (1 << 33) * time.Second,
+		},
+		"Overflow": {
+			header:    Header{TimebaseDenominator: 1, TimebaseNumerator: 1000},
+			timestamp: math.MaxUint64,
+			expected:  math.MaxInt64,
+		},
+		"InvalidTimebase": {
+			header:    Header{TimebaseNumerator: 1},
+			timestamp: 1000,
+			expected:  0,
+		},
+	}
+	for name, c := range testCases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			if d := c.header.Duration(c.timestamp); d != c.expected {
+				t.Errorf("Expected %v, got %v", c.expected, d)
+			}
+		})
+	}
+}
@@ -0,0 +1,378 @@
+// Package ogg implements reading and writing of Ogg files containing a single
+// Opus stream.
+// Reference: https://www.rfc-editor.org/rfc/rfc7845
+package ogg
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"time"
+)
+
+const (
+	pageHeaderSize  = 27
+	maxSegments     = 255
+	maxSegmentSize  = 255
+	maxPacketSize   = maxSegments*maxSegmentSize - 1
+	pageSignature   = "OggS"
+	opusHeadMagic   = "OpusHead"
+	opusTagsMagic   = "OpusTags"
+	opusHeadSize    = 19
+	opusSampleRate  = 48000
+	defaultPreSkip  = 312
+	vendor          = "pion/mediadevices"
+	headerContinued = 0x01
+	headerBeginning = 0x02
+	headerEndOfFile = 0x04
+)
+
+var (
+	errInvalidSignature = errors.New("ogg: invalid page signature")
+	errInvalidChecksum  = errors.New("ogg: invalid page checksum")
+	errInvalidOpusHead  = errors.New("ogg: invalid OpusHead packet")
+	errPacketTooLarge   = errors.New("ogg: packet is too large")
+	errInvalidChannels  = errors.New("ogg: channel count must be 1 or 2")
+	errWriterClosed     = errors.New("ogg: writer is closed")
+)
+
+var crcTable = func() [256]uint32 {
+	var table [256]uint32
+	const poly = 0x04c11db7
+	for i := range table {
+		r := uint32(i) << 24
+		for j := 0; j < 8; j++ {
+			if r&0x80000000 != 0 {
+				r = r<<1 ^ poly
+			} else {
+				r <<= 1
+			}
+		}
+		table[i] = r
+	}
+	return table
+}()
+
+func checksum(b []byte) uint32 {
+	var crc uint32
+	for _, v := range b {
+		crc = crc<<8 ^ crcTable[byte(crc>>24)^v]
+	}
+	return crc
+}
+
+// Header is the Opus identification header.
+type Header struct {
+	Channels uint8
+	// PreSkip is the number of samples, at 48 kHz, to discard from the decoder
+	// output when starting playback.
+	PreSkip uint16
+	// SampleRate is the sample rate of the original input. It's informational
+	// only, Opus is always decoded at 48 kHz.
+	SampleRate uint32
+	OutputGain int16
+}
+
+func (h *Header) marshal() []byte {
+	b := make([]byte, opusHeadSize)
+	copy(b, opusHeadMagic)
+	b[8] = 1 // version
+	b[9] = h.Channels
+	binary.LittleEndian.PutUint16(b[10:], h.PreSkip)
+	binary.LittleEndian.PutUint32(b[12:], h.SampleRate)
+	binary.LittleEndian.PutUint16(b[16:], uint16(h.OutputGain))
+	b[18] = 0 // channel mapping family
+	return b
+}
+
+func (h *Header) unmarshal(b []byte) error {
+	if len(b) < opusHeadSize || string(b[:8]) != opusHeadMagic || b[8]&0xF0 != 0 {
+		return errInvalidOpusHead
+	}
+	h.Channels = b[9]
+	h.PreSkip = binary.LittleEndian.Uint16(b[10:])
+	h.SampleRate = binary.LittleEndian.Uint32(b[12:])
+	h.OutputGain = int16(binary.LittleEndian.Uint16(b[16:]))
+	return nil
+}
+
+// packetDuration returns the duration of an Opus packet in 48 kHz samples.
+// Reference: https://www.rfc-editor.org/rfc/rfc6716#section-3.1
+func packetDuration(packet []byte) uint64 {
+	if len(packet) == 0 {
+		return 0
+	}
+
+	toc := packet[0]
+	config := toc >> 3
+	var frameSize uint64
+	switch {
+	case config < 12:
+		// SILK: 10, 20, 40, 60 ms
+		frameSize = []uint64{480, 960, 1920, 2880}[config%4]
+	case config < 16:
+		// Hybrid: 10, 20 ms
+		frameSize = []uint64{480, 960}[config%2]
+	default:
+		// CELT: 2.5, 5, 10, 20 ms
+		frameSize = []uint64{120, 240, 480, 960}[config%4]
+	}
+
+	var frames uint64
+	switch toc & 0x03 {
+	case 0:
+		frames = 1
+	case 1, 2:
+		frames = 2
+	default:
+		if len(packet) < 2 {
+			return 0
+		}
+		frames = uint64(packet[1] & 0x3F)
+	}
+
+	return frames * frameSize
+}
+
+// Writer writes Opus packets to an Ogg stream. Each packet is stored in its
+// own page, so the stream can be consumed while it's being written.
+type Writer struct {
+	w        io.Writer
+	serial   uint32
+	sequence uint32
+	granule  uint64
+	pending  []byte
+	closed   bool
+}
+
+// NewWriter writes the Opus headers to w, and returns a writer to write Opus
+// packets. When header.PreSkip is zero, the default libopus encoder delay
+// is used.
+func NewWriter(w io.Writer, header Header) (*Writer, error) {
+	if header.Channels != 1 && header.Channels != 2 {
+		return nil, errInvalidChannels
+	}
+	if header.PreSkip == 0 {
+		header.PreSkip = defaultPreSkip
+	}
+	if header.SampleRate == 0 {
+		header.SampleRate = opusSampleRate
+	}
+
+	writer := &Writer{
+		w:       w,
+		serial:  rand.Uint32(),
+		granule: uint64(header.PreSkip),
+	}
+
+	tags := make([]byte, 0, len(opusTagsMagic)+8+len(vendor))
+	tags = append(tags, opusTagsMagic...)
+	tags = appendUint32(tags, uint32(len(vendor)))
+	tags = append(tags, vendor...)
+	tags = appendUint32(tags, 0) // user comment list length
+
+	if err := writer.writePage(header.marshal(), 0, headerBeginning); err != nil {
+		return nil, err
+	}
+	if err := writer.writePage(tags, 0, 0); err != nil {
+		return nil, err
+	}
+
+	return writer, nil
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	var buf [4]byte
+	binary.LittleEndian.PutUint32(buf[:], v)
+	return append(b, buf[:]...)
+}
+
+// WritePacket writes an Opus packet. samples is the duration of the packet in
+// 48 kHz ticks, i.e. EncodedBuffer.Samples of an Opus track. The packet is
+// copied, so it can be reused right after the call.
+func (w *Writer) WritePacket(packet []byte, samples uint32) error {
+	if w.closed {
+		return errWriterClosed
+	}
+	if len(packet) > maxPacketSize {
+		return errPacketTooLarge
+	}
+
+	// The last page is held back, so that it can be flagged as the end of the stream
+	if w.pending != nil {
+		if err := w.writePage(w.pending, w.granule, 0); err != nil {
+			return err
+		}
+	}
+
+	w.granule += uint64(samples)
+	w.pending = append(w.pending[:0], packet...)
+	return nil
+}
+
+// Close writes the last page with the end of stream flag. It doesn't close
+// the underlying writer.
+func (w *Writer) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+
+	return w.writePage(w.pending, w.granule, headerEndOfFile)
+}
+
+func (w *Writer) writePage(packet []byte, granule uint64, headerType byte) error {
+	// A nil packet results in an empty page
+	var nSegments int
+	if packet != nil {
+		nSegments = len(packet)/maxSegmentSize + 1
+	}
+	page := make([]byte, pageHeaderSize+nSegments+len(packet))
+
+	copy(page, pageSignature)
+	page[4] = 0 // version
+	page[5] = headerType
+	binary.LittleEndian.PutUint64(page[6:], granule)
+	binary.LittleEndian.PutUint32(page[14:], w.serial)
+	binary.LittleEndian.PutUint32(page[18:], w.sequence)
+	page[26] = byte(nSegments)
+	for i := 0; i < nSegments; i++ {
+		page[pageHeaderSize+i] = maxSegmentSize
+	}
+	if nSegments > 0 {
+		page[pageHeaderSize+nSegments-1] = byte(len(packet) % maxSegmentSize)
+	}
+	copy(page[pageHeaderSize+nSegments:], packet)
+	binary.LittleEndian.PutUint32(page[22:], checksum(page))
+
+	w.sequence++
+	_, err := w.w.Write(page)
+	return err
+}
+
+// Reader reads Opus packets from an Ogg stream.
+type Reader struct {
+	r         io.Reader
+	header    Header
+	packets   [][]byte
+	partial   []byte
+	eos       bool
+	timestamp uint64
+}
+
+// NewReader reads the Opus headers from r, and returns a reader to read Opus
+// packets.
+func NewReader(r io.Reader) (*Reader, error) {
+	reader := &Reader{r: r}
+
+	head, err := reader.nextPacket()
+	if err != nil {
+		return nil, err
+	}
+	if err := reader.header.unmarshal(head); err != nil {
+		return nil, err
+	}
+
+	tags, err := reader.nextPacket()
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.HasPrefix(tags, []byte(opusTagsMagic)) {
+		return nil, fmt.Errorf("ogg: expected OpusTags packet")
+	}
+
+	return reader, nil
+}
+
+// Header returns the Opus identification header.
+func (r *Reader) Header() Header {
+	return r.header
+}
+
+// ReadPacket reads the next Opus packet and its presentation timestamp. The
+// timestamp is derived from the packet durations, starting from zero. io.EOF
+// is returned at the end of the stream.
+func (r *Reader) ReadPacket() (packet []byte, timestamp time.Duration, err error) {
+	packet, err = r.nextPacket()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	timestamp = time.Duration(r.timestamp) * time.Second / opusSampleRate
+	r.timestamp += packetDuration(packet)
+	return packet, timestamp, nil
+}
+
+func (r *Reader) nextPacket() ([]byte, error) {
+	for len(r.packets) == 0 {
+		if r.eos {
+			return nil, io.EOF
+		}
+		if err := r.readPage(); err != nil {
+			return nil, err
+		}
+	}
+
+	packet := r.packets[0]
+	r.packets = r.packets[1:]
+	return packet, nil
+}
+
+func (r *Reader) readPage() error {
+	header := make([]byte, pageHeaderSize)
+	if _, err := io.ReadFull(r.r, header); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			err = io.EOF
+		}
+		return err
+	}
+	if string(header[:4]) != pageSignature {
+		return errInvalidSignature
+	}
+
+	segmentTable := make([]byte, header[26])
+	if _, err := io.ReadFull(r.r, segmentTable); err != nil {
+		return io.EOF
+	}
+
+	var size int
+	for _, s := range segmentTable {
+		size += int(s)
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r.r, data); err != nil {
+		return io.EOF
+	}
+
+	expected := binary.LittleEndian.Uint32(header[22:])
+	binary.LittleEndian.PutUint32(header[22:], 0)
+	page := make([]byte, 0, len(header)+len(segmentTable)+len(data))
+	page = append(page, header...)
+	page = append(page, segmentTable...)
+	page = append(page, data...)
+	if checksum(page) != expected {
+		return errInvalidChecksum
+	}
+
+	if header[5]&headerContinued == 0 {
+		r.partial = r.partial[:0]
+	}
+
+	var offset int
+	for _, s := range segmentTable {
+		r.partial = append(r.partial, data[offset:offset+int(s)]...)
+		offset += int(s)
+		if s < maxSegmentSize {
+			r.packets = append(r.packets, r.partial)
+			r.partial = nil
+		}
+	}
+
+	if header[5]&headerEndOfFile != 0 {
+		r.eos = true
+	}
+	return nil
+}
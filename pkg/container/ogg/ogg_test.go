@@ -0,0 +1,96 @@
+package ogg
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestWriterReader(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, Header{Channels: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// CELT 20 ms packets, and a large packet spanning multiple segments
+	packets := [][]byte{
+		{0xfc, 0x01, 0x02},
+		{0xfc, 0x03},
+		append([]byte{0xfc}, bytes.Repeat([]byte{0xaa}, 600)...),
+	}
+	for _, p := range packets {
+		if err := w.WritePacket(p, 960); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WritePacket(packets[0], 960); err != errWriterClosed {
+		t.Errorf("Expected %v, got %v", errWriterClosed, err)
+	}
+
+	r, err := NewReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := r.Header()
+	if header.Channels != 2 || header.PreSkip != defaultPreSkip || header.SampleRate != 48000 {
+		t.Errorf("Unexpected header: %+v", header)
+	}
+
+	for i, expected := range packets {
+		packet, timestamp, err := r.ReadPacket()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(packet, expected) {
+			t.Errorf("Packet %d doesn't match", i)
+		}
+		if expectedTimestamp := time.Duration(i) * 20 * time.Millisecond; timestamp != expectedTimestamp {
+			t.Errorf("Expected packet %d timestamp %v, got %v", i, expectedTimestamp, timestamp)
+		}
+	}
+	if _, _, err := r.ReadPacket(); err != io.EOF {
+		t.Errorf("Expected %v, got %v", io.EOF, err)
+	}
+}
+
+func TestReaderChecksum(t *testing.T) {
+	var buf bytes.Buffer
+	if _, err := NewWriter(&buf, Header{Channels: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	b := buf.Bytes()
+	b[len(b)-1] ^= 0xff
+	if _, err := NewReader(bytes.NewReader(b)); err != errInvalidChecksum {
+		t.Errorf("Expected %v, got %v", errInvalidChecksum, err)
+	}
+}
+
+func TestPacketDuration(t *testing.T) {
+	testCases := map[string]struct {
+		packet   []byte
+		expected uint64
+	}{
+		"SILK20ms":          {packet: []byte{0x08}, expected: 960},
+		"SILK60ms":          {packet: []byte{0x18}, expected: 2880},
+		"Hybrid10ms":        {packet: []byte{0x60}, expected: 480},
+		"CELT2_5ms":         {packet: []byte{0x80}, expected: 120},
+		"CELT20msTwo":       {packet: []byte{0xf9}, expected: 1920},
+		"CELT20msArbitrary": {packet: []byte{0xfb, 0x03}, expected: 2880},
+		"Empty":             {packet: nil, expected: 0},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			if d := packetDuration(testCase.packet); d != testCase.expected {
+				t.Errorf("Expected %d, got %d", testCase.expected, d)
+			}
+		})
+	}
+}
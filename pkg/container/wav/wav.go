@@ -0,0 +1,508 @@
+// Package wav implements reading and writing of WAV files containing PCM or
+// IEEE float samples.
+// Reference: http://www-mmsp.ece.mcgill.ca/Documents/AudioFormats/WAVE/WAVE.html
+package wav
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/pion/mediadevices/pkg/io/audio"
+	"github.com/pion/mediadevices/pkg/prop"
+	"github.com/pion/mediadevices/pkg/wave"
+)
+
+const (
+	formatPCM        = 1
+	formatFloat      = 3
+	formatExtensible = 0xFFFE
+
+	riffHeaderSize  = 12
+	chunkHeaderSize = 8
+	fmtChunkSize    = 16
+	// unknownSize is used for sizes which aren't known when streaming, and as
+	// placeholder for the sizes stored in the ds64 chunk of RF64 files
+	unknownSize = 0xFFFFFFFF
+)
+
+var (
+	errInvalidSignature  = errors.New("wav: invalid signature")
+	errMissingFormat     = errors.New("wav: data chunk found before fmt chunk")
+	errUnsupportedFormat = errors.New("wav: unsupported sample format")
+	errInvalidChannels   = errors.New("wav: channel count must be positive")
+	errInvalidSampleRate = errors.New("wav: sample rate must be positive")
+	errChannelMismatch   = errors.New("wav: chunk channel count doesn't match the header")
+)
+
+// Header describes the samples of a file.
+type Header struct {
+	Channels   int
+	SampleRate int
+	// BitsPerSample is 16, 24 or 32 for PCM, and 32 for float samples.
+	BitsPerSample int
+	Float         bool
+}
+
+func (h *Header) validate() error {
+	if h.Channels <= 0 {
+		return errInvalidChannels
+	}
+	if h.SampleRate <= 0 {
+		return errInvalidSampleRate
+	}
+	switch {
+	case h.Float && h.BitsPerSample == 32:
+	case !h.Float && (h.BitsPerSample == 16 || h.BitsPerSample == 24 || h.BitsPerSample == 32):
+	default:
+		return fmt.Errorf("%w: %d bits, float: %t", errUnsupportedFormat, h.BitsPerSample, h.Float)
+	}
+	return nil
+}
+
+// Media returns the properties of the chunks of latency read from the file by a Reader.
+func (h *Header) Media(latency time.Duration) prop.Media {
+	sampleSize, isFloat := 4, true
+	if h.BitsPerSample == 16 {
+		sampleSize, isFloat = 2, false
+	}
+
+	return prop.Media{
+		Audio: prop.Audio{
+			ChannelCount:  h.Channels,
+			SampleRate:    h.SampleRate,
+			Latency:       latency,
+			SampleSize:    sampleSize,
+			IsFloat:       isFloat,
+			IsInterleaved: true,
+		},
+	}
+}
+
+func (h *Header) blockAlign() int {
+	return h.Channels * h.BitsPerSample / 8
+}
+
+// Reader reads audio chunks from a RIFF or RF64 WAV file. 16 bits samples are
+// read as wave.Int16Interleaved, other formats as wave.Float32Interleaved.
+type Reader struct {
+	r      *bufio.Reader
+	header Header
+	// remaining is the number of bytes left in the data chunk, or -1 when the
+	// size is unknown, e.g. when the file is streamed
+	remaining int64
+	buf       []byte
+}
+
+// NewReader reads the headers from r until the beginning of the samples, and
+// returns a reader to read audio chunks.
+func NewReader(r io.Reader) (*Reader, error) {
+	reader := &Reader{r: bufio.NewReader(r)}
+
+	var riff [riffHeaderSize]byte
+	if _, err := io.ReadFull(reader.r, riff[:]); err != nil {
+		return nil, err
+	}
+	rf64 := string(riff[0:4]) == "RF64"
+	if (string(riff[0:4]) != "RIFF" && !rf64) || string(riff[8:12]) != "WAVE" {
+		return nil, errInvalidSignature
+	}
+
+	var dataSize64 int64 = -1
+	var hasFormat bool
+	for {
+		var chunkHeader [chunkHeaderSize]byte
+		if _, err := io.ReadFull(reader.r, chunkHeader[:]); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		id := string(chunkHeader[0:4])
+		size := binary.LittleEndian.Uint32(chunkHeader[4:])
+
+		switch id {
+		case "ds64":
+			b := make([]byte, size)
+			if _, err := io.ReadFull(reader.r, b); err != nil {
+				return nil, err
+			}
+			if len(b) >= 16 {
+				dataSize64 = int64(binary.LittleEndian.Uint64(b[8:]))
+			}
+		case "fmt ":
+			b := make([]byte, size)
+			if _, err := io.ReadFull(reader.r, b); err != nil {
+				return nil, err
+			}
+			if err := reader.header.unmarshal(b); err != nil {
+				return nil, err
+			}
+			hasFormat = true
+		case "data":
+			if !hasFormat {
+				return nil, errMissingFormat
+			}
+			switch {
+			case rf64 && size == unknownSize:
+				reader.remaining = dataSize64
+			case size == unknownSize || size == 0:
+				// Streamed files don't know their size in advance
+				reader.remaining = -1
+			default:
+				reader.remaining = int64(size)
+			}
+			return reader, nil
+		default:
+			if _, err := io.CopyN(io.Discard, reader.r, int64(size)); err != nil {
+				return nil, err
+			}
+		}
+
+		// Chunks are padded to an even size
+		if size%2 == 1 {
+			if _, err := reader.r.Discard(1); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+func (h *Header) unmarshal(b []byte) error {
+	if len(b) < fmtChunkSize {
+		return fmt.Errorf("wav: fmt chunk is too short")
+	}
+
+	formatTag := binary.LittleEndian.Uint16(b[0:])
+	h.Channels = int(binary.LittleEndian.Uint16(b[2:]))
+	h.SampleRate = int(binary.LittleEndian.Uint32(b[4:]))
+	h.BitsPerSample = int(binary.LittleEndian.Uint16(b[14:]))
+
+	if formatTag == formatExtensible {
+		// The actual format is stored in the first bytes of the sub format GUID
+		if len(b) < 26 {
+			return fmt.Errorf("wav: extensible fmt chunk is too short")
+		}
+		formatTag = binary.LittleEndian.Uint16(b[24:])
+	}
+
+	switch formatTag {
+	case formatPCM:
+	case formatFloat:
+		h.Float = true
+	default:
+		return fmt.Errorf("%w: format tag %#x", errUnsupportedFormat, formatTag)
+	}
+
+	return h.validate()
+}
+
+// Header returns the format of the samples.
+func (r *Reader) Header() Header {
+	return r.header
+}
+
+// ReadChunk reads up to n samples per channel. A shorter chunk is returned at
+// the end of the file, and io.EOF once there are no more samples.
+func (r *Reader) ReadChunk(n int) (wave.Audio, error) {
+	blockAlign := r.header.blockAlign()
+	size := int64(n * blockAlign)
+	if r.remaining >= 0 && r.remaining < size {
+		size = r.remaining - r.remaining%int64(blockAlign)
+	}
+	if size == 0 {
+		return nil, io.EOF
+	}
+
+	if int64(cap(r.buf)) < size {
+		r.buf = make([]byte, size)
+	}
+	buf := r.buf[:size]
+	read, err := io.ReadFull(r.r, buf)
+	read -= read % blockAlign
+	if read == 0 {
+		if err == io.ErrUnexpectedEOF {
+			err = io.EOF
+		}
+		return nil, err
+	}
+	if r.remaining >= 0 {
+		r.remaining -= int64(read)
+	}
+	buf = buf[:read]
+
+	info := wave.ChunkInfo{
+		Len:          read / blockAlign,
+		Channels:     r.header.Channels,
+		SamplingRate: r.header.SampleRate,
+	}
+	if r.header.BitsPerSample == 16 {
+		chunk := wave.NewInt16Interleaved(info)
+		for i := range chunk.Data {
+			chunk.Data[i] = int16(binary.LittleEndian.Uint16(buf[i*2:]))
+		}
+		return chunk, nil
+	}
+
+	chunk := wave.NewFloat32Interleaved(info)
+	for i := range chunk.Data {
+		switch {
+		case r.header.Float:
+			chunk.Data[i] = math.Float32frombits(binary.LittleEndian.Uint32(buf[i*4:]))
+		case r.header.BitsPerSample == 24:
+			v := int32(buf[i*3]) | int32(buf[i*3+1])<<8 | int32(int8(buf[i*3+2]))<<16
+			chunk.Data[i] = float32(v) / (1 << 23)
+		default:
+			chunk.Data[i] = float32(int32(binary.LittleEndian.Uint32(buf[i*4:]))) / (1 << 31)
+		}
+	}
+	return chunk, nil
+}
+
+// Writer writes audio chunks to a WAV file.
+type Writer struct {
+	w        io.Writer
+	header   Header
+	dataSize int64
+	buf      []byte
+}
+
+// NewWriter writes the headers to w, and returns a writer to write audio
+// chunks. The sizes in the headers are only known when w is an io.WriteSeeker,
+// they are set to the streaming placeholder otherwise.
+func NewWriter(w io.Writer, header Header) (*Writer, error) {
+	if err := header.validate(); err != nil {
+		return nil, err
+	}
+
+	writer := &Writer{w: w, header: header}
+	if _, err := w.Write(writer.headers(unknownSize)); err != nil {
+		return nil, err
+	}
+	return writer, nil
+}
+
+func (w *Writer) headers(dataSize uint32) []byte {
+	formatTag := uint16(formatPCM)
+	if w.header.Float {
+		formatTag = formatFloat
+	}
+	blockAlign := w.header.blockAlign()
+
+	b := make([]byte, riffHeaderSize+chunkHeaderSize+fmtChunkSize+chunkHeaderSize)
+	riffSize := uint32(unknownSize)
+	if dataSize != unknownSize {
+		riffSize = uint32(len(b)) - chunkHeaderSize + dataSize
+	}
+	copy(b[0:], "RIFF")
+	binary.LittleEndian.PutUint32(b[4:], riffSize)
+	copy(b[8:], "WAVE")
+	copy(b[12:], "fmt ")
+	binary.LittleEndian.PutUint32(b[16:], fmtChunkSize)
+	binary.LittleEndian.PutUint16(b[20:], formatTag)
+	binary.LittleEndian.PutUint16(b[22:], uint16(w.header.Channels))
+	binary.LittleEndian.PutUint32(b[24:], uint32(w.header.SampleRate))
+	binary.LittleEndian.PutUint32(b[28:], uint32(w.header.SampleRate*blockAlign))
+	binary.LittleEndian.PutUint16(b[32:], uint16(blockAlign))
+	binary.LittleEndian.PutUint16(b[34:], uint16(w.header.BitsPerSample))
+	copy(b[36:], "data")
+	binary.LittleEndian.PutUint32(b[40:], dataSize)
+	return b
+}
+
+// Header returns the format of the samples.
+func (w *Writer) Header() Header {
+	return w.header
+}
+
+// WriteChunk writes an audio chunk, converting its samples to the header's
+// format. The sample rate of the chunk isn't checked.
+func (w *Writer) WriteChunk(chunk wave.Audio) error {
+	info := chunk.ChunkInfo()
+	if info.Channels != w.header.Channels {
+		return errChannelMismatch
+	}
+
+	bytesPerSample := w.header.BitsPerSample / 8
+	size := info.Len * info.Channels * bytesPerSample
+	if cap(w.buf) < size {
+		w.buf = make([]byte, size)
+	}
+	b := w.buf[:size]
+
+	var j int
+	for i := 0; i < info.Len; i++ {
+		for ch := 0; ch < info.Channels; ch++ {
+			s := chunk.At(i, ch)
+			switch {
+			case w.header.Float:
+				binary.LittleEndian.PutUint32(b[j:], math.Float32bits(toFloat32(s)))
+			case bytesPerSample == 2:
+				binary.LittleEndian.PutUint16(b[j:], uint16(toInt32(s)>>16))
+			case bytesPerSample == 3:
+				v := toInt32(s) >> 8
+				b[j], b[j+1], b[j+2] = byte(v), byte(v>>8), byte(v>>16)
+			default:
+				binary.LittleEndian.PutUint32(b[j:], uint32(toInt32(s)))
+			}
+			j += bytesPerSample
+		}
+	}
+
+	if _, err := w.w.Write(b); err != nil {
+		return err
+	}
+	w.dataSize += int64(size)
+	return nil
+}
+
+// Close updates the sizes in the headers if the underlying writer is seekable
+// and the data fits in a RIFF file. It doesn't close the underlying writer.
+func (w *Writer) Close() error {
+	seeker, ok := w.w.(io.WriteSeeker)
+	if !ok || w.dataSize >= unknownSize-riffHeaderSize-chunkHeaderSize-fmtChunkSize-chunkHeaderSize {
+		return nil
+	}
+
+	end, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := seeker.Write(w.headers(uint32(w.dataSize))); err != nil {
+		return err
+	}
+	_, err = seeker.Seek(end, io.SeekStart)
+	return err
+}
+
+// toFloat32 converts a sample to the [-1, 1] range
+func toFloat32(s wave.Sample) float32 {
+	switch s := s.(type) {
+	case wave.Float32Sample:
+		return float32(s)
+	case wave.Int16Sample:
+		return float32(s) / (1 << 15)
+	default:
+		return float32(s.Int()) / (1 << 31)
+	}
+}
+
+// toInt32 converts a sample to a full scale 32 bits integer
+func toInt32(s wave.Sample) int32 {
+	var v int64
+	switch s := s.(type) {
+	case wave.Float32Sample:
+		v = int64(float64(s) * (1 << 31))
+	case wave.Int16Sample:
+		v = int64(s) << 16
+	default:
+		v = s.Int()
+	}
+
+	if v > math.MaxInt32 {
+		return math.MaxInt32
+	}
+	if v < math.MinInt32 {
+		return math.MinInt32
+	}
+	return int32(v)
+}
+
+// Tee returns an audio.TransformFunc writing the chunks read through it to w,
+// and an io.Closer updating the sizes in the headers once the chunks are written,
+// like Writer.Close. When header.Channels and header.SampleRate are zero, they
+// are taken from the first chunk. When header.BitsPerSample is zero, float
+// chunks are written as 32 bits float, and others as 16 bits PCM. The headers
+// are written along with the first chunk.
+func Tee(w io.Writer, header Header) (audio.TransformFunc, io.Closer) {
+	t := &teeWriter{w: w, header: header}
+	return t.transform, t
+}
+
+// teeWriter writes the chunks of Tee and Copy, creating its Writer from the
+// first chunk
+type teeWriter struct {
+	w      io.Writer
+	header Header
+
+	mu     sync.Mutex
+	writer *Writer
+}
+
+func (t *teeWriter) transform(r audio.Reader) audio.Reader {
+	return audio.ReaderFunc(func() (wave.Audio, func(), error) {
+		chunk, release, err := r.Read()
+		if err != nil {
+			return nil, func() {}, err
+		}
+		if err := t.write(chunk); err != nil {
+			release()
+			return nil, func() {}, err
+		}
+		return chunk, release, nil
+	})
+}
+
+func (t *teeWriter) write(chunk wave.Audio) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.writer == nil {
+		h := t.header
+		if h.Channels == 0 && h.SampleRate == 0 {
+			info := chunk.ChunkInfo()
+			h.Channels, h.SampleRate = info.Channels, info.SamplingRate
+		}
+		if h.BitsPerSample == 0 {
+			h.BitsPerSample = 16
+			if chunk.SampleFormat() == wave.Float32SampleFormat {
+				h.BitsPerSample, h.Float = 32, true
+			}
+		}
+		writer, err := NewWriter(t.w, h)
+		if err != nil {
+			return err
+		}
+		t.writer = writer
+	}
+	return t.writer.WriteChunk(chunk)
+}
+
+// Close updates the sizes in the headers, if any chunk was written.
+func (t *teeWriter) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.writer == nil {
+		return nil
+	}
+	return t.writer.Close()
+}
+
+// Copy writes the chunks read from r to w until r returns io.EOF. The header is
+// handled like Tee. It returns the number of samples per channel written. The
+// sizes in the headers are updated if w is an io.WriteSeeker.
+func Copy(w io.Writer, r audio.Reader, header Header) (int, error) {
+	transform, closer := Tee(w, header)
+	reader := transform(r)
+	var n int
+	for {
+		chunk, release, err := reader.Read()
+		if err == io.EOF {
+			return n, closer.Close()
+		}
+		if err != nil {
+			return n, err
+		}
+		n += chunk.ChunkInfo().Len
+		release()
+	}
+}
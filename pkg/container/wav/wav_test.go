@@ -0,0 +1,355 @@
+package wav
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/pion/mediadevices/pkg/io/audio"
+	"github.com/pion/mediadevices/pkg/prop"
+	"github.com/pion/mediadevices/pkg/wave"
+)
+
+func TestReadWrite(t *testing.T) {
+	input := &wave.Float32Interleaved{
+		Data: []float32{0, 0.5, -0.5, 0.25, -1, 0.75, 0.125, -0.25, 0.5, 0},
+		Size: wave.ChunkInfo{Len: 5, Channels: 2, SamplingRate: 48000},
+	}
+
+	testCases := map[string]struct {
+		header   Header
+		expected wave.Audio
+	}{
+		"PCM16": {
+			header: Header{Channels: 2, SampleRate: 48000, BitsPerSample: 16},
+			expected: &wave.Int16Interleaved{
+				Data: []int16{0, 16384, -16384, 8192, -32768, 24576, 4096, -8192, 16384, 0},
+				Size: input.Size,
+			},
+		},
+		"PCM24": {
+			header:   Header{Channels: 2, SampleRate: 48000, BitsPerSample: 24},
+			expected: input,
+		},
+		"PCM32": {
+			header:   Header{Channels: 2, SampleRate: 48000, BitsPerSample: 32},
+			expected: input,
+		},
+		"Float32": {
+			header:   Header{Channels: 2, SampleRate: 48000, BitsPerSample: 32, Float: true},
+			expected: input,
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			f, err := os.Create(filepath.Join(t.TempDir(), "test.wav"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+
+			w, err := NewWriter(f, testCase.header)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := w.WriteChunk(input); err != nil {
+				t.Fatal(err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := f.Seek(0, io.SeekStart); err != nil {
+				t.Fatal(err)
+			}
+			r, err := NewReader(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if h := r.Header(); h != testCase.header {
+				t.Errorf("Expected %+v, got %+v", testCase.header, h)
+			}
+			if r.remaining != int64(5*testCase.header.blockAlign()) {
+				t.Errorf("Expected the data size to be patched, got %d", r.remaining)
+			}
+
+			chunk, err := r.ReadChunk(10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(testCase.expected, chunk) {
+				t.Errorf("Expected %v, got %v", testCase.expected, chunk)
+			}
+			if _, err := r.ReadChunk(10); err != io.EOF {
+				t.Errorf("Expected io.EOF, got %v", err)
+			}
+		})
+	}
+}
+
+func TestReadChunkStreaming(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, Header{Channels: 1, SampleRate: 8000, BitsPerSample: 16})
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunk := &wave.Int16Interleaved{
+		Data: []int16{1, 2, 3, 4, 5},
+		Size: wave.ChunkInfo{Len: 5, Channels: 1, SamplingRate: 8000},
+	}
+	if err := w.WriteChunk(chunk); err != nil {
+		t.Fatal(err)
+	}
+	// The writer isn't seekable, so the sizes are left unknown
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// A truncated sample must be ignored
+	buf.WriteByte(6)
+
+	r, err := NewReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range [][]int16{{1, 2}, {3, 4}, {5}} {
+		chunk, err := r.ReadChunk(2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if data := chunk.(*wave.Int16Interleaved).Data; !reflect.DeepEqual(expected, data) {
+			t.Errorf("Expected %v, got %v", expected, data)
+		}
+	}
+	if _, err := r.ReadChunk(2); err != io.EOF {
+		t.Errorf("Expected io.EOF, got %v", err)
+	}
+}
+
+func TestReaderHeaders(t *testing.T) {
+	fmtChunk := func(formatTag uint16, bits uint16, extra []byte) []byte {
+		b := make([]byte, 24)
+		copy(b, "fmt ")
+		binary.LittleEndian.PutUint32(b[4:], uint32(16+len(extra)))
+		binary.LittleEndian.PutUint16(b[8:], formatTag)
+		binary.LittleEndian.PutUint16(b[10:], 1)
+		binary.LittleEndian.PutUint32(b[12:], 16000)
+		binary.LittleEndian.PutUint16(b[22:], bits)
+		return append(b, extra...)
+	}
+	extensible := make([]byte, 26)
+	binary.LittleEndian.PutUint16(extensible[0:], 22)
+	binary.LittleEndian.PutUint16(extensible[8:], formatFloat)
+	ds64 := make([]byte, 36)
+	copy(ds64, "ds64")
+	binary.LittleEndian.PutUint32(ds64[4:], 28)
+	binary.LittleEndian.PutUint64(ds64[16:], 4)
+
+	cat := func(chunks ...[]byte) []byte {
+		return bytes.Join(chunks, nil)
+	}
+	data := func(size uint32) []byte {
+		b := []byte("data\x00\x00\x00\x00\x01\x00\x02\x00\x03\x00")
+		binary.LittleEndian.PutUint32(b[4:], size)
+		return b
+	}
+
+	testCases := map[string]struct {
+		file     []byte
+		expected Header
+		samples  int
+		err      error
+	}{
+		"SkipChunks": {
+			file:     cat([]byte("RIFF\x00\x00\x00\x00WAVE"), fmtChunk(formatPCM, 16, nil), []byte("LIST\x03\x00\x00\x00abc\x00"), data(4)),
+			expected: Header{Channels: 1, SampleRate: 16000, BitsPerSample: 16},
+			samples:  2,
+		},
+		"Extensible": {
+			file:     cat([]byte("RIFF\x00\x00\x00\x00WAVE"), fmtChunk(formatExtensible, 32, extensible), data(8)),
+			expected: Header{Channels: 1, SampleRate: 16000, BitsPerSample: 32, Float: true},
+			samples:  1,
+		},
+		"RF64": {
+			file:     cat([]byte("RF64\xff\xff\xff\xffWAVE"), ds64, fmtChunk(formatPCM, 16, nil), data(unknownSize)),
+			expected: Header{Channels: 1, SampleRate: 16000, BitsPerSample: 16},
+			samples:  2,
+		},
+		"InvalidSignature": {
+			file: cat([]byte("RIFF\x00\x00\x00\x00AVI "), fmtChunk(formatPCM, 16, nil), data(4)),
+			err:  errInvalidSignature,
+		},
+		"MissingFormat": {
+			file: cat([]byte("RIFF\x00\x00\x00\x00WAVE"), data(4)),
+			err:  errMissingFormat,
+		},
+		"8Bits": {
+			file: cat([]byte("RIFF\x00\x00\x00\x00WAVE"), fmtChunk(formatPCM, 8, nil), data(4)),
+			err:  errUnsupportedFormat,
+		},
+		"ALaw": {
+			file: cat([]byte("RIFF\x00\x00\x00\x00WAVE"), fmtChunk(6, 8, nil), data(4)),
+			err:  errUnsupportedFormat,
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			r, err := NewReader(bytes.NewReader(testCase.file))
+			if !errors.Is(err, testCase.err) {
+				t.Fatalf("Expected error %v, got %v", testCase.err, err)
+			}
+			if err != nil {
+				return
+			}
+			if h := r.Header(); h != testCase.expected {
+				t.Errorf("Expected %+v, got %+v", testCase.expected, h)
+			}
+			chunk, err := r.ReadChunk(10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n := chunk.ChunkInfo().Len; n != testCase.samples {
+				t.Errorf("Expected %d samples, got %d", testCase.samples, n)
+			}
+		})
+	}
+}
+
+func TestHeaderMedia(t *testing.T) {
+	testCases := map[string]struct {
+		header     Header
+		sampleSize int
+		isFloat    bool
+	}{
+		"Int16":   {header: Header{Channels: 2, SampleRate: 44100, BitsPerSample: 16}, sampleSize: 2},
+		"Int24":   {header: Header{Channels: 2, SampleRate: 44100, BitsPerSample: 24}, sampleSize: 4, isFloat: true},
+		"Float32": {header: Header{Channels: 2, SampleRate: 44100, BitsPerSample: 32, Float: true}, sampleSize: 4, isFloat: true},
+	}
+	for name, c := range testCases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			expected := prop.Audio{
+				ChannelCount:  2,
+				SampleRate:    44100,
+				Latency:       20 * time.Millisecond,
+				SampleSize:    c.sampleSize,
+				IsFloat:       c.isFloat,
+				IsInterleaved: true,
+			}
+			if media := c.header.Media(20 * time.Millisecond); media.Audio != expected {
+				t.Errorf("Expected %+v, got %+v", expected, media.Audio)
+			}
+		})
+	}
+}
+
+func TestWriteChunkChannelMismatch(t *testing.T) {
+	w, err := NewWriter(io.Discard, Header{Channels: 2, SampleRate: 48000, BitsPerSample: 16})
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunk := wave.NewInt16Interleaved(wave.ChunkInfo{Len: 1, Channels: 1, SamplingRate: 48000})
+	if err := w.WriteChunk(chunk); err != errChannelMismatch {
+		t.Errorf("Expected %v, got %v", errChannelMismatch, err)
+	}
+}
+
+func TestCopy(t *testing.T) {
+	chunks := []wave.Audio{
+		&wave.Float32Interleaved{
+			Data: []float32{0.5, -0.5, 0.25, -0.25},
+			Size: wave.ChunkInfo{Len: 2, Channels: 2, SamplingRate: 44100},
+		},
+		&wave.Float32Interleaved{
+			Data: []float32{1, -1},
+			Size: wave.ChunkInfo{Len: 1, Channels: 2, SamplingRate: 44100},
+		},
+	}
+	var i int
+	src := audio.ReaderFunc(func() (wave.Audio, func(), error) {
+		if i == len(chunks) {
+			return nil, func() {}, io.EOF
+		}
+		i++
+		return chunks[i-1], func() {}, nil
+	})
+
+	f, err := os.Create(filepath.Join(t.TempDir(), "test.wav"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	n, err := Copy(f, src, Header{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 {
+		t.Errorf("Expected 3 samples, got %d", n)
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := Header{Channels: 2, SampleRate: 44100, BitsPerSample: 32, Float: true}
+	if h := r.Header(); h != expected {
+		t.Errorf("Expected %+v, got %+v", expected, h)
+	}
+	chunk, err := r.ReadChunk(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedData := []float32{0.5, -0.5, 0.25, -0.25, 1, -1}
+	if data := chunk.(*wave.Float32Interleaved).Data; !reflect.DeepEqual(expectedData, data) {
+		t.Errorf("Expected %v, got %v", expectedData, data)
+	}
+}
+
+func TestTee(t *testing.T) {
+	chunk := wave.NewInt16Interleaved(wave.ChunkInfo{Len: 10, Channels: 2, SamplingRate: 8000})
+	src := audio.ReaderFunc(func() (wave.Audio, func(), error) {
+		return chunk, func() {}, nil
+	})
+
+	f, err := os.Create(filepath.Join(t.TempDir(), "test.wav"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	transform, closer := Tee(f, Header{})
+	r := transform(src)
+	for i := 0; i < 3; i++ {
+		if _, _, err := r.Read(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := closer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := os.ReadFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 3 chunks of 10 stereo 16 bits samples
+	if size := binary.LittleEndian.Uint32(b[40:]); size != 120 {
+		t.Errorf("Expected a data size of 120, got %d", size)
+	}
+	if size := binary.LittleEndian.Uint32(b[4:]); size != 36+120 {
+		t.Errorf("Expected a RIFF size of %d, got %d", 36+120, size)
+	}
+}
@@ -0,0 +1,468 @@
+// Package y4m implements reading and writing of YUV4MPEG2 streams, the raw
+// video format used by ffmpeg's yuv4mpegpipe and GStreamer's y4menc.
+// Reference: https://wiki.multimedia.cx/index.php/YUV4MPEG2
+package y4m
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pion/mediadevices/pkg/frame"
+	"github.com/pion/mediadevices/pkg/io/video"
+	"github.com/pion/mediadevices/pkg/prop"
+)
+
+const (
+	signature   = "YUV4MPEG2"
+	frameMarker = "FRAME"
+	// maxLineSize limits the size of header lines, which are read before the
+	// frame size is known
+	maxLineSize = 1024
+
+	defaultFrameRateNumerator   = 30
+	defaultFrameRateDenominator = 1
+)
+
+var (
+	errInvalidSignature      = errors.New("y4m: invalid signature")
+	errInvalidFrameHeader    = errors.New("y4m: invalid frame header")
+	errInvalidSize           = errors.New("y4m: width and height must be positive")
+	errInvalidFrameRate      = errors.New("y4m: invalid frame rate")
+	errLineTooLong           = errors.New("y4m: header line is too long")
+	errUnsupportedColorspace = errors.New("y4m: unsupported colorspace")
+	errFrameSize             = errors.New("y4m: frame size doesn't match the header")
+)
+
+// Interlacing is the field order of the frames. Interlaced frames are read
+// and written with their fields interleaved.
+type Interlacing byte
+
+const (
+	// InterlacingProgressive is used for progressive frames
+	InterlacingProgressive Interlacing = 'p'
+	// InterlacingTopFieldFirst is used for interlaced frames with the top field first
+	InterlacingTopFieldFirst Interlacing = 't'
+	// InterlacingBottomFieldFirst is used for interlaced frames with the bottom field first
+	InterlacingBottomFieldFirst Interlacing = 'b'
+	// InterlacingMixed is used when the field order is set per frame
+	InterlacingMixed Interlacing = 'm'
+)
+
+// Header is the stream header.
+type Header struct {
+	Width  int
+	Height int
+	// FrameRateNumerator/FrameRateDenominator is the frame rate in frames per
+	// second, e.g. 30000/1001. When both are zero, the writer uses 30 fps.
+	FrameRateNumerator   int
+	FrameRateDenominator int
+	// Interlacing defaults to InterlacingProgressive.
+	Interlacing Interlacing
+	// PixelAspectNumerator/PixelAspectDenominator is zero when unknown.
+	PixelAspectNumerator   int
+	PixelAspectDenominator int
+	// SubsampleRatio is the chroma subsampling of the frames. Only 4:2:0, 4:2:2
+	// and 4:4:4 are supported. Note that the zero value is 4:4:4.
+	SubsampleRatio image.YCbCrSubsampleRatio
+}
+
+// FrameRate returns the frame rate in frames per second.
+func (h *Header) FrameRate() float32 {
+	if h.FrameRateDenominator == 0 {
+		return 0
+	}
+	return float32(h.FrameRateNumerator) / float32(h.FrameRateDenominator)
+}
+
+// Timestamp returns the presentation timestamp of the n-th frame.
+func (h *Header) Timestamp(n int) time.Duration {
+	if h.FrameRateNumerator == 0 {
+		return 0
+	}
+	return time.Duration(int64(n) * int64(h.FrameRateDenominator) * int64(time.Second) / int64(h.FrameRateNumerator))
+}
+
+// Media returns the properties of the frames read from the stream.
+func (h *Header) Media() prop.Media {
+	var frameFormat frame.Format
+	switch h.SubsampleRatio {
+	case image.YCbCrSubsampleRatio420:
+		frameFormat = frame.FormatI420
+	case image.YCbCrSubsampleRatio422:
+		frameFormat = frame.FormatI422
+	default:
+		frameFormat = frame.FormatI444
+	}
+
+	return prop.Media{
+		Video: prop.Video{
+			Width:       h.Width,
+			Height:      h.Height,
+			FrameRate:   h.FrameRate(),
+			FrameFormat: frameFormat,
+		},
+	}
+}
+
+func (h *Header) marshal() (string, error) {
+	var colorspace string
+	switch h.SubsampleRatio {
+	case image.YCbCrSubsampleRatio420:
+		colorspace = "420jpeg"
+	case image.YCbCrSubsampleRatio422:
+		colorspace = "422"
+	case image.YCbCrSubsampleRatio444:
+		colorspace = "444"
+	default:
+		return "", fmt.Errorf("%w: %s", errUnsupportedColorspace, h.SubsampleRatio)
+	}
+
+	s := fmt.Sprintf("%s W%d H%d F%d:%d I%c", signature, h.Width, h.Height,
+		h.FrameRateNumerator, h.FrameRateDenominator, h.Interlacing)
+	if h.PixelAspectNumerator != 0 && h.PixelAspectDenominator != 0 {
+		s += fmt.Sprintf(" A%d:%d", h.PixelAspectNumerator, h.PixelAspectDenominator)
+	}
+	return s + " C" + colorspace + "\n", nil
+}
+
+func (h *Header) unmarshal(line string) error {
+	fields := strings.Fields(line)
+	if len(fields) == 0 || fields[0] != signature {
+		return errInvalidSignature
+	}
+
+	h.Interlacing = InterlacingProgressive
+	h.SubsampleRatio = image.YCbCrSubsampleRatio420
+	for _, field := range fields[1:] {
+		value := field[1:]
+		var err error
+		switch field[0] {
+		case 'W':
+			h.Width, err = strconv.Atoi(value)
+		case 'H':
+			h.Height, err = strconv.Atoi(value)
+		case 'F':
+			h.FrameRateNumerator, h.FrameRateDenominator, err = parseRatio(value)
+			if err == nil && (h.FrameRateNumerator <= 0 || h.FrameRateDenominator <= 0) {
+				err = errInvalidFrameRate
+			}
+		case 'I':
+			if len(value) != 1 {
+				return fmt.Errorf("y4m: invalid interlacing %q", value)
+			}
+			h.Interlacing = Interlacing(value[0])
+		case 'A':
+			h.PixelAspectNumerator, h.PixelAspectDenominator, err = parseRatio(value)
+		case 'C':
+			h.SubsampleRatio, err = parseColorspace(value)
+		default:
+			// X parameters and unknown tags are ignored
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if h.Width <= 0 || h.Height <= 0 {
+		return errInvalidSize
+	}
+	if h.FrameRateNumerator == 0 {
+		return errInvalidFrameRate
+	}
+	return nil
+}
+
+func parseRatio(s string) (int, int, error) {
+	parts := strings.SplitN(s, ":", 2)
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("y4m: invalid ratio %q", s)
+	}
+	numerator, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, 0, err
+	}
+	denominator, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return 0, 0, err
+	}
+	return numerator, denominator, nil
+}
+
+func parseColorspace(s string) (image.YCbCrSubsampleRatio, error) {
+	switch s {
+	case "420", "420jpeg", "420paldv", "420mpeg2":
+		return image.YCbCrSubsampleRatio420, nil
+	case "422":
+		return image.YCbCrSubsampleRatio422, nil
+	case "444":
+		return image.YCbCrSubsampleRatio444, nil
+	default:
+		return 0, fmt.Errorf("%w: %s", errUnsupportedColorspace, s)
+	}
+}
+
+// readLine reads a header line without the trailing new line
+func readLine(r *bufio.Reader) (string, error) {
+	var line []byte
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			if err == io.EOF && len(line) > 0 {
+				err = io.ErrUnexpectedEOF
+			}
+			return "", err
+		}
+		if b == '\n' {
+			return string(line), nil
+		}
+		if len(line) >= maxLineSize {
+			return "", errLineTooLong
+		}
+		line = append(line, b)
+	}
+}
+
+// Reader reads frames from a YUV4MPEG2 stream.
+type Reader struct {
+	r      *bufio.Reader
+	header Header
+	count  int
+}
+
+// NewReader reads the stream header from r, and returns a reader to read frames.
+func NewReader(r io.Reader) (*Reader, error) {
+	reader := &Reader{r: bufio.NewReader(r)}
+
+	line, err := readLine(reader.r)
+	if err != nil {
+		return nil, err
+	}
+	if err := reader.header.unmarshal(line); err != nil {
+		return nil, err
+	}
+
+	return reader, nil
+}
+
+// Header returns the stream header.
+func (r *Reader) Header() Header {
+	return r.header
+}
+
+// ReadFrame reads the next frame, and its presentation timestamp derived from
+// the frame rate. A new image is allocated for every frame. io.EOF is returned
+// when there are no more frames.
+func (r *Reader) ReadFrame() (*image.YCbCr, time.Duration, error) {
+	line, err := readLine(r.r)
+	if err != nil {
+		return nil, 0, err
+	}
+	if !strings.HasPrefix(line, frameMarker) {
+		return nil, 0, errInvalidFrameHeader
+	}
+
+	img := image.NewYCbCr(image.Rect(0, 0, r.header.Width, r.header.Height), r.header.SubsampleRatio)
+	for _, plane := range [][]byte{img.Y, img.Cb, img.Cr} {
+		if _, err := io.ReadFull(r.r, plane); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, 0, err
+		}
+	}
+
+	timestamp := r.header.Timestamp(r.count)
+	r.count++
+	return img, timestamp, nil
+}
+
+// Writer writes frames to a YUV4MPEG2 stream.
+type Writer struct {
+	w      io.Writer
+	header Header
+	buf    *image.YCbCr
+}
+
+// NewWriter writes the stream header to w, and returns a writer to write frames.
+func NewWriter(w io.Writer, header Header) (*Writer, error) {
+	if header.Width <= 0 || header.Height <= 0 {
+		return nil, errInvalidSize
+	}
+	if header.FrameRateNumerator == 0 && header.FrameRateDenominator == 0 {
+		header.FrameRateNumerator = defaultFrameRateNumerator
+		header.FrameRateDenominator = defaultFrameRateDenominator
+	}
+	if header.FrameRateNumerator <= 0 || header.FrameRateDenominator <= 0 {
+		return nil, errInvalidFrameRate
+	}
+	if header.Interlacing == 0 {
+		header.Interlacing = InterlacingProgressive
+	}
+
+	line, err := header.marshal()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.WriteString(w, line); err != nil {
+		return nil, err
+	}
+
+	return &Writer{w: w, header: header}, nil
+}
+
+// Header returns the stream header, with the defaults applied.
+func (w *Writer) Header() Header {
+	return w.header
+}
+
+// WriteFrame writes a frame. The image size must match the header. Images
+// which aren't image.YCbCr with the header's subsampling are converted.
+func (w *Writer) WriteFrame(img image.Image) error {
+	bounds := img.Bounds()
+	if bounds.Dx() != w.header.Width || bounds.Dy() != w.header.Height {
+		return errFrameSize
+	}
+
+	yuv, ok := img.(*image.YCbCr)
+	if !ok || yuv.SubsampleRatio != w.header.SubsampleRatio {
+		yuv = w.convert(img)
+	}
+
+	if _, err := io.WriteString(w.w, frameMarker+"\n"); err != nil {
+		return err
+	}
+
+	// Planes are written row by row, since the image may be a sub-image
+	cw, ch := chromaSize(w.header.Width, w.header.Height, w.header.SubsampleRatio)
+	for y := 0; y < w.header.Height; y++ {
+		i := yuv.YOffset(bounds.Min.X, bounds.Min.Y+y)
+		if _, err := w.w.Write(yuv.Y[i : i+w.header.Width]); err != nil {
+			return err
+		}
+	}
+	vstep := 1
+	if w.header.SubsampleRatio == image.YCbCrSubsampleRatio420 {
+		vstep = 2
+	}
+	for _, plane := range [][]byte{yuv.Cb, yuv.Cr} {
+		for y := 0; y < ch; y++ {
+			i := yuv.COffset(bounds.Min.X, bounds.Min.Y+y*vstep)
+			if _, err := w.w.Write(plane[i : i+cw]); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// convert converts img to the header's subsampling, by taking the chroma of the
+// top left pixel of each block.
+func (w *Writer) convert(img image.Image) *image.YCbCr {
+	if w.buf == nil {
+		w.buf = image.NewYCbCr(image.Rect(0, 0, w.header.Width, w.header.Height), w.header.SubsampleRatio)
+	}
+
+	bounds := img.Bounds()
+	for y := 0; y < w.header.Height; y++ {
+		for x := 0; x < w.header.Width; x++ {
+			c := color.YCbCrModel.Convert(img.At(bounds.Min.X+x, bounds.Min.Y+y)).(color.YCbCr)
+			w.buf.Y[y*w.buf.YStride+x] = c.Y
+		}
+	}
+
+	cw, ch := chromaSize(w.header.Width, w.header.Height, w.header.SubsampleRatio)
+	hstep, vstep := w.header.Width/cw, w.header.Height/ch
+	if hstep == 0 {
+		hstep = 1
+	}
+	if vstep == 0 {
+		vstep = 1
+	}
+	for y := 0; y < ch; y++ {
+		for x := 0; x < cw; x++ {
+			c := color.YCbCrModel.Convert(img.At(bounds.Min.X+x*hstep, bounds.Min.Y+y*vstep)).(color.YCbCr)
+			w.buf.Cb[y*w.buf.CStride+x] = c.Cb
+			w.buf.Cr[y*w.buf.CStride+x] = c.Cr
+		}
+	}
+
+	// Offsets are computed from the image bounds in WriteFrame
+	shifted := *w.buf
+	shifted.Rect = w.buf.Rect.Add(bounds.Min)
+	return &shifted
+}
+
+func chromaSize(width, height int, ratio image.YCbCrSubsampleRatio) (int, int) {
+	switch ratio {
+	case image.YCbCrSubsampleRatio420:
+		return (width + 1) / 2, (height + 1) / 2
+	case image.YCbCrSubsampleRatio422:
+		return (width + 1) / 2, height
+	default:
+		return width, height
+	}
+}
+
+// Tee returns a video.TransformFunc writing the frames read through it to w.
+// When header.Width and header.Height are zero, the size of the first frame is
+// used, along with its subsampling if it's supported, or 4:2:0 otherwise. The
+// stream header is written along with the first frame.
+func Tee(w io.Writer, header Header) video.TransformFunc {
+	return func(r video.Reader) video.Reader {
+		var writer *Writer
+		return video.ReaderFunc(func() (image.Image, func(), error) {
+			img, release, err := r.Read()
+			if err != nil {
+				return nil, func() {}, err
+			}
+
+			if writer == nil {
+				h := header
+				if h.Width == 0 && h.Height == 0 {
+					h.Width, h.Height = img.Bounds().Dx(), img.Bounds().Dy()
+					h.SubsampleRatio = image.YCbCrSubsampleRatio420
+					if yuv, ok := img.(*image.YCbCr); ok {
+						switch yuv.SubsampleRatio {
+						case image.YCbCrSubsampleRatio422, image.YCbCrSubsampleRatio444:
+							h.SubsampleRatio = yuv.SubsampleRatio
+						}
+					}
+				}
+				if writer, err = NewWriter(w, h); err != nil {
+					release()
+					return nil, func() {}, err
+				}
+			}
+
+			if err := writer.WriteFrame(img); err != nil {
+				release()
+				return nil, func() {}, err
+			}
+			return img, release, nil
+		})
+	}
+}
+
+// Copy writes the frames read from r to w until r returns io.EOF. The header is
+// handled like Tee. It returns the number of frames written.
+func Copy(w io.Writer, r video.Reader, header Header) (int, error) {
+	tee := Tee(w, header)(r)
+	for n := 0; ; n++ {
+		_, release, err := tee.Read()
+		if err == io.EOF {
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
+		release()
+	}
+}
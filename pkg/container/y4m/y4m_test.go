@@ -0,0 +1,260 @@
+package y4m
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pion/mediadevices/pkg/frame"
+	"github.com/pion/mediadevices/pkg/io/video"
+	"github.com/pion/mediadevices/pkg/prop"
+)
+
+func TestHeader(t *testing.T) {
+	testCases := map[string]struct {
+		line     string
+		expected Header
+		err      error
+	}{
+		"Full": {
+			line: "YUV4MPEG2 W640 H480 F30000:1001 It A1:1 C422 XYSCSS=422",
+			expected: Header{
+				Width: 640, Height: 480,
+				FrameRateNumerator: 30000, FrameRateDenominator: 1001,
+				Interlacing:          InterlacingTopFieldFirst,
+				PixelAspectNumerator: 1, PixelAspectDenominator: 1,
+				SubsampleRatio: image.YCbCrSubsampleRatio422,
+			},
+		},
+		"Defaults": {
+			line: "YUV4MPEG2 W3 H2 F25:1",
+			expected: Header{
+				Width: 3, Height: 2,
+				FrameRateNumerator: 25, FrameRateDenominator: 1,
+				Interlacing:    InterlacingProgressive,
+				SubsampleRatio: image.YCbCrSubsampleRatio420,
+			},
+		},
+		"InvalidSignature": {
+			line: "YUV4MPEG W640 H480 F30:1",
+			err:  errInvalidSignature,
+		},
+		"MissingSize": {
+			line: "YUV4MPEG2 W640 F30:1",
+			err:  errInvalidSize,
+		},
+		"HighBitDepth": {
+			line: "YUV4MPEG2 W640 H480 F30:1 C420p10",
+			err:  errUnsupportedColorspace,
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			r, err := NewReader(strings.NewReader(testCase.line + "\n"))
+			if !errors.Is(err, testCase.err) {
+				t.Fatalf("Expected error %v, got %v", testCase.err, err)
+			}
+			if err != nil {
+				return
+			}
+			if h := r.Header(); h != testCase.expected {
+				t.Errorf("Expected %+v, got %+v", testCase.expected, h)
+			}
+		})
+	}
+}
+
+func TestHeaderMedia(t *testing.T) {
+	testCases := map[string]struct {
+		ratio  image.YCbCrSubsampleRatio
+		format frame.Format
+	}{
+		"420": {ratio: image.YCbCrSubsampleRatio420, format: frame.FormatI420},
+		"422": {ratio: image.YCbCrSubsampleRatio422, format: frame.FormatI422},
+		"444": {ratio: image.YCbCrSubsampleRatio444, format: frame.FormatI444},
+	}
+	for name, c := range testCases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			h := Header{Width: 4, Height: 2, FrameRateNumerator: 25, FrameRateDenominator: 1, SubsampleRatio: c.ratio}
+			expected := prop.Video{Width: 4, Height: 2, FrameRate: 25, FrameFormat: c.format}
+			if media := h.Media(); media.Video != expected {
+				t.Errorf("Expected %+v, got %+v", expected, media.Video)
+			}
+		})
+	}
+}
+
+func TestReadWrite(t *testing.T) {
+	ratios := map[string]image.YCbCrSubsampleRatio{
+		"420": image.YCbCrSubsampleRatio420,
+		"422": image.YCbCrSubsampleRatio422,
+		"444": image.YCbCrSubsampleRatio444,
+	}
+
+	for name, ratio := range ratios {
+		ratio := ratio
+		t.Run(name, func(t *testing.T) {
+			// Odd sizes have rounded up chroma planes
+			img := image.NewYCbCr(image.Rect(0, 0, 5, 3), ratio)
+			for i := range img.Y {
+				img.Y[i] = byte(i)
+			}
+			for i := range img.Cb {
+				img.Cb[i] = byte(100 + i)
+				img.Cr[i] = byte(200 + i)
+			}
+
+			var buf bytes.Buffer
+			w, err := NewWriter(&buf, Header{Width: 5, Height: 3, FrameRateNumerator: 10, FrameRateDenominator: 1, SubsampleRatio: ratio})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := 0; i < 2; i++ {
+				if err := w.WriteFrame(img); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			r, err := NewReader(&buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := 0; i < 2; i++ {
+				frame, timestamp, err := r.ReadFrame()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if expected := time.Duration(i) * 100 * time.Millisecond; timestamp != expected {
+					t.Errorf("Expected timestamp %v, got %v", expected, timestamp)
+				}
+				if !bytes.Equal(frame.Y, img.Y) || !bytes.Equal(frame.Cb, img.Cb) || !bytes.Equal(frame.Cr, img.Cr) {
+					t.Errorf("Frame %d doesn't match, expected %+v, got %+v", i, img, frame)
+				}
+			}
+			if _, _, err := r.ReadFrame(); err != io.EOF {
+				t.Errorf("Expected %v, got %v", io.EOF, err)
+			}
+		})
+	}
+}
+
+func TestWriteSubImage(t *testing.T) {
+	img := image.NewYCbCr(image.Rect(0, 0, 4, 4), image.YCbCrSubsampleRatio420)
+	for i := range img.Y {
+		img.Y[i] = byte(i)
+	}
+	for i := range img.Cb {
+		img.Cb[i] = byte(100 + i)
+	}
+	sub := img.SubImage(image.Rect(2, 2, 4, 4))
+
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, Header{Width: 2, Height: 2, SubsampleRatio: image.YCbCrSubsampleRatio420})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WriteFrame(sub); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := NewReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	frame, _, err := r.ReadFrame()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []byte{10, 11, 14, 15}; !bytes.Equal(frame.Y, expected) {
+		t.Errorf("Expected Y %v, got %v", expected, frame.Y)
+	}
+	if expected := []byte{103}; !bytes.Equal(frame.Cb, expected) {
+		t.Errorf("Expected Cb %v, got %v", expected, frame.Cb)
+	}
+}
+
+func TestTee(t *testing.T) {
+	white := image.NewRGBA(image.Rect(0, 0, 4, 2))
+	for i := range white.Pix {
+		white.Pix[i] = 0xff
+	}
+	frames := 3
+	src := video.ReaderFunc(func() (image.Image, func(), error) {
+		if frames == 0 {
+			return nil, func() {}, io.EOF
+		}
+		frames--
+		return white, func() {}, nil
+	})
+
+	var buf bytes.Buffer
+	n, err := Copy(&buf, src, Header{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 {
+		t.Errorf("Expected 3 frames, got %d", n)
+	}
+
+	r, err := NewReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := r.Header()
+	if h.Width != 4 || h.Height != 2 || h.SubsampleRatio != image.YCbCrSubsampleRatio420 || h.FrameRate() != 30 {
+		t.Errorf("Unexpected header %+v", h)
+	}
+	frame, _, err := r.ReadFrame()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := color.YCbCrModel.Convert(color.White).(color.YCbCr)
+	if c := frame.YCbCrAt(3, 1); c != expected {
+		t.Errorf("Expected %v, got %v", expected, c)
+	}
+}
+
+func TestTeeSubsampling(t *testing.T) {
+	testCases := map[string]struct {
+		ratio    image.YCbCrSubsampleRatio
+		expected image.YCbCrSubsampleRatio
+	}{
+		"422": {ratio: image.YCbCrSubsampleRatio422, expected: image.YCbCrSubsampleRatio422},
+		"444": {ratio: image.YCbCrSubsampleRatio444, expected: image.YCbCrSubsampleRatio444},
+		"440": {ratio: image.YCbCrSubsampleRatio440, expected: image.YCbCrSubsampleRatio420},
+		"411": {ratio: image.YCbCrSubsampleRatio411, expected: image.YCbCrSubsampleRatio420},
+		"410": {ratio: image.YCbCrSubsampleRatio410, expected: image.YCbCrSubsampleRatio420},
+	}
+	for name, c := range testCases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			img := image.NewYCbCr(image.Rect(0, 0, 8, 4), c.ratio)
+			src := video.ReaderFunc(func() (image.Image, func(), error) {
+				return img, func() {}, nil
+			})
+
+			var buf bytes.Buffer
+			if _, _, err := Tee(&buf, Header{})(src).Read(); err != nil {
+				t.Fatal(err)
+			}
+			r, err := NewReader(&buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if h := r.Header(); h.SubsampleRatio != c.expected {
+				t.Errorf("Expected %v, got %v", c.expected, h.SubsampleRatio)
+			}
+			if _, _, err := r.ReadFrame(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package audiotest
+
+import (
+	"math"
+	"time"
+
+	"github.com/pion/mediadevices/pkg/wave"
+)
+
+// dtmfMinLevel is the minimum amplitude of both DTMF tones to detect a key
+const dtmfMinLevel = 0.05
+
+// AppendSamples appends the samples of the channel ch of chunk to dst, as
+// floats between -1 and 1, so that they can be analyzed.
+func AppendSamples(dst []float32, chunk wave.Audio, ch int) []float32 {
+	n := chunk.ChunkInfo().Len
+	for i := 0; i < n; i++ {
+		switch s := chunk.At(i, ch).(type) {
+		case wave.Float32Sample:
+			dst = append(dst, float32(s))
+		case wave.Int16Sample:
+			dst = append(dst, float32(s)/0x8000)
+		default:
+			dst = append(dst, float32(float64(s.Int())/0x80000000))
+		}
+	}
+	return dst
+}
+
+// DetectFrequency returns the frequency of a periodic signal, e.g. generated
+// by Sine, from the interval between its rising zero crossings. It returns 0
+// if there aren't at least 2 crossings.
+func DetectFrequency(samples []float32, sampleRate int) float64 {
+	var first, last float64
+	crossings := 0
+	for i := 1; i < len(samples); i++ {
+		a, b := samples[i-1], samples[i]
+		if a >= 0 || b < 0 {
+			continue
+		}
+		// interpolate the position of the crossing between the samples
+		pos := float64(i-1) + float64(-a)/float64(b-a)
+		if crossings == 0 {
+			first = pos
+		}
+		last = pos
+		crossings++
+	}
+	if crossings < 2 {
+		return 0
+	}
+	return float64(crossings-1) * float64(sampleRate) / (last - first)
+}
+
+// ToneLevel returns the amplitude of the component of samples at frequency, in
+// Hz, using the Goertzel algorithm. It's 1 for a full scale sine wave.
+func ToneLevel(samples []float32, sampleRate int, frequency float64) float64 {
+	if len(samples) == 0 {
+		return 0
+	}
+	coeff := 2 * math.Cos(2*math.Pi*frequency/float64(sampleRate))
+	var s1, s2 float64
+	for _, v := range samples {
+		s0 := float64(v) + coeff*s1 - s2
+		s2, s1 = s1, s0
+	}
+	power := s1*s1 + s2*s2 - coeff*s1*s2
+	if power < 0 {
+		power = 0
+	}
+	return 2 * math.Sqrt(power) / float64(len(samples))
+}
+
+// DetectDTMF returns the DTMF key played in samples, which should contain a
+// single tone, e.g. 20 ms of it. ok is false if no key is detected.
+func DetectDTMF(samples []float32, sampleRate int) (key rune, ok bool) {
+	lows := []float64{697, 770, 852, 941}
+	highs := []float64{1209, 1336, 1477, 1633}
+	strongest := func(frequencies []float64) (float64, float64) {
+		var best, level float64
+		for _, f := range frequencies {
+			if l := ToneLevel(samples, sampleRate, f); l > level {
+				best, level = f, l
+			}
+		}
+		return best, level
+	}
+
+	low, lowLevel := strongest(lows)
+	high, highLevel := strongest(highs)
+	if lowLevel < dtmfMinLevel || highLevel < dtmfMinLevel {
+		return 0, false
+	}
+	for k, f := range dtmfFrequencies {
+		if f[0] == low && f[1] == high {
+			return k, true
+		}
+	}
+	return 0, false
+}
+
+// DetectClicks returns the times of the clicks in samples, e.g. generated by
+// Clicks, relative to the first sample. A click starts when the absolute
+// value of a sample reaches threshold, after at least holdOff below it.
+func DetectClicks(samples []float32, sampleRate int, threshold float32, holdOff time.Duration) []time.Duration {
+	quiet := int(holdOff.Seconds() * float64(sampleRate))
+	var clicks []time.Duration
+	// below counts the samples below the threshold, the beginning counts as
+	// quiet
+	below := quiet
+	for i, v := range samples {
+		if v < 0 {
+			v = -v
+		}
+		if v < threshold {
+			below++
+			continue
+		}
+		if below >= quiet {
+			clicks = append(clicks, time.Duration(i)*time.Second/time.Duration(sampleRate))
+		}
+		below = 0
+	}
+	return clicks
+}
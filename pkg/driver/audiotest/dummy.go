@@ -1,29 +1,122 @@
 // Package audiotest provides dummy audio driver for testing.
+//
+// The driver registered as "AudioTest" plays a 480 Hz sine wave. More drivers
+// can be registered with AddAudioTest, playing other signals, whose analyzers
+// can check the audio after it went through a pipeline, e.g. an Opus encoder
+// and decoder.
 package audiotest
 
 import (
 	"context"
+	"errors"
 	"io"
-	"math"
 	"time"
 
+	"github.com/pion/mediadevices/pkg/clock"
 	"github.com/pion/mediadevices/pkg/driver"
 	"github.com/pion/mediadevices/pkg/io/audio"
 	"github.com/pion/mediadevices/pkg/prop"
 	"github.com/pion/mediadevices/pkg/wave"
 )
 
+const defaultLatency = 20 * time.Millisecond
+
+var errInvalidProperties = errors.New("properties must have a positive sample rate and channel count")
+
 func init() {
 	driver.GetManager().Register(
-		&dummy{}, driver.Info{Label: "AudioTest", DeviceType: driver.Microphone},
+		newAudioTest(), driver.Info{Label: "AudioTest", DeviceType: driver.Microphone},
 	)
 }
 
+type options struct {
+	signal     Signal
+	properties []prop.Audio
+	clock      clock.Clock
+}
+
+// Option configures a test audio driver.
+type Option func(*options) error
+
+// WithSignal sets the waveform played by the driver. The default is a 480 Hz
+// sine wave at -12 dBFS. All the channels play the same signal.
+func WithSignal(signal Signal) Option {
+	return func(o *options) error {
+		o.signal = signal
+		return nil
+	}
+}
+
+// WithProperties sets the properties of the driver. Any sample rate and
+// channel count is supported. The samples are int16 if SampleSize is 2 and
+// IsFloat is false, float32 otherwise. The default is 48 kHz float32
+// interleaved, mono or stereo.
+func WithProperties(properties ...prop.Audio) Option {
+	return func(o *options) error {
+		if len(properties) == 0 {
+			return errInvalidProperties
+		}
+		for _, p := range properties {
+			if p.SampleRate <= 0 || p.ChannelCount <= 0 {
+				return errInvalidProperties
+			}
+		}
+		o.properties = properties
+		return nil
+	}
+}
+
+// WithClock sets the clock Read sleeps on until the next chunk is due, one
+// latency after the previous one. The default is the system clock.
+func WithClock(c clock.Clock) Option {
+	return func(o *options) error {
+		o.clock = c
+		return nil
+	}
+}
+
+// AddAudioTest registers a test audio driver configured by opts.
+func AddAudioTest(label string, opts ...Option) error {
+	d := newAudioTest()
+	for _, opt := range opts {
+		if err := opt(&d.options); err != nil {
+			return err
+		}
+	}
+	return driver.GetManager().Register(d, driver.Info{
+		Label:      label,
+		DeviceType: driver.Microphone,
+		Priority:   driver.PriorityNormal,
+	})
+}
+
 type dummy struct {
+	options
 	closed <-chan struct{}
 	cancel func()
 }
 
+func newAudioTest() *dummy {
+	p := prop.Audio{
+		SampleRate:    48000,
+		Latency:       defaultLatency,
+		SampleSize:    4,
+		IsFloat:       true,
+		IsInterleaved: true,
+	}
+	mono, stereo := p, p
+	mono.ChannelCount = 1
+	stereo.ChannelCount = 2
+
+	return &dummy{
+		options: options{
+			signal:     Sine(480, 0.25),
+			properties: []prop.Audio{mono, stereo},
+			clock:      clock.New(),
+		},
+	}
+}
+
 func (d *dummy) Open() error {
 	ctx, cancel := context.WithCancel(context.Background())
 	d.closed = ctx.Done()
@@ -36,20 +129,37 @@ func (d *dummy) Close() error {
 	return nil
 }
 
-func (d *dummy) AudioRecord(p prop.Media) (audio.Reader, error) {
-	var sin [100]float32
-	for i := range sin {
-		sin[i] = float32(math.Sin(2*math.Pi*float64(i)/100) * 0.25) // 480 Hz
+func isInt16(p prop.Audio) bool {
+	return p.SampleSize == 2 && !p.IsFloat
+}
+
+// newChunk allocates a chunk in the format described by p
+func newChunk(p prop.Audio, info wave.ChunkInfo) wave.EditableAudio {
+	switch {
+	case isInt16(p) && p.IsInterleaved:
+		return wave.NewInt16Interleaved(info)
+	case isInt16(p):
+		return wave.NewInt16NonInterleaved(info)
+	case p.IsInterleaved:
+		return wave.NewFloat32Interleaved(info)
+	default:
+		return wave.NewFloat32NonInterleaved(info)
 	}
+}
 
+func (d *dummy) AudioRecord(p prop.Media) (audio.Reader, error) {
+	if p.SampleRate <= 0 || p.ChannelCount <= 0 {
+		return nil, errInvalidProperties
+	}
 	if p.Latency == 0 {
-		p.Latency = 20 * time.Millisecond
+		p.Latency = defaultLatency
 	}
 	nSample := int(uint64(p.SampleRate) * uint64(p.Latency) / uint64(time.Second))
 
-	nextReadTime := time.Now()
-	var phase int
-
+	generate := d.signal(p.SampleRate)
+	int16Samples := isInt16(p.Audio)
+	clk := d.clock
+	nextReadTime := clk.Now()
 	closed := d.closed
 
 	reader := audio.ReaderFunc(func() (wave.Audio, func(), error) {
@@ -59,24 +169,22 @@ func (d *dummy) AudioRecord(p prop.Media) (audio.Reader, error) {
 		default:
 		}
 
-		time.Sleep(nextReadTime.Sub(time.Now()))
+		clk.Sleep(nextReadTime.Sub(clk.Now()))
 		nextReadTime = nextReadTime.Add(p.Latency)
 
-		a := wave.NewFloat32Interleaved(
-			wave.ChunkInfo{
-				Channels:     p.ChannelCount,
-				Len:          nSample,
-				SamplingRate: p.SampleRate,
-			},
-		)
-
+		a := newChunk(p.Audio, wave.ChunkInfo{
+			Channels:     p.ChannelCount,
+			Len:          nSample,
+			SamplingRate: p.SampleRate,
+		})
 		for i := 0; i < nSample; i++ {
-			phase++
-			if phase >= 100 {
-				phase = 0
+			s := generate()
+			var sample wave.Sample = wave.Float32Sample(s)
+			if int16Samples {
+				sample = toInt16(s)
 			}
 			for ch := 0; ch < p.ChannelCount; ch++ {
-				a.SetFloat32(i, ch, wave.Float32Sample(sin[phase]))
+				a.Set(i, ch, sample)
 			}
 		}
 		return a, func() {}, nil
@@ -84,21 +192,20 @@ func (d *dummy) AudioRecord(p prop.Media) (audio.Reader, error) {
 	return reader, nil
 }
 
+func toInt16(s float32) wave.Int16Sample {
+	v := s * 0x7FFF
+	if v > 0x7FFF {
+		v = 0x7FFF
+	} else if v < -0x8000 {
+		v = -0x8000
+	}
+	return wave.Int16Sample(v)
+}
+
 func (d *dummy) Properties() []prop.Media {
-	return []prop.Media{
-		{
-			Audio: prop.Audio{
-				SampleRate:   48000,
-				Latency:      time.Millisecond * 20,
-				ChannelCount: 1,
-			},
-		},
-		{
-			Audio: prop.Audio{
-				SampleRate:   48000,
-				Latency:      time.Millisecond * 20,
-				ChannelCount: 2,
-			},
-		},
+	var properties []prop.Media
+	for _, p := range d.properties {
+		properties = append(properties, prop.Media{Audio: p})
 	}
+	return properties
 }
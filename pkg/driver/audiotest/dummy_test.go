@@ -0,0 +1,132 @@
+package audiotest
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/pion/mediadevices/pkg/clock"
+	"github.com/pion/mediadevices/pkg/prop"
+	"github.com/pion/mediadevices/pkg/wave"
+)
+
+func TestAudioRecord(t *testing.T) {
+	testCases := map[string]struct {
+		audio    prop.Audio
+		expected wave.Audio
+	}{
+		"Int16Interleaved": {
+			audio:    prop.Audio{SampleSize: 2, IsInterleaved: true},
+			expected: &wave.Int16Interleaved{},
+		},
+		"Int16NonInterleaved": {
+			audio:    prop.Audio{SampleSize: 2},
+			expected: &wave.Int16NonInterleaved{},
+		},
+		"Float32Interleaved": {
+			audio:    prop.Audio{SampleSize: 4, IsFloat: true, IsInterleaved: true},
+			expected: &wave.Float32Interleaved{},
+		},
+		"Float32NonInterleaved": {
+			audio:    prop.Audio{SampleSize: 4, IsFloat: true},
+			expected: &wave.Float32NonInterleaved{},
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			d := newAudioTest()
+			if err := WithSignal(Sine(1000, 0.5))(&d.options); err != nil {
+				t.Fatal(err)
+			}
+			if err := d.Open(); err != nil {
+				t.Fatal(err)
+			}
+			defer d.Close()
+
+			p := testCase.audio
+			p.SampleRate = 16000
+			p.ChannelCount = 2
+			p.Latency = 10 * time.Millisecond
+			r, err := d.AudioRecord(prop.Media{Audio: p})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var samples []float32
+			for i := 0; i < 5; i++ {
+				chunk, _, err := r.Read()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got, expected := typeName(chunk), typeName(testCase.expected); got != expected {
+					t.Fatalf("Expected %s, got %s", expected, got)
+				}
+				info := chunk.ChunkInfo()
+				if info.Len != 160 || info.Channels != 2 || info.SamplingRate != 16000 {
+					t.Fatalf("Unexpected chunk info: %+v", info)
+				}
+				samples = AppendSamples(samples, chunk, 1)
+			}
+
+			if f := DetectFrequency(samples, 16000); math.Abs(f-1000) > 1 {
+				t.Errorf("Expected 1000 Hz, got %f", f)
+			}
+			if l := ToneLevel(samples, 16000, 1000); math.Abs(l-0.5) > 0.01 {
+				t.Errorf("Expected a level of 0.5, got %f", l)
+			}
+		})
+	}
+}
+
+func typeName(a wave.Audio) string {
+	switch a.(type) {
+	case *wave.Int16Interleaved:
+		return "Int16Interleaved"
+	case *wave.Int16NonInterleaved:
+		return "Int16NonInterleaved"
+	case *wave.Float32Interleaved:
+		return "Float32Interleaved"
+	case *wave.Float32NonInterleaved:
+		return "Float32NonInterleaved"
+	}
+	return "unknown"
+}
+
+func TestAudioRecordWithClock(t *testing.T) {
+	c := clock.NewManual(time.Unix(0, 0))
+	d := newAudioTest()
+	if err := WithClock(c)(&d.options); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	r, err := d.AudioRecord(d.Properties()[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The first chunk is read right away, the next ones every 20 ms
+	if _, _, err := r.Read(); err != nil {
+		t.Fatal(err)
+	}
+	read := make(chan error)
+	go func() {
+		_, _, err := r.Read()
+		read <- err
+	}()
+	c.BlockUntil(1)
+	select {
+	case <-read:
+		t.Fatal("Expected Read to wait for the clock")
+	default:
+	}
+	c.Add(20 * time.Millisecond)
+	if err := <-read; err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,185 @@
+package audiotest
+
+import (
+	"math"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+// Signal creates the generator of a waveform at sampleRate. The generator
+// returns the successive samples, between -1 and 1.
+type Signal func(sampleRate int) func() float32
+
+// Sine generates a sine wave of frequency, in Hz.
+func Sine(frequency, amplitude float64) Signal {
+	return MultiTone(amplitude, frequency)
+}
+
+// MultiTone generates the sum of sine waves of the given frequencies, in Hz.
+// amplitude is the peak amplitude of the sum.
+func MultiTone(amplitude float64, frequencies ...float64) Signal {
+	return func(sampleRate int) func() float32 {
+		if len(frequencies) == 0 {
+			return func() float32 { return 0 }
+		}
+		phases := make([]float64, len(frequencies))
+		steps := make([]float64, len(frequencies))
+		for i, f := range frequencies {
+			steps[i] = 2 * math.Pi * f / float64(sampleRate)
+		}
+		scale := amplitude / float64(len(frequencies))
+
+		return func() float32 {
+			var v float64
+			for i := range phases {
+				v += math.Sin(phases[i])
+				phases[i] = math.Mod(phases[i]+steps[i], 2*math.Pi)
+			}
+			return float32(v * scale)
+		}
+	}
+}
+
+// LogSweep generates a sine wave whose frequency increases exponentially from
+// from to to, in Hz, during duration. The sweep restarts afterwards.
+func LogSweep(from, to float64, duration time.Duration, amplitude float64) Signal {
+	return func(sampleRate int) func() float32 {
+		length := int(duration.Seconds() * float64(sampleRate))
+		if length < 1 {
+			length = 1
+		}
+		ratio := math.Log(to / from)
+		var phase float64
+		var i int
+
+		return func() float32 {
+			v := math.Sin(phase)
+			f := from * math.Exp(ratio*float64(i)/float64(length))
+			phase = math.Mod(phase+2*math.Pi*f/float64(sampleRate), 2*math.Pi)
+			i++
+			if i >= length {
+				i, phase = 0, 0
+			}
+			return float32(v * amplitude)
+		}
+	}
+}
+
+// WhiteNoise generates uniform white noise. The noise is pseudo-random, and
+// the same on every run.
+func WhiteNoise(amplitude float64) Signal {
+	return func(sampleRate int) func() float32 {
+		random := rand.New(rand.NewSource(0))
+		return func() float32 {
+			return float32((random.Float64()*2 - 1) * amplitude)
+		}
+	}
+}
+
+// PinkNoise generates noise whose power decreases by 3 dB per octave, using
+// the filter of Paul Kellet. The noise is pseudo-random, and the same on every
+// run.
+func PinkNoise(amplitude float64) Signal {
+	return func(sampleRate int) func() float32 {
+		random := rand.New(rand.NewSource(0))
+		var b [7]float64
+		return func() float32 {
+			white := random.Float64()*2 - 1
+			b[0] = 0.99886*b[0] + white*0.0555179
+			b[1] = 0.99332*b[1] + white*0.0750759
+			b[2] = 0.96900*b[2] + white*0.1538520
+			b[3] = 0.86650*b[3] + white*0.3104856
+			b[4] = 0.55000*b[4] + white*0.5329522
+			b[5] = -0.7616*b[5] - white*0.0168980
+			v := b[0] + b[1] + b[2] + b[3] + b[4] + b[5] + b[6] + white*0.5362
+			b[6] = white * 0.115926
+			// the filter has a gain of about 4 at most
+			v /= 4
+			if v > 1 {
+				v = 1
+			} else if v < -1 {
+				v = -1
+			}
+			return float32(v * amplitude)
+		}
+	}
+}
+
+// Silence generates silence.
+func Silence() Signal {
+	return func(sampleRate int) func() float32 {
+		return func() float32 { return 0 }
+	}
+}
+
+// dtmfFrequencies lists the low and high frequencies of the DTMF keys
+var dtmfFrequencies = map[rune][2]float64{
+	'1': {697, 1209}, '2': {697, 1336}, '3': {697, 1477}, 'A': {697, 1633},
+	'4': {770, 1209}, '5': {770, 1336}, '6': {770, 1477}, 'B': {770, 1633},
+	'7': {852, 1209}, '8': {852, 1336}, '9': {852, 1477}, 'C': {852, 1633},
+	'*': {941, 1209}, '0': {941, 1336}, '#': {941, 1477}, 'D': {941, 1633},
+}
+
+// DTMF generates the DTMF tones of the keys of sequence, each played during
+// toneDuration and followed by gap of silence. The sequence is repeated.
+// Unknown keys are played as silence.
+func DTMF(sequence string, toneDuration, gap time.Duration, amplitude float64) Signal {
+	keys := []rune(strings.ToUpper(sequence))
+	return func(sampleRate int) func() float32 {
+		if len(keys) == 0 {
+			return func() float32 { return 0 }
+		}
+		toneLength := int(toneDuration.Seconds() * float64(sampleRate))
+		keyLength := toneLength + int(gap.Seconds()*float64(sampleRate))
+		if keyLength < 1 {
+			keyLength = 1
+		}
+		var i int
+
+		return func() float32 {
+			key := keys[i/keyLength%len(keys)]
+			pos := i % keyLength
+			i++
+			if i >= keyLength*len(keys) {
+				i = 0
+			}
+
+			f, ok := dtmfFrequencies[key]
+			if !ok || pos >= toneLength {
+				return 0
+			}
+			t := float64(pos) / float64(sampleRate)
+			v := math.Sin(2*math.Pi*f[0]*t) + math.Sin(2*math.Pi*f[1]*t)
+			return float32(v * amplitude / 2)
+		}
+	}
+}
+
+// clickLength is the duration of the pulses generated by Clicks
+const clickLength = time.Millisecond
+
+// Clicks generates pulses of 1 ms every interval, starting with one. They can
+// be timed with DetectClicks to measure the latency.
+func Clicks(interval time.Duration, amplitude float64) Signal {
+	return func(sampleRate int) func() float32 {
+		period := int(interval.Seconds() * float64(sampleRate))
+		length := int(clickLength.Seconds() * float64(sampleRate))
+		if length < 1 {
+			length = 1
+		}
+		if period < length {
+			period = length
+		}
+		var i int
+
+		return func() float32 {
+			pos := i
+			i = (i + 1) % period
+			if pos < length {
+				return float32(amplitude)
+			}
+			return 0
+		}
+	}
+}
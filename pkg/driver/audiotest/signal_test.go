@@ -0,0 +1,132 @@
+package audiotest
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+const testSampleRate = 48000
+
+func generate(signal Signal, d time.Duration) []float32 {
+	next := signal(testSampleRate)
+	samples := make([]float32, int(d.Seconds()*testSampleRate))
+	for i := range samples {
+		samples[i] = next()
+	}
+	return samples
+}
+
+func TestSine(t *testing.T) {
+	samples := generate(Sine(1000, 0.5), 100*time.Millisecond)
+	if f := DetectFrequency(samples, testSampleRate); math.Abs(f-1000) > 1 {
+		t.Errorf("Expected 1000 Hz, got %f", f)
+	}
+	if l := ToneLevel(samples, testSampleRate, 1000); math.Abs(l-0.5) > 0.01 {
+		t.Errorf("Expected a level of 0.5, got %f", l)
+	}
+}
+
+func TestMultiTone(t *testing.T) {
+	samples := generate(MultiTone(0.8, 440, 2500), 100*time.Millisecond)
+	for _, f := range []float64{440, 2500} {
+		if l := ToneLevel(samples, testSampleRate, f); math.Abs(l-0.4) > 0.01 {
+			t.Errorf("Expected a level of 0.4 at %f Hz, got %f", f, l)
+		}
+	}
+	if l := ToneLevel(samples, testSampleRate, 1000); l > 0.01 {
+		t.Errorf("Expected no tone at 1000 Hz, got %f", l)
+	}
+}
+
+func TestLogSweep(t *testing.T) {
+	samples := generate(LogSweep(100, 10000, time.Second, 1), time.Second)
+	// the frequency is multiplied by 100 in 1s
+	testCases := map[string]struct {
+		at time.Duration
+	}{
+		"Start":  {at: 0},
+		"Middle": {at: 490 * time.Millisecond},
+		"End":    {at: 970 * time.Millisecond},
+	}
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			start := int(testCase.at.Seconds() * testSampleRate)
+			window := samples[start : start+testSampleRate/50]
+			// the frequency at the center of the window
+			expected := 100 * math.Pow(100, (testCase.at+10*time.Millisecond).Seconds())
+			f := DetectFrequency(window, testSampleRate)
+			if math.Abs(f-expected)/expected > 0.05 {
+				t.Errorf("Expected about %f Hz, got %f", expected, f)
+			}
+		})
+	}
+}
+
+func TestNoise(t *testing.T) {
+	testCases := map[string]Signal{
+		"White": WhiteNoise(0.5),
+		"Pink":  PinkNoise(0.5),
+	}
+	for name, signal := range testCases {
+		signal := signal
+		t.Run(name, func(t *testing.T) {
+			samples := generate(signal, time.Second)
+			var sum float64
+			for _, v := range samples {
+				if v > 0.5 || v < -0.5 {
+					t.Fatalf("Sample out of range: %f", v)
+				}
+				sum += float64(v) * float64(v)
+			}
+			if rms := math.Sqrt(sum / float64(len(samples))); rms < 0.01 {
+				t.Errorf("Expected noise, got a RMS of %f", rms)
+			}
+		})
+	}
+
+	// pink noise has more energy in the low frequencies
+	pink := generate(PinkNoise(1), time.Second)
+	if low, high := ToneLevel(pink, testSampleRate, 100), ToneLevel(pink, testSampleRate, 10000); low < high {
+		t.Errorf("Expected more energy at 100 Hz than 10 kHz, got %f and %f", low, high)
+	}
+
+	for _, v := range generate(Silence(), 10*time.Millisecond) {
+		if v != 0 {
+			t.Fatalf("Expected silence, got %f", v)
+		}
+	}
+}
+
+func TestDTMF(t *testing.T) {
+	const tone = 50 * time.Millisecond
+	samples := generate(DTMF("159#", tone, tone, 0.5), 8*tone)
+
+	keyLength := int(2 * tone.Seconds() * testSampleRate)
+	toneLength := keyLength / 2
+	for i, expected := range "159#" {
+		key, ok := DetectDTMF(samples[i*keyLength:i*keyLength+toneLength], testSampleRate)
+		if !ok || key != expected {
+			t.Errorf("Expected key %c, got %c (%v)", expected, key, ok)
+		}
+		if _, ok := DetectDTMF(samples[i*keyLength+toneLength:(i+1)*keyLength], testSampleRate); ok {
+			t.Errorf("Expected no key in the gap after %c", expected)
+		}
+	}
+}
+
+func TestClicks(t *testing.T) {
+	samples := generate(Clicks(100*time.Millisecond, 0.9), 350*time.Millisecond)
+	clicks := DetectClicks(samples, testSampleRate, 0.5, 10*time.Millisecond)
+
+	expected := []time.Duration{0, 100 * time.Millisecond, 200 * time.Millisecond, 300 * time.Millisecond}
+	if len(clicks) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, clicks)
+	}
+	for i := range clicks {
+		if clicks[i] != expected[i] {
+			t.Errorf("Expected %v, got %v", expected, clicks)
+		}
+	}
+}
@@ -14,6 +14,8 @@ import (
 	"sync"
 
 	"github.com/blackjack/webcam"
+	"github.com/pion/mediadevices/pkg/codec"
+	"github.com/pion/mediadevices/pkg/container/h264"
 	"github.com/pion/mediadevices/pkg/driver"
 	"github.com/pion/mediadevices/pkg/frame"
 	"github.com/pion/mediadevices/pkg/io/video"
@@ -28,6 +30,10 @@ const (
 var (
 	errReadTimeout = errors.New("read timeout")
 	errEmptyFrame  = errors.New("empty frame")
+	// errEncodedNotSupported is returned when the selected format can't be passed through
+	errEncodedNotSupported = errors.New("format can't be read without decoding")
+	errAlreadyStreaming    = errors.New("camera is already streaming")
+	forceKeyFrameControl   = webcam.ControlID(C.V4L2_CID_MPEG_VIDEO_FORCE_KEY_FRAME)
 	// Reference: https://commons.wikimedia.org/wiki/File:Vector_Video_Standards2.svg
 	supportedResolutions = [][2]int{
 		{320, 240},
@@ -70,6 +76,8 @@ type camera struct {
 	started         bool
 	mutex           sync.Mutex
 	cancel          func()
+	encoded         *encodedBroadcaster
+	encodedMutex    sync.Mutex
 }
 
 func init() {
@@ -124,6 +132,7 @@ func newCamera(path string) *camera {
 		webcam.PixelFormat(C.V4L2_PIX_FMT_UYVY):   frame.FormatUYVY,
 		webcam.PixelFormat(C.V4L2_PIX_FMT_MJPEG):  frame.FormatMJPEG,
 		webcam.PixelFormat(C.V4L2_PIX_FMT_Z16):    frame.FormatZ16,
+		webcam.PixelFormat(C.V4L2_PIX_FMT_H264):   frame.FormatH264,
 	}
 
 	reversedFormats := make(map[frame.Format]webcam.PixelFormat)
@@ -182,6 +191,9 @@ func (c *camera) Close() error {
 		c.cam.StopStreaming()
 		c.cancel = nil
 	}
+	c.encodedMutex.Lock()
+	c.encoded = nil
+	c.encodedMutex.Unlock()
 	c.cam.Close()
 	return nil
 }
@@ -192,8 +204,77 @@ func (c *camera) VideoRecord(p prop.Media) (video.Reader, error) {
 		return nil, err
 	}
 
+	readFrame, err := c.startStreaming(p)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf []byte
+	r := video.ReaderFunc(func() (img image.Image, release func(), err error) {
+		b, err := readFrame(buf)
+		if err != nil {
+			return nil, func() {}, err
+		}
+
+		buf = b[:cap(b)]
+		return decoder.Decode(b, p.Width, p.Height)
+	})
+
+	return r, nil
+}
+
+// EncodedCodec implements driver.EncodedSource. Only H.264 can be passed through,
+// other formats, including MJPEG which has no WebRTC payload format, are decoded.
+func (c *camera) EncodedCodec(p prop.Media) *codec.RTPCodec {
+	if p.FrameFormat != frame.FormatH264 {
+		return nil
+	}
+	return codec.NewRTPH264Codec(90000)
+}
+
+// EncodedRecord implements driver.EncodedSource. The stream is shared by all the
+// readers, which start at the next key frame.
+func (c *camera) EncodedRecord(p prop.Media) (driver.EncodedReader, error) {
+	if c.EncodedCodec(p) == nil {
+		return nil, errEncodedNotSupported
+	}
+
+	c.encodedMutex.Lock()
+	defer c.encodedMutex.Unlock()
+
+	if c.encoded == nil {
+		readFrame, err := c.startStreaming(p)
+		if err != nil {
+			return nil, err
+		}
+
+		var forceKeyFrame func() error
+		if _, ok := c.cam.GetControls()[forceKeyFrameControl]; ok {
+			cam := c.cam
+			forceKeyFrame = func() error {
+				return cam.SetControl(forceKeyFrameControl, 1)
+			}
+		}
+
+		c.encoded = newEncodedBroadcaster(func() ([]byte, error) {
+			// Frames are queued by the readers, so every frame needs its own buffer
+			return readFrame(nil)
+		}, h264.IsKeyFrame, forceKeyFrame)
+	}
+
+	return c.encoded.newReader(), nil
+}
+
+// startStreaming configures the camera with p, and starts streaming. The returned
+// function reads the next frame, copied to buf if it's large enough.
+func (c *camera) startStreaming(p prop.Media) (func(buf []byte) ([]byte, error), error) {
+	// The raw and encoded streams can't share the device
+	if c.cancel != nil {
+		return nil, errAlreadyStreaming
+	}
+
 	pf := c.reversedFormats[p.FrameFormat]
-	_, _, _, err = c.cam.SetImageFormat(pf, uint32(p.Width), uint32(p.Height))
+	_, _, _, err := c.cam.SetImageFormat(pf, uint32(p.Width), uint32(p.Height))
 	if err != nil {
 		return nil, err
 	}
@@ -215,8 +296,7 @@ func (c *camera) VideoRecord(p prop.Media) (video.Reader, error) {
 
 	ctx, cancel := context.WithCancel(context.Background())
 	c.cancel = cancel
-	var buf []byte
-	r := video.ReaderFunc(func() (img image.Image, release func(), err error) {
+	return func(buf []byte) ([]byte, error) {
 		// Lock to avoid accessing the buffer after StopStreaming()
 		c.mutex.Lock()
 		defer c.mutex.Unlock()
@@ -225,23 +305,23 @@ func (c *camera) VideoRecord(p prop.Media) (video.Reader, error) {
 		for i := 0; i < maxEmptyFrameCount; i++ {
 			if ctx.Err() != nil {
 				// Return EOF if the camera is already closed.
-				return nil, func() {}, io.EOF
+				return nil, io.EOF
 			}
 
 			err := cam.WaitForFrame(readTimeoutSec)
 			switch err.(type) {
 			case nil:
 			case *webcam.Timeout:
-				return nil, func() {}, errReadTimeout
+				return nil, errReadTimeout
 			default:
 				// Camera has been stopped.
-				return nil, func() {}, err
+				return nil, err
 			}
 
 			b, err := cam.ReadFrame()
 			if err != nil {
 				// Camera has been stopped.
-				return nil, func() {}, err
+				return nil, err
 			}
 
 			// Frame is empty.
@@ -259,12 +339,10 @@ func (c *camera) VideoRecord(p prop.Media) (video.Reader, error) {
 			// from this reader will be Go safe. Otherwise, it's possible that outside of this reader
 			// that this memory is still being used even after we close it.
 			n := copy(buf, b)
-			return decoder.Decode(buf[:n], p.Width, p.Height)
+			return buf[:n], nil
 		}
-		return nil, func() {}, errEmptyFrame
-	})
-
-	return r, nil
+		return nil, errEmptyFrame
+	}, nil
 }
 
 func (c *camera) Properties() []prop.Media {
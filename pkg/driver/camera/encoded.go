@@ -0,0 +1,156 @@
+package camera
+
+import (
+	"io"
+	"sync"
+	"time"
+
+	"github.com/pion/mediadevices/pkg/driver"
+)
+
+// encodedQueueSize is the number of frames buffered for each encoded reader.
+// Compressed frames depend on each other, so a reader falling behind restarts
+// from the next key frame instead of skipping single frames.
+const encodedQueueSize = 30
+
+// encodedBroadcaster shares the compressed frames of a camera between multiple
+// readers, since the device can only be streamed once.
+type encodedBroadcaster struct {
+	mu            sync.Mutex
+	readers       map[*encodedReader]struct{}
+	err           error
+	isKeyFrame    func(frame []byte) bool
+	forceKeyFrame func() error
+}
+
+// newEncodedBroadcaster starts reading frames with readFrame until it fails.
+// forceKeyFrame is nil if the device can't be requested key frames.
+func newEncodedBroadcaster(readFrame func() ([]byte, error), isKeyFrame func([]byte) bool, forceKeyFrame func() error) *encodedBroadcaster {
+	b := &encodedBroadcaster{
+		readers:       make(map[*encodedReader]struct{}),
+		isKeyFrame:    isKeyFrame,
+		forceKeyFrame: forceKeyFrame,
+	}
+	go b.run(readFrame)
+	return b
+}
+
+func (b *encodedBroadcaster) run(readFrame func() ([]byte, error)) {
+	start := time.Now()
+	for {
+		data, err := readFrame()
+
+		b.mu.Lock()
+		if err != nil {
+			b.err = err
+			for r := range b.readers {
+				close(r.frames)
+			}
+			b.readers = nil
+			b.mu.Unlock()
+			return
+		}
+
+		frame := driver.EncodedFrame{Data: data, Timestamp: time.Since(start)}
+		keyFrame := b.isKeyFrame(data)
+		var needKeyFrame bool
+		for r := range b.readers {
+			if !r.started {
+				if !keyFrame {
+					continue
+				}
+				r.started = true
+			}
+
+			select {
+			case r.frames <- frame:
+			default:
+				// The reader is too slow, drop the queued frames and wait for the next key frame
+				for len(r.frames) > 0 {
+					<-r.frames
+				}
+				r.started = false
+				needKeyFrame = true
+			}
+		}
+		b.mu.Unlock()
+
+		if needKeyFrame {
+			b.requestKeyFrame()
+		}
+	}
+}
+
+func (b *encodedBroadcaster) requestKeyFrame() {
+	if b.forceKeyFrame != nil {
+		_ = b.forceKeyFrame()
+	}
+}
+
+// newReader creates a reader starting at the next key frame.
+func (b *encodedBroadcaster) newReader() driver.EncodedReader {
+	r := &encodedReader{
+		broadcaster: b,
+		frames:      make(chan driver.EncodedFrame, encodedQueueSize),
+		done:        make(chan struct{}),
+	}
+
+	b.mu.Lock()
+	if b.readers == nil {
+		close(r.frames)
+	} else {
+		b.readers[r] = struct{}{}
+	}
+	b.mu.Unlock()
+
+	b.requestKeyFrame()
+	if b.forceKeyFrame != nil {
+		return &keyFrameEncodedReader{r}
+	}
+	return r
+}
+
+type encodedReader struct {
+	broadcaster *encodedBroadcaster
+	frames      chan driver.EncodedFrame
+	// started is protected by the broadcaster's mutex
+	started   bool
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func (r *encodedReader) Read() (driver.EncodedFrame, func(), error) {
+	select {
+	case frame, ok := <-r.frames:
+		if !ok {
+			r.broadcaster.mu.Lock()
+			err := r.broadcaster.err
+			r.broadcaster.mu.Unlock()
+			return driver.EncodedFrame{}, func() {}, err
+		}
+		return frame, func() {}, nil
+	case <-r.done:
+		return driver.EncodedFrame{}, func() {}, io.EOF
+	}
+}
+
+func (r *encodedReader) Close() error {
+	r.closeOnce.Do(func() {
+		r.broadcaster.mu.Lock()
+		delete(r.broadcaster.readers, r)
+		r.broadcaster.mu.Unlock()
+		close(r.done)
+	})
+	return nil
+}
+
+// keyFrameEncodedReader is returned when the device supports key frame requests,
+// so that the track can forward them.
+type keyFrameEncodedReader struct {
+	*encodedReader
+}
+
+// ForceKeyFrame implements codec.KeyFrameController.
+func (r *keyFrameEncodedReader) ForceKeyFrame() error {
+	return r.broadcaster.forceKeyFrame()
+}
@@ -0,0 +1,90 @@
+package camera
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/pion/mediadevices/pkg/codec"
+	"github.com/pion/mediadevices/pkg/driver"
+)
+
+func TestEncodedBroadcaster(t *testing.T) {
+	frames := make(chan []byte)
+	keyFrameRequests := make(chan struct{}, 10)
+	readFrame := func() ([]byte, error) {
+		frame, ok := <-frames
+		if !ok {
+			return nil, io.EOF
+		}
+		return frame, nil
+	}
+	isKeyFrame := func(frame []byte) bool { return frame[0] == 'K' }
+	forceKeyFrame := func() error {
+		keyFrameRequests <- struct{}{}
+		return nil
+	}
+
+	expect := func(r driver.EncodedReader, expected ...string) {
+		t.Helper()
+		var last time.Duration
+		for _, e := range expected {
+			frame, _, err := r.Read()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(frame.Data) != e {
+				t.Errorf("Expected frame %s, got %s", e, frame.Data)
+			}
+			if frame.Timestamp < last {
+				t.Errorf("Expected increasing timestamps, got %v after %v", frame.Timestamp, last)
+			}
+			last = frame.Timestamp
+		}
+	}
+
+	b := newEncodedBroadcaster(readFrame, isKeyFrame, forceKeyFrame)
+	r1 := b.newReader()
+	if len(keyFrameRequests) != 1 {
+		t.Errorf("Expected a key frame request for the new reader, got %d", len(keyFrameRequests))
+	}
+	if _, ok := r1.(codec.KeyFrameController); !ok {
+		t.Error("Expected the reader to implement KeyFrameController")
+	}
+
+	// Readers start at the next key frame
+	frames <- []byte("P1")
+	frames <- []byte("K1")
+	expect(r1, "K1")
+
+	r2 := b.newReader()
+	frames <- []byte("P2")
+	frames <- []byte("K2")
+	expect(r1, "P2", "K2")
+	expect(r2, "K2")
+
+	r2.Close()
+	if _, _, err := r2.Read(); err != io.EOF {
+		t.Errorf("Expected %v, got %v", io.EOF, err)
+	}
+
+	close(frames)
+	if _, _, err := r1.Read(); err != io.EOF {
+		t.Errorf("Expected %v, got %v", io.EOF, err)
+	}
+
+	// Readers created after the end of the stream end right away
+	if _, _, err := b.newReader().Read(); err != io.EOF {
+		t.Errorf("Expected %v, got %v", io.EOF, err)
+	}
+}
+
+func TestEncodedBroadcasterWithoutKeyFrameControl(t *testing.T) {
+	b := newEncodedBroadcaster(func() ([]byte, error) {
+		return nil, io.EOF
+	}, func([]byte) bool { return true }, nil)
+
+	if _, ok := b.newReader().(codec.KeyFrameController); ok {
+		t.Error("Expected the reader not to implement KeyFrameController")
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"encoding/binary"
 	"fmt"
 	"io"
-	"time"
 
 	"github.com/pion/mediadevices/pkg/driver"
 	"github.com/pion/mediadevices/pkg/io/audio"
@@ -17,9 +16,11 @@ type audioCmdSource struct {
 	bufferSampleCount int
 	showStdErr        bool
 	label             string
+	// autoDetect is set when the command writes a WAV stream
+	autoDetect bool
 }
 
-func AddAudioCmdSource(label string, command string, mediaProperties []prop.Media, readTimeout uint32, sampleBufferSize int, showStdErr bool) error {
+func AddAudioCmdSource(label string, command string, mediaProperties []prop.Media, readTimeout uint32, sampleBufferSize int, showStdErr bool, opts ...Option) error {
 	audioCmdSource := &audioCmdSource{
 		cmdSource:         newCmdSource(command, mediaProperties, readTimeout),
 		bufferSampleCount: sampleBufferSize,
@@ -29,6 +30,9 @@ func AddAudioCmdSource(label string, command string, mediaProperties []prop.Medi
 	if len(audioCmdSource.cmdArgs) == 0 || audioCmdSource.cmdArgs[0] == "" {
 		return errInvalidCommand // no command specified
 	}
+	if err := audioCmdSource.applyOptions(opts); err != nil {
+		return err
+	}
 
 	// register this audio source with the driver manager
 	err := driver.GetManager().Register(audioCmdSource, driver.Info{
@@ -43,6 +47,14 @@ func AddAudioCmdSource(label string, command string, mediaProperties []prop.Medi
 }
 
 func (c *audioCmdSource) AudioRecord(inputProp prop.Media) (audio.Reader, error) {
+	if c.autoDetect {
+		stdOut, err := c.start(c.showStdErr, fmt.Sprintf("%s stderr > ", c.label+":"+c.cmdArgs[0]), inputProp.Audio)
+		if err != nil {
+			return nil, err
+		}
+		return c.recordWAV(stdOut)
+	}
+
 	decoder, err := wave.NewDecoder(&wave.RawFormat{
 		SampleSize:  inputProp.SampleSize,
 		IsFloat:     inputProp.IsFloat,
@@ -53,30 +65,12 @@ func (c *audioCmdSource) AudioRecord(inputProp prop.Media) (audio.Reader, error)
 		return nil, err
 	}
 
-	if c.showStdErr {
-		// get the command's standard error
-		stdErr, err := c.execCmd.StderrPipe()
-		if err != nil {
-			return nil, err
-		}
-		// send standard error to the console as debug logs prefixed with "{command} stdErr >"
-		go c.logStdIoWithPrefix(fmt.Sprintf("%s stderr > ", c.label+":"+c.cmdArgs[0]), stdErr)
-	}
-
-	// get the command's standard output
-	stdOut, err := c.execCmd.StdoutPipe()
+	var stdOut io.Reader
+	stdOut, err = c.start(c.showStdErr, fmt.Sprintf("%s stderr > ", c.label+":"+c.cmdArgs[0]), inputProp.Audio)
 	if err != nil {
 		return nil, err
 	}
 
-	// add environment variables to the command for each media property
-	c.addEnvVarsFromStruct(inputProp.Audio, c.showStdErr)
-
-	// start the command
-	if err := c.execCmd.Start(); err != nil {
-		return nil, err
-	}
-
 	// claclulate the sample size and chunk buffer size (as a multple of the sample size)
 	sampleSize := inputProp.ChannelCount * inputProp.SampleSize
 	chunkSize := c.bufferSampleCount * sampleSize
@@ -86,41 +80,61 @@ func (c *audioCmdSource) AudioRecord(inputProp prop.Media) (audio.Reader, error)
 	}
 
 	var chunkBuf []byte = make([]byte, chunkSize)
-	doneChan := make(chan error)
-	r := audio.ReaderFunc(func() (chunk wave.Audio, release func(), err error) {
-		go func() {
-			if _, err := io.ReadFull(stdOut, chunkBuf); err == io.ErrUnexpectedEOF {
-				doneChan <- io.EOF
-			} else if err != nil {
-				doneChan <- err
+	read := func() error {
+		if _, err := io.ReadFull(stdOut, chunkBuf); err != nil {
+			if err == io.ErrUnexpectedEOF {
+				err = io.EOF
 			}
-			doneChan <- nil
-		}()
+			return err
+		}
+		return nil
+	}
+	reset := func(newStdOut io.Reader) { stdOut = newStdOut }
 
-		select {
-		case err := <-doneChan:
-			if err != nil {
-				return nil, nil, err
-			} else {
-				decodedChunk, err := decoder.Decode(endienness, chunkBuf, inputProp.ChannelCount)
-				if err != nil {
-					return nil, nil, err
-				}
-				// FIXME: the decoder should also fill this information
-				switch decodedChunk := decodedChunk.(type) {
-				case *wave.Float32Interleaved:
-					decodedChunk.Size.SamplingRate = inputProp.SampleRate
-				case *wave.Int16Interleaved:
-					decodedChunk.Size.SamplingRate = inputProp.SampleRate
-				default:
-					panic("unsupported format")
-				}
-				return decodedChunk, func() {}, err
-			}
-		case <-time.After(time.Duration(c.readTimeout) * time.Second):
-			return nil, func() {}, errReadTimeout
+	r := audio.ReaderFunc(func() (chunk wave.Audio, release func(), err error) {
+		if err := c.read(read, reset); err != nil {
+			return nil, func() {}, err
+		}
+
+		decodedChunk, err := decoder.Decode(endienness, chunkBuf, inputProp.ChannelCount)
+		if err != nil {
+			return nil, nil, err
 		}
+		// FIXME: the decoder should also fill this information
+		if err := setSamplingRate(decodedChunk, inputProp.SampleRate); err != nil {
+			return nil, func() {}, err
+		}
+		return decodedChunk, func() {}, nil
 	})
 
 	return r, nil
 }
+
+// setSamplingRate sets the sampling rate of the chunks decoded by a wave.Decoder
+func setSamplingRate(chunk wave.Audio, rate int) error {
+	switch chunk := chunk.(type) {
+	case *wave.Float32Interleaved:
+		chunk.Size.SamplingRate = rate
+	case *wave.Float32NonInterleaved:
+		chunk.Size.SamplingRate = rate
+	case *wave.Uint8Interleaved:
+		chunk.Size.SamplingRate = rate
+	case *wave.Uint8NonInterleaved:
+		chunk.Size.SamplingRate = rate
+	case *wave.Int16Interleaved:
+		chunk.Size.SamplingRate = rate
+	case *wave.Int16NonInterleaved:
+		chunk.Size.SamplingRate = rate
+	case *wave.Int24Interleaved:
+		chunk.Size.SamplingRate = rate
+	case *wave.Int24NonInterleaved:
+		chunk.Size.SamplingRate = rate
+	case *wave.Int32Interleaved:
+		chunk.Size.SamplingRate = rate
+	case *wave.Int32NonInterleaved:
+		chunk.Size.SamplingRate = rate
+	default:
+		return errUnsupportedChunk
+	}
+	return nil
+}
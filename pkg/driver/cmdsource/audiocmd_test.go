@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/pion/mediadevices/pkg/prop"
+	"github.com/pion/mediadevices/pkg/wave"
 )
 
 // const minInt32 int32 = -2147483648
@@ -149,3 +150,18 @@ func TestWavFloatLeAudioCmdOut(t *testing.T) {
 func TestWavFloatBeAudioCmdOut(t *testing.T) {
 	RunAudioCmdTest(t, 110, 1, 44103, 1, 256, "f32be")
 }
+
+func TestSetSamplingRate(t *testing.T) {
+	chunk := wave.NewInt16Interleaved(wave.ChunkInfo{Len: 4, Channels: 1})
+	if err := setSamplingRate(chunk, 48000); err != nil {
+		t.Fatal(err)
+	}
+	if rate := chunk.ChunkInfo().SamplingRate; rate != 48000 {
+		t.Errorf("Expected a sampling rate of 48000, got %d", rate)
+	}
+
+	unsupported := struct{ wave.Audio }{chunk}
+	if err := setSamplingRate(unsupported, 44100); err != errUnsupportedChunk {
+		t.Errorf("Expected %v, got %v", errUnsupportedChunk, err)
+	}
+}
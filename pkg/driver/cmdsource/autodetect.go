@@ -0,0 +1,185 @@
+package cmdsource
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"time"
+
+	"github.com/pion/mediadevices/pkg/container/wav"
+	"github.com/pion/mediadevices/pkg/container/y4m"
+	"github.com/pion/mediadevices/pkg/driver"
+	"github.com/pion/mediadevices/pkg/io/audio"
+	"github.com/pion/mediadevices/pkg/io/video"
+	"github.com/pion/mediadevices/pkg/prop"
+	"github.com/pion/mediadevices/pkg/wave"
+)
+
+// autoDetectLatency is the duration of the audio chunks read from WAV streams
+const autoDetectLatency = 20 * time.Millisecond
+
+var errUnknownStream = errors.New("unknown stream format, expected YUV4MPEG2 or WAV")
+
+// AddAutoDetectCmdSource registers a source reading a self-describing stream
+// from the standard output of command: YUV4MPEG2 for video, e.g.
+// `ffmpeg -f yuv4mpegpipe -`, or WAV for audio, e.g. `ffmpeg -f wav -`.
+// The command is started right away to read the properties from the stream
+// header, and keeps running until the source is recorded, so that it's only run
+// once. The source shows up as a video or audio device depending on the stream.
+func AddAutoDetectCmdSource(label string, command string, readTimeout uint32, showStdErr bool, opts ...Option) error {
+	source := newCmdSource(command, nil, readTimeout)
+	if len(source.cmdArgs) == 0 || source.cmdArgs[0] == "" {
+		return errInvalidCommand // no command specified
+	}
+	if err := source.applyOptions(opts); err != nil {
+		return err
+	}
+	// the probed command is the one recorded, so its standard error is logged too
+	source.showStdErr = showStdErr
+	source.stdErrPrefix = fmt.Sprintf("%s stdErr> ", label+":"+source.cmdArgs[0])
+
+	var media prop.Media
+	probed, err := source.probe(func(stdOut io.Reader) (err error) {
+		media, err = detectStream(stdOut)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	var adapter driver.Adapter
+	if media.Video != (prop.Video{}) {
+		videoCmdSource := &videoCmdSource{
+			cmdSource:  newCmdSource(command, []prop.Media{media}, readTimeout),
+			label:      label,
+			showStdErr: showStdErr,
+			autoDetect: true,
+		}
+		videoCmdSource.options = source.options
+		videoCmdSource.probed = probed
+		adapter = videoCmdSource
+	} else {
+		bufferSampleCount := int(int64(media.SampleRate) * int64(autoDetectLatency) / int64(time.Second))
+		if bufferSampleCount < 1 {
+			bufferSampleCount = 1
+		}
+		audioCmdSource := &audioCmdSource{
+			cmdSource:         newCmdSource(command, []prop.Media{media}, readTimeout),
+			bufferSampleCount: bufferSampleCount,
+			label:             label,
+			showStdErr:        showStdErr,
+			autoDetect:        true,
+		}
+		audioCmdSource.options = source.options
+		audioCmdSource.probed = probed
+		adapter = audioCmdSource
+	}
+
+	err = driver.GetManager().Register(adapter, driver.Info{
+		Label:      label,
+		DeviceType: driver.CmdSource,
+		Priority:   driver.PriorityNormal,
+	})
+	if err != nil {
+		_ = probed.process.stop(source.stopTimeout)
+	}
+	return err
+}
+
+// detectStream reads the properties from the header of a YUV4MPEG2 or WAV stream
+func detectStream(r io.Reader) (prop.Media, error) {
+	reader := bufio.NewReader(r)
+	magic, err := reader.Peek(4)
+	if err != nil {
+		return prop.Media{}, err
+	}
+
+	switch string(magic) {
+	case "YUV4":
+		y4mReader, err := y4m.NewReader(reader)
+		if err != nil {
+			return prop.Media{}, err
+		}
+		header := y4mReader.Header()
+		return header.Media(), nil
+	case "RIFF", "RF64":
+		wavReader, err := wav.NewReader(reader)
+		if err != nil {
+			return prop.Media{}, err
+		}
+		header := wavReader.Header()
+		return header.Media(autoDetectLatency), nil
+	default:
+		return prop.Media{}, errUnknownStream
+	}
+}
+
+// recordY4M reads the frames of a YUV4MPEG2 stream. The properties of the
+// stream header are used, even if they changed since the command was probed.
+func (c *videoCmdSource) recordY4M(stdOut io.Reader) (video.Reader, error) {
+	var reader *y4m.Reader
+	var img image.Image
+	read := func() (err error) {
+		if reader == nil {
+			if reader, err = y4m.NewReader(stdOut); err != nil {
+				reader = nil
+				return err
+			}
+			if header := reader.Header(); header.Media().Video != c.props[0].Video {
+				logger.Debugf("%s: stream properties changed to %+v", c.label, header)
+			}
+		}
+
+		img, _, err = reader.ReadFrame()
+		if err == io.ErrUnexpectedEOF {
+			err = io.EOF
+		}
+		return err
+	}
+	reset := func(newStdOut io.Reader) {
+		stdOut = newStdOut
+		reader = nil
+	}
+
+	r := video.ReaderFunc(func() (image.Image, func(), error) {
+		if err := c.read(read, reset); err != nil {
+			return nil, func() {}, err
+		}
+		return img, func() {}, nil
+	})
+	return r, nil
+}
+
+// recordWAV reads the samples of a WAV stream in chunks of bufferSampleCount.
+func (c *audioCmdSource) recordWAV(stdOut io.Reader) (audio.Reader, error) {
+	var reader *wav.Reader
+	var chunk wave.Audio
+	read := func() (err error) {
+		if reader == nil {
+			if reader, err = wav.NewReader(stdOut); err != nil {
+				reader = nil
+				return err
+			}
+			if header := reader.Header(); header.Media(autoDetectLatency).Audio != c.props[0].Audio {
+				logger.Debugf("%s: stream properties changed to %+v", c.label, header)
+			}
+		}
+
+		chunk, err = reader.ReadChunk(c.bufferSampleCount)
+		return err
+	}
+	reset := func(newStdOut io.Reader) {
+		stdOut = newStdOut
+		reader = nil
+	}
+
+	r := audio.ReaderFunc(func() (wave.Audio, func(), error) {
+		if err := c.read(read, reset); err != nil {
+			return nil, func() {}, err
+		}
+		return chunk, func() {}, nil
+	})
+	return r, nil
+}
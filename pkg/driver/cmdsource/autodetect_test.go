@@ -0,0 +1,156 @@
+package cmdsource
+
+import (
+	"fmt"
+	"image"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pion/mediadevices/pkg/container/wav"
+	"github.com/pion/mediadevices/pkg/container/y4m"
+	"github.com/pion/mediadevices/pkg/driver"
+	"github.com/pion/mediadevices/pkg/frame"
+	"github.com/pion/mediadevices/pkg/prop"
+	"github.com/pion/mediadevices/pkg/wave"
+)
+
+func getAutoDetectDriver(t *testing.T, label string) driver.Driver {
+	for _, d := range driver.GetManager().Query(driver.FilterDeviceType(driver.CmdSource)) {
+		if d.Info().Label == label {
+			return d
+		}
+	}
+	t.Fatalf("Driver %s not found", label)
+	return nil
+}
+
+func TestAutoDetectY4M(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh command not found in path. Skipping test. Err: ", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "test.y4m")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := y4m.NewWriter(f, y4m.Header{
+		Width: 4, Height: 2,
+		FrameRateNumerator: 25, FrameRateDenominator: 1,
+		SubsampleRatio: image.YCbCrSubsampleRatio420,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	img := image.NewYCbCr(image.Rect(0, 0, 4, 2), image.YCbCrSubsampleRatio420)
+	img.Y[0] = 42
+	if err := w.WriteFrame(img); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	// the command counts its runs
+	runs := filepath.Join(t.TempDir(), "runs")
+	command := fmt.Sprintf(`sh -c "echo >> %s; cat %s"`, runs, path)
+	if err := AddAutoDetectCmdSource("autodetect y4m", command, 10, false); err != nil {
+		t.Fatal(err)
+	}
+	d := getAutoDetectDriver(t, "autodetect y4m")
+
+	expected := prop.Video{Width: 4, Height: 2, FrameRate: 25, FrameFormat: frame.FormatI420}
+	if err := d.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	if p := d.Properties(); len(p) != 1 || p[0].Video != expected {
+		t.Fatalf("Expected %+v, got %+v", expected, p)
+	}
+	r, err := d.(driver.VideoRecorder).VideoRecord(d.Properties()[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	frame, _, err := r.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if yuv, ok := frame.(*image.YCbCr); !ok || yuv.Y[0] != 42 || yuv.Rect != img.Rect {
+		t.Errorf("Unexpected frame: %+v", frame)
+	}
+	// the probed command is the one recorded
+	if b, err := os.ReadFile(runs); err != nil || len(b) != 1 {
+		t.Errorf("Expected the command to run once, got %d runs: %v", len(b), err)
+	}
+}
+
+func TestAutoDetectWAV(t *testing.T) {
+	if _, err := exec.LookPath("cat"); err != nil {
+		t.Skip("cat command not found in path. Skipping test. Err: ", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "test.wav")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := wav.NewWriter(f, wav.Header{Channels: 2, SampleRate: 8000, BitsPerSample: 24})
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunk := wave.NewFloat32Interleaved(wave.ChunkInfo{Len: 200, Channels: 2, SamplingRate: 8000})
+	chunk.Data[0] = 0.5
+	if err := w.WriteChunk(chunk); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	if err := AddAutoDetectCmdSource("autodetect wav", "cat "+path, 10, false); err != nil {
+		t.Fatal(err)
+	}
+	d := getAutoDetectDriver(t, "autodetect wav")
+
+	expected := prop.Audio{
+		ChannelCount:  2,
+		SampleRate:    8000,
+		Latency:       20 * time.Millisecond,
+		SampleSize:    4,
+		IsFloat:       true,
+		IsInterleaved: true,
+	}
+	if err := d.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	if p := d.Properties(); len(p) != 1 || p[0].Audio != expected {
+		t.Fatalf("Expected %+v, got %+v", expected, p)
+	}
+	r, err := d.(driver.AudioRecorder).AudioRecord(d.Properties()[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	read, _, err := r.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := read.ChunkInfo()
+	if info.Len != 160 || info.Channels != 2 || info.SamplingRate != 8000 {
+		t.Errorf("Unexpected chunk info: %+v", info)
+	}
+	if s := read.At(0, 0); s != wave.Float32Sample(0.5) {
+		t.Errorf("Expected 0.5, got %v", s)
+	}
+}
+
+func TestAutoDetectUnknownStream(t *testing.T) {
+	if _, err := exec.LookPath("echo"); err != nil {
+		t.Skip("echo command not found in path. Skipping test. Err: ", err)
+	}
+
+	if err := AddAutoDetectCmdSource("autodetect unknown", "echo hello", 10, false); err != errUnknownStream {
+		t.Errorf("Expected %v, got %v", errUnknownStream, err)
+	}
+}
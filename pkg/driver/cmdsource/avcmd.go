@@ -0,0 +1,374 @@
+package cmdsource
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/pion/mediadevices/pkg/driver"
+	"github.com/pion/mediadevices/pkg/frame"
+	"github.com/pion/mediadevices/pkg/io/audio"
+	"github.com/pion/mediadevices/pkg/io/video"
+	"github.com/pion/mediadevices/pkg/prop"
+	"github.com/pion/mediadevices/pkg/wave"
+)
+
+// Stream types of the multiplexed packets read by AddAVCmdSource.
+const (
+	AVStreamVideo byte = 'V'
+	AVStreamAudio byte = 'A'
+)
+
+const (
+	avPacketHeaderSize = 13
+	// avQueueSize is the number of packets buffered for each stream. The oldest
+	// packets are dropped when a stream isn't read fast enough, so that it
+	// doesn't block the other one.
+	avQueueSize = 30
+)
+
+var errUnknownAVStream = errors.New("unknown stream type in multiplexed packet")
+
+type avPacket struct {
+	payload []byte
+	// due is when the packet should be delivered, according to its timestamp
+	due time.Time
+}
+
+// avStream receives the packets of a stream type
+type avStream struct {
+	packets chan avPacket
+	// ended is set once packets is closed after the command ended, the packets
+	// already queued are still read
+	ended bool
+	// closed is closed with the driver of the stream, to stop waiting for the
+	// next packet to be due
+	closed chan struct{}
+}
+
+// avCmdSource runs a command writing multiplexed video and audio packets, and
+// dispatches them to a video and an audio driver.
+type avCmdSource struct {
+	cmdSource
+	label      string
+	showStdErr bool
+	videoProp  prop.Video
+	audioProp  prop.Audio
+
+	avMu sync.Mutex
+	// opened counts the opened drivers, the command is stopped once both are closed
+	opened  int
+	started bool
+	streams map[byte]*avStream
+	err     error
+	// generation is incremented when the drivers are opened again, so that the
+	// demuxer of a previous run doesn't end the new streams
+	generation int
+}
+
+// AddAVCmdSource registers a video and an audio driver reading a multiplexed
+// stream from the standard output of a single command, so that both are
+// captured in sync. Both drivers share the given label.
+//
+// The stream is a sequence of packets, each made of a 13 bytes header followed
+// by its payload:
+//   - stream type, 1 byte: AVStreamVideo or AVStreamAudio
+//   - capture timestamp, 8 bytes: big-endian signed nanoseconds
+//   - payload size, 4 bytes: big-endian
+//
+// Video payloads are single frames in videoProperties.FrameFormat, audio
+// payloads contain any number of samples in the format of audioProperties.
+// Packets are delivered to the tracks according to their timestamps, so that
+// video and audio stay aligned.
+func AddAVCmdSource(label string, command string, videoProperties prop.Video, audioProperties prop.Audio, readTimeout uint32, showStdErr bool, opts ...Option) error {
+	source := &avCmdSource{
+		cmdSource: newCmdSource(command, []prop.Media{
+			{Video: videoProperties},
+			{Audio: audioProperties},
+		}, readTimeout),
+		label:      label,
+		showStdErr: showStdErr,
+		videoProp:  videoProperties,
+		audioProp:  audioProperties,
+		streams:    make(map[byte]*avStream),
+	}
+	if len(source.cmdArgs) == 0 || source.cmdArgs[0] == "" {
+		return errInvalidCommand // no command specified
+	}
+	if err := source.applyOptions(opts); err != nil {
+		return err
+	}
+
+	adapters := []driver.Adapter{&avVideoDriver{source}, &avAudioDriver{source}}
+	for _, adapter := range adapters {
+		err := driver.GetManager().Register(adapter, driver.Info{
+			Label:      label,
+			DeviceType: driver.CmdSource,
+			Priority:   driver.PriorityNormal,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *avCmdSource) open() error {
+	c.avMu.Lock()
+	defer c.avMu.Unlock()
+
+	c.opened++
+	if c.opened > 1 {
+		return nil
+	}
+	c.started = false
+	c.err = nil
+	c.generation++
+	return c.cmdSource.Open()
+}
+
+func (c *avCmdSource) close(streamType byte) error {
+	c.avMu.Lock()
+	if stream, ok := c.streams[streamType]; ok {
+		if !stream.ended {
+			close(stream.packets)
+		}
+		close(stream.closed)
+		delete(c.streams, streamType)
+	}
+	c.opened--
+	last := c.opened == 0
+	c.avMu.Unlock()
+
+	if !last {
+		return nil
+	}
+	return c.cmdSource.Close()
+}
+
+// subscribe starts the command if it's not running yet, and returns the
+// packets of streamType.
+func (c *avCmdSource) subscribe(streamType byte) (*avStream, error) {
+	c.avMu.Lock()
+	defer c.avMu.Unlock()
+
+	stream := &avStream{
+		packets: make(chan avPacket, avQueueSize),
+		closed:  make(chan struct{}),
+	}
+	if c.err != nil {
+		// the command already ended
+		close(stream.packets)
+		return stream, nil
+	}
+
+	if !c.started {
+		stdOut, err := c.start(c.showStdErr, fmt.Sprintf("%s stdErr> ", c.label+":"+c.cmdArgs[0]), c.videoProp, c.audioProp)
+		if err != nil {
+			return nil, err
+		}
+		c.started = true
+		go c.demux(stdOut, c.generation)
+	}
+	c.streams[streamType] = stream
+	return stream, nil
+}
+
+// {BLOCKING GOROUTINE} demux reads the packets of the command and dispatches
+// them to the subscribed streams until reading fails.
+func (c *avCmdSource) demux(stdOut io.Reader, generation int) {
+	var header [avPacketHeaderSize]byte
+	var payload []byte
+	// the clock maps the timestamps of the command to the local time, it's reset
+	// when the command is restarted
+	var start time.Time
+	var origin time.Duration
+
+	read := func() error {
+		if _, err := io.ReadFull(stdOut, header[:]); err != nil {
+			if err == io.ErrUnexpectedEOF {
+				err = io.EOF
+			}
+			return err
+		}
+		size := binary.BigEndian.Uint32(header[9:])
+		if size > maxFrameSize {
+			return errFrameTooLarge
+		}
+		// the payload is queued, so it can't be reused
+		payload = make([]byte, size)
+		if _, err := io.ReadFull(stdOut, payload); err != nil {
+			if err == io.ErrUnexpectedEOF {
+				err = io.EOF
+			}
+			return err
+		}
+		return nil
+	}
+	reset := func(newStdOut io.Reader) {
+		stdOut = newStdOut
+		start = time.Time{}
+	}
+
+	for {
+		if err := c.read(read, reset); err != nil {
+			c.avMu.Lock()
+			if c.generation == generation {
+				c.err = err
+				for _, stream := range c.streams {
+					if !stream.ended {
+						close(stream.packets)
+						stream.ended = true
+					}
+				}
+			}
+			c.avMu.Unlock()
+			return
+		}
+
+		streamType := header[0]
+		if streamType != AVStreamVideo && streamType != AVStreamAudio {
+			logger.Warnf("%s: %v: %#x", c.label, errUnknownAVStream, streamType)
+			continue
+		}
+		timestamp := time.Duration(binary.BigEndian.Uint64(header[1:]))
+		if start.IsZero() {
+			start, origin = time.Now(), timestamp
+		}
+		packet := avPacket{payload: payload, due: start.Add(timestamp - origin)}
+
+		c.avMu.Lock()
+		if stream, ok := c.streams[streamType]; ok && c.generation == generation {
+			select {
+			case stream.packets <- packet:
+			default:
+				// the stream is too slow, drop its oldest packet
+				select {
+				case <-stream.packets:
+				default:
+				}
+				stream.packets <- packet
+				logger.Debugf("%s: dropped a %c packet", c.label, streamType)
+			}
+		}
+		c.avMu.Unlock()
+	}
+}
+
+// next returns the next payload of stream when it's due
+func (c *avCmdSource) next(stream *avStream) ([]byte, error) {
+	packet, ok := <-stream.packets
+	if !ok {
+		c.avMu.Lock()
+		err := c.err
+		c.avMu.Unlock()
+		if err == nil {
+			err = io.EOF
+		}
+		return nil, err
+	}
+
+	if wait := time.Until(packet.due); wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-stream.closed:
+			return nil, io.EOF
+		}
+	}
+	return packet.payload, nil
+}
+
+type avVideoDriver struct {
+	*avCmdSource
+}
+
+func (d *avVideoDriver) Open() error {
+	return d.open()
+}
+
+func (d *avVideoDriver) Close() error {
+	return d.close(AVStreamVideo)
+}
+
+func (d *avVideoDriver) Properties() []prop.Media {
+	return d.props[:1]
+}
+
+func (d *avVideoDriver) VideoRecord(inputProp prop.Media) (video.Reader, error) {
+	decoder, err := frame.NewDecoder(d.videoProp.FrameFormat)
+	if err != nil {
+		return nil, err
+	}
+	stream, err := d.subscribe(AVStreamVideo)
+	if err != nil {
+		return nil, err
+	}
+
+	r := video.ReaderFunc(func() (image.Image, func(), error) {
+		payload, err := d.next(stream)
+		if err != nil {
+			return nil, func() {}, err
+		}
+		return decoder.Decode(payload, d.videoProp.Width, d.videoProp.Height)
+	})
+	return r, nil
+}
+
+type avAudioDriver struct {
+	*avCmdSource
+}
+
+func (d *avAudioDriver) Open() error {
+	return d.open()
+}
+
+func (d *avAudioDriver) Close() error {
+	return d.close(AVStreamAudio)
+}
+
+func (d *avAudioDriver) Properties() []prop.Media {
+	return d.props[1:]
+}
+
+func (d *avAudioDriver) AudioRecord(inputProp prop.Media) (audio.Reader, error) {
+	decoder, err := wave.NewDecoder(&wave.RawFormat{
+		SampleSize:  d.audioProp.SampleSize,
+		IsFloat:     d.audioProp.IsFloat,
+		Interleaved: d.audioProp.IsInterleaved,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var endianness binary.ByteOrder = binary.LittleEndian
+	if d.audioProp.IsBigEndian {
+		endianness = binary.BigEndian
+	}
+	stream, err := d.subscribe(AVStreamAudio)
+	if err != nil {
+		return nil, err
+	}
+
+	r := audio.ReaderFunc(func() (wave.Audio, func(), error) {
+		payload, err := d.next(stream)
+		if err != nil {
+			return nil, func() {}, err
+		}
+
+		chunk, err := decoder.Decode(endianness, payload, d.audioProp.ChannelCount)
+		if err != nil {
+			return nil, func() {}, err
+		}
+		// FIXME: the decoder should also fill this information
+		if err := setSamplingRate(chunk, d.audioProp.SampleRate); err != nil {
+			return nil, func() {}, err
+		}
+		return chunk, func() {}, nil
+	})
+	return r, nil
+}
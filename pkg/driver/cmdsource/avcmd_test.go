@@ -0,0 +1,155 @@
+package cmdsource
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pion/mediadevices/pkg/driver"
+	"github.com/pion/mediadevices/pkg/frame"
+	"github.com/pion/mediadevices/pkg/prop"
+	"github.com/pion/mediadevices/pkg/wave"
+)
+
+func writeAVPacket(buf *bytes.Buffer, streamType byte, timestamp time.Duration, payload []byte) {
+	var header [avPacketHeaderSize]byte
+	header[0] = streamType
+	binary.BigEndian.PutUint64(header[1:], uint64(timestamp))
+	binary.BigEndian.PutUint32(header[9:], uint32(len(payload)))
+	buf.Write(header[:])
+	buf.Write(payload)
+}
+
+func TestAVCmdSource(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh command not found in path. Skipping test. Err: ", err)
+	}
+
+	const interval = 50 * time.Millisecond
+	var stream bytes.Buffer
+	for i := 0; i < 2; i++ {
+		timestamp := time.Second + time.Duration(i)*interval
+		// Z16 frame of 2x1 pixels, and 2 mono int16 samples
+		writeAVPacket(&stream, AVStreamVideo, timestamp, []byte{byte(i), 0, 0, 0})
+		writeAVPacket(&stream, AVStreamAudio, timestamp, []byte{byte(i), 0, 0, 0})
+	}
+	writeAVPacket(&stream, 'X', 0, []byte{1})
+	path := filepath.Join(t.TempDir(), "test.av")
+	if err := os.WriteFile(path, stream.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	videoProp := prop.Video{Width: 2, Height: 1, FrameFormat: frame.FormatZ16}
+	audioProp := prop.Audio{ChannelCount: 1, SampleRate: 40, SampleSize: 2, IsInterleaved: true}
+	// The command waits for both streams to be subscribed. The path is used as
+	// label to find the drivers of this test.
+	command := fmt.Sprintf(`sh -c "sleep 0.1; cat %s"`, path)
+	if err := AddAVCmdSource(path, command, videoProp, audioProp, 10, false); err != nil {
+		t.Fatal(err)
+	}
+
+	var videoDriver, audioDriver driver.Driver
+	for _, d := range driver.GetManager().Query(driver.FilterDeviceType(driver.CmdSource)) {
+		if d.Info().Label != path {
+			continue
+		}
+		if _, ok := d.(driver.VideoRecorder); ok {
+			videoDriver = d
+		} else {
+			audioDriver = d
+		}
+	}
+	if videoDriver == nil || audioDriver == nil {
+		t.Fatal("Expected a video and an audio driver")
+	}
+
+	for _, d := range []driver.Driver{videoDriver, audioDriver} {
+		if err := d.Open(); err != nil {
+			t.Fatal(err)
+		}
+		defer d.Close()
+	}
+	if p := videoDriver.Properties(); len(p) != 1 || p[0].Video != videoProp {
+		t.Errorf("Expected %+v, got %+v", videoProp, p)
+	}
+	if p := audioDriver.Properties(); len(p) != 1 || p[0].Audio != audioProp {
+		t.Errorf("Expected %+v, got %+v", audioProp, p)
+	}
+
+	// Subscribe both streams before the packets are read
+	videoReader, err := videoDriver.(driver.VideoRecorder).VideoRecord(videoDriver.Properties()[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	audioReader, err := audioDriver.(driver.AudioRecorder).AudioRecord(audioDriver.Properties()[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	for i := 0; i < 2; i++ {
+		img, _, err := videoReader.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v := img.(*image.Gray16).Pix; v[1] != byte(i) {
+			t.Errorf("Unexpected video frame %d: %v", i, v)
+		}
+
+		chunk, _, err := audioReader.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s := chunk.At(0, 0); s != wave.Int16Sample(i) {
+			t.Errorf("Unexpected audio chunk %d: %v", i, s)
+		}
+		if info := chunk.ChunkInfo(); info.Len != 2 || info.SamplingRate != 40 {
+			t.Errorf("Unexpected audio chunk info: %+v", info)
+		}
+	}
+	// The second packets are delivered according to their timestamps
+	if elapsed := time.Since(start); elapsed < interval*4/5 {
+		t.Errorf("Expected the packets to be paced, took %v", elapsed)
+	}
+
+	for _, err := range []error{
+		func() error { _, _, err := videoReader.Read(); return err }(),
+		func() error { _, _, err := audioReader.Read(); return err }(),
+	} {
+		if !errors.Is(err, io.EOF) {
+			t.Errorf("Expected %v, got %v", io.EOF, err)
+		}
+	}
+}
+
+func TestAVNextClose(t *testing.T) {
+	c := &avCmdSource{}
+	stream := &avStream{
+		packets: make(chan avPacket, 1),
+		closed:  make(chan struct{}),
+	}
+	stream.packets <- avPacket{due: time.Now().Add(time.Hour)}
+
+	done := make(chan error)
+	go func() {
+		_, err := c.next(stream)
+		done <- err
+	}()
+	close(stream.closed)
+	select {
+	case err := <-done:
+		if err != io.EOF {
+			t.Errorf("Expected %v, got %v", io.EOF, err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected closing the stream to stop waiting for the packet")
+	}
+}
@@ -2,6 +2,7 @@ package cmdsource
 
 import (
 	"bufio"
+	"bytes"
 	"errors"
 	"fmt"
 	"io"
@@ -9,26 +10,180 @@ import (
 	"os/exec"
 	"reflect"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/shlex"
+	pionlogging "github.com/pion/logging"
 	"github.com/pion/mediadevices/internal/logging"
 	"github.com/pion/mediadevices/pkg/prop"
 )
 
+const (
+	defaultStopTimeout    = 3 * time.Second
+	defaultInitialBackoff = 500 * time.Millisecond
+	defaultMaxBackoff     = 10 * time.Second
+)
+
 var (
 	errReadTimeout       = errors.New("read timeout")
 	errInvalidCommand    = errors.New("invalid command")
 	errUnsupportedFormat = errors.New("Unsupported frame format, no frame size function found")
+	errClosed            = errors.New("command source closed")
+	errInvalidLogLevel   = errors.New("invalid log level")
+	errUnsupportedChunk  = errors.New("unsupported audio chunk type")
 )
 
 var logger = logging.NewLogger("mediadevices/driver/cmdsource")
 
+// ExitError is returned by the readers when the command can't be read anymore,
+// and isn't restarted. It reports the exit status of the command.
+type ExitError struct {
+	Command string
+	// ExitCode is the exit code of the command, or -1 if it was terminated by
+	// a signal
+	ExitCode int
+	// Err is the error which stopped the reading, e.g. io.EOF when the command
+	// closed its output
+	Err error
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("%s exited with code %d: %v", e.Command, e.ExitCode, e.Err)
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+// RestartPolicy defines how a command is restarted when its output can't be
+// read, e.g. because it crashed. The backoff doubles after each consecutive
+// restart, and is reset once a frame is read successfully.
+type RestartPolicy struct {
+	// MaxAttempts is the number of consecutive restarts before giving up. Zero
+	// disables restarts, and a negative value restarts forever.
+	MaxAttempts int
+	// InitialBackoff is the delay before the first restart. The default is 500 ms.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay between restarts. The default is 10 seconds.
+	MaxBackoff time.Duration
+}
+
+type options struct {
+	restartPolicy RestartPolicy
+	stopTimeout   time.Duration
+	stdErrLevel   pionlogging.LogLevel
+	stdin         io.Reader
+}
+
+// Option configures a command source.
+type Option func(*options) error
+
+// WithRestartPolicy sets how the command is restarted. By default, it isn't.
+func WithRestartPolicy(policy RestartPolicy) Option {
+	return func(o *options) error {
+		if policy.InitialBackoff <= 0 {
+			policy.InitialBackoff = defaultInitialBackoff
+		}
+		if policy.MaxBackoff <= 0 {
+			policy.MaxBackoff = defaultMaxBackoff
+		}
+		o.restartPolicy = policy
+		return nil
+	}
+}
+
+// WithStopTimeout sets how long the command is given to exit after being
+// interrupted, before being killed. The default is 3 seconds.
+func WithStopTimeout(timeout time.Duration) Option {
+	return func(o *options) error {
+		o.stopTimeout = timeout
+		return nil
+	}
+}
+
+// WithStdErrLevel sets the level at which the lines of the command's standard
+// error are logged when showStdErr is set. The default is LogLevelInfo.
+func WithStdErrLevel(level pionlogging.LogLevel) Option {
+	return func(o *options) error {
+		if level < pionlogging.LogLevelError || level > pionlogging.LogLevelTrace {
+			return errInvalidLogLevel
+		}
+		o.stdErrLevel = level
+		return nil
+	}
+}
+
+// WithStdin forwards r to the standard input of the command, e.g. to send it
+// control commands. Data written while the command isn't running is dropped.
+// The standard input of the command is closed once r returns an error.
+func WithStdin(r io.Reader) Option {
+	return func(o *options) error {
+		o.stdin = r
+		return nil
+	}
+}
+
+// process is a running instance of the command
+type process struct {
+	cmd      *exec.Cmd
+	stdin    io.WriteCloser
+	waitOnce sync.Once
+	waitErr  error
+}
+
+// wait waits for the process to exit. It must only be called once the output
+// isn't read anymore.
+func (p *process) wait() error {
+	p.waitOnce.Do(func() {
+		p.waitErr = p.cmd.Wait()
+	})
+	return p.waitErr
+}
+
+// stop interrupts the process, and kills it if it doesn't exit within timeout.
+func (p *process) stop(timeout time.Duration) error {
+	_ = p.cmd.Process.Signal(os.Interrupt) // send SIGINT to process to stop it
+	done := make(chan error, 1)
+	go func() { done <- p.wait() }()
+	select {
+	case err := <-done:
+		return err // command exited normally or with an error code
+	case <-time.After(timeout):
+		// command timed out, kill it & return error
+		if err := p.cmd.Process.Kill(); err != nil {
+			return err
+		}
+		return <-done
+	}
+}
+
 type cmdSource struct {
 	cmdArgs     []string
 	props       []prop.Media
 	readTimeout uint32 // in seconds
-	execCmd     *exec.Cmd
+	options
+
+	mu sync.Mutex
+	// process is nil until the command is started, and after it's stopped
+	process *process
+	// env, showStdErr and stdErrPrefix are kept to restart the command
+	env          []string
+	showStdErr   bool
+	stdErrPrefix string
+	attempts     int
+	closed       chan struct{}
+	stdinOnce    sync.Once
+	// probed is the command run by probe, which is read by the first start
+	// instead of running the command again
+	probed *probedCommand
+}
+
+// probedCommand is a command whose standard output was probed. The probed bytes
+// are read again from stdOut.
+type probedCommand struct {
+	process *process
+	stdOut  io.ReadCloser
 }
 
 func init() {
@@ -44,46 +199,297 @@ func newCmdSource(command string, mediaProperties []prop.Media, readTimeout uint
 		cmdArgs:     cmdArgs,
 		props:       mediaProperties,
 		readTimeout: readTimeout,
+		options: options{
+			stopTimeout: defaultStopTimeout,
+			stdErrLevel: pionlogging.LogLevelInfo,
+		},
+	}
+}
+
+// applyOptions applies opts on top of the defaults of newCmdSource
+func (c *cmdSource) applyOptions(opts []Option) error {
+	for _, opt := range opts {
+		if err := opt(&c.options); err != nil {
+			return err
+		}
 	}
+	return nil
 }
 
 func (c *cmdSource) Open() error {
-	c.execCmd = exec.Command(c.cmdArgs[0], c.cmdArgs[1:]...)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.closed = make(chan struct{})
+	c.attempts = 0
 	return nil
 }
 
 func (c *cmdSource) Close() error {
-	if c.execCmd == nil || c.execCmd.Process == nil {
+	c.mu.Lock()
+	p := c.process
+	c.process = nil
+	if c.closed != nil {
+		close(c.closed)
+		c.closed = nil
+	}
+	c.mu.Unlock()
+
+	if p == nil {
 		return nil
 	}
+	return p.stop(c.stopTimeout)
+}
+
+func (c *cmdSource) Properties() []prop.Media {
+	return c.props
+}
+
+// start starts the command with the fields of the media properties structs as
+// environment variables, and returns its standard output. The standard error is
+// logged with the given prefix when showStdErr is set.
+func (c *cmdSource) start(showStdErr bool, stdErrPrefix string, props ...interface{}) (io.ReadCloser, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// add environment variables to the command for each media property
+	c.env = envVarsFromStructs(props...)
+	c.showStdErr = showStdErr
+	c.stdErrPrefix = stdErrPrefix
+	if c.probed != nil && c.closed != nil {
+		probed := c.probed
+		c.probed = nil
+		c.run(probed.process)
+		return probed.stdOut, nil
+	}
+	return c.startProcess()
+}
 
-	_ = c.execCmd.Process.Signal(os.Interrupt) // send SIGINT to process to stop it
-	done := make(chan error)
-	go func() { done <- c.execCmd.Wait() }()
+// startProcess starts a new instance of the command. c.mu must be held.
+func (c *cmdSource) startProcess() (io.ReadCloser, error) {
+	if c.closed == nil {
+		return nil, errClosed
+	}
+
+	p, stdOut, err := c.spawn()
+	if err != nil {
+		return nil, err
+	}
+	c.run(p)
+	return stdOut, nil
+}
+
+// run makes p the running instance of the command. c.mu must be held.
+func (c *cmdSource) run(p *process) {
+	c.process = p
+	if c.stdin != nil {
+		c.stdinOnce.Do(func() { go c.forwardStdin() })
+	}
+}
+
+// spawn starts the command, and returns it with its standard output. c.mu must
+// be held.
+func (c *cmdSource) spawn() (*process, io.ReadCloser, error) {
+	cmd := exec.Command(c.cmdArgs[0], c.cmdArgs[1:]...)
+	cmd.Env = c.env
+	if c.showStdErr {
+		// get the command's standard error
+		stdErr, err := cmd.StderrPipe()
+		if err != nil {
+			return nil, nil, err
+		}
+		// send standard error to the logger prefixed with "{command} stdErr >"
+		go c.logStdIoWithPrefix(c.stdErrPrefix, stdErr)
+	}
+	// get the command's standard output
+	stdOut, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, nil, err
+	}
+	p := &process{cmd: cmd}
+	if c.stdin != nil {
+		if p.stdin, err = cmd.StdinPipe(); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// start the command
+	if err := cmd.Start(); err != nil {
+		return nil, nil, err
+	}
+	return p, stdOut, nil
+}
+
+// restart stops the command after readErr, and starts it again according to the
+// restart policy. It returns the new standard output, or an *ExitError when the
+// command isn't restarted.
+func (c *cmdSource) restart(readErr error) (io.ReadCloser, error) {
+	c.mu.Lock()
+	p := c.process
+	c.process = nil
+	closed := c.closed
+	attempt := c.attempts
+	c.attempts++
+	c.mu.Unlock()
+
+	if p == nil || closed == nil {
+		// the source was closed while reading
+		return nil, readErr
+	}
+
+	exitErr := &ExitError{Command: c.cmdArgs[0], Err: readErr}
+	waitErr := p.stop(c.stopTimeout)
+	switch waitErr := waitErr.(type) {
+	case nil:
+	case *exec.ExitError:
+		exitErr.ExitCode = waitErr.ExitCode()
+	default:
+		exitErr.ExitCode = -1
+	}
+
+	policy := c.restartPolicy
+	if policy.MaxAttempts == 0 || (policy.MaxAttempts > 0 && attempt >= policy.MaxAttempts) {
+		return nil, exitErr
+	}
+
+	backoff := policy.InitialBackoff
+	for i := 0; i < attempt && backoff < policy.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > policy.MaxBackoff {
+		backoff = policy.MaxBackoff
+	}
+	logger.Warnf("%v, restarting in %v (attempt %d)", exitErr, backoff, attempt+1)
+
+	timer := time.NewTimer(backoff)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-closed:
+		return nil, exitErr
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.startProcess()
+}
+
+// read calls read until it succeeds, restarting the command when it fails. reset
+// is called with the standard output of the restarted command.
+func (c *cmdSource) read(read func() error, reset func(stdOut io.Reader)) error {
+	for {
+		done := startRead(read)
+		err := c.waitRead(done)
+		if err == nil {
+			c.mu.Lock()
+			c.attempts = 0
+			c.mu.Unlock()
+			return nil
+		}
+
+		stdOut, restartErr := c.restart(err)
+		if err == errReadTimeout {
+			// the command was stopped, which closed its standard output: the timed out
+			// read returns, and doesn't use the buffers of read anymore once it's done
+			<-done
+		}
+		if restartErr != nil {
+			return restartErr
+		}
+		reset(stdOut)
+	}
+}
+
+// startRead calls read in the background, and returns the channel receiving its result.
+func startRead(read func() error) <-chan error {
+	done := make(chan error, 1)
+	go func() { done <- read() }()
+	return done
+}
+
+// waitRead waits for the result of a read started by startRead, giving up after the read
+// timeout. The read keeps running in the background after a timeout, until the standard
+// output is closed.
+func (c *cmdSource) waitRead(done <-chan error) error {
 	select {
 	case err := <-done:
-		return err // command exited normally or with an error code
-	case <-time.After(3 * time.Second):
-		err := c.execCmd.Process.Kill() // command timed out, kill it & return error
 		return err
+	case <-time.After(time.Duration(c.readTimeout) * time.Second):
+		return errReadTimeout
+	}
+}
+
+// probe runs the command until parseHeader has read the header of its standard
+// output. The command is stopped if parseHeader fails. Otherwise, it keeps
+// running, so that commands opening devices aren't run twice: it's returned to
+// be read by the first start, from the beginning of its output.
+func (c *cmdSource) probe(parseHeader func(stdOut io.Reader) error) (*probedCommand, error) {
+	c.mu.Lock()
+	p, stdOut, err := c.spawn()
+	c.mu.Unlock()
+	if err != nil {
+		return nil, err
 	}
 
+	var header bytes.Buffer
+	done := startRead(func() error {
+		return parseHeader(io.TeeReader(stdOut, &header))
+	})
+	if err := c.waitRead(done); err != nil {
+		_ = p.cmd.Process.Kill()
+		_ = p.wait()
+		if err == errReadTimeout {
+			// waiting for the command closed its standard output, parseHeader returns
+			<-done
+		}
+		return nil, err
+	}
+
+	return &probedCommand{
+		process: p,
+		stdOut: struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(&header, stdOut), stdOut},
+	}, nil
 }
 
-func (c *cmdSource) Properties() []prop.Media {
-	return c.props
+// {BLOCKING GOROUTINE} forwardStdin copies the stdin option to the standard
+// input of the running command, until the stdin option returns an error.
+func (c *cmdSource) forwardStdin() {
+	buf := make([]byte, 4096)
+	for {
+		n, err := c.stdin.Read(buf)
+		c.mu.Lock()
+		var stdin io.WriteCloser
+		if c.process != nil {
+			stdin = c.process.stdin
+		}
+		c.mu.Unlock()
+
+		if n > 0 && stdin != nil {
+			if _, err := stdin.Write(buf[:n]); err != nil {
+				logger.Debugf("failed to write to %s stdin: %v", c.cmdArgs[0], err)
+			}
+		}
+		if err != nil {
+			if stdin != nil {
+				_ = stdin.Close()
+			}
+			return
+		}
+	}
 }
 
-// {BLOCKING GOROUTINE} logStdIoWithPrefix reads from the command's standard output or error, and prints it to the console as debug logs prefixed with the provided prefix
+// {BLOCKING GOROUTINE} logStdIoWithPrefix reads from the command's standard output or error, and logs it at the stdErrLevel prefixed with the provided prefix
 func (c *cmdSource) logStdIoWithPrefix(prefix string, stdIo io.ReadCloser) {
 	reader := bufio.NewReader(stdIo)
 	for {
-		if line, err := reader.ReadBytes('\n'); err == nil {
-			// logger.Debug(prefix + string(line))
-			println(prefix + strings.Trim(string(line), " \r\n"))
-		} else if err == io.EOF || err == io.ErrUnexpectedEOF {
-			// logger.Debug(prefix + string(line))
-			println(prefix + string(line))
+		line, err := reader.ReadString('\n')
+		if line = strings.Trim(line, " \r\n"); line != "" {
+			c.logStdIo(prefix + line)
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
 			break
 		} else if err != nil {
 			logger.Error(err.Error())
@@ -92,23 +498,35 @@ func (c *cmdSource) logStdIoWithPrefix(prefix string, stdIo io.ReadCloser) {
 	}
 }
 
-func (c *cmdSource) addEnvVarsFromStruct(props interface{}, logProps bool) {
-	c.execCmd.Env = os.Environ() // inherit environment variables
-	values := reflect.ValueOf(props)
-	types := values.Type()
-	if logProps {
-		fmt.Print("Adding cmdsource environment variables: ")
+func (c *cmdSource) logStdIo(msg string) {
+	switch c.stdErrLevel {
+	case pionlogging.LogLevelError:
+		logger.Error(msg)
+	case pionlogging.LogLevelWarn:
+		logger.Warn(msg)
+	case pionlogging.LogLevelDebug:
+		logger.Debug(msg)
+	case pionlogging.LogLevelTrace:
+		logger.Trace(msg)
+	default:
+		logger.Info(msg)
 	}
-	for i := 0; i < values.NumField(); i++ {
-		name := types.Field(i).Name
-		value := values.Field(i)
-		envVar := fmt.Sprintf("PION_MEDIA_%s=%v", name, value)
-		if logProps {
-			fmt.Print(envVar + ", ")
+}
+
+// envVarsFromStructs returns the environment with a variable added for each
+// field of props
+func envVarsFromStructs(props ...interface{}) []string {
+	env := os.Environ() // inherit environment variables
+	var vars []string
+	for _, p := range props {
+		values := reflect.ValueOf(p)
+		types := values.Type()
+		for i := 0; i < values.NumField(); i++ {
+			name := types.Field(i).Name
+			value := values.Field(i)
+			vars = append(vars, fmt.Sprintf("PION_MEDIA_%s=%v", name, value))
 		}
-		c.execCmd.Env = append(c.execCmd.Env, envVar)
-	}
-	if logProps {
-		fmt.Println()
 	}
+	logger.Debugf("Adding cmdsource environment variables: %s", strings.Join(vars, ", "))
+	return append(env, vars...)
 }
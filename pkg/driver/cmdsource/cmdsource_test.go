@@ -0,0 +1,152 @@
+package cmdsource
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pion/mediadevices/pkg/frame"
+	"github.com/pion/mediadevices/pkg/prop"
+)
+
+func newTestVideoCmdSource(t *testing.T, command string, opts ...Option) (*videoCmdSource, prop.Media) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh command not found in path. Skipping test. Err: ", err)
+	}
+
+	// Z16 frames of 2x1 pixels are 4 bytes long
+	properties := []prop.Media{
+		{
+			Video: prop.Video{Width: 2, Height: 1, FrameFormat: frame.FormatZ16},
+		},
+	}
+	source := &videoCmdSource{
+		cmdSource: newCmdSource(command, properties, 10),
+		label:     "test_source",
+	}
+	if err := source.applyOptions(opts); err != nil {
+		t.Fatal(err)
+	}
+	if err := source.Open(); err != nil {
+		t.Fatal(err)
+	}
+	return source, properties[0]
+}
+
+func TestRestartPolicy(t *testing.T) {
+	// The command only writes a frame the first time it runs
+	marker := filepath.Join(t.TempDir(), "started")
+	command := fmt.Sprintf(`sh -c "if [ -e %s ]; then exit 3; fi; touch %s; printf abcd"`, marker, marker)
+
+	testCases := map[string]struct {
+		policy       RestartPolicy
+		expectedCode int
+	}{
+		"NoRestart": {
+			expectedCode: 0,
+		},
+		"MaxAttempts": {
+			// The restarted commands exit with code 3
+			policy:       RestartPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond},
+			expectedCode: 3,
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			defer os.Remove(marker)
+
+			source, p := newTestVideoCmdSource(t, command, WithRestartPolicy(testCase.policy))
+			defer source.Close()
+
+			reader, err := source.VideoRecord(p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, _, err := reader.Read(); err != nil {
+				t.Fatal(err)
+			}
+
+			_, _, err = reader.Read()
+			var exitErr *ExitError
+			if !errors.As(err, &exitErr) {
+				t.Fatalf("Expected ExitError, got %v", err)
+			}
+			if !errors.Is(err, io.EOF) {
+				t.Errorf("Expected the error to wrap %v, got %v", io.EOF, exitErr.Err)
+			}
+			if exitErr.ExitCode != testCase.expectedCode {
+				t.Errorf("Expected exit code %d, got %d", testCase.expectedCode, exitErr.ExitCode)
+			}
+		})
+	}
+}
+
+func TestRestartOnTimeout(t *testing.T) {
+	// The command hangs after a frame the first time it runs, and is restarted after the
+	// read timeout
+	marker := filepath.Join(t.TempDir(), "started")
+	command := fmt.Sprintf(`sh -c "if [ -e %s ]; then printf efgh; exec sleep 10; fi; touch %s; printf abcd; exec sleep 10"`, marker, marker)
+
+	source, p := newTestVideoCmdSource(t, command, WithRestartPolicy(RestartPolicy{MaxAttempts: 1, InitialBackoff: time.Millisecond}))
+	defer source.Close()
+	source.readTimeout = 1
+
+	reader, err := source.VideoRecord(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, _, err := reader.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, _, err := reader.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.(*image.Gray16).Gray16At(0, 0) == second.(*image.Gray16).Gray16At(0, 0) {
+		t.Error("Expected the frame of the restarted command")
+	}
+}
+
+func TestStdin(t *testing.T) {
+	stdin, stdinWriter := io.Pipe()
+	defer stdinWriter.Close()
+
+	source, p := newTestVideoCmdSource(t, `sh -c "head -c 4"`, WithStdin(stdin))
+	defer source.Close()
+
+	reader, err := source.VideoRecord(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		_, _ = stdinWriter.Write([]byte{0x01, 0x00, 0xFF, 0xFF})
+	}()
+
+	img, _, err := reader.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	gray, ok := img.(*image.Gray16)
+	if !ok {
+		t.Fatalf("Expected *image.Gray16, got %T", img)
+	}
+	if v := gray.Gray16At(1, 0).Y; v != 0xFFFF {
+		t.Errorf("Expected 0xFFFF, got %#x", v)
+	}
+}
+
+func TestWithStdErrLevel(t *testing.T) {
+	o := options{}
+	if err := WithStdErrLevel(0)(&o); err != errInvalidLogLevel {
+		t.Errorf("Expected %v, got %v", errInvalidLogLevel, err)
+	}
+}
@@ -0,0 +1,250 @@
+package cmdsource
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+
+	"github.com/pion/mediadevices/pkg/frame"
+	"github.com/pion/mediadevices/pkg/prop"
+)
+
+// Framing defines how the frames written by a video command are delimited.
+type Framing int
+
+const (
+	// FramingAuto reads fixed size frames for raw formats, and splits MJPEG
+	// streams at the JPEG markers.
+	FramingAuto Framing = iota
+	// FramingFixedSize reads frames of the size given by the frame format and
+	// resolution. Only raw formats are supported.
+	FramingFixedSize
+	// FramingJPEG splits a stream of concatenated JPEG images. Data between
+	// images is skipped.
+	FramingJPEG
+	// FramingLengthPrefixed reads frames preceded by their size in bytes, as a
+	// 32 bits big-endian integer.
+	FramingLengthPrefixed
+)
+
+// maxFrameSize limits the size of variable-size frames, so that a corrupted
+// stream doesn't allocate unbounded memory
+const maxFrameSize = 64 << 20
+
+// JPEG markers, see https://www.w3.org/Graphics/JPEG/itu-t81.pdf Table B.1
+const (
+	jpegMarkerTEM  = 0x01
+	jpegMarkerRST0 = 0xD0
+	jpegMarkerRST7 = 0xD7
+	jpegMarkerSOI  = 0xD8
+	jpegMarkerEOI  = 0xD9
+	jpegMarkerSOS  = 0xDA
+)
+
+var (
+	errInvalidJPEG   = errors.New("invalid JPEG stream")
+	errFrameTooLarge = errors.New("frame is too large")
+)
+
+// frameReader reads the next frame. The returned buffer is only valid until the
+// next call.
+type frameReader func() ([]byte, error)
+
+// newFrameReader returns a function creating frameReaders, which read frames of
+// the given properties according to framing.
+func newFrameReader(framing Framing, inputProp prop.Video) (func(r io.Reader) frameReader, error) {
+	if framing == FramingAuto {
+		framing = FramingFixedSize
+		if inputProp.FrameFormat == frame.FormatMJPEG {
+			framing = FramingJPEG
+		}
+	}
+
+	switch framing {
+	case FramingFixedSize:
+		getFrameSize, ok := frame.FrameSizeMap[inputProp.FrameFormat]
+		if !ok {
+			return nil, errUnsupportedFormat
+		}
+		frameSize := int(getFrameSize(inputProp.Width, inputProp.Height))
+		return func(r io.Reader) frameReader {
+			return newFixedSizeFrameReader(r, frameSize)
+		}, nil
+	case FramingJPEG:
+		return newJPEGFrameReader, nil
+	case FramingLengthPrefixed:
+		return newLengthPrefixedFrameReader, nil
+	default:
+		return nil, errUnsupportedFormat
+	}
+}
+
+func newFixedSizeFrameReader(r io.Reader, frameSize int) frameReader {
+	buf := make([]byte, frameSize)
+	return func() ([]byte, error) {
+		if _, err := io.ReadFull(r, buf); err != nil {
+			if err == io.ErrUnexpectedEOF {
+				err = io.EOF
+			}
+			return nil, err
+		}
+		return buf, nil
+	}
+}
+
+func newLengthPrefixedFrameReader(r io.Reader) frameReader {
+	var buf []byte
+	return func() ([]byte, error) {
+		var header [4]byte
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			if err == io.ErrUnexpectedEOF {
+				err = io.EOF
+			}
+			return nil, err
+		}
+		size := binary.BigEndian.Uint32(header[:])
+		if size > maxFrameSize {
+			return nil, errFrameTooLarge
+		}
+
+		if cap(buf) < int(size) {
+			buf = make([]byte, size)
+		}
+		buf = buf[:size]
+		if _, err := io.ReadFull(r, buf); err != nil {
+			if err == io.ErrUnexpectedEOF {
+				err = io.EOF
+			}
+			return nil, err
+		}
+		return buf, nil
+	}
+}
+
+// newJPEGFrameReader splits a stream of JPEG images by following the marker
+// segments from SOI to EOI. Segments are skipped using their length, so that
+// thumbnails embedded in APP segments don't end the frame early.
+func newJPEGFrameReader(r io.Reader) frameReader {
+	reader := bufio.NewReader(r)
+	var buf []byte
+	return func() ([]byte, error) {
+		var err error
+		buf, err = readJPEG(reader, buf[:0])
+		if err == io.ErrUnexpectedEOF {
+			err = io.EOF
+		}
+		return buf, err
+	}
+}
+
+func readJPEG(r *bufio.Reader, buf []byte) ([]byte, error) {
+	// Skip everything until the start of image
+	var prev byte
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if prev == 0xFF && b == jpegMarkerSOI {
+			break
+		}
+		prev = b
+	}
+	buf = append(buf, 0xFF, jpegMarkerSOI)
+
+	marker, err := readJPEGMarker(r)
+	for {
+		if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		if len(buf) > maxFrameSize {
+			return nil, errFrameTooLarge
+		}
+		buf = append(buf, 0xFF, marker)
+
+		switch {
+		case marker == jpegMarkerEOI:
+			return buf, nil
+		case marker == jpegMarkerTEM, marker >= jpegMarkerRST0 && marker <= jpegMarkerRST7:
+			// Standalone markers don't have a segment
+			marker, err = readJPEGMarker(r)
+			continue
+		}
+
+		var length [2]byte
+		if _, err := io.ReadFull(r, length[:]); err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		size := int(binary.BigEndian.Uint16(length[:]))
+		if size < 2 {
+			return nil, errInvalidJPEG
+		}
+		buf = append(buf, length[:]...)
+		start := len(buf)
+		buf = append(buf, make([]byte, size-2)...)
+		if _, err := io.ReadFull(r, buf[start:]); err != nil {
+			return nil, unexpectedEOF(err)
+		}
+
+		if marker == jpegMarkerSOS {
+			buf, marker, err = readJPEGEntropyCodedData(r, buf)
+		} else {
+			marker, err = readJPEGMarker(r)
+		}
+	}
+}
+
+// readJPEGMarker reads a marker, skipping fill bytes.
+func readJPEGMarker(r *bufio.Reader) (byte, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	if b != 0xFF {
+		return 0, errInvalidJPEG
+	}
+	for b == 0xFF {
+		if b, err = r.ReadByte(); err != nil {
+			return 0, err
+		}
+	}
+	return b, nil
+}
+
+// readJPEGEntropyCodedData appends the scan data following a SOS segment to
+// buf, and returns the marker ending it. Stuffed zero bytes and restart markers
+// are part of the scan data.
+func readJPEGEntropyCodedData(r *bufio.Reader, buf []byte) ([]byte, byte, error) {
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return buf, 0, err
+		}
+		if b != 0xFF {
+			buf = append(buf, b)
+			if len(buf) > maxFrameSize {
+				return buf, 0, errFrameTooLarge
+			}
+			continue
+		}
+
+		for b == 0xFF {
+			if b, err = r.ReadByte(); err != nil {
+				return buf, 0, err
+			}
+		}
+		if b == 0 || (b >= jpegMarkerRST0 && b <= jpegMarkerRST7) {
+			buf = append(buf, 0xFF, b)
+			continue
+		}
+		return buf, b, nil
+	}
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
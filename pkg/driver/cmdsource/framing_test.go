@@ -0,0 +1,179 @@
+package cmdsource
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/pion/mediadevices/pkg/frame"
+	"github.com/pion/mediadevices/pkg/prop"
+)
+
+func encodeJPEG(t *testing.T, c color.Color) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, 16, 8))
+	for i := 0; i < len(img.Pix); i += 4 {
+		r, g, b, a := c.RGBA()
+		img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = byte(r>>8), byte(g>>8), byte(b>>8), byte(a>>8)
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// withThumbnail inserts an APP1 segment containing JPEG markers after the SOI
+// of a JPEG image, like an EXIF thumbnail.
+func withThumbnail(jpg []byte) []byte {
+	thumbnail := []byte{0xFF, 0xD8, 0xFF, 0xD9}
+	segment := []byte{0xFF, 0xE1, 0, byte(len(thumbnail) + 2)}
+	segment = append(segment, thumbnail...)
+	return append(append(append([]byte{}, jpg[:2]...), segment...), jpg[2:]...)
+}
+
+func TestFrameReader(t *testing.T) {
+	red := encodeJPEG(t, color.RGBA{R: 255, A: 255})
+	blue := withThumbnail(encodeJPEG(t, color.RGBA{B: 255, A: 255}))
+
+	lengthPrefixed := func(frames ...[]byte) []byte {
+		var buf []byte
+		for _, f := range frames {
+			var size [4]byte
+			binary.BigEndian.PutUint32(size[:], uint32(len(f)))
+			buf = append(append(buf, size[:]...), f...)
+		}
+		return buf
+	}
+
+	testCases := map[string]struct {
+		framing  Framing
+		prop     prop.Video
+		stream   []byte
+		expected [][]byte
+		err      error
+	}{
+		"AutoMJPEG": {
+			framing:  FramingAuto,
+			prop:     prop.Video{FrameFormat: frame.FormatMJPEG},
+			stream:   bytes.Join([][]byte{red, blue}, nil),
+			expected: [][]byte{red, blue},
+		},
+		"JPEGSkipGarbage": {
+			framing:  FramingJPEG,
+			stream:   bytes.Join([][]byte{[]byte("garbage\xff"), red, []byte("\n"), blue}, nil),
+			expected: [][]byte{red, blue},
+		},
+		"JPEGTruncated": {
+			framing:  FramingJPEG,
+			stream:   bytes.Join([][]byte{red, blue[:len(blue)/2]}, nil),
+			expected: [][]byte{red},
+		},
+		"LengthPrefixed": {
+			framing:  FramingLengthPrefixed,
+			stream:   lengthPrefixed([]byte{1, 2, 3}, []byte{}, []byte{4}),
+			expected: [][]byte{{1, 2, 3}, {}, {4}},
+		},
+		"LengthPrefixedTooLarge": {
+			framing: FramingLengthPrefixed,
+			stream:  []byte{0xFF, 0xFF, 0xFF, 0xFF},
+			err:     errFrameTooLarge,
+		},
+		"AutoFixedSize": {
+			framing:  FramingAuto,
+			prop:     prop.Video{FrameFormat: frame.FormatYUY2, Width: 2, Height: 1},
+			stream:   []byte{1, 2, 3, 4, 5, 6, 7, 8, 9},
+			expected: [][]byte{{1, 2, 3, 4}, {5, 6, 7, 8}},
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			newReader, err := newFrameReader(testCase.framing, testCase.prop)
+			if err != nil {
+				t.Fatal(err)
+			}
+			readFrame := newReader(bytes.NewReader(testCase.stream))
+
+			for i, expected := range testCase.expected {
+				buf, err := readFrame()
+				if err != nil {
+					t.Fatalf("Frame %d: %v", i, err)
+				}
+				if !bytes.Equal(expected, buf) {
+					t.Errorf("Frame %d: expected %d bytes, got %d bytes", i, len(expected), len(buf))
+				}
+			}
+
+			expectedErr := testCase.err
+			if expectedErr == nil {
+				expectedErr = io.EOF
+			}
+			if _, err := readFrame(); err != expectedErr {
+				t.Errorf("Expected %v, got %v", expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestFrameReaderUnsupportedFormat(t *testing.T) {
+	_, err := newFrameReader(FramingFixedSize, prop.Video{FrameFormat: frame.FormatMJPEG})
+	if err != errUnsupportedFormat {
+		t.Errorf("Expected %v, got %v", errUnsupportedFormat, err)
+	}
+}
+
+func TestMJPEGVideoCmdOut(t *testing.T) {
+	if _, err := exec.LookPath("cat"); err != nil {
+		t.Skip("cat command not found in path. Skipping test. Err: ", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "test.mjpeg")
+	stream := append(encodeJPEG(t, color.RGBA{R: 255, A: 255}), encodeJPEG(t, color.RGBA{B: 255, A: 255})...)
+	if err := os.WriteFile(path, stream, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	properties := []prop.Media{
+		{
+			Video: prop.Video{Width: 16, Height: 8, FrameFormat: frame.FormatMJPEG},
+		},
+	}
+	videoCmdSource := &videoCmdSource{
+		cmdSource: newCmdSource("cat "+path, properties, 10),
+		label:     "test_source",
+	}
+	if err := videoCmdSource.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer videoCmdSource.Close()
+
+	reader, err := videoCmdSource.VideoRecord(properties[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, isRed := range []bool{true, false} {
+		img, _, err := reader.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if img.Bounds().Dx() != 16 || img.Bounds().Dy() != 8 {
+			t.Fatalf("Frame %d: unexpected size %v", i, img.Bounds())
+		}
+		r, _, b, _ := img.At(8, 4).RGBA()
+		if (r > b) != isRed {
+			t.Errorf("Frame %d: unexpected color %v", i, img.At(8, 4))
+		}
+	}
+	if _, _, err := reader.Read(); !errors.Is(err, io.EOF) {
+		t.Errorf("Expected %v, got %v", io.EOF, err)
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"image"
 	"io"
-	"time"
 
 	"github.com/pion/mediadevices/pkg/driver"
 	"github.com/pion/mediadevices/pkg/frame"
@@ -16,17 +15,31 @@ type videoCmdSource struct {
 	cmdSource
 	showStdErr bool
 	label      string
+	// autoDetect is set when the command writes a YUV4MPEG2 stream
+	autoDetect bool
+	framing    Framing
 }
 
-func AddVideoCmdSource(label string, command string, mediaProperties []prop.Media, readTimeout uint32, showStdErr bool) error {
+func AddVideoCmdSource(label string, command string, mediaProperties []prop.Media, readTimeout uint32, showStdErr bool, opts ...Option) error {
+	return AddVideoCmdSourceWithFraming(label, command, mediaProperties, readTimeout, showStdErr, FramingAuto, opts...)
+}
+
+// AddVideoCmdSourceWithFraming is like AddVideoCmdSource, with the frames
+// delimited according to framing. It allows variable-size frames, e.g. MJPEG
+// from ffmpeg or libcamera-vid.
+func AddVideoCmdSourceWithFraming(label string, command string, mediaProperties []prop.Media, readTimeout uint32, showStdErr bool, framing Framing, opts ...Option) error {
 	videoCmdSource := &videoCmdSource{
 		cmdSource:  newCmdSource(command, mediaProperties, readTimeout),
 		label:      label,
 		showStdErr: showStdErr,
+		framing:    framing,
 	}
 	if len(videoCmdSource.cmdArgs) == 0 || videoCmdSource.cmdArgs[0] == "" {
 		return errInvalidCommand // no command specified
 	}
+	if err := videoCmdSource.applyOptions(opts); err != nil {
+		return err
+	}
 
 	err := driver.GetManager().Register(videoCmdSource, driver.Info{
 		Label:      label,
@@ -40,63 +53,40 @@ func AddVideoCmdSource(label string, command string, mediaProperties []prop.Medi
 }
 
 func (c *videoCmdSource) VideoRecord(inputProp prop.Media) (video.Reader, error) {
-	getFrameSize, ok := frame.FrameSizeMap[inputProp.FrameFormat]
-	if !ok {
-		return nil, errUnsupportedFormat
+	if c.autoDetect {
+		stdOut, err := c.start(c.showStdErr, fmt.Sprintf("%s stdErr> ", c.label+":"+c.cmdArgs[0]), inputProp.Video)
+		if err != nil {
+			return nil, err
+		}
+		return c.recordY4M(stdOut)
 	}
-	frameSize := getFrameSize(inputProp.Width, inputProp.Height)
 
 	decoder, err := frame.NewDecoder(inputProp.FrameFormat)
 	if err != nil {
 		return nil, err
 	}
-
-	if c.showStdErr {
-		// get the command's standard error
-		stdErr, err := c.execCmd.StderrPipe()
-		if err != nil {
-			return nil, err
-		}
-		// send standard error to the console as debug logs prefixed with "{command} stdErr >"
-		go c.logStdIoWithPrefix(fmt.Sprintf("%s stdErr> ", c.label+":"+c.cmdArgs[0]), stdErr)
-	}
-	// get the command's standard output
-	stdOut, err := c.execCmd.StdoutPipe()
+	newReader, err := newFrameReader(c.framing, inputProp.Video)
 	if err != nil {
 		return nil, err
 	}
 
-	// add environment variables to the command for each media property
-	c.addEnvVarsFromStruct(inputProp.Video, c.showStdErr)
-
-	// start the command
-	if err := c.execCmd.Start(); err != nil {
+	stdOut, err := c.start(c.showStdErr, fmt.Sprintf("%s stdErr> ", c.label+":"+c.cmdArgs[0]), inputProp.Video)
+	if err != nil {
 		return nil, err
 	}
+	readFrame := newReader(stdOut)
+	var buf []byte
+	read := func() (err error) {
+		buf, err = readFrame()
+		return err
+	}
+	reset := func(stdOut io.Reader) { readFrame = newReader(stdOut) }
 
-	var buf []byte = make([]byte, frameSize)
-	doneChan := make(chan error)
-	// fmt.Printf("frameSize: %d\n", frameSize)
 	r := video.ReaderFunc(func() (img image.Image, release func(), err error) {
-		go func() {
-			if _, err := io.ReadFull(stdOut, buf); err == io.ErrUnexpectedEOF {
-				doneChan <- io.EOF
-			} else if err != nil {
-				doneChan <- err
-			}
-			doneChan <- nil
-		}()
-
-		select {
-		case err := <-doneChan:
-			if err != nil {
-				return nil, nil, err
-			} else {
-				return decoder.Decode(buf, inputProp.Width, inputProp.Height)
-			}
-		case <-time.After(time.Duration(c.readTimeout) * time.Second):
-			return nil, func() {}, errReadTimeout
+		if err := c.read(read, reset); err != nil {
+			return nil, func() {}, err
 		}
+		return decoder.Decode(buf, inputProp.Width, inputProp.Height)
 	})
 
 	return r, nil
@@ -13,4 +13,8 @@ const (
 	Screen = "screen"
 	// CmdSource represents command sources
 	CmdSource = "cmdsource"
+	// File represents file sources
+	File = "file"
+	// PushSource represents sources fed by the application
+	PushSource = "pushsource"
 )
@@ -1,6 +1,9 @@
 package driver
 
 import (
+	"time"
+
+	"github.com/pion/mediadevices/pkg/codec"
 	"github.com/pion/mediadevices/pkg/io/audio"
 	"github.com/pion/mediadevices/pkg/io/video"
 	"github.com/pion/mediadevices/pkg/prop"
@@ -14,6 +17,33 @@ type AudioRecorder interface {
 	AudioRecord(p prop.Media) (r audio.Reader, err error)
 }
 
+// EncodedFrame is a frame compressed by the device, or read from a file, in
+// the codec returned by EncodedSource.EncodedCodec.
+type EncodedFrame struct {
+	Data []byte
+	// Timestamp is the presentation time of the frame relative to the first
+	// frame of the stream. It must be monotonically increasing.
+	Timestamp time.Duration
+}
+
+// EncodedReader reads compressed frames from an EncodedSource. Read blocks
+// until the next frame is due.
+type EncodedReader interface {
+	Read() (frame EncodedFrame, release func(), err error)
+	Close() error
+}
+
+// EncodedSource is implemented by drivers which natively produce compressed
+// frames, alongside VideoRecorder or AudioRecorder. When the codec negotiated
+// for a track matches EncodedCodec, the frames are sent as is instead of being
+// decoded and encoded again.
+type EncodedSource interface {
+	// EncodedCodec returns the codec of the frames produced for p, or nil if
+	// p can't be served without decoding.
+	EncodedCodec(p prop.Media) *codec.RTPCodec
+	EncodedRecord(p prop.Media) (r EncodedReader, err error)
+}
+
 // Priority represents device selection priority level
 type Priority float32
 
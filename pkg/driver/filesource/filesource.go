@@ -0,0 +1,637 @@
+// Package filesource implements drivers playing back media files. The frames of
+// pre-encoded files are sent as is when the codec negotiated for the track
+// matches the codec of the file, without being decoded and encoded again.
+//
+// Supported containers are detected from the file extension:
+//   - .ivf: VP8 or VP9
+//   - .h264, .264: H.264 Annex-B byte stream
+//   - .ogg, .opus: Opus
+//   - .y4m: raw video
+//   - .wav: raw audio, 16, 24 or 32 bits PCM, or 32 bits float
+package filesource
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pion/mediadevices/pkg/clock"
+	"github.com/pion/mediadevices/pkg/codec"
+	"github.com/pion/mediadevices/pkg/container/h264"
+	"github.com/pion/mediadevices/pkg/container/ivf"
+	"github.com/pion/mediadevices/pkg/container/ogg"
+	"github.com/pion/mediadevices/pkg/container/wav"
+	"github.com/pion/mediadevices/pkg/container/y4m"
+	"github.com/pion/mediadevices/pkg/driver"
+	"github.com/pion/mediadevices/pkg/io/audio"
+	"github.com/pion/mediadevices/pkg/io/video"
+	"github.com/pion/mediadevices/pkg/prop"
+	"github.com/pion/mediadevices/pkg/wave"
+)
+
+const (
+	defaultFrameRate = 30
+	defaultLatency   = 20 * time.Millisecond
+	// opusFrameDuration is the most common Opus frame duration, used as latency
+	opusFrameDuration = 20 * time.Millisecond
+	// maxProbedAccessUnits limits how far an H.264 stream is searched for its SPS
+	maxProbedAccessUnits = 64
+)
+
+var (
+	errUnsupportedContainer = errors.New("unsupported file container")
+	errUnsupportedCodec     = errors.New("unsupported codec")
+	errNoSPS                = errors.New("no SPS found in H.264 stream")
+	errDecodingNotSupported = errors.New("decoding is not supported, the file can only be read in its native codec")
+	errNotEncoded           = errors.New("raw files can't be read without encoding")
+	errInvalidFrameRate     = errors.New("frame rate must be positive")
+	errInvalidLatency       = errors.New("latency must be positive")
+	errClosed               = errors.New("file source is closed")
+)
+
+// EOFPolicy defines what happens when the end of the file is reached.
+type EOFPolicy int
+
+const (
+	// EOFEnd ends the stream, and the track reading it, with io.EOF
+	EOFEnd EOFPolicy = iota
+	// EOFLoop restarts the playback from the beginning of the file. Timestamps
+	// keep increasing across loops.
+	EOFLoop
+	// EOFHold keeps the stream open without sending more frames until the
+	// driver is closed
+	EOFHold
+)
+
+type options struct {
+	eofPolicy EOFPolicy
+	frameRate float32
+	latency   time.Duration
+	clock     clock.Clock
+}
+
+// Option configures a file source.
+type Option func(*options) error
+
+// WithEOFPolicy sets what happens at the end of the file. The default is EOFEnd.
+func WithEOFPolicy(policy EOFPolicy) Option {
+	return func(o *options) error {
+		o.eofPolicy = policy
+		return nil
+	}
+}
+
+// WithFrameRate sets the frame rate of files without timestamps, i.e. H.264
+// Annex-B streams. The default is 30 fps.
+func WithFrameRate(frameRate float32) Option {
+	return func(o *options) error {
+		if frameRate <= 0 {
+			return errInvalidFrameRate
+		}
+		o.frameRate = frameRate
+		return nil
+	}
+}
+
+// WithLatency sets the duration of the audio chunks read from raw audio files.
+// The default is 20 ms.
+func WithLatency(latency time.Duration) Option {
+	return func(o *options) error {
+		if latency <= 0 {
+			return errInvalidLatency
+		}
+		o.latency = latency
+		return nil
+	}
+}
+
+// WithClock sets the clock the frames are held back on until their
+// timestamp, relative to the first frame read, is reached. The default is the
+// system clock.
+func WithClock(c clock.Clock) Option {
+	return func(o *options) error {
+		o.clock = c
+		return nil
+	}
+}
+
+// readFrameFunc reads the next frame of a container, and its presentation
+// timestamp. Frames are []byte for encoded streams, image.Image for raw video,
+// and wave.Audio for raw audio.
+type readFrameFunc func() (frame interface{}, timestamp time.Duration, err error)
+
+// mediaInfo describes the stream of a file
+type mediaInfo struct {
+	media prop.Media
+	// newCodec creates the codec of encoded streams, and is nil for raw streams.
+	// Payloaders have a state, so every reader needs its own.
+	newCodec func() *codec.RTPCodec
+	// frameDuration is used to continue the timestamps when looping
+	frameDuration time.Duration
+	open          func(r io.Reader) (readFrameFunc, error)
+}
+
+func probe(path string, o options) (*mediaInfo, error) {
+	var probeFn func(r io.Reader, o options) (*mediaInfo, error)
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".ivf":
+		probeFn = probeIVF
+	case ".h264", ".264":
+		probeFn = probeH264
+	case ".ogg", ".opus":
+		probeFn = probeOgg
+	case ".y4m":
+		probeFn = probeY4M
+	case ".wav":
+		probeFn = probeWAV
+	default:
+		return nil, fmt.Errorf("%w: %s", errUnsupportedContainer, filepath.Ext(path))
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return probeFn(f, o)
+}
+
+func probeIVF(r io.Reader, o options) (*mediaInfo, error) {
+	reader, err := ivf.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	header := reader.Header()
+
+	info := &mediaInfo{
+		open: func(r io.Reader) (readFrameFunc, error) {
+			reader, err := ivf.NewReader(r)
+			if err != nil {
+				return nil, err
+			}
+			return func() (interface{}, time.Duration, error) {
+				return reader.ReadFrame()
+			}, nil
+		},
+	}
+	switch header.FourCC {
+	case ivf.FourCCVP8:
+		info.newCodec = func() *codec.RTPCodec { return codec.NewRTPVP8Codec(90000) }
+	case ivf.FourCCVP9:
+		info.newCodec = func() *codec.RTPCodec { return codec.NewRTPVP9Codec(90000) }
+	default:
+		return nil, fmt.Errorf("%w: %s", errUnsupportedCodec, header.FourCC)
+	}
+
+	// The timebase is usually the inverse of the frame rate
+	frameRate := o.frameRate
+	if rate := float32(header.TimebaseDenominator) / float32(header.TimebaseNumerator); rate <= 240 {
+		frameRate = rate
+	}
+	info.media.Video = prop.Video{
+		Width:     int(header.Width),
+		Height:    int(header.Height),
+		FrameRate: frameRate,
+	}
+	info.frameDuration = time.Duration(float32(time.Second) / frameRate)
+	return info, nil
+}
+
+func probeH264(r io.Reader, o options) (*mediaInfo, error) {
+	reader := h264.NewReader(r)
+
+	var sps *h264.SPS
+	for i := 0; i < maxProbedAccessUnits && sps == nil; i++ {
+		au, err := reader.ReadAccessUnit()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		for _, nalu := range h264.SplitNALUs(au) {
+			if h264.NALUType(nalu) == h264.NALUTypeSPS {
+				if sps, err = h264.ParseSPS(nalu); err != nil {
+					return nil, err
+				}
+				break
+			}
+		}
+	}
+	if sps == nil {
+		return nil, errNoSPS
+	}
+
+	frameRate := o.frameRate
+	return &mediaInfo{
+		media: prop.Media{
+			Video: prop.Video{
+				Width:     sps.Width,
+				Height:    sps.Height,
+				FrameRate: frameRate,
+			},
+		},
+		newCodec:      func() *codec.RTPCodec { return codec.NewRTPH264Codec(90000) },
+		frameDuration: time.Duration(float32(time.Second) / frameRate),
+		open: func(r io.Reader) (readFrameFunc, error) {
+			reader := h264.NewReader(r)
+			var count int
+			// Annex-B streams don't have timestamps, so they are generated from the frame rate
+			return func() (interface{}, time.Duration, error) {
+				au, err := reader.ReadAccessUnit()
+				if err != nil {
+					return nil, 0, err
+				}
+
+				timestamp := time.Duration(float64(count) * float64(time.Second) / float64(frameRate))
+				count++
+				return au, timestamp, nil
+			}, nil
+		},
+	}, nil
+}
+
+func probeOgg(r io.Reader, o options) (*mediaInfo, error) {
+	reader, err := ogg.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return &mediaInfo{
+		media: prop.Media{
+			Audio: prop.Audio{
+				ChannelCount: int(reader.Header().Channels),
+				SampleRate:   48000,
+				Latency:      opusFrameDuration,
+			},
+		},
+		newCodec:      func() *codec.RTPCodec { return codec.NewRTPOpusCodec(48000) },
+		frameDuration: opusFrameDuration,
+		open: func(r io.Reader) (readFrameFunc, error) {
+			reader, err := ogg.NewReader(r)
+			if err != nil {
+				return nil, err
+			}
+			return func() (interface{}, time.Duration, error) {
+				return reader.ReadPacket()
+			}, nil
+		},
+	}, nil
+}
+
+func probeY4M(r io.Reader, o options) (*mediaInfo, error) {
+	reader, err := y4m.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	header := reader.Header()
+
+	return &mediaInfo{
+		media:         header.Media(),
+		frameDuration: header.Timestamp(1),
+		open: func(r io.Reader) (readFrameFunc, error) {
+			reader, err := y4m.NewReader(r)
+			if err != nil {
+				return nil, err
+			}
+			return func() (interface{}, time.Duration, error) {
+				return reader.ReadFrame()
+			}, nil
+		},
+	}, nil
+}
+
+func probeWAV(r io.Reader, o options) (*mediaInfo, error) {
+	reader, err := wav.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	header := reader.Header()
+	chunkSize := int(int64(header.SampleRate) * int64(o.latency) / int64(time.Second))
+	if chunkSize < 1 {
+		chunkSize = 1
+	}
+
+	return &mediaInfo{
+		media:         header.Media(o.latency),
+		frameDuration: o.latency,
+		open: func(r io.Reader) (readFrameFunc, error) {
+			reader, err := wav.NewReader(r)
+			if err != nil {
+				return nil, err
+			}
+			var samples int64
+			// Timestamps are generated from the number of samples read
+			return func() (interface{}, time.Duration, error) {
+				chunk, err := reader.ReadChunk(chunkSize)
+				if err != nil {
+					return nil, 0, err
+				}
+
+				timestamp := time.Duration(samples * int64(time.Second) / int64(header.SampleRate))
+				samples += int64(chunk.ChunkInfo().Len)
+				return chunk, timestamp, nil
+			}, nil
+		},
+	}, nil
+}
+
+type fileSource struct {
+	path string
+	info *mediaInfo
+	options
+
+	mu sync.Mutex
+	// players are closed with the driver, even if their readers aren't read anymore
+	players map[*player]struct{}
+}
+
+// AddFileSource registers a driver playing back the file at path. The file is
+// probed right away to detect its codec and properties. The driver shows up as
+// a video or audio device depending on the file content.
+func AddFileSource(label string, path string, opts ...Option) error {
+	o := options{
+		eofPolicy: EOFEnd,
+		frameRate: defaultFrameRate,
+		latency:   defaultLatency,
+		clock:     clock.New(),
+	}
+	for _, opt := range opts {
+		if err := opt(&o); err != nil {
+			return err
+		}
+	}
+
+	info, err := probe(path, o)
+	if err != nil {
+		return err
+	}
+
+	source := &fileSource{
+		path:    path,
+		info:    info,
+		options: o,
+	}
+	var adapter driver.Adapter = &audioFileSource{source}
+	if info.media.Video != (prop.Video{}) {
+		adapter = &videoFileSource{source}
+	}
+
+	return driver.GetManager().Register(adapter, driver.Info{
+		Label:      label,
+		DeviceType: driver.File,
+		Priority:   driver.PriorityNormal,
+	})
+}
+
+func (s *fileSource) Open() error {
+	s.mu.Lock()
+	s.players = make(map[*player]struct{})
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *fileSource) Close() error {
+	s.mu.Lock()
+	players := s.players
+	s.players = nil
+	s.mu.Unlock()
+
+	for p := range players {
+		p.Close()
+	}
+	return nil
+}
+
+func (s *fileSource) Properties() []prop.Media {
+	return []prop.Media{s.info.media}
+}
+
+// EncodedCodec implements driver.EncodedSource. It returns nil for raw files.
+func (s *fileSource) EncodedCodec(p prop.Media) *codec.RTPCodec {
+	if s.info.newCodec == nil {
+		return nil
+	}
+	return s.info.newCodec()
+}
+
+// EncodedRecord opens the file again, so that every reader plays it back from
+// the beginning independently.
+func (s *fileSource) EncodedRecord(p prop.Media) (driver.EncodedReader, error) {
+	if s.info.newCodec == nil {
+		return nil, errNotEncoded
+	}
+
+	player, err := s.newPlayer()
+	if err != nil {
+		return nil, err
+	}
+	return &encodedReader{player}, nil
+}
+
+type videoFileSource struct {
+	*fileSource
+}
+
+func (s *videoFileSource) VideoRecord(p prop.Media) (video.Reader, error) {
+	if s.info.newCodec != nil {
+		return nil, errDecodingNotSupported
+	}
+
+	player, err := s.newPlayer()
+	if err != nil {
+		return nil, err
+	}
+
+	r := video.ReaderFunc(func() (image.Image, func(), error) {
+		img, _, err := player.next()
+		if err != nil {
+			player.Close()
+			return nil, func() {}, err
+		}
+		return img.(image.Image), func() {}, nil
+	})
+	return r, nil
+}
+
+type audioFileSource struct {
+	*fileSource
+}
+
+func (s *audioFileSource) AudioRecord(p prop.Media) (audio.Reader, error) {
+	if s.info.newCodec != nil {
+		return nil, errDecodingNotSupported
+	}
+
+	player, err := s.newPlayer()
+	if err != nil {
+		return nil, err
+	}
+
+	r := audio.ReaderFunc(func() (wave.Audio, func(), error) {
+		chunk, _, err := player.next()
+		if err != nil {
+			player.Close()
+			return nil, func() {}, err
+		}
+		return chunk.(wave.Audio), func() {}, nil
+	})
+	return r, nil
+}
+
+type encodedReader struct {
+	*player
+}
+
+func (r *encodedReader) Read() (driver.EncodedFrame, func(), error) {
+	data, timestamp, err := r.next()
+	if err != nil {
+		return driver.EncodedFrame{}, func() {}, err
+	}
+	return driver.EncodedFrame{Data: data.([]byte), Timestamp: timestamp}, func() {}, nil
+}
+
+// player paces the frames of a file according to their timestamps, and applies
+// the EOF policy
+type player struct {
+	file          *os.File
+	readFrame     readFrameFunc
+	open          func(r io.Reader) (readFrameFunc, error)
+	eofPolicy     EOFPolicy
+	frameDuration time.Duration
+	clock         clock.Clock
+	done          chan struct{}
+	closeOnce     sync.Once
+	onClose       func()
+
+	start time.Time
+	// offset is added to the timestamps of the file after looping
+	offset    time.Duration
+	last      time.Duration
+	count     int
+	loopCount int
+}
+
+func (s *fileSource) newPlayer() (*player, error) {
+	f, err := os.Open(s.path)
+	if err != nil {
+		return nil, err
+	}
+
+	readFrame, err := s.info.open(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	p := &player{
+		file:          f,
+		readFrame:     readFrame,
+		open:          s.info.open,
+		eofPolicy:     s.eofPolicy,
+		frameDuration: s.info.frameDuration,
+		clock:         s.clock,
+		done:          make(chan struct{}),
+	}
+	p.onClose = func() {
+		s.mu.Lock()
+		delete(s.players, p)
+		s.mu.Unlock()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.players == nil {
+		f.Close()
+		return nil, errClosed
+	}
+	s.players[p] = struct{}{}
+	return p, nil
+}
+
+// next returns the next frame when it's due
+func (p *player) next() (interface{}, time.Duration, error) {
+	for {
+		frame, timestamp, err := p.readFrame()
+		if err == io.EOF {
+			if err := p.handleEOF(); err != nil {
+				return nil, 0, err
+			}
+			continue
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+
+		timestamp += p.offset
+		if p.count > 0 && timestamp > p.last {
+			p.frameDuration = timestamp - p.last
+		}
+		p.last = timestamp
+		p.count++
+		p.loopCount++
+
+		if err := p.wait(timestamp); err != nil {
+			return nil, 0, err
+		}
+		return frame, timestamp, nil
+	}
+}
+
+func (p *player) handleEOF() error {
+	switch p.eofPolicy {
+	case EOFLoop:
+		// An empty file would loop forever
+		if p.loopCount == 0 {
+			return io.EOF
+		}
+		if _, err := p.file.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		readFrame, err := p.open(p.file)
+		if err != nil {
+			return err
+		}
+		p.readFrame = readFrame
+		p.offset = p.last + p.frameDuration
+		p.loopCount = 0
+		return nil
+	case EOFHold:
+		<-p.done
+		return io.EOF
+	default:
+		return io.EOF
+	}
+}
+
+// wait blocks until the frame at timestamp is due
+func (p *player) wait(timestamp time.Duration) error {
+	if p.start.IsZero() {
+		p.start = p.clock.Now()
+	}
+
+	select {
+	case <-p.clock.After(p.start.Add(timestamp).Sub(p.clock.Now())):
+		return nil
+	case <-p.done:
+		return io.EOF
+	}
+}
+
+func (p *player) Close() error {
+	var err error
+	p.closeOnce.Do(func() {
+		close(p.done)
+		err = p.file.Close()
+		p.onClose()
+	})
+	return err
+}
@@ -0,0 +1,400 @@
+package filesource
+
+import (
+	"errors"
+	"image"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/pion/mediadevices/pkg/clock"
+	"github.com/pion/mediadevices/pkg/container/ivf"
+	"github.com/pion/mediadevices/pkg/container/ogg"
+	"github.com/pion/mediadevices/pkg/container/wav"
+	"github.com/pion/mediadevices/pkg/container/y4m"
+	"github.com/pion/mediadevices/pkg/driver"
+	"github.com/pion/mediadevices/pkg/frame"
+	"github.com/pion/mediadevices/pkg/prop"
+	"github.com/pion/mediadevices/pkg/wave"
+	"github.com/pion/webrtc/v3"
+)
+
+// frameInterval is kept short so that the paced playback doesn't slow down tests
+const frameInterval = 10 * time.Millisecond
+
+func writeIVF(t *testing.T, frames int) string {
+	path := filepath.Join(t.TempDir(), "test.ivf")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	w, err := ivf.NewWriter(f, ivf.Header{FourCC: ivf.FourCCVP8, Width: 320, Height: 240})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < frames; i++ {
+		if err := w.WriteFrame([]byte{byte(i)}, uint32(frameInterval*90000/time.Second)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func newTestSource(t *testing.T, path string, opts ...Option) *fileSource {
+	o := options{frameRate: defaultFrameRate, latency: defaultLatency, clock: clock.New()}
+	for _, opt := range opts {
+		if err := opt(&o); err != nil {
+			t.Fatal(err)
+		}
+	}
+	info, err := probe(path, o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fileSource{path: path, info: info, options: o}
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestProbe(t *testing.T) {
+	s := newTestSource(t, writeIVF(t, 1))
+	defer s.Close()
+
+	expected := prop.Video{Width: 320, Height: 240, FrameRate: defaultFrameRate}
+	if p := s.Properties(); len(p) != 1 || p[0].Video != expected {
+		t.Errorf("Expected %+v, got %+v", expected, p)
+	}
+	if mimeType := s.EncodedCodec(prop.Media{}).MimeType; mimeType != webrtc.MimeTypeVP8 {
+		t.Errorf("Expected %s, got %s", webrtc.MimeTypeVP8, mimeType)
+	}
+
+	if _, err := probe("test.mp4", options{}); !errors.Is(err, errUnsupportedContainer) {
+		t.Errorf("Expected %v, got %v", errUnsupportedContainer, err)
+	}
+}
+
+func TestEOFPolicy(t *testing.T) {
+	testCases := map[string]struct {
+		policy     EOFPolicy
+		timestamps []time.Duration
+	}{
+		"End": {
+			policy:     EOFEnd,
+			timestamps: []time.Duration{0, frameInterval, 2 * frameInterval},
+		},
+		"Loop": {
+			policy: EOFLoop,
+			timestamps: []time.Duration{
+				0, frameInterval, 2 * frameInterval,
+				3 * frameInterval, 4 * frameInterval, 5 * frameInterval,
+				6 * frameInterval,
+			},
+		},
+	}
+
+	path := writeIVF(t, 3)
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			s := newTestSource(t, path, WithEOFPolicy(testCase.policy))
+			defer s.Close()
+
+			r, err := s.EncodedRecord(prop.Media{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Close()
+
+			start := time.Now()
+			for i, expected := range testCase.timestamps {
+				frame, _, err := r.Read()
+				if err != nil {
+					t.Fatalf("Frame %d: %v", i, err)
+				}
+				if frame.Timestamp != expected {
+					t.Errorf("Frame %d: expected timestamp %v, got %v", i, expected, frame.Timestamp)
+				}
+				if int(frame.Data[0]) != i%3 {
+					t.Errorf("Frame %d: unexpected data %v", i, frame.Data)
+				}
+			}
+			if elapsed, last := time.Since(start), testCase.timestamps[len(testCase.timestamps)-1]; elapsed < last {
+				t.Errorf("Expected frames to be paced over %v, took %v", last, elapsed)
+			}
+
+			if testCase.policy == EOFEnd {
+				if _, _, err := r.Read(); err != io.EOF {
+					t.Errorf("Expected %v, got %v", io.EOF, err)
+				}
+			}
+		})
+	}
+}
+
+func TestPacing(t *testing.T) {
+	m := clock.NewManual(time.Unix(0, 0))
+	s := newTestSource(t, writeIVF(t, 2), WithClock(m))
+	defer s.Close()
+
+	r, err := s.EncodedRecord(prop.Media{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	// the first frame is due immediately
+	if _, _, err := r.Read(); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error)
+	go func() {
+		_, _, err := r.Read()
+		done <- err
+	}()
+	// the second frame is due once the clock moved forward by the frame interval
+	m.BlockUntil(1)
+	m.Add(frameInterval - time.Millisecond)
+	select {
+	case err := <-done:
+		t.Fatalf("Expected the frame to be paced, got %v", err)
+	default:
+	}
+	m.Add(time.Millisecond)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEOFHold(t *testing.T) {
+	s := newTestSource(t, writeIVF(t, 1), WithEOFPolicy(EOFHold))
+
+	r, err := s.EncodedRecord(prop.Media{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	if _, _, err := r.Read(); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error)
+	go func() {
+		_, _, err := r.Read()
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		t.Fatalf("Expected read to block, got %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	s.Close()
+	select {
+	case err := <-done:
+		if err != io.EOF {
+			t.Errorf("Expected %v, got %v", io.EOF, err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timeout")
+	}
+}
+
+func TestCloseReleasesPlayers(t *testing.T) {
+	s := newTestSource(t, writeIVF(t, 1))
+
+	// the reader is never read, nor closed
+	r, err := s.EncodedRecord(prop.Media{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := r.(*encodedReader).player
+
+	s.Close()
+	if _, err := p.file.Read(make([]byte, 1)); !errors.Is(err, os.ErrClosed) {
+		t.Errorf("Expected the file to be closed, got %v", err)
+	}
+	if len(s.players) != 0 {
+		t.Errorf("Expected the players to be released, got %d", len(s.players))
+	}
+	if _, err := s.EncodedRecord(prop.Media{}); err != errClosed {
+		t.Errorf("Expected %v, got %v", errClosed, err)
+	}
+}
+
+func TestAddFileSource(t *testing.T) {
+	oggPath := filepath.Join(t.TempDir(), "test.opus")
+	f, err := os.Create(oggPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := ogg.NewWriter(f, ogg.Header{Channels: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WritePacket([]byte{0xfc, 0xff, 0xfe}, 960); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	if err := AddFileSource("filesource test video", writeIVF(t, 1)); err != nil {
+		t.Fatal(err)
+	}
+	if err := AddFileSource("filesource test audio", oggPath); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, d := range driver.GetManager().Query(driver.FilterDeviceType(driver.File)) {
+		switch d.Info().Label {
+		case "filesource test video":
+			if _, ok := d.(driver.VideoRecorder); !ok {
+				t.Error("Expected video driver")
+			}
+		case "filesource test audio":
+			if _, ok := d.(driver.AudioRecorder); !ok {
+				t.Error("Expected audio driver")
+			}
+		default:
+			continue
+		}
+		if _, ok := d.(driver.EncodedSource); !ok {
+			t.Error("Expected the driver to expose EncodedSource")
+		}
+	}
+
+	if err := AddFileSource("invalid", oggPath, WithFrameRate(0)); err != errInvalidFrameRate {
+		t.Errorf("Expected %v, got %v", errInvalidFrameRate, err)
+	}
+}
+
+func TestY4M(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.y4m")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := y4m.NewWriter(f, y4m.Header{
+		Width: 4, Height: 2,
+		FrameRateNumerator: 100, FrameRateDenominator: 1,
+		SubsampleRatio: image.YCbCrSubsampleRatio422,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		img := image.NewYCbCr(image.Rect(0, 0, 4, 2), image.YCbCrSubsampleRatio422)
+		img.Y[0] = byte(i)
+		if err := w.WriteFrame(img); err != nil {
+			t.Fatal(err)
+		}
+	}
+	f.Close()
+
+	s := newTestSource(t, path)
+	defer s.Close()
+
+	expected := prop.Video{Width: 4, Height: 2, FrameRate: 100, FrameFormat: frame.FormatI422}
+	if p := s.Properties(); p[0].Video != expected {
+		t.Errorf("Expected %+v, got %+v", expected, p[0].Video)
+	}
+	if c := s.EncodedCodec(prop.Media{}); c != nil {
+		t.Errorf("Expected no codec for raw files, got %v", c.MimeType)
+	}
+
+	r, err := (&videoFileSource{s}).VideoRecord(s.Properties()[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		img, _, err := r.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		yuv, ok := img.(*image.YCbCr)
+		if !ok || yuv.SubsampleRatio != image.YCbCrSubsampleRatio422 || yuv.Y[0] != byte(i) {
+			t.Errorf("Unexpected frame %d: %+v", i, img)
+		}
+	}
+	if _, _, err := r.Read(); err != io.EOF {
+		t.Errorf("Expected %v, got %v", io.EOF, err)
+	}
+}
+
+func TestWAV(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.wav")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := wav.NewWriter(f, wav.Header{Channels: 1, SampleRate: 1000, BitsPerSample: 16})
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunk := wave.NewInt16Interleaved(wave.ChunkInfo{Len: 25, Channels: 1, SamplingRate: 1000})
+	for i := range chunk.Data {
+		chunk.Data[i] = int16(i)
+	}
+	if err := w.WriteChunk(chunk); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	s := newTestSource(t, path, WithLatency(10*time.Millisecond))
+	defer s.Close()
+
+	expected := prop.Audio{
+		ChannelCount:  1,
+		SampleRate:    1000,
+		Latency:       10 * time.Millisecond,
+		SampleSize:    2,
+		IsInterleaved: true,
+	}
+	if p := s.Properties(); p[0].Audio != expected {
+		t.Errorf("Expected %+v, got %+v", expected, p[0].Audio)
+	}
+
+	r, err := (&audioFileSource{s}).AudioRecord(s.Properties()[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var first []int16
+	for _, n := range []int{10, 10, 5} {
+		chunk, _, err := r.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data := chunk.(*wave.Int16Interleaved).Data
+		if len(data) != n {
+			t.Errorf("Expected %d samples, got %d", n, len(data))
+		}
+		first = append(first, data[0])
+	}
+	if !reflect.DeepEqual(first, []int16{0, 10, 20}) {
+		t.Errorf("Unexpected chunks starting with %v", first)
+	}
+	if _, _, err := r.Read(); err != io.EOF {
+		t.Errorf("Expected %v, got %v", io.EOF, err)
+	}
+
+	if err := AddFileSource("invalid", path, WithLatency(0)); err != errInvalidLatency {
+		t.Errorf("Expected %v, got %v", errInvalidLatency, err)
+	}
+}
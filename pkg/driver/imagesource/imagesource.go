@@ -0,0 +1,542 @@
+// Package imagesource implements a video driver showing still images, e.g. as
+// "camera off" slates or test cards. It loads PNG, JPEG and GIF files from a
+// single file or a directory:
+//   - still images are shown for a configurable duration
+//   - animated GIFs are played with the delays of their frames
+//   - the images of a directory are shown one after the other in name order,
+//     and the slideshow loops
+//
+// Images are scaled to the configured resolution once when they are loaded,
+// and the files are reloaded in the background when they change.
+package imagesource
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	// Register the decoders of image.Decode
+	_ "image/jpeg"
+	_ "image/png"
+
+	"github.com/pion/mediadevices/internal/logging"
+	"github.com/pion/mediadevices/pkg/clock"
+	"github.com/pion/mediadevices/pkg/driver"
+	"github.com/pion/mediadevices/pkg/frame"
+	"github.com/pion/mediadevices/pkg/io/video"
+	"github.com/pion/mediadevices/pkg/prop"
+	"golang.org/x/image/draw"
+)
+
+const (
+	defaultFrameRate      = 30
+	defaultImageDuration  = 5 * time.Second
+	defaultReloadInterval = time.Second
+	// defaultGIFDelay is used for GIF frames without delay, like most browsers do
+	defaultGIFDelay = 100 * time.Millisecond
+)
+
+var logger = logging.NewLogger("mediadevices/driver/imagesource")
+
+var (
+	errNoImages          = errors.New("no image found")
+	errInvalidResolution = errors.New("width and height must be positive")
+	errInvalidFrameRate  = errors.New("frame rate must be positive")
+	errInvalidDuration   = errors.New("duration must be positive")
+)
+
+// ScaleMode defines how images are fitted to the resolution of the source.
+type ScaleMode int
+
+const (
+	// ScaleLetterbox keeps the aspect ratio of the images, and fills the
+	// remaining area with black bars
+	ScaleLetterbox ScaleMode = iota
+	// ScaleStretch stretches the images to the whole frame
+	ScaleStretch
+)
+
+type options struct {
+	width, height  int
+	frameRate      float32
+	scaleMode      ScaleMode
+	imageDuration  time.Duration
+	durations      map[string]time.Duration
+	reloadInterval time.Duration
+	clock          clock.Clock
+}
+
+// Option configures an image source.
+type Option func(*options) error
+
+// WithResolution sets the size of the frames. The default is the size of the
+// first image.
+func WithResolution(width, height int) Option {
+	return func(o *options) error {
+		if width <= 0 || height <= 0 {
+			return errInvalidResolution
+		}
+		o.width, o.height = width, height
+		return nil
+	}
+}
+
+// WithFrameRate sets the frame rate of the source. The default is 30 fps.
+func WithFrameRate(frameRate float32) Option {
+	return func(o *options) error {
+		if frameRate <= 0 {
+			return errInvalidFrameRate
+		}
+		o.frameRate = frameRate
+		return nil
+	}
+}
+
+// WithScaleMode sets how images are fitted to the resolution. The default is
+// ScaleLetterbox.
+func WithScaleMode(mode ScaleMode) Option {
+	return func(o *options) error {
+		o.scaleMode = mode
+		return nil
+	}
+}
+
+// WithImageDuration sets how long each still image of a directory is shown. The
+// default is 5 seconds.
+func WithImageDuration(d time.Duration) Option {
+	return func(o *options) error {
+		if d <= 0 {
+			return errInvalidDuration
+		}
+		o.imageDuration = d
+		return nil
+	}
+}
+
+// WithDurations sets how long the images of a directory are shown, by file
+// name. Animated GIFs given a duration loop until it elapses, instead of being
+// played once.
+func WithDurations(durations map[string]time.Duration) Option {
+	return func(o *options) error {
+		for _, d := range durations {
+			if d <= 0 {
+				return errInvalidDuration
+			}
+		}
+		o.durations = durations
+		return nil
+	}
+}
+
+// WithReloadInterval sets how often the files are checked for changes. The
+// default is 1 second.
+func WithReloadInterval(d time.Duration) Option {
+	return func(o *options) error {
+		if d <= 0 {
+			return errInvalidDuration
+		}
+		o.reloadInterval = d
+		return nil
+	}
+}
+
+// WithClock sets the clock timing the frames, the slideshow and the checks
+// for changed files. The default is the system clock.
+func WithClock(c clock.Clock) Option {
+	return func(o *options) error {
+		o.clock = c
+		return nil
+	}
+}
+
+// slide is an image of the slideshow, with a single frame for still images
+type slide struct {
+	frames []*image.RGBA
+	delays []time.Duration
+	// duration is how long the slide is shown
+	duration time.Duration
+}
+
+// slideshow is the timeline of the loaded images
+type slideshow struct {
+	slides   []slide
+	duration time.Duration
+}
+
+// frameAt returns the frame shown at elapsed since the beginning of the
+// slideshow
+func (s *slideshow) frameAt(elapsed time.Duration) *image.RGBA {
+	elapsed %= s.duration
+	for _, sl := range s.slides {
+		if elapsed >= sl.duration {
+			elapsed -= sl.duration
+			continue
+		}
+
+		var animation time.Duration
+		for _, delay := range sl.delays {
+			animation += delay
+		}
+		elapsed %= animation
+		for i, delay := range sl.delays {
+			if elapsed < delay {
+				return sl.frames[i]
+			}
+			elapsed -= delay
+		}
+	}
+	// unreachable, the durations are positive
+	return s.slides[len(s.slides)-1].frames[0]
+}
+
+// stamp identifies the state of the files, to detect changes
+type stamp string
+
+type imageSource struct {
+	path string
+	options
+
+	mu        sync.Mutex
+	slideshow *slideshow
+	stamp     stamp
+
+	closed chan struct{}
+}
+
+// AddImageSource registers a video driver showing the image file, or the images
+// of the directory at path. The images are loaded right away, to check them and
+// to find the default resolution.
+func AddImageSource(label string, path string, opts ...Option) error {
+	o := options{
+		frameRate:      defaultFrameRate,
+		scaleMode:      ScaleLetterbox,
+		imageDuration:  defaultImageDuration,
+		reloadInterval: defaultReloadInterval,
+		clock:          clock.New(),
+	}
+	for _, opt := range opts {
+		if err := opt(&o); err != nil {
+			return err
+		}
+	}
+
+	source := &imageSource{
+		path:    path,
+		options: o,
+	}
+	if err := source.load(); err != nil {
+		return err
+	}
+
+	return driver.GetManager().Register(source, driver.Info{
+		Label:      label,
+		DeviceType: driver.File,
+		Priority:   driver.PriorityNormal,
+	})
+}
+
+// files lists the images to show, in order
+func (s *imageSource) files() ([]string, error) {
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{s.path}, nil
+	}
+
+	entries, err := os.ReadDir(s.path)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, entry := range entries {
+		if entry.IsDir() || !isImage(entry.Name()) {
+			continue
+		}
+		files = append(files, filepath.Join(s.path, entry.Name()))
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+func isImage(name string) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".png", ".jpg", ".jpeg", ".gif":
+		return true
+	}
+	return false
+}
+
+// stampOf returns the names, sizes and modification times of files
+func stampOf(files []string) (stamp, error) {
+	var b strings.Builder
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&b, "%s:%d:%d\n", file, info.Size(), info.ModTime().UnixNano())
+	}
+	return stamp(b.String()), nil
+}
+
+// load reads all the images, and replaces the slideshow
+func (s *imageSource) load() error {
+	files, err := s.files()
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return errNoImages
+	}
+	st, err := stampOf(files)
+	if err != nil {
+		return err
+	}
+
+	// the images are decoded without holding the lock, so that the readers
+	// aren't blocked during reloads. The resolution is only set by the first
+	// load.
+	show := &slideshow{}
+	for _, file := range files {
+		srcFrames, delays, err := decodeFile(file)
+		if err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+		if s.width == 0 || s.height == 0 {
+			// the first image defines the resolution
+			size := srcFrames[0].Bounds().Size()
+			s.width, s.height = size.X, size.Y
+		}
+
+		sl := slide{delays: delays}
+		for _, src := range srcFrames {
+			sl.frames = append(sl.frames, s.fit(src))
+		}
+		if d, ok := s.durations[filepath.Base(file)]; ok {
+			sl.duration = d
+		} else if len(srcFrames) > 1 {
+			// animations are played once
+			for _, delay := range delays {
+				sl.duration += delay
+			}
+		} else {
+			sl.duration = s.imageDuration
+		}
+		show.slides = append(show.slides, sl)
+		show.duration += sl.duration
+	}
+
+	s.mu.Lock()
+	s.slideshow = show
+	s.stamp = st
+	s.mu.Unlock()
+	return nil
+}
+
+// watch reloads the images when the files change, until closed is closed. The
+// files are checked in the background, so that decoding them doesn't delay the
+// frames.
+func (s *imageSource) watch(closed <-chan struct{}) {
+	for {
+		select {
+		case <-closed:
+			return
+		case <-s.clock.After(s.reloadInterval):
+		}
+		s.reload()
+	}
+}
+
+// reload loads the images again if the files changed since the last load
+func (s *imageSource) reload() {
+	s.mu.Lock()
+	old := s.stamp
+	s.mu.Unlock()
+
+	files, err := s.files()
+	if err != nil {
+		return
+	}
+	st, err := stampOf(files)
+	if err != nil || st == old {
+		return
+	}
+	if err := s.load(); err != nil {
+		// the files may be partially written, keep showing the previous images
+		// until the next check
+		logger.Warnf("failed to reload %s: %v", s.path, err)
+		return
+	}
+	logger.Debugf("reloaded %s", s.path)
+}
+
+// decodeFile decodes the frames of an image file. Still images have a single
+// frame.
+func decodeFile(path string) ([]image.Image, []time.Duration, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	if strings.ToLower(filepath.Ext(path)) == ".gif" {
+		return decodeGIF(f)
+	}
+
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, nil, err
+	}
+	return []image.Image{img}, []time.Duration{defaultImageDuration}, nil
+}
+
+// decodeGIF composes the frames of a GIF, applying their disposal methods
+func decodeGIF(r io.Reader) ([]image.Image, []time.Duration, error) {
+	g, err := gif.DecodeAll(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(g.Image) == 0 {
+		return nil, nil, errNoImages
+	}
+
+	bounds := image.Rect(0, 0, g.Config.Width, g.Config.Height)
+	if bounds.Empty() {
+		bounds = g.Image[0].Bounds()
+	}
+	canvas := image.NewRGBA(bounds)
+	var frames []image.Image
+	var delays []time.Duration
+	for i, paletted := range g.Image {
+		var previous *image.RGBA
+		disposal := byte(0)
+		if i < len(g.Disposal) {
+			disposal = g.Disposal[i]
+		}
+		if disposal == gif.DisposalPrevious {
+			previous = image.NewRGBA(bounds)
+			draw.Draw(previous, bounds, canvas, bounds.Min, draw.Src)
+		}
+
+		draw.Draw(canvas, paletted.Bounds(), paletted, paletted.Bounds().Min, draw.Over)
+		composed := image.NewRGBA(bounds)
+		draw.Draw(composed, bounds, canvas, bounds.Min, draw.Src)
+		frames = append(frames, composed)
+
+		delay := defaultGIFDelay
+		if i < len(g.Delay) && g.Delay[i] > 0 {
+			delay = time.Duration(g.Delay[i]) * 10 * time.Millisecond
+		}
+		delays = append(delays, delay)
+
+		switch disposal {
+		case gif.DisposalBackground:
+			draw.Draw(canvas, paletted.Bounds(), image.Transparent, image.Point{}, draw.Src)
+		case gif.DisposalPrevious:
+			canvas = previous
+		}
+	}
+	return frames, delays, nil
+}
+
+// fit scales img to the resolution of the source
+func (s *imageSource) fit(img image.Image) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, s.width, s.height))
+	draw.Draw(dst, dst.Rect, image.NewUniform(color.Black), image.Point{}, draw.Src)
+
+	rect := dst.Rect
+	size := img.Bounds().Size()
+	if s.scaleMode == ScaleLetterbox && size.X > 0 && size.Y > 0 {
+		if s.width*size.Y > s.height*size.X {
+			// pillarbox
+			w := size.X * s.height / size.Y
+			rect.Min.X = (s.width - w) / 2
+			rect.Max.X = rect.Min.X + w
+		} else {
+			h := size.Y * s.width / size.X
+			rect.Min.Y = (s.height - h) / 2
+			rect.Max.Y = rect.Min.Y + h
+		}
+	}
+	draw.BiLinear.Scale(dst, rect, img, img.Bounds(), draw.Over, nil)
+	return dst
+}
+
+func (s *imageSource) Open() error {
+	s.closed = make(chan struct{})
+	go s.watch(s.closed)
+	return nil
+}
+
+func (s *imageSource) Close() error {
+	if s.closed != nil {
+		close(s.closed)
+		s.closed = nil
+	}
+	return nil
+}
+
+func (s *imageSource) Properties() []prop.Media {
+	return []prop.Media{
+		{
+			Video: prop.Video{
+				Width:       s.width,
+				Height:      s.height,
+				FrameFormat: frame.FormatRGBA,
+				FrameRate:   s.frameRate,
+			},
+		},
+	}
+}
+
+func (s *imageSource) VideoRecord(p prop.Media) (video.Reader, error) {
+	closed := s.closed
+	interval := time.Duration(float32(time.Second) / s.frameRate)
+	tick := s.clock.NewTicker(interval)
+	var start time.Time
+	var dst *image.RGBA
+
+	r := video.ReaderFunc(func() (image.Image, func(), error) {
+		select {
+		case <-closed:
+			tick.Stop()
+			return nil, func() {}, io.EOF
+		default:
+		}
+		if start.IsZero() {
+			// the first frame is sent right away
+			start = s.clock.Now()
+		} else {
+			select {
+			case <-closed:
+				tick.Stop()
+				return nil, func() {}, io.EOF
+			case <-tick.C():
+			}
+		}
+
+		s.mu.Lock()
+		img := s.slideshow.frameAt(s.clock.Now().Sub(start))
+		s.mu.Unlock()
+
+		// the frames are shared by the readers, so they are copied to let the
+		// transforms modify them
+		if dst == nil || dst.Rect != img.Rect {
+			dst = image.NewRGBA(img.Rect)
+		}
+		copy(dst.Pix, img.Pix)
+		return dst, func() {}, nil
+	})
+	return r, nil
+}
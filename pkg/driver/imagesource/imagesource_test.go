@@ -0,0 +1,250 @@
+package imagesource
+
+import (
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pion/mediadevices/pkg/clock"
+	"github.com/pion/mediadevices/pkg/driver"
+	"github.com/pion/mediadevices/pkg/io/video"
+)
+
+func writePNG(t *testing.T, path string, width, height int, c color.Color) {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for i := 0; i < len(img.Pix); i += 4 {
+		r, g, b, a := c.RGBA()
+		img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = uint8(r>>8), uint8(g>>8), uint8(b>>8), uint8(a>>8)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := png.Encode(f, img); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeGIF(t *testing.T, path string, delays []int, colors []color.Color) {
+	g := &gif.GIF{}
+	for i, c := range colors {
+		img := image.NewPaletted(image.Rect(0, 0, 2, 2), color.Palette{c})
+		g.Image = append(g.Image, img)
+		g.Delay = append(g.Delay, delays[i])
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := gif.EncodeAll(f, g); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newTestSource(t *testing.T, path string, opts ...Option) *imageSource {
+	o := options{
+		frameRate:      defaultFrameRate,
+		imageDuration:  defaultImageDuration,
+		reloadInterval: defaultReloadInterval,
+		clock:          clock.New(),
+	}
+	for _, opt := range opts {
+		if err := opt(&o); err != nil {
+			t.Fatal(err)
+		}
+	}
+	source := &imageSource{path: path, options: o}
+	if err := source.load(); err != nil {
+		t.Fatal(err)
+	}
+	return source
+}
+
+func TestSlideshow(t *testing.T) {
+	dir := t.TempDir()
+	writePNG(t, filepath.Join(dir, "a.png"), 4, 4, color.White)
+	writeGIF(t, filepath.Join(dir, "b.gif"), []int{10, 30}, []color.Color{color.Black, color.White})
+	writePNG(t, filepath.Join(dir, "c.png"), 4, 4, color.Black)
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("ignored"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	source := newTestSource(t, dir,
+		WithResolution(4, 4),
+		WithImageDuration(time.Second),
+		WithDurations(map[string]time.Duration{"c.png": 2 * time.Second}),
+	)
+	show := source.slideshow
+	// a.png: 1s, b.gif played once: 100ms + 300ms, c.png: 2s
+	if expected := 3400 * time.Millisecond; show.duration != expected {
+		t.Fatalf("Expected the slideshow to last %v, got %v", expected, show.duration)
+	}
+
+	testCases := map[string]struct {
+		elapsed  time.Duration
+		expected uint8
+	}{
+		"FirstImage":   {elapsed: 500 * time.Millisecond, expected: 0xFF},
+		"GIFFrame1":    {elapsed: 1050 * time.Millisecond, expected: 0},
+		"GIFFrame2":    {elapsed: 1200 * time.Millisecond, expected: 0xFF},
+		"CustomLength": {elapsed: 3300 * time.Millisecond, expected: 0},
+		"Loop":         {elapsed: 3900 * time.Millisecond, expected: 0xFF},
+	}
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			img := show.frameAt(testCase.elapsed)
+			if v := img.Pix[0]; v != testCase.expected {
+				t.Errorf("Expected %#x, got %#x", testCase.expected, v)
+			}
+		})
+	}
+}
+
+func TestFit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wide.png")
+	writePNG(t, path, 8, 4, color.White)
+
+	testCases := map[string]struct {
+		mode ScaleMode
+		// top is the red value of the first row
+		top uint8
+	}{
+		"Letterbox": {mode: ScaleLetterbox, top: 0},
+		"Stretch":   {mode: ScaleStretch, top: 0xFF},
+	}
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			source := newTestSource(t, path, WithResolution(8, 8), WithScaleMode(testCase.mode))
+			img := source.slideshow.frameAt(0)
+			if img.Rect.Dx() != 8 || img.Rect.Dy() != 8 {
+				t.Fatalf("Expected 8x8 frames, got %v", img.Rect)
+			}
+			if v := img.RGBAAt(4, 0).R; v != testCase.top {
+				t.Errorf("Expected %#x at the top, got %#x", testCase.top, v)
+			}
+			if v := img.RGBAAt(4, 4).R; v != 0xFF {
+				t.Errorf("Expected the image at the center, got %#x", v)
+			}
+		})
+	}
+}
+
+// readPixel reads a frame, and returns the red value of its first pixel
+func readPixel(t *testing.T, r video.Reader) uint8 {
+	t.Helper()
+	img, _, err := r.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return img.(*image.RGBA).Pix[0]
+}
+
+func TestVideoRecord(t *testing.T) {
+	dir := t.TempDir()
+	writePNG(t, filepath.Join(dir, "a.png"), 2, 2, color.White)
+	writePNG(t, filepath.Join(dir, "b.png"), 2, 2, color.Black)
+
+	m := clock.NewManual(time.Unix(0, 0))
+	source := newTestSource(t, dir, WithImageDuration(time.Second), WithClock(m))
+	if err := source.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer source.Close()
+	reader, err := source.VideoRecord(source.Properties()[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the first frame is sent right away, the next ones at the frame rate
+	if v := readPixel(t, reader); v != 0xFF {
+		t.Fatalf("Expected a white frame, got %#x", v)
+	}
+	m.Add(time.Second)
+	if v := readPixel(t, reader); v != 0 {
+		t.Errorf("Expected a black frame after 1s, got %#x", v)
+	}
+}
+
+func TestReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "slate.png")
+	writePNG(t, path, 2, 2, color.Black)
+
+	m := clock.NewManual(time.Unix(0, 0))
+	source := newTestSource(t, path, WithReloadInterval(time.Second), WithClock(m))
+	if err := source.Open(); err != nil {
+		t.Fatal(err)
+	}
+	reader, err := source.VideoRecord(source.Properties()[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := readPixel(t, reader); v != 0 {
+		t.Fatalf("Expected a black frame, got %#x", v)
+	}
+
+	writePNG(t, path, 2, 2, color.White)
+	// Make sure that the modification time changes
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+	// the files are checked once the reload interval elapsed, and checked again
+	// after the reload, when the reader ticker and the next check are waiting
+	m.BlockUntil(2)
+	m.Add(time.Second)
+	m.BlockUntil(2)
+	if v := readPixel(t, reader); v != 0xFF {
+		t.Errorf("Expected the image to be reloaded, got %#x", v)
+	}
+
+	if err := source.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := reader.Read(); err != io.EOF {
+		t.Errorf("Expected %v, got %v", io.EOF, err)
+	}
+	// closing again, or without opening, is a no-op
+	if err := source.Close(); err != nil {
+		t.Error(err)
+	}
+	if err := newTestSource(t, path).Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestAddImageSource(t *testing.T) {
+	dir := t.TempDir()
+	if err := AddImageSource("empty", dir); err != errNoImages {
+		t.Errorf("Expected %v, got %v", errNoImages, err)
+	}
+
+	writePNG(t, filepath.Join(dir, "slate.png"), 16, 8, color.White)
+	if err := AddImageSource(dir, dir, WithFrameRate(5)); err != nil {
+		t.Fatal(err)
+	}
+	drivers := driver.GetManager().Query(func(d driver.Driver) bool {
+		return d.Info().Label == dir
+	})
+	if len(drivers) != 1 {
+		t.Fatalf("Expected 1 driver, got %d", len(drivers))
+	}
+	d := drivers[0]
+	if err := d.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+	p := d.Properties()
+	if len(p) != 1 || p[0].Width != 16 || p[0].Height != 8 || p[0].FrameRate != 5 {
+		t.Errorf("Unexpected properties: %+v", p)
+	}
+}
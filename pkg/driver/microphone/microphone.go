@@ -20,11 +20,14 @@ import (
 
 const (
 	maxDeviceIDLength = 20
-	// TODO: should replace this with a more flexible approach
-	sampleRateStep    = 1000
 	initialBufferSize = 1024
+	// captureSampleRate is the sample rate of the device, the other rates are resampled
+	captureSampleRate = 48000
 )
 
+// sampleRates are the advertised sample rates
+var sampleRates = []int{8000, 16000, 24000, 32000, 44100, 48000}
+
 var logger = logging.NewLogger("mediadevices/driver/microphone")
 var ctx *malgo.AllocatedContext
 var hostEndian binary.ByteOrder
@@ -117,7 +120,7 @@ func (m *microphone) AudioRecord(inputProp prop.Media) (audio.Reader, error) {
 	config.DeviceType = malgo.Capture
 	config.PerformanceProfile = malgo.LowLatency
 	config.Capture.Channels = uint32(inputProp.ChannelCount)
-	config.SampleRate = uint32(inputProp.SampleRate)
+	config.SampleRate = captureSampleRate
 	config.PeriodSizeInMilliseconds = uint32(inputProp.Latency.Milliseconds())
 	//FIX: Turn on the microphone with the current device id
 	config.Capture.DeviceID = m.ID.Pointer()
@@ -174,14 +177,17 @@ func (m *microphone) AudioRecord(inputProp prop.Media) (audio.Reader, error) {
 		// FIXME: the decoder should also fill this information
 		switch decodedChunk := decodedChunk.(type) {
 		case *wave.Float32Interleaved:
-			decodedChunk.Size.SamplingRate = inputProp.SampleRate
+			decodedChunk.Size.SamplingRate = captureSampleRate
 		case *wave.Int16Interleaved:
-			decodedChunk.Size.SamplingRate = inputProp.SampleRate
+			decodedChunk.Size.SamplingRate = captureSampleRate
 		default:
 			panic("unsupported format")
 		}
 		return decodedChunk, func() {}, err
 	})
+	if inputProp.SampleRate != captureSampleRate {
+		reader = audio.Resample(inputProp.SampleRate)(reader)
+	}
 
 	return reader, nil
 }
@@ -197,32 +203,31 @@ func (m *microphone) Properties() []prop.Media {
 	}
 
 	for _, format := range m.Formats {
-		// FIXME: Currently support 48kHz only. We need to implement a resampler first.
-		// for sampleRate := m.MinSampleRate; sampleRate <= m.MaxSampleRate; sampleRate += sampleRateStep {
-		sampleRate := 48000
-		supportedProp := prop.Media{
-			Audio: prop.Audio{
-				ChannelCount: int(format.Channels),
-				SampleRate:   int(sampleRate),
-				IsBigEndian:  isBigEndian,
-				// miniaudio only supports interleaved at the moment
-				IsInterleaved: true,
-				// FIXME: should change this to a less discrete value
-				Latency: time.Millisecond * 20,
-			},
-		}
+		// The device captures at 48kHz, the other rates are resampled
+		for _, sampleRate := range sampleRates {
+			supportedProp := prop.Media{
+				Audio: prop.Audio{
+					ChannelCount: int(format.Channels),
+					SampleRate:   sampleRate,
+					IsBigEndian:  isBigEndian,
+					// miniaudio only supports interleaved at the moment
+					IsInterleaved: true,
+					// FIXME: should change this to a less discrete value
+					Latency: time.Millisecond * 20,
+				},
+			}
 
-		switch malgo.FormatType(format.Format) {
-		case malgo.FormatF32:
-			supportedProp.SampleSize = 4
-			supportedProp.IsFloat = true
-		case malgo.FormatS16:
-			supportedProp.SampleSize = 2
-			supportedProp.IsFloat = false
-		}
+			switch malgo.FormatType(format.Format) {
+			case malgo.FormatF32:
+				supportedProp.SampleSize = 4
+				supportedProp.IsFloat = true
+			case malgo.FormatS16:
+				supportedProp.SampleSize = 2
+				supportedProp.IsFloat = false
+			}
 
-		supportedProps = append(supportedProps, supportedProp)
-		// }
+			supportedProps = append(supportedProps, supportedProp)
+		}
 	}
 	return supportedProps
 }
@@ -0,0 +1,351 @@
+// Package pushsource implements video and audio sources fed by the application.
+// Frames are queued with Push, and read by a track created with
+// mediadevices.NewVideoTrack or mediadevices.NewAudioTrack, or through the
+// driver manager after calling Register.
+//
+//	source, _ := pushsource.NewVideoPushSource(prop.Media{
+//		Video: prop.Video{Width: 640, Height: 480, FrameRate: 30},
+//	})
+//	track := mediadevices.NewVideoTrack(source, codecSelector)
+//	...
+//	source.Push(img, timestamp)
+package pushsource
+
+import (
+	"errors"
+	"image"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pion/mediadevices/pkg/driver"
+	"github.com/pion/mediadevices/pkg/io/audio"
+	"github.com/pion/mediadevices/pkg/io/video"
+	"github.com/pion/mediadevices/pkg/prop"
+	"github.com/pion/mediadevices/pkg/wave"
+)
+
+const defaultQueueSize = 8
+
+var (
+	errClosed            = errors.New("push source is closed")
+	errDropped           = errors.New("frame dropped, the queue is full")
+	errInvalidQueueSize  = errors.New("queue size must be positive")
+	errInvalidDropPolicy = errors.New("invalid drop policy")
+)
+
+// DropPolicy defines what Push does when the queue is full.
+type DropPolicy int
+
+const (
+	// DropOldest drops the oldest queued frame to make room for the new one
+	DropOldest DropPolicy = iota
+	// DropNewest drops the pushed frame, and Push returns an error
+	DropNewest
+	// Block blocks Push until the frame can be queued
+	Block
+)
+
+type options struct {
+	queueSize  int
+	dropPolicy DropPolicy
+}
+
+// Option configures a push source.
+type Option func(*options) error
+
+// WithQueueSize sets how many frames can be queued. The default is 8.
+func WithQueueSize(size int) Option {
+	return func(o *options) error {
+		if size < 1 {
+			return errInvalidQueueSize
+		}
+		o.queueSize = size
+		return nil
+	}
+}
+
+// WithDropPolicy sets what happens when the queue is full. The default is
+// DropOldest, which keeps the latency low.
+func WithDropPolicy(policy DropPolicy) Option {
+	return func(o *options) error {
+		switch policy {
+		case DropOldest, DropNewest, Block:
+		default:
+			return errInvalidDropPolicy
+		}
+		o.dropPolicy = policy
+		return nil
+	}
+}
+
+type item struct {
+	data      interface{}
+	timestamp time.Duration
+}
+
+// queue holds the pushed frames, and delivers them according to their
+// timestamps
+type queue struct {
+	options
+	id     string
+	media  prop.Media
+	items  chan item
+	closed chan struct{}
+	once   sync.Once
+
+	// start and origin map the timestamps to the local time, they are shared
+	// by the readers of the source and its drivers
+	mu     sync.Mutex
+	start  time.Time
+	origin time.Duration
+}
+
+func newQueue(p prop.Media, opts []Option) (*queue, error) {
+	o := options{
+		queueSize:  defaultQueueSize,
+		dropPolicy: DropOldest,
+	}
+	for _, opt := range opts {
+		if err := opt(&o); err != nil {
+			return nil, err
+		}
+	}
+
+	id := p.DeviceID
+	if id == "" {
+		id = uuid.New().String()
+	}
+	return &queue{
+		options: o,
+		id:      id,
+		media:   p,
+		items:   make(chan item, o.queueSize),
+		closed:  make(chan struct{}),
+	}, nil
+}
+
+func (q *queue) push(data interface{}, timestamp time.Duration) error {
+	it := item{data: data, timestamp: timestamp}
+	select {
+	case <-q.closed:
+		return errClosed
+	default:
+	}
+
+	switch q.dropPolicy {
+	case Block:
+		select {
+		case q.items <- it:
+			return nil
+		case <-q.closed:
+			return errClosed
+		}
+	case DropNewest:
+		select {
+		case q.items <- it:
+			return nil
+		default:
+			return errDropped
+		}
+	default:
+		for {
+			select {
+			case q.items <- it:
+				return nil
+			default:
+			}
+			// the queue is full, drop the oldest frame and retry
+			select {
+			case <-q.items:
+			default:
+			}
+		}
+	}
+}
+
+// pop returns the next frame when it's due. done can interrupt the wait.
+func (q *queue) pop(done <-chan struct{}) (interface{}, error) {
+	var it item
+	select {
+	case it = <-q.items:
+	case <-q.closed:
+		return nil, io.EOF
+	case <-done:
+		return nil, io.EOF
+	}
+
+	due := q.due(it.timestamp)
+	if wait := time.Until(due); wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-q.closed:
+			return nil, io.EOF
+		case <-done:
+			return nil, io.EOF
+		}
+	}
+	return it.data, nil
+}
+
+// due returns the local time at which the frame with timestamp is read.
+func (q *queue) due(timestamp time.Duration) time.Time {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.start.IsZero() || timestamp < q.origin {
+		// first frame, or the timestamps restarted
+		q.start, q.origin = time.Now(), timestamp
+	}
+	return q.start.Add(timestamp - q.origin)
+}
+
+// ID returns the DeviceID of the properties given at creation, or a random
+// identifier if it's empty.
+func (q *queue) ID() string {
+	return q.id
+}
+
+// Close ends the stream. Readers get io.EOF, and Push fails afterwards.
+func (q *queue) Close() error {
+	q.once.Do(func() {
+		close(q.closed)
+	})
+	return nil
+}
+
+// VideoPushSource is a video source fed with Push. It implements
+// mediadevices.VideoSource.
+type VideoPushSource struct {
+	*queue
+}
+
+// NewVideoPushSource creates a video source with the properties p.
+func NewVideoPushSource(p prop.Media, opts ...Option) (*VideoPushSource, error) {
+	q, err := newQueue(p, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &VideoPushSource{q}, nil
+}
+
+// Push queues img, to be read when timestamp is due relative to the first
+// frame. img must not be modified afterwards.
+func (s *VideoPushSource) Push(img image.Image, timestamp time.Duration) error {
+	return s.push(img, timestamp)
+}
+
+// Read implements video.Reader.
+func (s *VideoPushSource) Read() (image.Image, func(), error) {
+	img, err := s.pop(nil)
+	if err != nil {
+		return nil, func() {}, err
+	}
+	return img.(image.Image), func() {}, nil
+}
+
+// Register registers the source to the driver manager with label, so that it
+// can be selected with constraints. Closing the driver ends the reading, but
+// doesn't close the source.
+func (s *VideoPushSource) Register(label string) error {
+	return driver.GetManager().Register(&videoDriver{pushDriver{queue: s.queue}}, driver.Info{
+		Label:      label,
+		DeviceType: driver.PushSource,
+		Priority:   driver.PriorityNormal,
+	})
+}
+
+// AudioPushSource is an audio source fed with Push. It implements
+// mediadevices.AudioSource.
+type AudioPushSource struct {
+	*queue
+}
+
+// NewAudioPushSource creates an audio source with the properties p.
+func NewAudioPushSource(p prop.Media, opts ...Option) (*AudioPushSource, error) {
+	q, err := newQueue(p, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &AudioPushSource{q}, nil
+}
+
+// Push queues chunk, to be read when timestamp is due relative to the first
+// chunk. chunk must not be modified afterwards.
+func (s *AudioPushSource) Push(chunk wave.Audio, timestamp time.Duration) error {
+	return s.push(chunk, timestamp)
+}
+
+// Read implements audio.Reader.
+func (s *AudioPushSource) Read() (wave.Audio, func(), error) {
+	chunk, err := s.pop(nil)
+	if err != nil {
+		return nil, func() {}, err
+	}
+	return chunk.(wave.Audio), func() {}, nil
+}
+
+// Register registers the source to the driver manager with label, so that it
+// can be selected with constraints. Closing the driver ends the reading, but
+// doesn't close the source.
+func (s *AudioPushSource) Register(label string) error {
+	return driver.GetManager().Register(&audioDriver{pushDriver{queue: s.queue}}, driver.Info{
+		Label:      label,
+		DeviceType: driver.PushSource,
+		Priority:   driver.PriorityNormal,
+	})
+}
+
+type pushDriver struct {
+	*queue
+	done chan struct{}
+}
+
+func (d *pushDriver) Open() error {
+	d.done = make(chan struct{})
+	return nil
+}
+
+func (d *pushDriver) Close() error {
+	close(d.done)
+	return nil
+}
+
+func (d *pushDriver) Properties() []prop.Media {
+	return []prop.Media{d.media}
+}
+
+type videoDriver struct {
+	pushDriver
+}
+
+func (d *videoDriver) VideoRecord(p prop.Media) (video.Reader, error) {
+	done := d.done
+	r := video.ReaderFunc(func() (image.Image, func(), error) {
+		img, err := d.pop(done)
+		if err != nil {
+			return nil, func() {}, err
+		}
+		return img.(image.Image), func() {}, nil
+	})
+	return r, nil
+}
+
+type audioDriver struct {
+	pushDriver
+}
+
+func (d *audioDriver) AudioRecord(p prop.Media) (audio.Reader, error) {
+	done := d.done
+	r := audio.ReaderFunc(func() (wave.Audio, func(), error) {
+		chunk, err := d.pop(done)
+		if err != nil {
+			return nil, func() {}, err
+		}
+		return chunk.(wave.Audio), func() {}, nil
+	})
+	return r, nil
+}
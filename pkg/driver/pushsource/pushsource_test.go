@@ -0,0 +1,265 @@
+package pushsource
+
+import (
+	"image"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/pion/mediadevices/pkg/driver"
+	"github.com/pion/mediadevices/pkg/prop"
+	"github.com/pion/mediadevices/pkg/wave"
+)
+
+func newTestImage(v uint8) image.Image {
+	img := image.NewGray(image.Rect(0, 0, 1, 1))
+	img.Pix[0] = v
+	return img
+}
+
+func TestDropPolicy(t *testing.T) {
+	testCases := map[string]struct {
+		policy   DropPolicy
+		expected []uint8
+	}{
+		"DropOldest": {
+			policy:   DropOldest,
+			expected: []uint8{1, 2},
+		},
+		"DropNewest": {
+			policy:   DropNewest,
+			expected: []uint8{0, 1},
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			source, err := NewVideoPushSource(prop.Media{}, WithQueueSize(2), WithDropPolicy(testCase.policy))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer source.Close()
+
+			for i := 0; i < 3; i++ {
+				err := source.Push(newTestImage(uint8(i)), 0)
+				if i == 2 && testCase.policy == DropNewest {
+					if err != errDropped {
+						t.Errorf("Expected %v, got %v", errDropped, err)
+					}
+				} else if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			for _, expected := range testCase.expected {
+				img, _, err := source.Read()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if v := img.(*image.Gray).Pix[0]; v != expected {
+					t.Errorf("Expected %d, got %d", expected, v)
+				}
+			}
+		})
+	}
+}
+
+func TestBlock(t *testing.T) {
+	source, err := NewVideoPushSource(prop.Media{}, WithQueueSize(1), WithDropPolicy(Block))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := source.Push(newTestImage(0), 0); err != nil {
+		t.Fatal(err)
+	}
+
+	pushed := make(chan error)
+	go func() {
+		pushed <- source.Push(newTestImage(1), 0)
+	}()
+	select {
+	case <-pushed:
+		t.Fatal("Expected Push to block while the queue is full")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	if _, _, err := source.Read(); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-pushed; err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		pushed <- source.Push(newTestImage(2), 0)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	source.Close()
+	if err := <-pushed; err != errClosed {
+		t.Errorf("Expected %v, got %v", errClosed, err)
+	}
+}
+
+func TestPacing(t *testing.T) {
+	source, err := NewAudioPushSource(prop.Media{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer source.Close()
+
+	const interval = 30 * time.Millisecond
+	for i := 0; i < 3; i++ {
+		chunk := wave.NewInt16Interleaved(wave.ChunkInfo{Len: 1, Channels: 1, SamplingRate: 8000})
+		if err := source.Push(chunk, time.Second+time.Duration(i)*interval); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if _, _, err := source.Read(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < interval*2*4/5 {
+		t.Errorf("Expected the chunks to be paced, took %v", elapsed)
+	}
+}
+
+func TestConcurrentReaders(t *testing.T) {
+	source, err := NewVideoPushSource(prop.Media{}, WithQueueSize(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer source.Close()
+	for i := 0; i < 10; i++ {
+		if err := source.Push(newTestImage(uint8(i)), time.Duration(i)*time.Millisecond); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	read := make(chan error)
+	for i := 0; i < 2; i++ {
+		go func() {
+			for j := 0; j < 5; j++ {
+				if _, _, err := source.Read(); err != nil {
+					read <- err
+					return
+				}
+			}
+			read <- nil
+		}()
+	}
+	for i := 0; i < 2; i++ {
+		if err := <-read; err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestClose(t *testing.T) {
+	source, err := NewVideoPushSource(prop.Media{DeviceID: "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id := source.ID(); id != "test" {
+		t.Errorf("Expected ID test, got %s", id)
+	}
+
+	read := make(chan error)
+	go func() {
+		_, _, err := source.Read()
+		read <- err
+	}()
+	source.Close()
+	if err := <-read; err != io.EOF {
+		t.Errorf("Expected %v, got %v", io.EOF, err)
+	}
+	if err := source.Push(newTestImage(0), 0); err != errClosed {
+		t.Errorf("Expected %v, got %v", errClosed, err)
+	}
+}
+
+func TestInvalidOptions(t *testing.T) {
+	testCases := map[string]struct {
+		option   Option
+		expected error
+	}{
+		"QueueSize": {
+			option:   WithQueueSize(0),
+			expected: errInvalidQueueSize,
+		},
+		"DropPolicy": {
+			option:   WithDropPolicy(Block + 1),
+			expected: errInvalidDropPolicy,
+		},
+	}
+	for name, c := range testCases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			if _, err := NewVideoPushSource(prop.Media{}, c.option); err != c.expected {
+				t.Errorf("Expected %v, got %v", c.expected, err)
+			}
+			if _, err := NewAudioPushSource(prop.Media{}, c.option); err != c.expected {
+				t.Errorf("Expected %v, got %v", c.expected, err)
+			}
+		})
+	}
+}
+
+func TestRegister(t *testing.T) {
+	const label = "pushsource_test"
+	media := prop.Media{Video: prop.Video{Width: 1, Height: 1}}
+	source, err := NewVideoPushSource(media)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer source.Close()
+	if err := source.Register(label); err != nil {
+		t.Fatal(err)
+	}
+
+	drivers := driver.GetManager().Query(func(d driver.Driver) bool {
+		return d.Info().Label == label
+	})
+	if len(drivers) != 1 {
+		t.Fatalf("Expected 1 driver, got %d", len(drivers))
+	}
+	d := drivers[0]
+	if d.Info().DeviceType != driver.PushSource {
+		t.Errorf("Expected device type %s, got %s", driver.PushSource, d.Info().DeviceType)
+	}
+	if err := d.Open(); err != nil {
+		t.Fatal(err)
+	}
+	if p := d.Properties(); len(p) != 1 || p[0].Video != media.Video {
+		t.Errorf("Expected %+v, got %+v", media, p)
+	}
+
+	reader, err := d.(driver.VideoRecorder).VideoRecord(d.Properties()[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := source.Push(newTestImage(7), 0); err != nil {
+		t.Fatal(err)
+	}
+	img, _, err := reader.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := img.(*image.Gray).Pix[0]; v != 7 {
+		t.Errorf("Expected 7, got %d", v)
+	}
+
+	// Closing the driver ends the reader, but not the source
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := reader.Read(); err != io.EOF {
+		t.Errorf("Expected %v, got %v", io.EOF, err)
+	}
+	if err := source.Push(newTestImage(8), 0); err != nil {
+		t.Errorf("Expected the source to stay open, got %v", err)
+	}
+}
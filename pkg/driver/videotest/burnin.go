@@ -0,0 +1,180 @@
+package videotest
+
+import (
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"time"
+)
+
+// The burn-in is a grid of black and white blocks at the top of the frames,
+// large enough to survive lossy compression. It encodes 12 bytes, most
+// significant bit first:
+//   - sequence number, 4 bytes
+//   - capture time, 7 bytes: milliseconds since the Unix epoch
+//   - checksum, 1 byte: XOR of the previous bytes and burnInChecksumSeed
+//
+// The blocks are laid out relatively to the width of the frame, so that the
+// burn-in can be read back from scaled frames.
+const (
+	burnInBlocksPerRow = 48
+	burnInRows         = 2
+	burnInBytes        = burnInBlocksPerRow * burnInRows / 8
+	// minBurnInBlockSize is the smallest block size, in pixels, which can be
+	// read back reliably
+	minBurnInBlockSize = 2
+	// burnInChecksumSeed makes frames all black or all white invalid
+	burnInChecksumSeed = 0xA5
+)
+
+var (
+	errFrameTooSmall   = errors.New("frame is too small for the burn-in")
+	errInvalidChecksum = errors.New("invalid burn-in checksum")
+)
+
+// BurnIn is the information burned into the frames by WithBurnIn.
+type BurnIn struct {
+	// Sequence is the number of the frame, starting at 0
+	Sequence uint32
+	// Timestamp is when the frame was captured, in milliseconds precision
+	Timestamp time.Time
+}
+
+func checkBurnInSize(width, height int) error {
+	if width/burnInBlocksPerRow < minBurnInBlockSize || width*burnInRows/burnInBlocksPerRow > height {
+		return errFrameTooSmall
+	}
+	return nil
+}
+
+// burnInBlock returns the area of the block of bit i in a frame of width pixels
+func burnInBlock(i, width int) image.Rectangle {
+	col, row := i%burnInBlocksPerRow, i/burnInBlocksPerRow
+	return image.Rect(
+		col*width/burnInBlocksPerRow, row*width/burnInBlocksPerRow,
+		(col+1)*width/burnInBlocksPerRow, (row+1)*width/burnInBlocksPerRow,
+	)
+}
+
+func (b BurnIn) encode() [burnInBytes]byte {
+	var data [burnInBytes]byte
+	binary.BigEndian.PutUint32(data[0:], b.Sequence)
+	var ts [8]byte
+	binary.BigEndian.PutUint64(ts[:], uint64(b.Timestamp.UnixNano()/int64(time.Millisecond)))
+	copy(data[4:11], ts[1:])
+	data[burnInBytes-1] = burnInChecksumSeed
+	for _, v := range data[:burnInBytes-1] {
+		data[burnInBytes-1] ^= v
+	}
+	return data
+}
+
+// drawBurnIn draws b at the top left corner of img, a 4:4:4 image
+func drawBurnIn(img *image.YCbCr, b BurnIn) error {
+	if err := checkBurnInSize(img.Rect.Dx(), img.Rect.Dy()); err != nil {
+		return err
+	}
+
+	data := b.encode()
+	for i := 0; i < burnInBytes*8; i++ {
+		c := black
+		if data[i/8]&(0x80>>uint(i%8)) != 0 {
+			c = white
+		}
+		fillRect(img, burnInBlock(i, img.Rect.Dx()).Add(img.Rect.Min), c)
+	}
+	return nil
+}
+
+// ReadBurnIn reads the information burned into a frame by WithBurnIn. img can
+// be a decoded and scaled frame, as long as its aspect ratio is kept.
+func ReadBurnIn(img image.Image) (BurnIn, error) {
+	bounds := img.Bounds()
+	if err := checkBurnInSize(bounds.Dx(), bounds.Dy()); err != nil {
+		return BurnIn{}, err
+	}
+
+	var data [burnInBytes]byte
+	for i := 0; i < burnInBytes*8; i++ {
+		// the center of the blocks is the least affected by compression
+		block := burnInBlock(i, bounds.Dx()).Add(bounds.Min)
+		x, y := (block.Min.X+block.Max.X)/2, (block.Min.Y+block.Max.Y)/2
+		if color.GrayModel.Convert(img.At(x, y)).(color.Gray).Y >= 128 {
+			data[i/8] |= 0x80 >> uint(i%8)
+		}
+	}
+
+	var checksum byte = burnInChecksumSeed
+	for _, v := range data[:burnInBytes-1] {
+		checksum ^= v
+	}
+	if checksum != data[burnInBytes-1] {
+		return BurnIn{}, errInvalidChecksum
+	}
+
+	var ts [8]byte
+	copy(ts[1:], data[4:11])
+	ms := int64(binary.BigEndian.Uint64(ts[:]))
+	return BurnIn{
+		Sequence:  binary.BigEndian.Uint32(data[0:]),
+		Timestamp: time.Unix(0, ms*int64(time.Millisecond)),
+	}, nil
+}
+
+// AnalyzerStats summarizes the frames read by an Analyzer.
+type AnalyzerStats struct {
+	// Frames is the number of frames with a valid burn-in
+	Frames int
+	// Lost is the number of frames missing between the received sequence
+	// numbers
+	Lost int
+	// Invalid is the number of frames whose burn-in couldn't be read
+	Invalid int
+	// MinLatency, MaxLatency and AvgLatency are the differences between the
+	// capture and the reception times of the frames
+	MinLatency, MaxLatency, AvgLatency time.Duration
+}
+
+// Analyzer measures the end-to-end latency and the frame loss of a stream of
+// frames with a burn-in, e.g. after encoding, sending and decoding them. The
+// clocks of the sender and the receiver must be synchronized.
+type Analyzer struct {
+	stats        AnalyzerStats
+	totalLatency time.Duration
+	last         uint32
+}
+
+// Analyze reads the burn-in of img, received at the given time, and updates
+// the statistics.
+func (a *Analyzer) Analyze(img image.Image, received time.Time) (BurnIn, error) {
+	b, err := ReadBurnIn(img)
+	if err != nil {
+		a.stats.Invalid++
+		return BurnIn{}, err
+	}
+
+	if a.stats.Frames > 0 && b.Sequence > a.last {
+		a.stats.Lost += int(b.Sequence - a.last - 1)
+	}
+	if a.stats.Frames == 0 || b.Sequence > a.last {
+		a.last = b.Sequence
+	}
+
+	latency := received.Sub(b.Timestamp)
+	if a.stats.Frames == 0 || latency < a.stats.MinLatency {
+		a.stats.MinLatency = latency
+	}
+	if a.stats.Frames == 0 || latency > a.stats.MaxLatency {
+		a.stats.MaxLatency = latency
+	}
+	a.stats.Frames++
+	a.totalLatency += latency
+	a.stats.AvgLatency = a.totalLatency / time.Duration(a.stats.Frames)
+	return b, nil
+}
+
+// Stats returns the statistics of the analyzed frames.
+func (a *Analyzer) Stats() AnalyzerStats {
+	return a.stats
+}
@@ -0,0 +1,118 @@
+package videotest
+
+import (
+	"image"
+	"testing"
+	"time"
+
+	"github.com/pion/mediadevices/pkg/clock"
+	"github.com/pion/mediadevices/pkg/frame"
+	"github.com/pion/mediadevices/pkg/prop"
+	"golang.org/x/image/draw"
+)
+
+func TestBurnIn(t *testing.T) {
+	d := newTestDriver(t, WithPattern(PatternZonePlate), WithBurnIn())
+	defer d.Close()
+
+	r, err := d.VideoRecord(prop.Media{Video: prop.Video{Width: 320, Height: 240, FrameFormat: frame.FormatI420, FrameRate: 1000}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var analyzer Analyzer
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		img, _, err := r.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i == 2 {
+			// simulate a lost frame
+			continue
+		}
+
+		// the burn-in survives scaling
+		scaled := image.NewRGBA(image.Rect(0, 0, 640, 480))
+		draw.BiLinear.Scale(scaled, scaled.Rect, img, img.Bounds(), draw.Src, nil)
+
+		b, err := analyzer.Analyze(scaled, time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b.Sequence != uint32(i) {
+			t.Errorf("Expected sequence %d, got %d", i, b.Sequence)
+		}
+		if b.Timestamp.Before(start.Truncate(time.Millisecond)) || b.Timestamp.After(time.Now()) {
+			t.Errorf("Unexpected timestamp %v", b.Timestamp)
+		}
+	}
+
+	stats := analyzer.Stats()
+	if stats.Frames != 3 || stats.Lost != 1 || stats.Invalid != 0 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+	if stats.MinLatency < 0 || stats.MaxLatency < stats.MinLatency {
+		t.Errorf("Unexpected latencies: %+v", stats)
+	}
+}
+
+func TestReadBurnInErrors(t *testing.T) {
+	testCases := map[string]struct {
+		img      image.Image
+		expected error
+	}{
+		"TooSmall": {
+			img:      image.NewGray(image.Rect(0, 0, 64, 64)),
+			expected: errFrameTooSmall,
+		},
+		"NoBurnIn": {
+			// all the bits are set, which doesn't match the checksum
+			img:      image.NewUniform(white),
+			expected: errInvalidChecksum,
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			img := testCase.img
+			if u, ok := img.(*image.Uniform); ok {
+				gray := image.NewGray(image.Rect(0, 0, 96, 96))
+				draw.Draw(gray, gray.Rect, u, image.Point{}, draw.Src)
+				img = gray
+			}
+			if _, err := ReadBurnIn(img); err != testCase.expected {
+				t.Errorf("Expected %v, got %v", testCase.expected, err)
+			}
+		})
+	}
+}
+
+func TestBurnInWithClock(t *testing.T) {
+	start := time.Unix(1600000000, 0)
+	c := clock.NewManual(start)
+	d := newTestDriver(t, WithPattern(PatternSolid), WithBurnIn(), WithClock(c))
+	defer d.Close()
+
+	r, err := d.VideoRecord(prop.Media{Video: prop.Video{Width: 96, Height: 96, FrameFormat: frame.FormatI444, FrameRate: 10}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		c.Add(100 * time.Millisecond)
+		img, _, err := r.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := ReadBurnIn(img)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := start.Add(time.Duration(i+1) * 100 * time.Millisecond)
+		if b.Sequence != uint32(i) || !b.Timestamp.Equal(expected) {
+			t.Errorf("Expected frame %d at %v, got %+v", i, expected, b)
+		}
+	}
+}
@@ -1,19 +1,29 @@
 // Package videotest provides dummy video driver for testing.
+//
+// The driver registered as "VideoTest" shows color bars. More drivers can be
+// registered with AddVideoTest, showing other patterns, or with a burn-in that
+// ReadBurnIn and Analyzer read back to measure the latency and the frame loss.
 package videotest
 
 import (
 	"context"
+	"errors"
 	"image"
+	"image/color"
 	"io"
-	"math/rand"
 	"time"
 
+	"github.com/pion/mediadevices/pkg/clock"
 	"github.com/pion/mediadevices/pkg/driver"
 	"github.com/pion/mediadevices/pkg/frame"
 	"github.com/pion/mediadevices/pkg/io/video"
 	"github.com/pion/mediadevices/pkg/prop"
 )
 
+const defaultFrameRate = 30
+
+var errInvalidProperties = errors.New("properties must have a positive width and height")
+
 func init() {
 	driver.GetManager().Register(
 		newVideoTest(),
@@ -21,14 +31,107 @@ func init() {
 	)
 }
 
+type options struct {
+	pattern    Pattern
+	color      color.Color
+	burnIn     bool
+	properties []prop.Video
+	clock      clock.Clock
+}
+
+// Option configures a test video driver.
+type Option func(*options) error
+
+// WithPattern sets the content of the video. The default is PatternColorBars.
+func WithPattern(pattern Pattern) Option {
+	return func(o *options) error {
+		o.pattern = pattern
+		return nil
+	}
+}
+
+// WithColor sets the color of PatternSolid. The default is gray.
+func WithColor(c color.Color) Option {
+	return func(o *options) error {
+		o.color = c
+		return nil
+	}
+}
+
+// WithBurnIn burns the sequence number and the capture time into the frames.
+// It needs frames at least 96 pixels wide.
+func WithBurnIn() Option {
+	return func(o *options) error {
+		o.burnIn = true
+		return nil
+	}
+}
+
+// WithProperties sets the properties of the driver. Any resolution is
+// supported. I420, NV12 and NV21 are generated as 4:2:0 images, I444 as 4:4:4,
+// RGBA as RGBA, and the other formats as 4:2:2. The default is 640x480 YUYV
+// at 30 fps.
+func WithProperties(properties ...prop.Video) Option {
+	return func(o *options) error {
+		if len(properties) == 0 {
+			return errInvalidProperties
+		}
+		for _, p := range properties {
+			if p.Width <= 0 || p.Height <= 0 {
+				return errInvalidProperties
+			}
+		}
+		o.properties = properties
+		return nil
+	}
+}
+
+// WithClock sets the clock ticking at the frame rate, which also gives the
+// time drawn by the burn-in. The default is the system clock.
+func WithClock(c clock.Clock) Option {
+	return func(o *options) error {
+		o.clock = c
+		return nil
+	}
+}
+
+// AddVideoTest registers a test video driver configured by opts.
+func AddVideoTest(label string, opts ...Option) error {
+	d := newVideoTest()
+	for _, opt := range opts {
+		if err := opt(&d.options); err != nil {
+			return err
+		}
+	}
+	return driver.GetManager().Register(d, driver.Info{
+		Label:      label,
+		DeviceType: driver.Camera,
+		Priority:   driver.PriorityNormal,
+	})
+}
+
 type dummy struct {
+	options
 	closed <-chan struct{}
 	cancel func()
-	tick   *time.Ticker
 }
 
 func newVideoTest() *dummy {
-	return &dummy{}
+	return &dummy{
+		options: options{
+			pattern: PatternColorBars,
+			color:   color.Gray{Y: 128},
+			properties: []prop.Video{
+				{
+					Width:       640,
+					Height:      480,
+					FrameFormat: frame.FormatYUYV,
+					FrameRate:   defaultFrameRate,
+				},
+			},
+			clock: clock.New(),
+		},
+	}
 }
 
 func (d *dummy) Open() error {
@@ -40,107 +143,130 @@ func (d *dummy) Open() error {
 
 func (d *dummy) Close() error {
 	d.cancel()
-	if d.tick != nil {
-		d.tick.Stop()
-	}
 	return nil
 }
 
-func (d *dummy) VideoRecord(p prop.Media) (video.Reader, error) {
-	colors := [][3]byte{
-		{235, 128, 128},
-		{210, 16, 146},
-		{170, 166, 16},
-		{145, 54, 34},
-		{107, 202, 222},
-		{82, 90, 240},
-		{41, 240, 110},
+// newOutput allocates the frames sent for format
+func newOutput(format frame.Format, rect image.Rectangle) image.Image {
+	switch format {
+	case frame.FormatRGBA:
+		return image.NewRGBA(rect)
+	case frame.FormatI444:
+		return image.NewYCbCr(rect, image.YCbCrSubsampleRatio444)
+	case frame.FormatI420, frame.FormatNV12, frame.FormatNV21:
+		return image.NewYCbCr(rect, image.YCbCrSubsampleRatio420)
+	default:
+		return image.NewYCbCr(rect, image.YCbCrSubsampleRatio422)
 	}
+}
 
-	yi := p.Width * p.Height
-	ci := yi / 2
-	yy := make([]byte, yi)
-	cb := make([]byte, ci)
-	cr := make([]byte, ci)
-	yyBase := make([]byte, yi)
-	cbBase := make([]byte, ci)
-	crBase := make([]byte, ci)
-	hColorBarEnd := p.Height * 3 / 4
-	wGradationEnd := p.Width * 5 / 7
-	for y := 0; y < hColorBarEnd; y++ {
-		yi := p.Width * y
-		ci := p.Width * y / 2
-		// Color bar
-		for x := 0; x < p.Width; x++ {
-			c := x * 7 / p.Width
-			yyBase[yi+x] = uint8(uint16(colors[c][0]) * 75 / 100)
-			cbBase[ci+x/2] = colors[c][1]
-			crBase[ci+x/2] = colors[c][2]
+// convert copies src, a 4:4:4 image, into dst. The chroma is subsampled by
+// picking the top left sample.
+func convert(dst image.Image, src *image.YCbCr) {
+	rect := src.Rect
+	switch dst := dst.(type) {
+	case *image.RGBA:
+		for y := rect.Min.Y; y < rect.Max.Y; y++ {
+			for x := rect.Min.X; x < rect.Max.X; x++ {
+				c := src.YCbCrAt(x, y)
+				r, g, b := color.YCbCrToRGB(c.Y, c.Cb, c.Cr)
+				i := dst.PixOffset(x, y)
+				dst.Pix[i], dst.Pix[i+1], dst.Pix[i+2], dst.Pix[i+3] = r, g, b, 0xFF
+			}
 		}
-	}
-	for y := hColorBarEnd; y < p.Height; y++ {
-		yi := p.Width * y
-		ci := p.Width * y / 2
-		for x := 0; x < wGradationEnd; x++ {
-			// Gray gradation
-			yyBase[yi+x] = uint8(x * 255 / wGradationEnd)
-			cbBase[ci+x/2] = 128
-			crBase[ci+x/2] = 128
+	case *image.YCbCr:
+		for y := rect.Min.Y; y < rect.Max.Y; y++ {
+			copy(dst.Y[dst.YOffset(rect.Min.X, y):], src.Y[src.YOffset(rect.Min.X, y):src.YOffset(rect.Max.X-1, y)+1])
+		}
+		for y := rect.Min.Y; y < rect.Max.Y; y++ {
+			for x := rect.Min.X; x < rect.Max.X; x++ {
+				ci := dst.COffset(x, y)
+				si := src.COffset(x, y)
+				dst.Cb[ci] = src.Cb[si]
+				dst.Cr[ci] = src.Cr[si]
+			}
+			// skip the rows sharing the same chroma samples
+			if dst.SubsampleRatio == image.YCbCrSubsampleRatio420 {
+				y++
+			}
 		}
-		for x := wGradationEnd; x < p.Width; x++ {
-			// Noise area
-			cbBase[ci+x/2] = 128
-			crBase[ci+x/2] = 128
+	}
+}
+
+func (d *dummy) VideoRecord(p prop.Media) (video.Reader, error) {
+	if p.Width <= 0 || p.Height <= 0 {
+		return nil, errInvalidProperties
+	}
+	rect := image.Rect(0, 0, p.Width, p.Height)
+	if d.burnIn {
+		if err := checkBurnInSize(p.Width, p.Height); err != nil {
+			return nil, err
 		}
 	}
-	random := rand.New(rand.NewSource(0))
+	frameRate := p.FrameRate
+	if frameRate <= 0 {
+		frameRate = defaultFrameRate
+	}
 
-	tick := time.NewTicker(time.Duration(float32(time.Second) / p.FrameRate))
-	d.tick = tick
+	render, static := newRenderer(d.pattern, d.color)
+	base := image.NewYCbCr(rect, image.YCbCrSubsampleRatio444)
+	if static {
+		render(base, 0)
+	}
+	canvas := base
+	if static && d.burnIn {
+		// the burn-in is drawn on a copy of the static pattern
+		canvas = image.NewYCbCr(rect, image.YCbCrSubsampleRatio444)
+	}
+	output := newOutput(p.FrameFormat, rect)
+
+	clk := d.clock
+	tick := clk.NewTicker(time.Duration(float32(time.Second) / frameRate))
 	closed := d.closed
+	burnIn := d.burnIn
+	var n int
 
 	r := video.ReaderFunc(func() (image.Image, func(), error) {
 		select {
 		case <-closed:
+			tick.Stop()
 			return nil, func() {}, io.EOF
 		default:
 		}
 
-		<-tick.C
+		select {
+		case <-closed:
+			tick.Stop()
+			return nil, func() {}, io.EOF
+		case <-tick.C():
+		}
 
-		copy(yy, yyBase)
-		copy(cb, cbBase)
-		copy(cr, crBase)
-		for y := hColorBarEnd; y < p.Height; y++ {
-			yi := p.Width * y
-			for x := wGradationEnd; x < p.Width; x++ {
-				// Noise
-				yy[yi+x] = uint8(random.Int31n(2) * 255)
+		if !static {
+			render(canvas, n)
+		} else if canvas != base {
+			copy(canvas.Y, base.Y)
+			copy(canvas.Cb, base.Cb)
+			copy(canvas.Cr, base.Cr)
+		}
+		if burnIn {
+			err := drawBurnIn(canvas, BurnIn{Sequence: uint32(n), Timestamp: clk.Now()})
+			if err != nil {
+				return nil, func() {}, err
 			}
 		}
-		return &image.YCbCr{
-			Y:              yy,
-			YStride:        p.Width,
-			Cb:             cb,
-			Cr:             cr,
-			CStride:        p.Width / 2,
-			SubsampleRatio: image.YCbCrSubsampleRatio422,
-			Rect:           image.Rect(0, 0, p.Width, p.Height),
-		}, func() {}, nil
+		n++
+
+		convert(output, canvas)
+		return output, func() {}, nil
 	})
 
 	return r, nil
 }
 
-func (d dummy) Properties() []prop.Media {
-	return []prop.Media{
-		{
-			Video: prop.Video{
-				Width:       640,
-				Height:      480,
-				FrameFormat: frame.FormatYUYV,
-				FrameRate:   30,
-			},
-		},
+func (d *dummy) Properties() []prop.Media {
+	var properties []prop.Media
+	for _, p := range d.properties {
+		properties = append(properties, prop.Media{Video: p})
 	}
+	return properties
 }
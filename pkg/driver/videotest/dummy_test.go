@@ -0,0 +1,117 @@
+package videotest
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/pion/mediadevices/pkg/frame"
+	"github.com/pion/mediadevices/pkg/prop"
+)
+
+func newTestDriver(t *testing.T, opts ...Option) *dummy {
+	d := newVideoTest()
+	for _, opt := range opts {
+		if err := opt(&d.options); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := d.Open(); err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestFrameFormat(t *testing.T) {
+	testCases := map[frame.Format]struct {
+		ratio image.YCbCrSubsampleRatio
+		rgba  bool
+	}{
+		frame.FormatI420: {ratio: image.YCbCrSubsampleRatio420},
+		frame.FormatNV12: {ratio: image.YCbCrSubsampleRatio420},
+		frame.FormatI444: {ratio: image.YCbCrSubsampleRatio444},
+		frame.FormatYUYV: {ratio: image.YCbCrSubsampleRatio422},
+		frame.FormatRGBA: {rgba: true},
+	}
+
+	for format, testCase := range testCases {
+		testCase := testCase
+		t.Run(string(format), func(t *testing.T) {
+			d := newTestDriver(t, WithPattern(PatternSolid), WithColor(color.White))
+			defer d.Close()
+
+			// odd sizes are supported
+			r, err := d.VideoRecord(prop.Media{Video: prop.Video{Width: 33, Height: 17, FrameFormat: format, FrameRate: 1000}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			img, _, err := r.Read()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if img.Bounds() != image.Rect(0, 0, 33, 17) {
+				t.Errorf("Expected 33x17 frames, got %v", img.Bounds())
+			}
+
+			switch img := img.(type) {
+			case *image.RGBA:
+				if !testCase.rgba {
+					t.Fatalf("Unexpected RGBA frame")
+				}
+				if c := img.RGBAAt(32, 16); c.R != 0xFF || c.G != 0xFF || c.B != 0xFF {
+					t.Errorf("Expected white, got %v", c)
+				}
+			case *image.YCbCr:
+				if testCase.rgba || img.SubsampleRatio != testCase.ratio {
+					t.Fatalf("Unexpected subsample ratio %v", img.SubsampleRatio)
+				}
+				if c := img.YCbCrAt(32, 16); c.Y != 0xFF || c.Cb != 128 || c.Cr != 128 {
+					t.Errorf("Expected white, got %v", c)
+				}
+			default:
+				t.Fatalf("Unexpected image type %T", img)
+			}
+		})
+	}
+}
+
+func TestPatterns(t *testing.T) {
+	patterns := map[string]Pattern{
+		"ColorBars":    PatternColorBars,
+		"SMPTE":        PatternSMPTE,
+		"MovingBox":    PatternMovingBox,
+		"ZonePlate":    PatternZonePlate,
+		"Checkerboard": PatternCheckerboard,
+		"Solid":        PatternSolid,
+		"FrameCounter": PatternFrameCounter,
+	}
+
+	for name, pattern := range patterns {
+		pattern := pattern
+		t.Run(name, func(t *testing.T) {
+			render, static := newRenderer(pattern, color.Black)
+			first := image.NewYCbCr(image.Rect(0, 0, 64, 48), image.YCbCrSubsampleRatio444)
+			second := image.NewYCbCr(first.Rect, image.YCbCrSubsampleRatio444)
+			render(first, 0)
+			render(second, 1)
+
+			// moving patterns change between frames, static ones don't
+			changed := string(first.Y) != string(second.Y)
+			if changed == static {
+				t.Errorf("Expected static %v, but the frames changed: %v", static, changed)
+			}
+		})
+	}
+}
+
+func TestProperties(t *testing.T) {
+	d := newVideoTest()
+	p := d.Properties()
+	if len(p) != 1 || p[0].Width != 640 || p[0].Height != 480 || p[0].FrameFormat != frame.FormatYUYV {
+		t.Errorf("Unexpected default properties: %+v", p)
+	}
+
+	if err := WithProperties(prop.Video{Width: 0, Height: 10})(&d.options); err != errInvalidProperties {
+		t.Errorf("Expected %v, got %v", errInvalidProperties, err)
+	}
+}
@@ -0,0 +1,301 @@
+package videotest
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"math/rand"
+)
+
+// Pattern is the content of the test video.
+type Pattern int
+
+const (
+	// PatternColorBars shows 75% color bars, a gray gradation and noise
+	PatternColorBars Pattern = iota
+	// PatternSMPTE shows SMPTE color bars, with the castellations and the
+	// -I, white, +Q and PLUGE row
+	PatternSMPTE
+	// PatternMovingBox shows a white box bouncing on a gray background
+	PatternMovingBox
+	// PatternZonePlate shows a moving circular zone plate, whose frequency
+	// increases up to the Nyquist frequency at the edges
+	PatternZonePlate
+	// PatternCheckerboard shows black and white squares, 8 along the height
+	PatternCheckerboard
+	// PatternSolid shows a single color, see WithColor
+	PatternSolid
+	// PatternFrameCounter shows the frame number with large digits
+	PatternFrameCounter
+)
+
+// renderFunc draws the frame number n into img, a 4:4:4 image
+type renderFunc func(img *image.YCbCr, n int)
+
+var (
+	black = ycc(16, 128, 128)
+	white = ycc(235, 128, 128)
+	gray  = ycc(128, 128, 128)
+)
+
+func ycc(y, cb, cr uint8) color.YCbCr {
+	return color.YCbCr{Y: y, Cb: cb, Cr: cr}
+}
+
+// newRenderer returns the renderer of pattern. Static patterns only need to be
+// rendered once.
+func newRenderer(pattern Pattern, fill color.Color) (render renderFunc, static bool) {
+	switch pattern {
+	case PatternSMPTE:
+		return renderSMPTE, true
+	case PatternMovingBox:
+		return renderMovingBox, false
+	case PatternZonePlate:
+		return renderZonePlate, false
+	case PatternCheckerboard:
+		return renderCheckerboard, true
+	case PatternSolid:
+		c := color.YCbCrModel.Convert(fill).(color.YCbCr)
+		return func(img *image.YCbCr, n int) {
+			fillRect(img, img.Rect, c)
+		}, true
+	case PatternFrameCounter:
+		return renderFrameCounter, false
+	default:
+		return newColorBarsRenderer(), false
+	}
+}
+
+func setPixel(img *image.YCbCr, x, y int, c color.YCbCr) {
+	img.Y[img.YOffset(x, y)] = c.Y
+	ci := img.COffset(x, y)
+	img.Cb[ci] = c.Cb
+	img.Cr[ci] = c.Cr
+}
+
+func fillRect(img *image.YCbCr, r image.Rectangle, c color.YCbCr) {
+	r = r.Intersect(img.Rect)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			setPixel(img, x, y, c)
+		}
+	}
+}
+
+// newColorBarsRenderer renders the historical pattern of the driver. The noise
+// changes on every frame.
+func newColorBarsRenderer() renderFunc {
+	colors := []color.YCbCr{
+		ycc(235, 128, 128),
+		ycc(210, 16, 146),
+		ycc(170, 166, 16),
+		ycc(145, 54, 34),
+		ycc(107, 202, 222),
+		ycc(82, 90, 240),
+		ycc(41, 240, 110),
+	}
+	random := rand.New(rand.NewSource(0))
+
+	return func(img *image.YCbCr, n int) {
+		w, h := img.Rect.Dx(), img.Rect.Dy()
+		hColorBarEnd := h * 3 / 4
+		wGradationEnd := w * 5 / 7
+		for y := 0; y < hColorBarEnd; y++ {
+			for x := 0; x < w; x++ {
+				c := colors[x*7/w]
+				c.Y = uint8(uint16(c.Y) * 75 / 100)
+				setPixel(img, x, y, c)
+			}
+		}
+		for y := hColorBarEnd; y < h; y++ {
+			for x := 0; x < wGradationEnd; x++ {
+				// Gray gradation
+				setPixel(img, x, y, color.YCbCr{Y: uint8(x * 255 / wGradationEnd), Cb: 128, Cr: 128})
+			}
+			for x := wGradationEnd; x < w; x++ {
+				// Noise
+				setPixel(img, x, y, color.YCbCr{Y: uint8(random.Int31n(2) * 255), Cb: 128, Cr: 128})
+			}
+		}
+	}
+}
+
+// studioYCbCr converts 8 bits RGB levels to studio range YCbCr
+func studioYCbCr(r, g, b uint8) color.YCbCr {
+	scale := func(v uint8) uint8 {
+		return uint8(16 + int(v)*219/255)
+	}
+	y, cb, cr := color.RGBToYCbCr(scale(r), scale(g), scale(b))
+	return color.YCbCr{Y: y, Cb: cb, Cr: cr}
+}
+
+func renderSMPTE(img *image.YCbCr, n int) {
+	const l = 191 // 75%
+	bars := []color.YCbCr{
+		studioYCbCr(l, l, l), // gray
+		studioYCbCr(l, l, 0), // yellow
+		studioYCbCr(0, l, l), // cyan
+		studioYCbCr(0, l, 0), // green
+		studioYCbCr(l, 0, l), // magenta
+		studioYCbCr(l, 0, 0), // red
+		studioYCbCr(0, 0, l), // blue
+	}
+	castellations := []color.YCbCr{bars[6], black, bars[4], black, bars[2], black, bars[0]}
+	bottom := []struct {
+		c     color.YCbCr
+		width int // in 1/28 of the width, i.e. a quarter of a bar
+	}{
+		{studioYCbCr(0, 33, 76), 5},  // -I
+		{white, 5},                   // 100% white
+		{studioYCbCr(50, 0, 106), 5}, // +Q
+		{black, 5},                   // black
+		{ycc(7, 128, 128), 1},        // PLUGE, superblack
+		{black, 1},
+		{ycc(26, 128, 128), 1}, // PLUGE, 4% above black
+		{black, 5},
+	}
+
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	barsEnd := h * 2 / 3
+	castellationsEnd := h * 3 / 4
+	for x := 0; x < w; x++ {
+		i := x * 7 / w
+		fillRect(img, image.Rect(x, 0, x+1, barsEnd), bars[i])
+		fillRect(img, image.Rect(x, barsEnd, x+1, castellationsEnd), castellations[i])
+	}
+	var x0 int
+	var units int
+	for _, b := range bottom {
+		units += b.width
+		x1 := w * units / 28
+		fillRect(img, image.Rect(x0, castellationsEnd, x1, h), b.c)
+		x0 = x1
+	}
+}
+
+// bounce returns a position moving back and forth between 0 and max
+func bounce(pos, max int) int {
+	if max <= 0 {
+		return 0
+	}
+	pos %= 2 * max
+	if pos > max {
+		return 2*max - pos
+	}
+	return pos
+}
+
+func renderMovingBox(img *image.YCbCr, n int) {
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	size := h / 6
+	if size < 1 {
+		size = 1
+	}
+	// the box crosses the frame horizontally in about 2 seconds at 30 fps
+	speed := w / 60
+	if speed < 1 {
+		speed = 1
+	}
+	x := bounce(n*speed, w-size)
+	y := bounce(n*speed*3/4, h-size)
+
+	fillRect(img, img.Rect, gray)
+	fillRect(img, image.Rect(x, y, x+size, y+size), white)
+}
+
+func renderZonePlate(img *image.YCbCr, n int) {
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	r := float64(w)
+	if h > w {
+		r = float64(h)
+	}
+	r /= 2
+	cx, cy := float64(w)/2, float64(h)/2
+	phase := float64(n) * 0.2
+
+	for y := 0; y < h; y++ {
+		dy := float64(y) - cy
+		for x := 0; x < w; x++ {
+			dx := float64(x) - cx
+			// the local frequency is d²/(2r) cycles per pixel, 0.5 at the edges
+			v := math.Cos(math.Pi*(dx*dx+dy*dy)/(2*r) - phase)
+			img.Y[img.YOffset(x, y)] = uint8(126 + 109*v)
+		}
+	}
+	for i := range img.Cb {
+		img.Cb[i] = 128
+		img.Cr[i] = 128
+	}
+}
+
+func renderCheckerboard(img *image.YCbCr, n int) {
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	size := h / 8
+	if size < 1 {
+		size = 1
+	}
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := black
+			if (x/size+y/size)%2 == 0 {
+				c = white
+			}
+			setPixel(img, x, y, c)
+		}
+	}
+}
+
+// segments lists the 7-segment display of the digits, as bits abcdefg
+var segments = [10]uint8{
+	0x7E, 0x30, 0x6D, 0x79, 0x33, 0x5B, 0x5F, 0x70, 0x7F, 0x7B,
+}
+
+// drawDigit draws a 7-segment digit of size w x 2w at (x, y)
+func drawDigit(img *image.YCbCr, digit, x, y, w int, c color.YCbCr) {
+	t := w / 5 // thickness
+	if t < 1 {
+		t = 1
+	}
+	h := 2 * w
+	rects := [7]image.Rectangle{
+		image.Rect(x, y, x+w, y+t),                 // a
+		image.Rect(x+w-t, y, x+w, y+h/2),           // b
+		image.Rect(x+w-t, y+h/2, x+w, y+h),         // c
+		image.Rect(x, y+h-t, x+w, y+h),             // d
+		image.Rect(x, y+h/2, x+t, y+h),             // e
+		image.Rect(x, y, x+t, y+h/2),               // f
+		image.Rect(x, y+h/2-t/2, x+w, y+h/2+t-t/2), // g
+	}
+	for i, r := range rects {
+		if segments[digit]&(0x40>>uint(i)) != 0 {
+			fillRect(img, r, c)
+		}
+	}
+}
+
+func renderFrameCounter(img *image.YCbCr, n int) {
+	fillRect(img, img.Rect, black)
+
+	var digits []int
+	for v := n; ; v /= 10 {
+		digits = append([]int{v % 10}, digits...)
+		if v < 10 {
+			break
+		}
+	}
+
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	// digits are w x 2w, separated by w/2
+	digitWidth := h / 6
+	if max := w * 2 / (3*len(digits) + 1); digitWidth > max {
+		digitWidth = max
+	}
+	spacing := digitWidth / 2
+	total := len(digits)*(digitWidth+spacing) - spacing
+	x := (w - total) / 2
+	y := (h - 2*digitWidth) / 2
+	for _, d := range digits {
+		drawDigit(img, d, x, y, digitWidth, white)
+		x += digitWidth + spacing
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/pion/mediadevices/pkg/clock"
 	"github.com/pion/mediadevices/pkg/driver/vncdriver/vnc"
 
 	"github.com/pion/mediadevices/pkg/frame"
@@ -21,16 +22,33 @@ import (
 type vncDevice struct {
 	closed   <-chan struct{}
 	cancel   func()
-	tick     *time.Ticker
+	tick     clock.Ticker
 	h, w     int
 	rawPixel []byte
 	mutex    sync.Mutex
 	vClient  *vnc.ClientConn
 	vncAddr  string
+	clock    clock.Clock
 }
 
-func NewVnc(vncAddr string) *vncDevice {
-	return &vncDevice{vncAddr: vncAddr}
+// Option configures a VNC device.
+type Option func(*vncDevice)
+
+// WithClock sets the clock ticking at the frame rate of the recorded properties,
+// and timing the 10 seconds after which a framebuffer update is requested again
+// when the server sent none. The default is the system clock.
+func WithClock(c clock.Clock) Option {
+	return func(d *vncDevice) {
+		d.clock = c
+	}
+}
+
+func NewVnc(vncAddr string, opts ...Option) *vncDevice {
+	d := &vncDevice{vncAddr: vncAddr, clock: clock.New()}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
 }
 func (d *vncDevice) PointerEvent(mask uint8, x, y uint16) {
 	if d.vClient != nil {
@@ -114,7 +132,7 @@ func (d *vncDevice) Open() error {
 				default:
 
 				}
-			case <-time.After(10 * time.Second):
+			case <-d.clock.After(10 * time.Second):
 				if d.vClient.FramebufferUpdateRequest(true, 0, 0, uint16(d.w), uint16(d.h)) != nil {
 					d.cancel()
 					return
@@ -145,7 +163,7 @@ func (d *vncDevice) VideoRecord(p prop.Media) (video.Reader, error) {
 		p.FrameRate = 30
 	}
 
-	tick := time.NewTicker(time.Duration(float32(time.Second) / p.FrameRate))
+	tick := d.clock.NewTicker(time.Duration(float32(time.Second) / p.FrameRate))
 	d.tick = tick
 	closed := d.closed
 	r := video.ReaderFunc(func() (image.Image, func(), error) {
@@ -156,7 +174,7 @@ func (d *vncDevice) VideoRecord(p prop.Media) (video.Reader, error) {
 		default:
 		}
 
-		<-tick.C
+		<-tick.C()
 		return &image.RGBA{
 			Pix:    d.rawPixel,
 			Stride: 4,
@@ -2,6 +2,7 @@ package driver
 
 import (
 	"github.com/google/uuid"
+	"github.com/pion/mediadevices/pkg/codec"
 	"github.com/pion/mediadevices/pkg/io/audio"
 	"github.com/pion/mediadevices/pkg/io/video"
 	"github.com/pion/mediadevices/pkg/prop"
@@ -25,6 +26,14 @@ func wrapAdapter(a Adapter, info Info) Driver {
 	case VideoRecorder:
 		// Only expose Driver and VideoRecorder interfaces
 		d.VideoRecorder = v
+		if s, ok := a.(EncodedSource); ok {
+			d.EncodedSource = s
+			return &struct {
+				Driver
+				VideoRecorder
+				EncodedSource
+			}{d, d, d}
+		}
 		r := &struct {
 			Driver
 			VideoRecorder
@@ -33,6 +42,14 @@ func wrapAdapter(a Adapter, info Info) Driver {
 	case AudioRecorder:
 		// Only expose Driver and AudioRecorder interfaces
 		d.AudioRecorder = v
+		if s, ok := a.(EncodedSource); ok {
+			d.EncodedSource = s
+			return &struct {
+				Driver
+				AudioRecorder
+				EncodedSource
+			}{d, d, d}
+		}
 		return &struct {
 			Driver
 			AudioRecorder
@@ -46,9 +63,12 @@ type adapterWrapper struct {
 	Adapter
 	VideoRecorder
 	AudioRecorder
+	EncodedSource
 	id    string
 	info  Info
 	state State
+	// raw is set while the raw stream of the driver is recording
+	raw bool
 }
 
 func (w *adapterWrapper) ID() string {
@@ -68,7 +88,10 @@ func (w *adapterWrapper) Open() error {
 }
 
 func (w *adapterWrapper) Close() error {
-	return w.state.Update(StateClosed, w.Adapter.Close)
+	return w.state.Update(StateClosed, func() error {
+		w.raw = false
+		return w.Adapter.Close()
+	})
 }
 
 func (w *adapterWrapper) Properties() []prop.Media {
@@ -83,24 +106,59 @@ func (w *adapterWrapper) Properties() []prop.Media {
 	return p
 }
 
+// record moves the driver to the running state while calling f, and closes the
+// driver if f fails. encoded tells if f records the encoded stream of an
+// EncodedSource.
+func (w *adapterWrapper) record(encoded bool, f func() error) error {
+	// A track reading an EncodedSource passes its encoded stream through, and
+	// only records its raw stream once it's read in another codec. The encoded
+	// stream may be recorded several times, but the raw stream only once.
+	if w.EncodedSource != nil && w.state == StateRunning && (encoded || !w.raw) {
+		err := f()
+		if err == nil && !encoded {
+			w.raw = true
+		}
+		// The streams already running aren't closed on failure
+		return err
+	}
+
+	err := w.state.Update(StateRunning, f)
+	if err != nil {
+		if w.EncodedSource == nil || w.state != StateRunning {
+			_ = w.Close()
+		}
+		return err
+	}
+	if !encoded {
+		w.raw = true
+	}
+	return nil
+}
+
 func (w *adapterWrapper) VideoRecord(p prop.Media) (r video.Reader, err error) {
-	err = w.state.Update(StateRunning, func() error {
+	err = w.record(false, func() error {
 		r, err = w.VideoRecorder.VideoRecord(p)
 		return err
 	})
-	if err != nil {
-		_ = w.Close()
-	}
 	return
 }
 
 func (w *adapterWrapper) AudioRecord(p prop.Media) (r audio.Reader, err error) {
-	err = w.state.Update(StateRunning, func() error {
+	err = w.record(false, func() error {
 		r, err = w.AudioRecorder.AudioRecord(p)
 		return err
 	})
-	if err != nil {
-		_ = w.Close()
-	}
+	return
+}
+
+func (w *adapterWrapper) EncodedCodec(p prop.Media) *codec.RTPCodec {
+	return w.EncodedSource.EncodedCodec(p)
+}
+
+func (w *adapterWrapper) EncodedRecord(p prop.Media) (r EncodedReader, err error) {
+	err = w.record(true, func() error {
+		r, err = w.EncodedSource.EncodedRecord(p)
+		return err
+	})
 	return
 }
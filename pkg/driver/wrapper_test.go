@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/pion/mediadevices/pkg/codec"
 	"github.com/pion/mediadevices/pkg/io/audio"
 	"github.com/pion/mediadevices/pkg/io/video"
 	"github.com/pion/mediadevices/pkg/prop"
@@ -136,3 +137,98 @@ func TestAudioWrapperWithBrokenRecorderState(t *testing.T) {
 		t.Errorf("expected the status to be %v, but got %v", StateClosed, d.Status())
 	}
 }
+
+type encodedVideoAdapterMock struct {
+	adapterMock
+	recordErr error
+}
+
+func (a *encodedVideoAdapterMock) VideoRecord(p prop.Media) (r video.Reader, err error) {
+	return nil, a.recordErr
+}
+func (a *encodedVideoAdapterMock) EncodedCodec(p prop.Media) *codec.RTPCodec { return nil }
+func (a *encodedVideoAdapterMock) EncodedRecord(p prop.Media) (r EncodedReader, err error) {
+	return nil, nil
+}
+
+func TestEncodedWrapperState(t *testing.T) {
+	var a encodedVideoAdapterMock
+	d := wrapAdapter(&a, Info{})
+
+	es, ok := d.(EncodedSource)
+	if !ok {
+		t.Fatal("expected the driver to expose EncodedSource")
+	}
+
+	err := d.Open()
+	if err != nil {
+		t.Errorf("expected to open successfully")
+	}
+
+	// Encoded streams can be recorded multiple times
+	for i := 0; i < 2; i++ {
+		_, err = es.EncodedRecord(prop.Media{})
+		if err != nil {
+			t.Errorf("expected to successfully start recording, but got %v", err)
+		}
+	}
+	if d.Status() != StateRunning {
+		t.Errorf("expected the status to be %v, but got %v", StateRunning, d.Status())
+	}
+
+	// The raw stream can be recorded alongside, but only once
+	vr := d.(VideoRecorder)
+	_, err = vr.VideoRecord(prop.Media{})
+	if err != nil {
+		t.Errorf("expected to successfully start recording, but got %v", err)
+	}
+	_, err = vr.VideoRecord(prop.Media{})
+	if err == nil {
+		t.Errorf("expected to get an invalid state")
+	}
+	if d.Status() != StateRunning {
+		t.Errorf("expected the status to be %v, but got %v", StateRunning, d.Status())
+	}
+}
+
+func TestEncodedWrapperWithBrokenRecorderState(t *testing.T) {
+	testCases := map[string]struct {
+		passthrough bool
+		expected    State
+	}{
+		"Idle": {
+			passthrough: false,
+			expected:    StateClosed,
+		},
+		"Passthrough": {
+			passthrough: true,
+			expected:    StateRunning,
+		},
+	}
+
+	for name, c := range testCases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			a := encodedVideoAdapterMock{recordErr: recordErr}
+			d := wrapAdapter(&a, Info{})
+
+			err := d.Open()
+			if err != nil {
+				t.Errorf("expected to open successfully")
+			}
+			if c.passthrough {
+				if _, err := d.(EncodedSource).EncodedRecord(prop.Media{}); err != nil {
+					t.Fatalf("expected to successfully start recording, but got %v", err)
+				}
+			}
+
+			_, err = d.(VideoRecorder).VideoRecord(prop.Media{})
+			if err != recordErr {
+				t.Errorf("expected to get %v, but got %v", recordErr, err)
+			}
+			if d.Status() != c.expected {
+				t.Errorf("expected the status to be %v, but got %v", c.expected, d.Status())
+			}
+		})
+	}
+}
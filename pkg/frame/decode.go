@@ -11,6 +11,8 @@ const (
 	FormatI420 Format = "I420"
 	// FormatI444 is a YUV format without sub-sampling
 	FormatI444 Format = "I444"
+	// FormatI422 is a planar YUV format with horizontal chroma sub-sampling
+	FormatI422 Format = "I422"
 	// FormatNV21 https://www.fourcc.org/pixel-format/yuv-nv21/
 	FormatNV21 = "NV21"
 	// FormatNV12 https://www.fourcc.org/pixel-format/yuv-nv12/
@@ -26,6 +28,10 @@ const (
 	// FormatMJPEG https://www.fourcc.org/mjpg/
 	FormatMJPEG = "MJPEG"
 
+	// FormatH264 is an H.264 Annex-B byte stream. It can't be decoded, drivers
+	// can only pass it through in its native codec.
+	FormatH264 = "H264"
+
 	// FormatZ16 https://www.kernel.org/doc/html/v5.9/userspace-api/media/v4l/pixfmt-z16.html
 	FormatZ16 = "Z16"
 )
@@ -51,3 +57,9 @@ func NewDecoder(f Format) (Decoder, error) {
 
 	return decoder, nil
 }
+
+// IsEncodedOnly reports whether f is a compressed format which can't be
+// decoded to images.
+func IsEncodedOnly(f Format) bool {
+	return f == FormatH264
+}
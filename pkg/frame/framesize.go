@@ -1,14 +1,14 @@
 package frame
 
-// Return a function to get the number of bytes a frame will occupy in the given format
+// Return a function to get the number of bytes a frame will occupy in the given format.
+// Compressed formats, like MJPEG, don't have a fixed frame size and aren't listed.
 var FrameSizeMap = map[Format]frameSizeFunc{
-	FormatI420:  frameSizeI420,
-	FormatNV21:  frameSizeNV21,
-	FormatNV12:  frameSizeNV21, // NV12 and NV21 have the same frame size
-	FormatYUY2:  frameSizeYUY2,
-	FormatUYVY:  frameSizeYUY2, // UYVY and YUY2 have the same frame size
-	FormatMJPEG: frameSizeMJPEG,
-	FormatZ16:   frameSizeZ16,
+	FormatI420: frameSizeI420,
+	FormatNV21: frameSizeNV21,
+	FormatNV12: frameSizeNV21, // NV12 and NV21 have the same frame size
+	FormatYUY2: frameSizeYUY2,
+	FormatUYVY: frameSizeYUY2, // UYVY and YUY2 have the same frame size
+	FormatZ16:  frameSizeZ16,
 }
 
 type frameSizeFunc func(width, height int) uint
@@ -38,8 +38,3 @@ func frameSizeZ16(width, height int) uint {
 	expectedSize := 2 * (width * height)
 	return uint(expectedSize)
 }
-
-func frameSizeMJPEG(width, height int) uint {
-	// MJPEG is a compressed format, so we don't know the size
-	panic("Not possible to get frame size with MJPEG format. Since it is a compressed format, so we don't know the size")
-}
@@ -0,0 +1,263 @@
+package audio
+
+import (
+	"math"
+	"time"
+
+	"github.com/pion/mediadevices/pkg/wave"
+)
+
+type dynamicsOptions struct {
+	attack  time.Duration
+	release time.Duration
+	makeup  float64
+	maxGain float64
+}
+
+// DynamicsOption configures NoiseGate, Compressor, Limiter and AutoGain.
+type DynamicsOption func(*dynamicsOptions)
+
+// WithAttack sets how fast the gain reacts to a louder signal.
+func WithAttack(d time.Duration) DynamicsOption {
+	return func(o *dynamicsOptions) {
+		o.attack = d
+	}
+}
+
+// WithRelease sets how fast the gain reacts to a quieter signal.
+func WithRelease(d time.Duration) DynamicsOption {
+	return func(o *dynamicsOptions) {
+		o.release = d
+	}
+}
+
+// WithMakeupGain sets the gain in dB applied by Compressor after the compression, 0 by
+// default.
+func WithMakeupGain(db float64) DynamicsOption {
+	return func(o *dynamicsOptions) {
+		o.makeup = db
+	}
+}
+
+// WithMaxGain sets the highest gain in dB applied by AutoGain, 30 by default. The gain
+// applied to quiet signals is limited to the same amount of attenuation.
+func WithMaxGain(db float64) DynamicsOption {
+	return func(o *dynamicsOptions) {
+		o.maxGain = db
+	}
+}
+
+func newDynamicsOptions(attack, release time.Duration, opts []DynamicsOption) dynamicsOptions {
+	options := dynamicsOptions{
+		attack:  attack,
+		release: release,
+		maxGain: 30,
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return options
+}
+
+// smoothing returns the coefficient of a one-pole filter of time constant d, at rate.
+func smoothing(d time.Duration, rate int) float64 {
+	if d <= 0 || rate <= 0 {
+		return 0
+	}
+	return math.Exp(-1 / (d.Seconds() * float64(rate)))
+}
+
+// decibelsToGain converts db to a linear gain
+func decibelsToGain(db float64) float64 {
+	return math.Pow(10, db/20)
+}
+
+// processFloat32 returns a reader of the chunks of r processed by process, which is called
+// with their interleaved samples as float32 values in [-1, 1]. The chunks keep their type,
+// the chunks of r aren't modified.
+func processFloat32(r Reader, process func(samples []float32, info wave.ChunkInfo)) Reader {
+	var samples []float32
+	return ReaderFunc(func() (wave.Audio, func(), error) {
+		chunk, _, err := r.Read()
+		if err != nil {
+			return nil, func() {}, err
+		}
+		info := chunk.ChunkInfo()
+		if info.Channels <= 0 {
+			return chunk, func() {}, nil
+		}
+
+		samples = appendFloat32(samples[:0], chunk)
+		process(samples, info)
+		out := newChunkLike(chunk, info)
+		setFloat32(out, samples)
+		return out, func() {}, nil
+	})
+}
+
+// frames calls process with each frame of interleaved samples, and the peak of the frame.
+func frames(samples []float32, channels int, process func(frame []float32, peak float64)) {
+	for i := 0; i+channels <= len(samples); i += channels {
+		frame := samples[i : i+channels]
+		var peak float32
+		for _, v := range frame {
+			if v < 0 {
+				v = -v
+			}
+			if v > peak {
+				peak = v
+			}
+		}
+		process(frame, float64(peak))
+	}
+}
+
+// Gain returns a transform amplifying the chunks by db decibels. The integer samples out of
+// range are clipped.
+func Gain(db float64) TransformFunc {
+	gain := float32(decibelsToGain(db))
+	return func(r Reader) Reader {
+		return processFloat32(r, func(samples []float32, _ wave.ChunkInfo) {
+			for i := range samples {
+				samples[i] *= gain
+			}
+		})
+	}
+}
+
+// noiseGateHold is how long the noise gate stays open once the signal is below the threshold
+const noiseGateHold = 50 * time.Millisecond
+
+// NoiseGate returns a transform muting the chunks while their level is below threshold, in
+// dBFS, e.g. to remove the background noise between sentences. The gate opens in 1ms and
+// closes in 100ms by default, see WithAttack and WithRelease, after being held open for 50ms.
+func NoiseGate(threshold float64, opts ...DynamicsOption) TransformFunc {
+	options := newDynamicsOptions(time.Millisecond, 100*time.Millisecond, opts)
+	limit := decibelsToGain(threshold)
+	return func(r Reader) Reader {
+		var env, gain float64
+		// held counts the frames below the threshold, the gate starts closed
+		held := math.MaxInt32
+		return processFloat32(r, func(samples []float32, info wave.ChunkInfo) {
+			attack := smoothing(options.attack, info.SamplingRate)
+			release := smoothing(options.release, info.SamplingRate)
+			// the envelope detects the peaks, and decays over 10ms
+			decay := smoothing(10*time.Millisecond, info.SamplingRate)
+			hold := int(noiseGateHold.Seconds() * float64(info.SamplingRate))
+
+			frames(samples, info.Channels, func(frame []float32, peak float64) {
+				env = math.Max(peak, env*decay)
+				target := 0.0
+				if env >= limit {
+					target, held = 1, 0
+				} else if held < hold {
+					target = 1
+					held++
+				}
+
+				if target > gain {
+					gain = target + (gain-target)*attack
+				} else {
+					gain = target + (gain-target)*release
+				}
+				for ch := range frame {
+					frame[ch] *= float32(gain)
+				}
+			})
+		})
+	}
+}
+
+// Compressor returns a transform reducing the dynamic range of the chunks: the level above
+// threshold, in dBFS, is divided by ratio. The gain reacts to the louder peaks in 5ms and to
+// the quieter ones in 100ms by default, see WithAttack and WithRelease. The channels are
+// compressed together, to keep their balance.
+func Compressor(threshold, ratio float64, opts ...DynamicsOption) TransformFunc {
+	options := newDynamicsOptions(5*time.Millisecond, 100*time.Millisecond, opts)
+	return compressor(threshold, ratio, options)
+}
+
+// Limiter returns a transform keeping the peaks of the chunks under threshold, in dBFS, by
+// compressing them with an infinite ratio. It reacts immediately to the peaks by default,
+// and releases the gain in 50ms.
+func Limiter(threshold float64, opts ...DynamicsOption) TransformFunc {
+	options := newDynamicsOptions(0, 50*time.Millisecond, opts)
+	return compressor(threshold, math.Inf(1), options)
+}
+
+func compressor(threshold, ratio float64, options dynamicsOptions) TransformFunc {
+	if ratio < 1 {
+		panic("The compression ratio must be at least 1!")
+	}
+	slope := 1 - 1/ratio
+	return func(r Reader) Reader {
+		var env float64
+		return processFloat32(r, func(samples []float32, info wave.ChunkInfo) {
+			attack := smoothing(options.attack, info.SamplingRate)
+			release := smoothing(options.release, info.SamplingRate)
+
+			frames(samples, info.Channels, func(frame []float32, peak float64) {
+				if peak > env {
+					env = peak + (env-peak)*attack
+				} else {
+					env = peak + (env-peak)*release
+				}
+
+				reduction := 0.0
+				if level := toDecibels(env); level > threshold {
+					reduction = (level - threshold) * slope
+				}
+				gain := float32(decibelsToGain(options.makeup - reduction))
+				for ch := range frame {
+					frame[ch] *= gain
+				}
+			})
+		})
+	}
+}
+
+const (
+	// autoGainWindow is the time constant of the level measured by AutoGain
+	autoGainWindow = 300 * time.Millisecond
+	// autoGainSilence is the level under which AutoGain keeps its gain, in dBFS, not to
+	// amplify the background noise
+	autoGainSilence = -60.0
+)
+
+// AutoGain returns a transform adjusting the gain of the chunks so that their RMS level
+// reaches target, in dBFS, within WithMaxGain. The gain is reduced in 100ms and raised in 2s
+// by default, see WithAttack and WithRelease. It's kept while the level is below -60 dBFS,
+// and the amplified peaks are clipped smoothly.
+func AutoGain(target float64, opts ...DynamicsOption) TransformFunc {
+	options := newDynamicsOptions(100*time.Millisecond, 2*time.Second, opts)
+	return func(r Reader) Reader {
+		// power is the mean square of the input, gain is in dB
+		var power, gain float64
+		return processFloat32(r, func(samples []float32, info wave.ChunkInfo) {
+			window := smoothing(autoGainWindow, info.SamplingRate)
+			attack := smoothing(options.attack, info.SamplingRate)
+			release := smoothing(options.release, info.SamplingRate)
+
+			frames(samples, info.Channels, func(frame []float32, _ float64) {
+				var sum float64
+				for _, v := range frame {
+					sum += float64(v) * float64(v)
+				}
+				power = sum/float64(len(frame)) + (power-sum/float64(len(frame)))*window
+
+				if level := 10 * math.Log10(power+1e-20); level > autoGainSilence {
+					desired := math.Max(-options.maxGain, math.Min(options.maxGain, target-level))
+					if desired < gain {
+						gain = desired + (gain-desired)*attack
+					} else {
+						gain = desired + (gain-desired)*release
+					}
+				}
+				g := float32(decibelsToGain(gain))
+				for ch := range frame {
+					frame[ch] = softClip(frame[ch] * g)
+				}
+			})
+		})
+	}
+}
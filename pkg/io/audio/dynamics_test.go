@@ -0,0 +1,251 @@
+package audio
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/pion/mediadevices/pkg/wave"
+)
+
+// tones returns a reader of count chunks of a 1 kHz tone, the amplitude of the chunk i being
+// amp(i).
+func tones(count int, amp func(i int) float64) Reader {
+	chunks := make([]wave.Audio, count)
+	for i := range chunks {
+		chunks[i] = toneChunk(1000, amp(i), i*480)
+	}
+	return sliceReader(chunks)
+}
+
+// readLevels reads n chunks of r, and returns their levels.
+func readLevels(t *testing.T, r Reader, n int) []Level {
+	t.Helper()
+	m := NewLevelMeter()
+	levels := make([]Level, n)
+	for i := range levels {
+		chunk, _, err := r.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		levels[i] = m.Measure(chunk)
+	}
+	return levels
+}
+
+func TestGain(t *testing.T) {
+	testCases := map[string]struct {
+		chunk    wave.Audio
+		db       float64
+		expected float32
+	}{
+		"Double": {
+			chunk:    constantInt16(0x1000, 480, 2, 48000),
+			db:       6.0206,
+			expected: 0x2000 / 32768.0,
+		},
+		"Half": {
+			chunk:    constantFloat32(0.5, 480, 2, 48000),
+			db:       -6.0206,
+			expected: 0.25,
+		},
+		"Clip": {
+			chunk:    constantInt16(0x4000, 480, 1, 48000),
+			db:       12,
+			expected: 1,
+		},
+	}
+	for name, c := range testCases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			input := appendFloat32(nil, c.chunk)
+			r := Gain(c.db)(sliceReader([]wave.Audio{c.chunk}))
+			chunk, _, err := r.Read()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if chunkType(chunk) != chunkType(c.chunk) {
+				t.Errorf("Expected %s, got %s", chunkType(c.chunk), chunkType(chunk))
+			}
+			for i, v := range appendFloat32(nil, chunk) {
+				if math.Abs(float64(v-c.expected)) > 1e-3 {
+					t.Fatalf("Expected %f, got %f at %d", c.expected, v, i)
+				}
+			}
+			for i, v := range appendFloat32(nil, c.chunk) {
+				if v != input[i] {
+					t.Fatal("Expected the input chunk not to be modified")
+				}
+			}
+		})
+	}
+}
+
+func TestNoiseGate(t *testing.T) {
+	// -46 dBFS of noise, a -20 dBFS tone, and the noise again
+	r := NoiseGate(-40)(tones(130, func(i int) float64 {
+		if i >= 10 && i < 30 {
+			return 0.1
+		}
+		return 0.005
+	}))
+	levels := readLevels(t, r, 130)
+
+	for i, level := range levels[:10] {
+		if level.Peak != MinLevel {
+			t.Fatalf("Expected the gate to start closed, got %f dBFS in the chunk %d", level.Peak, i)
+		}
+	}
+	// the gate opens in 1ms
+	for i, level := range levels[11:30] {
+		if math.Abs(level.Peak+20) > 0.1 {
+			t.Fatalf("Expected the tone to pass at -20 dBFS, got %f in the chunk %d", level.Peak, 11+i)
+		}
+	}
+	// the gate is held open for 50ms, and closes in 100ms
+	if math.Abs(levels[33].Peak+46.02) > 0.1 {
+		t.Errorf("Expected the noise to pass while the gate is held, got %f dBFS", levels[33].Peak)
+	}
+	if levels[129].RMS > -100 {
+		t.Errorf("Expected the noise to be muted, got %f dBFS", levels[129].RMS)
+	}
+}
+
+func TestCompressor(t *testing.T) {
+	testCases := map[string]struct {
+		transform TransformFunc
+		amp       float64
+		peak      float64
+	}{
+		"AboveThreshold": {
+			transform: Compressor(-20, 4),
+			amp:       1,
+			peak:      -15,
+		},
+		"BelowThreshold": {
+			transform: Compressor(-20, 4),
+			amp:       0.05,
+			peak:      -26.02,
+		},
+		"MakeupGain": {
+			transform: Compressor(-20, 4, WithMakeupGain(6)),
+			amp:       0.05,
+			peak:      -20.02,
+		},
+		"NoCompression": {
+			transform: Compressor(-20, 1),
+			amp:       1,
+			peak:      0,
+		},
+	}
+	for name, c := range testCases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			r := c.transform(tones(50, func(int) float64 { return c.amp }))
+			levels := readLevels(t, r, 50)
+			// the envelope of the sine is a bit lower than its peaks, between them
+			if peak := levels[49].Peak; math.Abs(peak-c.peak) > 0.6 {
+				t.Errorf("Expected a peak of %f dBFS, got %f", c.peak, peak)
+			}
+		})
+	}
+
+	t.Run("Release", func(t *testing.T) {
+		// the gain is restored slowly after a loud tone
+		r := Compressor(-20, 4, WithRelease(50*time.Millisecond))(tones(60, func(i int) float64 {
+			if i < 20 {
+				return 1
+			}
+			return 0.05
+		}))
+		levels := readLevels(t, r, 60)
+		if levels[20].Peak > -30 {
+			t.Errorf("Expected the quiet tone to be compressed after the loud one, got %f dBFS", levels[20].Peak)
+		}
+		if math.Abs(levels[59].Peak+26.02) > 0.1 {
+			t.Errorf("Expected the gain to be restored, got %f dBFS", levels[59].Peak)
+		}
+	})
+
+	t.Run("InvalidRatio", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Error("Expected a panic")
+			}
+		}()
+		Compressor(-20, 0.5)
+	})
+}
+
+func TestLimiter(t *testing.T) {
+	r := Limiter(-6)(tones(50, func(i int) float64 {
+		if i%10 < 5 {
+			return 1
+		}
+		return 0.25
+	}))
+	for i, level := range readLevels(t, r, 50) {
+		if level.Peak > -6+0.01 {
+			t.Fatalf("Expected the peaks to be limited to -6 dBFS, got %f in the chunk %d", level.Peak, i)
+		}
+	}
+}
+
+func TestAutoGain(t *testing.T) {
+	testCases := map[string]struct {
+		transform TransformFunc
+		amp       float64
+		rms       float64
+	}{
+		"Quiet": {
+			transform: AutoGain(-20),
+			amp:       0.01 * math.Sqrt2,
+			rms:       -20,
+		},
+		"Loud": {
+			transform: AutoGain(-20),
+			amp:       1,
+			rms:       -20,
+		},
+		"MaxGain": {
+			transform: AutoGain(-20, WithMaxGain(10)),
+			amp:       0.01 * math.Sqrt2,
+			rms:       -30,
+		},
+		"Silence": {
+			transform: AutoGain(-20),
+			amp:       0.0001 * math.Sqrt2,
+			rms:       -80,
+		},
+	}
+	for name, c := range testCases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			// 10s of the tone
+			r := c.transform(tones(1000, func(int) float64 { return c.amp }))
+			levels := readLevels(t, r, 1000)
+			if rms := levels[999].RMS; math.Abs(rms-c.rms) > 0.2 {
+				t.Errorf("Expected %f dBFS, got %f", c.rms, rms)
+			}
+		})
+	}
+
+	t.Run("Attack", func(t *testing.T) {
+		// the gain is reduced faster than it's raised
+		r := AutoGain(-20)(tones(1100, func(i int) float64 {
+			if i < 1000 {
+				return 0.01 * math.Sqrt2
+			}
+			return 0.1 * math.Sqrt2
+		}))
+		levels := readLevels(t, r, 1100)
+		if rms := levels[1099].RMS; math.Abs(rms+20) > 0.5 {
+			t.Errorf("Expected the gain to be reduced to -20 dBFS in 1s, got %f", rms)
+		}
+		for i, level := range levels[1000:] {
+			if level.Peak > 0 || level.Peak < -20 {
+				t.Fatalf("Expected the peaks to be clipped, got %f dBFS in the chunk %d", level.Peak, 1000+i)
+			}
+		}
+	})
+}
@@ -0,0 +1,195 @@
+package audio
+
+import (
+	"github.com/pion/mediadevices/pkg/prop"
+	"github.com/pion/mediadevices/pkg/wave"
+)
+
+// appendFloat32 appends the samples of chunk to dst, interleaved, as float32 values in
+// [-1, 1].
+func appendFloat32(dst []float32, chunk wave.Audio) []float32 {
+	info := chunk.ChunkInfo()
+	switch c := chunk.(type) {
+	case *wave.Float32Interleaved:
+		return append(dst, c.Data[:info.Len*info.Channels]...)
+	case *wave.Int16Interleaved:
+		for _, s := range c.Data[:info.Len*info.Channels] {
+			dst = append(dst, float32(s)/0x8000)
+		}
+		return dst
+	}
+
+	for i := 0; i < info.Len; i++ {
+		for ch := 0; ch < info.Channels; ch++ {
+			switch c := chunk.(type) {
+			case *wave.Float32NonInterleaved:
+				dst = append(dst, c.Data[ch][i])
+			case *wave.Int16NonInterleaved:
+				dst = append(dst, float32(c.Data[ch][i])/0x8000)
+			default:
+				// the integer samples range within [-0x80000000, 0x7fffffff]
+				dst = append(dst, float32(chunk.At(i, ch).Int())/0x80000000)
+			}
+		}
+	}
+	return dst
+}
+
+// clampInt16 converts v in [-1, 1] to an int16 sample, clamping the values out of range.
+func clampInt16(v float32) int16 {
+	switch {
+	case v >= 1:
+		return 0x7FFF
+	case v <= -1:
+		return -0x8000
+	default:
+		return int16(v * 0x8000)
+	}
+}
+
+// clampInt32 converts v in [-1, 1] to a 32-bits sample, clamping the values out of range.
+func clampInt32(v float32) int32 {
+	switch {
+	case v >= 1:
+		return 0x7FFFFFFF
+	case v <= -1:
+		return -0x80000000
+	default:
+		return int32(float64(v) * 0x80000000)
+	}
+}
+
+// setFloat32 sets the samples of dst from the interleaved samples src.
+func setFloat32(dst wave.EditableAudio, src []float32) {
+	info := dst.ChunkInfo()
+	switch c := dst.(type) {
+	case *wave.Float32Interleaved:
+		copy(c.Data, src)
+		return
+	case *wave.Int16Interleaved:
+		for i, v := range src[:info.Len*info.Channels] {
+			c.Data[i] = clampInt16(v)
+		}
+		return
+	}
+
+	for i := 0; i < info.Len; i++ {
+		for ch := 0; ch < info.Channels; ch++ {
+			v := src[i*info.Channels+ch]
+			switch c := dst.(type) {
+			case *wave.Float32NonInterleaved:
+				c.Data[ch][i] = v
+			case *wave.Int16NonInterleaved:
+				c.Data[ch][i] = clampInt16(v)
+			default:
+				dst.Set(i, ch, wave.Int64Sample(clampInt32(v)))
+			}
+		}
+	}
+}
+
+// newChunk allocates a chunk in the sample format described by p: Uint8, Int16, Int24 or
+// Int32 when p.SampleSize is 1, 2, 3 or 4 and p.IsFloat is false, Float32 otherwise.
+func newChunk(p prop.Audio, info wave.ChunkInfo) wave.EditableAudio {
+	chunk := newChunkFormat(&wave.RawFormat{
+		SampleSize:  p.SampleSize,
+		IsFloat:     p.IsFloat,
+		Interleaved: p.IsInterleaved,
+	}, info)
+	if chunk == nil {
+		chunk = newChunkFormat(&wave.RawFormat{SampleSize: 4, IsFloat: true, Interleaved: p.IsInterleaved}, info)
+	}
+	return chunk
+}
+
+// newChunkFormat allocates a chunk in format f, or returns nil if f isn't supported.
+func newChunkFormat(f *wave.RawFormat, info wave.ChunkInfo) wave.EditableAudio {
+	if f.IsFloat {
+		if f.SampleSize != 4 {
+			return nil
+		}
+		if f.Interleaved {
+			return wave.NewFloat32Interleaved(info)
+		}
+		return wave.NewFloat32NonInterleaved(info)
+	}
+
+	switch f.SampleSize {
+	case 1:
+		if f.Interleaved {
+			return wave.NewUint8Interleaved(info)
+		}
+		return wave.NewUint8NonInterleaved(info)
+	case 2:
+		if f.Interleaved {
+			return wave.NewInt16Interleaved(info)
+		}
+		return wave.NewInt16NonInterleaved(info)
+	case 3:
+		if f.Interleaved {
+			return wave.NewInt24Interleaved(info)
+		}
+		return wave.NewInt24NonInterleaved(info)
+	case 4:
+		if f.Interleaved {
+			return wave.NewInt32Interleaved(info)
+		}
+		return wave.NewInt32NonInterleaved(info)
+	default:
+		return nil
+	}
+}
+
+// formatOf returns the format of chunk, or nil if it's of an unknown type.
+func formatOf(chunk wave.Audio) *wave.RawFormat {
+	switch chunk.(type) {
+	case *wave.Float32Interleaved:
+		return &wave.RawFormat{SampleSize: 4, IsFloat: true, Interleaved: true}
+	case *wave.Float32NonInterleaved:
+		return &wave.RawFormat{SampleSize: 4, IsFloat: true}
+	case *wave.Uint8Interleaved:
+		return &wave.RawFormat{SampleSize: 1, Interleaved: true}
+	case *wave.Uint8NonInterleaved:
+		return &wave.RawFormat{SampleSize: 1}
+	case *wave.Int16Interleaved:
+		return &wave.RawFormat{SampleSize: 2, Interleaved: true}
+	case *wave.Int16NonInterleaved:
+		return &wave.RawFormat{SampleSize: 2}
+	case *wave.Int24Interleaved:
+		return &wave.RawFormat{SampleSize: 3, Interleaved: true}
+	case *wave.Int24NonInterleaved:
+		return &wave.RawFormat{SampleSize: 3}
+	case *wave.Int32Interleaved:
+		return &wave.RawFormat{SampleSize: 4, Interleaved: true}
+	case *wave.Int32NonInterleaved:
+		return &wave.RawFormat{SampleSize: 4}
+	default:
+		return nil
+	}
+}
+
+// remapChannels converts the interleaved samples src of srcChannels channels to dstChannels
+// channels, appended to dst. Mono is copied to all the channels, and mixed down from all the
+// channels. Otherwise, the channels are copied in order, repeating the first ones if there
+// are fewer source channels.
+func remapChannels(dst, src []float32, srcChannels, dstChannels int) []float32 {
+	if srcChannels == dstChannels {
+		return append(dst, src...)
+	}
+	n := len(src) / srcChannels
+	for i := 0; i < n; i++ {
+		frame := src[i*srcChannels : (i+1)*srcChannels]
+		if dstChannels == 1 {
+			var sum float32
+			for _, v := range frame {
+				sum += v
+			}
+			dst = append(dst, sum/float32(srcChannels))
+			continue
+		}
+		for ch := 0; ch < dstChannels; ch++ {
+			dst = append(dst, frame[ch%srcChannels])
+		}
+	}
+	return dst
+}
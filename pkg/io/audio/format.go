@@ -0,0 +1,41 @@
+package audio
+
+import (
+	"errors"
+
+	"github.com/pion/mediadevices/pkg/wave"
+)
+
+var errUnsupportedFormat = errors.New("unsupported audio format")
+
+// ToFormat returns a transform converting the chunks to the sample type and layout of f,
+// e.g. &wave.RawFormat{SampleSize: 2, Interleaved: true} for interleaved Int16 samples. The
+// integer samples of 1 byte are unsigned, the samples of 3 bytes are 24-bits integers. The
+// chunks already in format f are passed through. The reader fails with an error if f isn't
+// supported.
+func ToFormat(f *wave.RawFormat) TransformFunc {
+	format := *f
+	return func(r Reader) Reader {
+		if newChunkFormat(&format, wave.ChunkInfo{}) == nil {
+			return ReaderFunc(func() (wave.Audio, func(), error) {
+				return nil, func() {}, errUnsupportedFormat
+			})
+		}
+
+		var samples []float32
+		return ReaderFunc(func() (wave.Audio, func(), error) {
+			chunk, _, err := r.Read()
+			if err != nil {
+				return nil, func() {}, err
+			}
+			if cf := formatOf(chunk); cf != nil && *cf == format {
+				return chunk, func() {}, nil
+			}
+
+			samples = appendFloat32(samples[:0], chunk)
+			out := newChunkFormat(&format, chunk.ChunkInfo())
+			setFloat32(out, samples)
+			return out, func() {}, nil
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package audio
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/pion/mediadevices/pkg/wave"
+)
+
+func TestToFormat(t *testing.T) {
+	info := wave.ChunkInfo{Len: 3, Channels: 1, SamplingRate: 48000}
+	input := &wave.Int16Interleaved{
+		Data: []int16{0x4000, -0x4000, 0},
+		Size: info,
+	}
+	testCases := map[string]struct {
+		format   *wave.RawFormat
+		expected wave.Audio
+	}{
+		"Uint8NonInterleaved": {
+			format:   &wave.RawFormat{SampleSize: 1},
+			expected: &wave.Uint8NonInterleaved{Data: [][]uint8{{0xc0, 0x40, 0x80}}, Size: info},
+		},
+		"Int16Interleaved": {
+			format:   &wave.RawFormat{SampleSize: 2, Interleaved: true},
+			expected: input,
+		},
+		"Int24Interleaved": {
+			format:   &wave.RawFormat{SampleSize: 3, Interleaved: true},
+			expected: &wave.Int24Interleaved{Data: []int32{0x400000, -0x400000, 0}, Size: info},
+		},
+		"Int32NonInterleaved": {
+			format:   &wave.RawFormat{SampleSize: 4},
+			expected: &wave.Int32NonInterleaved{Data: [][]int32{{0x40000000, -0x40000000, 0}}, Size: info},
+		},
+		"Float32Interleaved": {
+			format:   &wave.RawFormat{SampleSize: 4, IsFloat: true, Interleaved: true},
+			expected: &wave.Float32Interleaved{Data: []float32{0.5, -0.5, 0}, Size: info},
+		},
+	}
+	for name, c := range testCases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			r := ToFormat(c.format)(ReaderFunc(func() (wave.Audio, func(), error) {
+				return input, func() {}, nil
+			}))
+			out, _, err := r.Read()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(c.expected, out) {
+				t.Errorf("Expected %v, got %v", c.expected, out)
+			}
+
+			// the conversion back is lossless
+			back, _, err := ToFormat(&wave.RawFormat{SampleSize: 2, Interleaved: true})(ReaderFunc(func() (wave.Audio, func(), error) {
+				return out, func() {}, nil
+			})).Read()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(input, back) {
+				t.Errorf("Expected %v, got %v", input, back)
+			}
+		})
+	}
+}
+
+func TestToFormatUnsupported(t *testing.T) {
+	r := ToFormat(&wave.RawFormat{SampleSize: 8, IsFloat: true})(ReaderFunc(func() (wave.Audio, func(), error) {
+		return wave.NewInt16Interleaved(wave.ChunkInfo{Len: 1, Channels: 1}), func() {}, nil
+	}))
+	if _, _, err := r.Read(); err != errUnsupportedFormat {
+		t.Errorf("Expected %v, got %v", errUnsupportedFormat, err)
+	}
+}
@@ -0,0 +1,220 @@
+package audio
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/pion/mediadevices/pkg/wave"
+)
+
+// MinLevel is the level of silence, in dBFS. It's the lowest level of the RTP audio level
+// header extension (RFC 6464).
+const MinLevel = -127.0
+
+const (
+	// minVoiceLevel is the lowest RMS level of voice, in dBFS
+	minVoiceLevel = -55.0
+	// maxVoiceCrossings is the highest rate of zero crossings of voice, per second per
+	// channel. Noise, e.g. hiss, crosses zero much more often.
+	maxVoiceCrossings = 5000
+	// voiceOnset is the duration of voice starting a speech, to ignore clicks
+	voiceOnset = 30 * time.Millisecond
+	// noiseRise is how fast the noise floor rises, in dB per second. It drops immediately
+	// to the quieter chunks.
+	noiseRise = 3.0
+)
+
+// Level is the level of an audio chunk.
+type Level struct {
+	// RMS is the root mean square of the samples, in dBFS.
+	RMS float64
+	// Peak is the highest absolute sample, in dBFS.
+	Peak float64
+	// Smoothed follows the rises of RMS immediately, and decays slowly, e.g. for meters.
+	Smoothed float64
+	// Voice reports whether someone is speaking, e.g. to only send the audio of the
+	// speakers. The audio tracks pass it to the encoders implementing
+	// codec.VoiceActivityController, e.g. Opus with DTX, to stop transmitting.
+	Voice bool
+}
+
+type levelOptions struct {
+	decay     time.Duration
+	threshold float64
+	hangover  time.Duration
+}
+
+// LevelOption configures a LevelMeter.
+type LevelOption func(*levelOptions)
+
+// WithLevelDecay sets the time constant of the decay of Level.Smoothed, 300ms by default.
+func WithLevelDecay(d time.Duration) LevelOption {
+	return func(o *levelOptions) {
+		o.decay = d
+	}
+}
+
+// WithVoiceThreshold sets how much louder than the noise floor voice is, in dB, 9 by default.
+func WithVoiceThreshold(db float64) LevelOption {
+	return func(o *levelOptions) {
+		o.threshold = db
+	}
+}
+
+// WithVoiceHangover sets how long the speech continues after the last voice, so that the
+// pauses between words don't stop it, 300ms by default.
+func WithVoiceHangover(d time.Duration) LevelOption {
+	return func(o *levelOptions) {
+		o.hangover = d
+	}
+}
+
+// LevelMeter measures the level of audio chunks, and detects voice. The voice is detected
+// in the chunks which are louder than the noise floor by a threshold, and don't cross zero
+// too often like noise.
+type LevelMeter struct {
+	options levelOptions
+
+	mu    sync.Mutex
+	level Level
+	// smoothed is the amplitude of Level.Smoothed
+	smoothed float64
+	// noise is the level of the noise floor, in dBFS
+	noise            float64
+	measured         bool
+	voiced, unvoiced time.Duration
+	onVoiceActivity  func(speaking bool)
+	samples          []float32
+}
+
+// NewLevelMeter returns a LevelMeter, at MinLevel until it measures a chunk.
+func NewLevelMeter(opts ...LevelOption) *LevelMeter {
+	options := levelOptions{
+		decay:     300 * time.Millisecond,
+		threshold: 9,
+		hangover:  300 * time.Millisecond,
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return &LevelMeter{
+		options: options,
+		level:   Level{RMS: MinLevel, Peak: MinLevel, Smoothed: MinLevel},
+	}
+}
+
+// Transform returns a transform measuring the chunks read through it, unchanged.
+func (m *LevelMeter) Transform() TransformFunc {
+	return func(r Reader) Reader {
+		return ReaderFunc(func() (wave.Audio, func(), error) {
+			chunk, release, err := r.Read()
+			if err != nil {
+				return chunk, release, err
+			}
+			m.Measure(chunk)
+			return chunk, release, nil
+		})
+	}
+}
+
+// Level returns the level of the last chunk.
+func (m *LevelMeter) Level() Level {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.level
+}
+
+// OnVoiceActivity sets the handler called with true when a speech starts, and false when it
+// stops. It's called by the reader of the chunks, and should return quickly.
+func (m *LevelMeter) OnVoiceActivity(handler func(speaking bool)) {
+	m.mu.Lock()
+	m.onVoiceActivity = handler
+	m.mu.Unlock()
+}
+
+// Measure measures chunk, and returns its level.
+func (m *LevelMeter) Measure(chunk wave.Audio) Level {
+	m.mu.Lock()
+	info := chunk.ChunkInfo()
+	m.samples = appendFloat32(m.samples[:0], chunk)
+
+	var sum float64
+	var peak float32
+	var crossings int
+	for i, v := range m.samples {
+		sum += float64(v) * float64(v)
+		if v < 0 {
+			v = -v
+		}
+		if v > peak {
+			peak = v
+		}
+		if prev := i - info.Channels; prev >= 0 && (m.samples[prev] < 0) != (m.samples[i] < 0) {
+			crossings++
+		}
+	}
+
+	var duration time.Duration
+	if info.SamplingRate > 0 {
+		duration = time.Duration(info.Len) * time.Second / time.Duration(info.SamplingRate)
+	}
+	var rms float64
+	if len(m.samples) > 0 {
+		rms = math.Sqrt(sum / float64(len(m.samples)))
+	}
+	level := Level{RMS: toDecibels(rms), Peak: toDecibels(float64(peak)), Voice: m.level.Voice}
+
+	// the smoothed amplitude decays exponentially towards the current one
+	if m.smoothed > rms && m.options.decay > 0 {
+		k := math.Exp(-float64(duration) / float64(m.options.decay))
+		m.smoothed = rms + (m.smoothed-rms)*k
+	} else {
+		m.smoothed = rms
+	}
+	level.Smoothed = toDecibels(m.smoothed)
+
+	if !m.measured || level.RMS < m.noise {
+		m.noise = level.RMS
+	} else {
+		m.noise += noiseRise * duration.Seconds()
+	}
+	m.measured = true
+
+	voice := level.RMS > minVoiceLevel && level.RMS > m.noise+m.options.threshold
+	if info.Channels > 0 && duration > 0 {
+		rate := float64(crossings) / float64(info.Channels) / duration.Seconds()
+		voice = voice && rate < maxVoiceCrossings
+	}
+	if voice {
+		m.voiced += duration
+		m.unvoiced = 0
+	} else {
+		m.voiced = 0
+		m.unvoiced += duration
+	}
+	switch {
+	case !level.Voice && m.voiced >= voiceOnset:
+		level.Voice = true
+	case level.Voice && m.unvoiced >= m.options.hangover:
+		level.Voice = false
+	}
+
+	changed := level.Voice != m.level.Voice
+	m.level = level
+	handler := m.onVoiceActivity
+	m.mu.Unlock()
+
+	if changed && handler != nil {
+		handler(level.Voice)
+	}
+	return level
+}
+
+// toDecibels converts v in [0, 1] to dBFS, down to MinLevel
+func toDecibels(v float64) float64 {
+	if v <= 0 {
+		return MinLevel
+	}
+	return math.Max(20*math.Log10(v), MinLevel)
+}
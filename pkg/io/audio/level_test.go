@@ -0,0 +1,160 @@
+package audio
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/pion/mediadevices/pkg/wave"
+)
+
+// toneChunk returns a 10ms mono chunk at 48 kHz of a sine wave of freq Hz and amplitude amp,
+// starting at the sample offset.
+func toneChunk(freq, amp float64, offset int) wave.Audio {
+	chunk := wave.NewFloat32Interleaved(wave.ChunkInfo{Len: 480, Channels: 1, SamplingRate: 48000})
+	for i := range chunk.Data {
+		chunk.Data[i] = float32(amp * math.Sin(2*math.Pi*freq*float64(offset+i)/48000))
+	}
+	return chunk
+}
+
+// noiseChunk returns a 10ms mono chunk at 48 kHz of white noise of amplitude amp.
+func noiseChunk(rnd *rand.Rand, amp float64) wave.Audio {
+	chunk := wave.NewFloat32Interleaved(wave.ChunkInfo{Len: 480, Channels: 1, SamplingRate: 48000})
+	for i := range chunk.Data {
+		chunk.Data[i] = float32(amp * (2*rnd.Float64() - 1))
+	}
+	return chunk
+}
+
+func TestLevelMeter(t *testing.T) {
+	testCases := map[string]struct {
+		chunk    wave.Audio
+		rms      float64
+		peak     float64
+		smoothed float64
+	}{
+		"Silence": {
+			chunk:    constantInt16(0, 480, 2, 48000),
+			rms:      MinLevel,
+			peak:     MinLevel,
+			smoothed: MinLevel,
+		},
+		"Square": {
+			chunk:    constantFloat32(-0.5, 480, 2, 48000),
+			rms:      -6.02,
+			peak:     -6.02,
+			smoothed: -6.02,
+		},
+		"Sine": {
+			chunk:    toneChunk(1000, 1, 0),
+			rms:      -3.01,
+			peak:     0,
+			smoothed: -3.01,
+		},
+	}
+	for name, c := range testCases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			m := NewLevelMeter()
+			level := m.Measure(c.chunk)
+			if math.Abs(level.RMS-c.rms) > 0.01 || math.Abs(level.Peak-c.peak) > 0.01 || math.Abs(level.Smoothed-c.smoothed) > 0.01 {
+				t.Errorf("Expected RMS %f, peak %f and smoothed %f, got %+v", c.rms, c.peak, c.smoothed, level)
+			}
+			if m.Level() != level {
+				t.Errorf("Expected the last level %+v, got %+v", level, m.Level())
+			}
+		})
+	}
+
+	t.Run("Decay", func(t *testing.T) {
+		m := NewLevelMeter(WithLevelDecay(100 * time.Millisecond))
+		m.Measure(constantFloat32(0.5, 480, 1, 48000))
+		level := m.Measure(constantFloat32(0, 4800, 1, 48000))
+		// the smoothed power decays by 1/e in 100ms, from 0.5 towards silence
+		if expected := toDecibels(0.5 * math.Exp(-1)); math.Abs(level.Smoothed-expected) > 0.01 {
+			t.Errorf("Expected %f, got %f", expected, level.Smoothed)
+		}
+		if level.RMS != MinLevel {
+			t.Errorf("Expected %f, got %f", MinLevel, level.RMS)
+		}
+	})
+}
+
+func TestVoiceActivity(t *testing.T) {
+	rnd := rand.New(rand.NewSource(0))
+	m := NewLevelMeter()
+	var events []bool
+	var eventChunks []int
+	chunks := 0
+	m.OnVoiceActivity(func(speaking bool) {
+		events = append(events, speaking)
+		eventChunks = append(eventChunks, chunks)
+	})
+	measure := func(chunk wave.Audio) {
+		chunks++
+		m.Measure(chunk)
+	}
+
+	// background noise, then loud noise which isn't voice
+	for i := 0; i < 50; i++ {
+		measure(noiseChunk(rnd, 0.001))
+	}
+	for i := 0; i < 20; i++ {
+		measure(noiseChunk(rnd, 0.3))
+	}
+	for i := 0; i < 20; i++ {
+		measure(noiseChunk(rnd, 0.001))
+	}
+	if len(events) != 0 {
+		t.Fatalf("Expected no voice in noise, got %v", events)
+	}
+
+	// a tone with a short pause, like words
+	for i := 0; i < 30; i++ {
+		measure(toneChunk(200, 0.1, i*480))
+	}
+	for i := 0; i < 10; i++ {
+		measure(noiseChunk(rnd, 0.001))
+	}
+	for i := 0; i < 30; i++ {
+		measure(toneChunk(200, 0.1, i*480))
+	}
+	if len(events) != 1 || !events[0] || !m.Level().Voice {
+		t.Fatalf("Expected the speech to start once, got %v", events)
+	}
+	// the speech starts after 30ms of voice
+	if eventChunks[0] != 93 {
+		t.Errorf("Expected the speech to start in the chunk 93, got %d", eventChunks[0])
+	}
+
+	// the speech stops after 300ms of silence
+	for i := 0; i < 50; i++ {
+		measure(noiseChunk(rnd, 0.001))
+	}
+	if len(events) != 2 || events[1] || m.Level().Voice {
+		t.Fatalf("Expected the speech to stop, got %v", events)
+	}
+	if eventChunks[1] != 160+30 {
+		t.Errorf("Expected the speech to stop in the chunk %d, got %d", 160+30, eventChunks[1])
+	}
+}
+
+func TestLevelMeterTransform(t *testing.T) {
+	m := NewLevelMeter()
+	chunk := constantFloat32(0.5, 480, 1, 48000)
+	r := m.Transform()(ReaderFunc(func() (wave.Audio, func(), error) {
+		return chunk, func() {}, nil
+	}))
+	out, _, err := r.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != chunk {
+		t.Error("Expected the chunk to be passed through")
+	}
+	if level := m.Level(); math.Abs(level.RMS+6.02) > 0.01 {
+		t.Errorf("Expected -6.02 dBFS, got %f", level.RMS)
+	}
+}
@@ -0,0 +1,401 @@
+package audio
+
+import (
+	"errors"
+	"io"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pion/mediadevices/pkg/clock"
+	"github.com/pion/mediadevices/pkg/prop"
+	"github.com/pion/mediadevices/pkg/wave"
+)
+
+var errInvalidInput = errors.New("mixer: invalid input index")
+
+type mixerOptions struct {
+	properties prop.Audio
+	buffer     time.Duration
+	clock      clock.Clock
+}
+
+// MixerOption configures a Mixer.
+type MixerOption func(*mixerOptions)
+
+// WithMixerProperties sets the format of the mixed audio. The chunks last p.Latency, and are
+// Uint8, Int16, Int24 or Int32 when p.SampleSize is 1, 2, 3 or 4 and p.IsFloat is false,
+// Float32 otherwise. The default is 48 kHz stereo interleaved Float32, in chunks of 20ms.
+func WithMixerProperties(p prop.Audio) MixerOption {
+	return func(o *mixerOptions) {
+		o.properties = p
+	}
+}
+
+// WithMixerBuffer sets how much audio of an input is buffered before it's mixed, which
+// absorbs the jitter of the inputs at the cost of latency, 2 chunks by default. An input
+// buffering more than 4 times this duration, or 4 chunks if it's shorter, e.g. after being
+// late, drops its oldest samples.
+func WithMixerBuffer(d time.Duration) MixerOption {
+	return func(o *mixerOptions) {
+		o.buffer = d
+	}
+}
+
+// WithMixerClock sets the clock ticking once per output chunk, the inputs which haven't
+// delivered a chunk by then being mixed as silence. The default is the system clock.
+func WithMixerClock(c clock.Clock) MixerOption {
+	return func(o *mixerOptions) {
+		o.clock = c
+	}
+}
+
+// mixerInput holds the samples of an input, converted to the output format
+type mixerInput struct {
+	// fifo holds interleaved samples at the output rate and channel count
+	fifo   []float32
+	primed bool
+	ended  bool
+	gain   float32
+	muted  bool
+	// mixed holds the samples of the input in the current chunk, with the gain applied
+	mixed  []float32
+	active bool
+}
+
+// mixerOutput receives the chunks mixed without the input exclude, or all the inputs if it's
+// negative
+type mixerOutput struct {
+	exclude int
+	chunks  chan wave.Audio
+}
+
+// Mixer sums several audio inputs in one stream of a fixed format, read at the pace of the
+// chunk duration. The inputs are converted to the output sample rate and channel count, and
+// read in the background, so that late inputs are mixed as silence instead of delaying the
+// others. The mixed samples are soft clipped.
+//
+// Besides the main output, MixMinus returns outputs excluding one input, e.g. to send each
+// participant of a conference the mix of the others.
+type Mixer struct {
+	id         string
+	properties prop.Audio
+	chunkLen   int
+	bufferLen  int
+	// keepLen is the number of frames an input keeps when it buffers too much
+	keepLen int
+	clock   clock.Clock
+	// readers is done once the inputs aren't read anymore
+	readers sync.WaitGroup
+
+	mu      sync.Mutex
+	inputs  []*mixerInput
+	outputs []*mixerOutput
+	closed  bool
+	sum     []float32
+	out     []float32
+
+	main      *mixerOutput
+	ticker    clock.Ticker
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewMixer returns a Mixer of the inputs.
+func NewMixer(inputs []Reader, opts ...MixerOption) *Mixer {
+	options := mixerOptions{
+		properties: prop.Audio{
+			ChannelCount:  2,
+			SampleRate:    48000,
+			Latency:       20 * time.Millisecond,
+			SampleSize:    4,
+			IsFloat:       true,
+			IsInterleaved: true,
+		},
+		buffer: -1,
+		clock:  clock.New(),
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	p := options.properties
+	if p.SampleRate <= 0 || p.ChannelCount <= 0 || p.Latency <= 0 {
+		panic("The sample rate, channel count and latency must be positive!")
+	}
+	if options.buffer < 0 {
+		options.buffer = 2 * p.Latency
+	}
+
+	m := &Mixer{
+		id:         uuid.New().String(),
+		properties: p,
+		chunkLen:   durationToSamples(p.Latency, p.SampleRate),
+		bufferLen:  durationToSamples(options.buffer, p.SampleRate),
+		clock:      options.clock,
+		inputs:     make([]*mixerInput, len(inputs)),
+		done:       make(chan struct{}),
+	}
+	m.keepLen = m.bufferLen
+	if m.keepLen < m.chunkLen {
+		m.keepLen = m.chunkLen
+	}
+	m.main = m.addOutput(-1)
+	m.ticker = m.clock.NewTicker(p.Latency)
+
+	for i, r := range inputs {
+		m.inputs[i] = &mixerInput{gain: 1}
+		m.readers.Add(1)
+		go m.readInput(m.inputs[i], r)
+	}
+	go m.run()
+	return m
+}
+
+func durationToSamples(d time.Duration, rate int) int {
+	return int(int64(d) * int64(rate) / int64(time.Second))
+}
+
+func (m *Mixer) addOutput(exclude int) *mixerOutput {
+	o := &mixerOutput{
+		exclude: exclude,
+		chunks:  make(chan wave.Audio, 4),
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		close(o.chunks)
+		return o
+	}
+	m.outputs = append(m.outputs, o)
+	return o
+}
+
+func (m *Mixer) readInput(in *mixerInput, r Reader) {
+	defer m.readers.Done()
+	channels := m.properties.ChannelCount
+	resampler := newResampler(ResampleSinc)
+	var samples, remapped, resampled []float32
+
+	for {
+		select {
+		case <-m.done:
+			return
+		default:
+		}
+
+		chunk, release, err := r.Read()
+		if err != nil {
+			m.mu.Lock()
+			in.ended = true
+			m.mu.Unlock()
+			return
+		}
+		info := chunk.ChunkInfo()
+		samples = appendFloat32(samples[:0], chunk)
+		release()
+		if info.Channels <= 0 || info.Len == 0 {
+			continue
+		}
+
+		remapped = remapChannels(remapped[:0], samples, info.Channels, channels)
+		resampled = resampler.process(resampled[:0], remapped, channels, info.SamplingRate, m.properties.SampleRate)
+
+		m.mu.Lock()
+		in.fifo = append(in.fifo, resampled...)
+		if len(in.fifo) > 4*m.keepLen*channels {
+			// the oldest samples are dropped to keep the latency bounded
+			in.fifo = append(in.fifo[:0], in.fifo[len(in.fifo)-m.keepLen*channels:]...)
+		}
+		m.mu.Unlock()
+	}
+}
+
+func (m *Mixer) run() {
+	defer m.ticker.Stop()
+
+	for {
+		select {
+		case <-m.ticker.C():
+		case <-m.done:
+			m.closeOutputs()
+			return
+		}
+		if !m.mix() {
+			m.closeOutputs()
+			return
+		}
+	}
+}
+
+func (m *Mixer) closeOutputs() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return
+	}
+	m.closed = true
+	for _, o := range m.outputs {
+		close(o.chunks)
+	}
+}
+
+// mix mixes a chunk and sends it to the outputs. It returns false once all the inputs ended.
+func (m *Mixer) mix() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	channels := m.properties.ChannelCount
+	n := m.chunkLen * channels
+	if cap(m.sum) < n {
+		m.sum, m.out = make([]float32, n), make([]float32, n)
+	}
+	m.sum, m.out = m.sum[:n], m.out[:n]
+	for i := range m.sum {
+		m.sum[i] = 0
+	}
+
+	ended := len(m.inputs) > 0
+	for _, in := range m.inputs {
+		in.active = false
+		if in.ended && len(in.fifo) == 0 {
+			continue
+		}
+		ended = false
+
+		if !in.primed {
+			if len(in.fifo) < m.bufferLen*channels && !in.ended {
+				continue
+			}
+			in.primed = true
+		}
+
+		if cap(in.mixed) < n {
+			in.mixed = make([]float32, n)
+		}
+		in.mixed = in.mixed[:n]
+		k := copy(in.mixed, in.fifo)
+		for i := k; i < n; i++ {
+			in.mixed[i] = 0
+		}
+		in.fifo = append(in.fifo[:0], in.fifo[k:]...)
+		if k < n {
+			// the input is late, it's buffered again before being mixed
+			in.primed = false
+		}
+
+		if in.muted || in.gain == 0 {
+			continue
+		}
+		in.active = true
+		for i, v := range in.mixed {
+			v *= in.gain
+			in.mixed[i] = v
+			m.sum[i] += v
+		}
+	}
+	if ended {
+		return false
+	}
+
+	info := wave.ChunkInfo{Len: m.chunkLen, Channels: channels, SamplingRate: m.properties.SampleRate}
+	for _, o := range m.outputs {
+		copy(m.out, m.sum)
+		if o.exclude >= 0 && m.inputs[o.exclude].active {
+			for i, v := range m.inputs[o.exclude].mixed {
+				m.out[i] -= v
+			}
+		}
+		for i, v := range m.out {
+			m.out[i] = softClip(v)
+		}
+
+		chunk := newChunk(m.properties, info)
+		setFloat32(chunk, m.out)
+		select {
+		case o.chunks <- chunk:
+		default:
+			// the oldest chunk is dropped for the outputs which aren't read
+			select {
+			case <-o.chunks:
+			default:
+			}
+			o.chunks <- chunk
+		}
+	}
+	return true
+}
+
+// softClip keeps v in [-1, 1], compressing the values above 0.8 smoothly instead of clipping
+// them.
+func softClip(v float32) float32 {
+	const knee = 0.8
+	switch {
+	case v > knee:
+		return knee + (1-knee)*float32(math.Tanh(float64((v-knee)/(1-knee))))
+	case v < -knee:
+		return -knee - (1-knee)*float32(math.Tanh(float64((-v-knee)/(1-knee))))
+	default:
+		return v
+	}
+}
+
+func (o *mixerOutput) read() (wave.Audio, func(), error) {
+	chunk, ok := <-o.chunks
+	if !ok {
+		return nil, func() {}, io.EOF
+	}
+	return chunk, func() {}, nil
+}
+
+// Read returns the next chunk mixing all the inputs. It returns io.EOF once the mixer is
+// closed, or all the inputs ended.
+func (m *Mixer) Read() (wave.Audio, func(), error) {
+	return m.main.read()
+}
+
+// MixMinus returns a reader of the chunks mixing all the inputs but the input i. It ends
+// with the mixer.
+func (m *Mixer) MixMinus(i int) (Reader, error) {
+	if i < 0 || i >= len(m.inputs) {
+		return nil, errInvalidInput
+	}
+	return ReaderFunc(m.addOutput(i).read), nil
+}
+
+// SetGain sets the linear gain applied to the input i, 1 by default.
+func (m *Mixer) SetGain(i int, gain float32) error {
+	if i < 0 || i >= len(m.inputs) {
+		return errInvalidInput
+	}
+	m.mu.Lock()
+	m.inputs[i].gain = gain
+	m.mu.Unlock()
+	return nil
+}
+
+// SetMute mutes or unmutes the input i.
+func (m *Mixer) SetMute(i int, muted bool) error {
+	if i < 0 || i >= len(m.inputs) {
+		return errInvalidInput
+	}
+	m.mu.Lock()
+	m.inputs[i].muted = muted
+	m.mu.Unlock()
+	return nil
+}
+
+// ID returns the identifier of the mixer.
+func (m *Mixer) ID() string {
+	return m.id
+}
+
+// Close stops the mixer, ending its outputs, and waits until the inputs aren't read anymore,
+// i.e. after their current chunk. The inputs aren't closed.
+func (m *Mixer) Close() error {
+	m.closeOnce.Do(func() {
+		close(m.done)
+	})
+	m.readers.Wait()
+	return nil
+}
@@ -0,0 +1,199 @@
+package audio
+
+import (
+	"io"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/pion/mediadevices/pkg/clock"
+	"github.com/pion/mediadevices/pkg/io/internal/iotest"
+	"github.com/pion/mediadevices/pkg/prop"
+	"github.com/pion/mediadevices/pkg/wave"
+)
+
+// newInput returns a reader of the chunks sent to in.
+func newInput() (Reader, *iotest.Input) {
+	in := iotest.NewInput()
+	return ReaderFunc(func() (wave.Audio, func(), error) {
+		chunk, err := in.Read()
+		if err != nil {
+			return nil, func() {}, err
+		}
+		return chunk.(wave.Audio), func() {}, nil
+	}), in
+}
+
+func constantInt16(v int16, n, channels, rate int) wave.Audio {
+	chunk := wave.NewInt16Interleaved(wave.ChunkInfo{Len: n, Channels: channels, SamplingRate: rate})
+	for i := range chunk.Data {
+		chunk.Data[i] = v
+	}
+	return chunk
+}
+
+func constantFloat32(v float32, n, channels, rate int) wave.Audio {
+	chunk := wave.NewFloat32NonInterleaved(wave.ChunkInfo{Len: n, Channels: channels, SamplingRate: rate})
+	for _, data := range chunk.Data {
+		for i := range data {
+			data[i] = v
+		}
+	}
+	return chunk
+}
+
+func expectConstant(t *testing.T, r Reader, expected float32, n int) {
+	t.Helper()
+	chunk, _, err := r.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	samples := appendFloat32(nil, chunk)
+	if len(samples) < n {
+		t.Fatalf("Expected at least %d samples, got %d", n, len(samples))
+	}
+	for i, v := range samples[:n] {
+		if math.Abs(float64(v-expected)) > 1e-3 {
+			t.Fatalf("Expected %f, got %f at %d", expected, v, i)
+		}
+	}
+}
+
+func TestNewMixer(t *testing.T) {
+	m := clock.NewManual(time.Unix(0, 0))
+	r0, in0 := newInput()
+	r1, in1 := newInput()
+	mixer := NewMixer([]Reader{r0, r1}, WithMixerClock(m), WithMixerBuffer(0))
+	defer mixer.Close()
+	defer in1.Close()
+	defer in0.Close()
+	minus0, err := mixer.MixMinus(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mixer.MixMinus(2); err != errInvalidInput {
+		t.Errorf("Expected %v, got %v", errInvalidInput, err)
+	}
+
+	step := func(v0 int16, v1 float32) {
+		in0.Send(constantInt16(v0, 960, 1, 48000))
+		in1.Send(constantFloat32(v1, 960, 2, 48000))
+		m.Add(20 * time.Millisecond)
+	}
+
+	step(0x2000, 0.125)
+	chunk, _, err := mixer.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info := chunk.ChunkInfo(); info != (wave.ChunkInfo{Len: 960, Channels: 2, SamplingRate: 48000}) {
+		t.Errorf("Unexpected chunk %+v", info)
+	}
+	for _, v := range chunk.(*wave.Float32Interleaved).Data {
+		if v != 0.375 {
+			t.Fatalf("Expected 0.375, got %f", v)
+		}
+	}
+	expectConstant(t, minus0, 0.125, 1920)
+
+	if err := mixer.SetGain(0, 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := mixer.SetMute(1, true); err != nil {
+		t.Fatal(err)
+	}
+	step(0x2000, 0.125)
+	expectConstant(t, mixer, 0.5, 1920)
+	expectConstant(t, minus0, 0, 1920)
+	mixer.SetGain(0, 1)
+	mixer.SetMute(1, false)
+
+	// the late input is mixed as silence
+	in0.Send(constantInt16(0x2000, 960, 1, 48000))
+	m.Add(20 * time.Millisecond)
+	expectConstant(t, mixer, 0.25, 1920)
+	expectConstant(t, minus0, 0, 1920)
+
+	// the sum is clipped smoothly
+	step(0x7000, 0.75)
+	chunk, _, err = mixer.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := chunk.(*wave.Float32Interleaved).Data[0]; v <= 0.9 || v > 1 {
+		t.Errorf("Expected a clipped sample in (0.9, 1], got %f", v)
+	}
+	expectConstant(t, minus0, 0.75, 1920)
+
+	in0.Close()
+	in1.Close()
+	// the mixer ends at the next chunk once it saw the end of the inputs
+	mixer.readers.Wait()
+	m.Add(20 * time.Millisecond)
+	if _, _, err := mixer.Read(); err != io.EOF {
+		t.Errorf("Expected io.EOF once the inputs ended, got %v", err)
+	}
+	// the chunks buffered before the end are still read
+	for i := 0; ; i++ {
+		if _, _, err := minus0.Read(); err == io.EOF {
+			break
+		}
+		if i > 4 {
+			t.Fatal("Expected the mix-minus output to end with the mixer")
+		}
+	}
+}
+
+func TestMixerFormat(t *testing.T) {
+	m := clock.NewManual(time.Unix(0, 0))
+	r, in := newInput()
+	mixer := NewMixer([]Reader{r}, WithMixerClock(m), WithMixerProperties(prop.Audio{
+		ChannelCount: 1,
+		SampleRate:   48000,
+		Latency:      10 * time.Millisecond,
+		SampleSize:   2,
+	}))
+	defer func() {
+		in.Close()
+		mixer.Close()
+	}()
+
+	// 16 kHz stereo is converted to 48 kHz mono, and buffered for 2 chunks
+	in.Send(constantFloat32(0.5, 160, 2, 16000))
+	m.Add(10 * time.Millisecond)
+	chunk, _, err := mixer.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// nothing is mixed until the buffer is full
+	for _, v := range chunk.(*wave.Int16NonInterleaved).Data[0] {
+		if v != 0 {
+			t.Fatalf("Expected silence, got %d", v)
+		}
+	}
+
+	in.Send(constantFloat32(0.5, 320, 2, 16000))
+	m.Add(10 * time.Millisecond)
+	expectConstant(t, mixer, 0.5, 480)
+}
+
+func TestMixerBufferBound(t *testing.T) {
+	m := clock.NewManual(time.Unix(0, 0))
+	r, in := newInput()
+	mixer := NewMixer([]Reader{r}, WithMixerClock(m), WithMixerBuffer(0))
+	defer func() {
+		in.Close()
+		mixer.Close()
+	}()
+
+	// the input is read faster than it's mixed, its oldest samples are dropped
+	for i := 0; i < 10; i++ {
+		in.Send(constantInt16(0x2000, 960, 2, 48000))
+	}
+	mixer.mu.Lock()
+	buffered := len(mixer.inputs[0].fifo)
+	mixer.mu.Unlock()
+	if max := 4 * 960 * 2; buffered > max {
+		t.Errorf("Expected at most %d buffered samples, got %d", max, buffered)
+	}
+}
@@ -5,8 +5,12 @@ import (
 	"github.com/pion/mediadevices/pkg/wave/mixer"
 )
 
-// NewChannelMixer creates audio transform to mix audio channels.
-func NewChannelMixer(channels int, mixer mixer.ChannelMixer) TransformFunc {
+// NewChannelMixer creates audio transform to mix audio channels. The mixed chunks keep the
+// sample format of the input. The chunks which already have the expected number of channels
+// are passed through, unless mixer is a mixer.MatrixMixer, which also maps the channels, e.g.
+// to swap them.
+func NewChannelMixer(channels int, m mixer.ChannelMixer) TransformFunc {
+	_, isMatrix := m.(*mixer.MatrixMixer)
 	return func(r Reader) Reader {
 		return ReaderFunc(func() (wave.Audio, func(), error) {
 			buff, _, err := r.Read()
@@ -14,24 +18,14 @@ func NewChannelMixer(channels int, mixer mixer.ChannelMixer) TransformFunc {
 				return nil, func() {}, err
 			}
 			ci := buff.ChunkInfo()
-			if ci.Channels == channels {
+			if ci.Channels == channels && !isMatrix {
 				return buff, func() {}, nil
 			}
 
 			ci.Channels = channels
 
-			var mixed wave.Audio
-			switch buff.(type) {
-			case *wave.Int16Interleaved:
-				mixed = wave.NewInt16Interleaved(ci)
-			case *wave.Int16NonInterleaved:
-				mixed = wave.NewInt16NonInterleaved(ci)
-			case *wave.Float32Interleaved:
-				mixed = wave.NewFloat32Interleaved(ci)
-			case *wave.Float32NonInterleaved:
-				mixed = wave.NewFloat32NonInterleaved(ci)
-			}
-			if err := mixer.Mix(mixed, buff); err != nil {
+			mixed := newChunkLike(buff, ci)
+			if err := m.Mix(mixed, buff); err != nil {
 				return nil, func() {}, err
 			}
 			return mixed, func() {}, nil
@@ -55,3 +55,27 @@ func TestMixer(t *testing.T) {
 		}
 	}
 }
+
+func TestMatrixChannelMixer(t *testing.T) {
+	input := &wave.Float32NonInterleaved{
+		Size: wave.ChunkInfo{Len: 2, Channels: 2, SamplingRate: 1234},
+		Data: [][]float32{{0.25, 0.5}, {-0.25, -0.5}},
+	}
+	expected := &wave.Float32NonInterleaved{
+		Size: wave.ChunkInfo{Len: 2, Channels: 2, SamplingRate: 1234},
+		Data: [][]float32{{-0.25, -0.5}, {0.25, 0.5}},
+	}
+
+	// the channels are swapped although their number doesn't change
+	trans := NewChannelMixer(2, mixer.SwapChannels())
+	r := trans(ReaderFunc(func() (wave.Audio, func(), error) {
+		return input, func() {}, nil
+	}))
+	a, _, err := r.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(expected, a) {
+		t.Errorf("Expected wave: %v, got: %v", expected, a)
+	}
+}
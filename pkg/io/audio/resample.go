@@ -0,0 +1,269 @@
+package audio
+
+import (
+	"math"
+
+	"github.com/pion/mediadevices/pkg/wave"
+)
+
+// ResampleQuality is the interpolation used to change the sample rate.
+type ResampleQuality int
+
+const (
+	// ResampleSinc interpolates with a Kaiser windowed sinc, low-pass filtered to avoid
+	// aliasing when downsampling. It's the default.
+	ResampleSinc ResampleQuality = iota
+	// ResampleLinear interpolates linearly, which is faster but adds aliasing and attenuates
+	// the high frequencies.
+	ResampleLinear
+)
+
+type resampleOptions struct {
+	quality ResampleQuality
+}
+
+// ResampleOption configures Resample.
+type ResampleOption func(*resampleOptions)
+
+// WithResampleQuality sets the interpolation, ResampleSinc by default.
+func WithResampleQuality(q ResampleQuality) ResampleOption {
+	return func(o *resampleOptions) {
+		o.quality = q
+	}
+}
+
+// Resample returns a transform converting the chunks to targetRate. The chunks keep their
+// sample type and layout, interleaved or not. The interpolation state is kept across the
+// chunks, so that there's no discontinuity between them. The chunks which are already at
+// targetRate, or don't have a sample rate, are passed through.
+func Resample(targetRate int, opts ...ResampleOption) TransformFunc {
+	if targetRate <= 0 {
+		panic("The sample rate must be positive!")
+	}
+	options := resampleOptions{quality: ResampleSinc}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	return func(r Reader) Reader {
+		rs := newResampler(options.quality)
+		var samples, resampled []float32
+
+		return ReaderFunc(func() (wave.Audio, func(), error) {
+			chunk, _, err := r.Read()
+			if err != nil {
+				return nil, func() {}, err
+			}
+			info := chunk.ChunkInfo()
+			if info.SamplingRate == targetRate || info.SamplingRate <= 0 || info.Channels <= 0 {
+				return chunk, func() {}, nil
+			}
+
+			samples = appendFloat32(samples[:0], chunk)
+			resampled = rs.process(resampled[:0], samples, info.Channels, info.SamplingRate, targetRate)
+
+			info.SamplingRate = targetRate
+			info.Len = len(resampled) / info.Channels
+			out := newChunkLike(chunk, info)
+			setFloat32(out, resampled)
+			return out, func() {}, nil
+		})
+	}
+}
+
+// newChunkLike allocates a chunk of the same type as chunk, or Float32Interleaved for the
+// unknown types.
+func newChunkLike(chunk wave.Audio, info wave.ChunkInfo) wave.EditableAudio {
+	if f := formatOf(chunk); f != nil {
+		return newChunkFormat(f, info)
+	}
+	return wave.NewFloat32Interleaved(info)
+}
+
+// resampler converts the sample rate of interleaved samples, keeping its state across chunks.
+type resampler interface {
+	process(dst, src []float32, channels, inRate, outRate int) []float32
+}
+
+func newResampler(q ResampleQuality) resampler {
+	if q == ResampleLinear {
+		return &linearResampler{}
+	}
+	return newSincResampler(16)
+}
+
+// linearResampler interpolates linearly between the samples.
+type linearResampler struct {
+	// pos is the position of the next output sample from the first sample of the next chunk,
+	// in input samples. It's negative when it's between prev and the next chunk.
+	pos      float64
+	prev     []float32
+	channels int
+}
+
+func (r *linearResampler) process(dst, src []float32, channels, inRate, outRate int) []float32 {
+	if inRate == outRate || inRate <= 0 {
+		return append(dst, src...)
+	}
+	n := len(src) / channels
+	if n == 0 {
+		return dst
+	}
+	if channels != r.channels {
+		*r = linearResampler{channels: channels}
+	}
+	sample := func(i, ch int) float32 {
+		if i < 0 {
+			return r.prev[ch]
+		}
+		return src[i*channels+ch]
+	}
+
+	step := float64(inRate) / float64(outRate)
+	for ; r.pos < float64(n-1); r.pos += step {
+		i := int(math.Floor(r.pos))
+		frac := float32(r.pos - float64(i))
+		for ch := 0; ch < channels; ch++ {
+			a, b := sample(i, ch), sample(i+1, ch)
+			dst = append(dst, a+(b-a)*frac)
+		}
+	}
+	r.pos -= float64(n)
+	r.prev = append(r.prev[:0], src[(n-1)*channels:n*channels]...)
+	return dst
+}
+
+const (
+	// sincTableResolution is the number of kernel values per input sample
+	sincTableResolution = 256
+	// kaiserBeta trades the width of the transition band for the stopband attenuation
+	kaiserBeta = 8.6
+)
+
+// sincResampler interpolates with a Kaiser windowed sinc of halfTaps zero crossings on each
+// side.
+type sincResampler struct {
+	halfTaps int
+	// table holds the kernel from 0 to halfTaps
+	table []float32
+
+	channels        int
+	inRate, outRate int
+	// history holds the interleaved input samples which are still needed
+	history []float32
+	// pos is the position of the next output sample in history, in input samples
+	pos float64
+}
+
+func newSincResampler(halfTaps int) *sincResampler {
+	table := make([]float32, halfTaps*sincTableResolution+2)
+	i0Beta := besselI0(kaiserBeta)
+	for i := range table {
+		t := float64(i) / sincTableResolution
+		if t >= float64(halfTaps) {
+			continue
+		}
+		sinc := 1.0
+		if t != 0 {
+			sinc = math.Sin(math.Pi*t) / (math.Pi * t)
+		}
+		ratio := t / float64(halfTaps)
+		window := besselI0(kaiserBeta*math.Sqrt(1-ratio*ratio)) / i0Beta
+		table[i] = float32(sinc * window)
+	}
+	return &sincResampler{halfTaps: halfTaps, table: table}
+}
+
+// besselI0 is the modified Bessel function of the first kind of order 0.
+func besselI0(x float64) float64 {
+	sum, term := 1.0, 1.0
+	for k := 1; k < 50; k++ {
+		term *= (x / (2 * float64(k))) * (x / (2 * float64(k)))
+		sum += term
+		if term < sum*1e-12 {
+			break
+		}
+	}
+	return sum
+}
+
+// kernel returns the value of the kernel at t, interpolated from the table
+func (r *sincResampler) kernel(t float64) float32 {
+	if t < 0 {
+		t = -t
+	}
+	f := t * sincTableResolution
+	i := int(f)
+	if i >= len(r.table)-1 {
+		return 0
+	}
+	frac := float32(f - float64(i))
+	return r.table[i] + (r.table[i+1]-r.table[i])*frac
+}
+
+func (r *sincResampler) process(dst, src []float32, channels, inRate, outRate int) []float32 {
+	if inRate == outRate || inRate <= 0 {
+		return append(dst, src...)
+	}
+	step := float64(inRate) / float64(outRate)
+	// the cutoff frequency is lowered to the output Nyquist frequency when downsampling
+	scale := 1.0
+	if outRate < inRate {
+		scale = float64(outRate) / float64(inRate)
+	}
+	radius := float64(r.halfTaps) / scale
+
+	if channels != r.channels || inRate != r.inRate || outRate != r.outRate {
+		r.channels, r.inRate, r.outRate = channels, inRate, outRate
+		r.history = r.history[:0]
+	}
+	if len(r.history) == 0 {
+		if len(src) < channels {
+			return dst
+		}
+		// the history starts with copies of the first sample, so that the first output
+		// sample is aligned with it, without a step from silence
+		pad := int(math.Ceil(radius))
+		for i := 0; i < pad; i++ {
+			r.history = append(r.history, src[:channels]...)
+		}
+		r.pos = float64(pad)
+	}
+	r.history = append(r.history, src...)
+	n := len(r.history) / channels
+
+	sums := make([]float32, channels)
+	for r.pos+radius < float64(n-1) {
+		first := int(math.Ceil(r.pos - radius))
+		if first < 0 {
+			first = 0
+		}
+		last := int(math.Floor(r.pos + radius))
+
+		for ch := range sums {
+			sums[ch] = 0
+		}
+		var weights float32
+		for i := first; i <= last; i++ {
+			w := r.kernel((float64(i) - r.pos) * scale)
+			weights += w
+			frame := r.history[i*channels : (i+1)*channels]
+			for ch, v := range frame {
+				sums[ch] += v * w
+			}
+		}
+		// normalizing by the sum of the weights keeps the DC gain exactly 1
+		for _, s := range sums {
+			dst = append(dst, s/weights)
+		}
+		r.pos += step
+	}
+
+	// the samples before the window of the next output sample aren't needed anymore
+	drop := int(math.Ceil(r.pos-radius)) - 1
+	if drop > 0 {
+		r.history = append(r.history[:0], r.history[drop*channels:]...)
+		r.pos -= float64(drop)
+	}
+	return dst
+}
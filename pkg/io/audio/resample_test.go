@@ -0,0 +1,178 @@
+package audio
+
+import (
+	"io"
+	"math"
+	"testing"
+
+	"github.com/pion/mediadevices/pkg/wave"
+)
+
+// sineChunks returns chunks of chunkLen samples of a sine wave of freq Hz and amplitude 0.5,
+// on all the channels, in the format of newChunk.
+func sineChunks(freq float64, rate, channels, chunkLen, count int, newChunk func(wave.ChunkInfo) wave.EditableAudio) []wave.Audio {
+	var chunks []wave.Audio
+	for c := 0; c < count; c++ {
+		info := wave.ChunkInfo{Len: chunkLen, Channels: channels, SamplingRate: rate}
+		samples := make([]float32, 0, chunkLen*channels)
+		for i := 0; i < chunkLen; i++ {
+			v := float32(0.5 * math.Sin(2*math.Pi*freq*float64(c*chunkLen+i)/float64(rate)))
+			for ch := 0; ch < channels; ch++ {
+				samples = append(samples, v)
+			}
+		}
+		chunk := newChunk(info)
+		setFloat32(chunk, samples)
+		chunks = append(chunks, chunk)
+	}
+	return chunks
+}
+
+// sliceReader returns a reader of chunks, which ends with io.EOF.
+func sliceReader(chunks []wave.Audio) Reader {
+	var i int
+	return ReaderFunc(func() (wave.Audio, func(), error) {
+		if i >= len(chunks) {
+			return nil, func() {}, io.EOF
+		}
+		i++
+		return chunks[i-1], func() {}, nil
+	})
+}
+
+func TestResample(t *testing.T) {
+	newChunks := map[string]func(wave.ChunkInfo) wave.EditableAudio{
+		"Int16Interleaved":      func(info wave.ChunkInfo) wave.EditableAudio { return wave.NewInt16Interleaved(info) },
+		"Int16NonInterleaved":   func(info wave.ChunkInfo) wave.EditableAudio { return wave.NewInt16NonInterleaved(info) },
+		"Float32Interleaved":    func(info wave.ChunkInfo) wave.EditableAudio { return wave.NewFloat32Interleaved(info) },
+		"Float32NonInterleaved": func(info wave.ChunkInfo) wave.EditableAudio { return wave.NewFloat32NonInterleaved(info) },
+	}
+	testCases := map[string]struct {
+		quality         ResampleQuality
+		inRate, outRate int
+		tolerance       float64
+	}{
+		"SincUp":     {quality: ResampleSinc, inRate: 16000, outRate: 48000, tolerance: 0.005},
+		"SincDown":   {quality: ResampleSinc, inRate: 48000, outRate: 16000, tolerance: 0.005},
+		"SincCD":     {quality: ResampleSinc, inRate: 44100, outRate: 48000, tolerance: 0.005},
+		"LinearUp":   {quality: ResampleLinear, inRate: 16000, outRate: 48000, tolerance: 0.02},
+		"LinearDown": {quality: ResampleLinear, inRate: 48000, outRate: 16000, tolerance: 0.005},
+	}
+	for name, c := range testCases {
+		c := c
+		for format, newChunk := range newChunks {
+			newChunk := newChunk
+			t.Run(name+"/"+format, func(t *testing.T) {
+				// chunks of 10ms of a 1 kHz tone
+				chunkLen := c.inRate / 100
+				r := Resample(c.outRate, WithResampleQuality(c.quality))(
+					sliceReader(sineChunks(1000, c.inRate, 2, chunkLen, 10, newChunk)),
+				)
+
+				var out []float32
+				for {
+					chunk, _, err := r.Read()
+					if err != nil {
+						break
+					}
+					info := chunk.ChunkInfo()
+					if info.SamplingRate != c.outRate || info.Channels != 2 {
+						t.Fatalf("Unexpected chunk %+v", info)
+					}
+					if chunkType(chunk) != format {
+						t.Fatalf("Expected a %s chunk, got %T", format, chunk)
+					}
+					out = appendFloat32(out, chunk)
+				}
+
+				// the resampler holds back the samples needed to interpolate the next ones
+				expectedLen := 10 * c.outRate / 100
+				if n := len(out) / 2; n > expectedLen || n < expectedLen-64 {
+					t.Fatalf("Expected about %d samples, got %d", expectedLen, n)
+				}
+				// the tone is continuous across the chunks. The first samples are skipped, as
+				// the tone starts abruptly.
+				for i := 64; i < len(out)/2; i++ {
+					expected := 0.5 * math.Sin(2*math.Pi*1000*float64(i)/float64(c.outRate))
+					for ch := 0; ch < 2; ch++ {
+						if v := float64(out[2*i+ch]); math.Abs(v-expected) > c.tolerance {
+							t.Fatalf("Expected %f, got %f at %d", expected, v, i)
+						}
+					}
+				}
+			})
+		}
+	}
+}
+
+func chunkType(chunk wave.Audio) string {
+	switch chunk.(type) {
+	case *wave.Int16Interleaved:
+		return "Int16Interleaved"
+	case *wave.Int16NonInterleaved:
+		return "Int16NonInterleaved"
+	case *wave.Float32Interleaved:
+		return "Float32Interleaved"
+	case *wave.Float32NonInterleaved:
+		return "Float32NonInterleaved"
+	default:
+		return ""
+	}
+}
+
+func TestResampleAliasing(t *testing.T) {
+	// a 12 kHz tone can't be represented at 16 kHz, and is filtered out
+	r := Resample(16000)(sliceReader(sineChunks(12000, 48000, 1, 480, 10, func(info wave.ChunkInfo) wave.EditableAudio {
+		return wave.NewFloat32Interleaved(info)
+	})))
+	var out []float32
+	for {
+		chunk, _, err := r.Read()
+		if err != nil {
+			break
+		}
+		out = appendFloat32(out, chunk)
+	}
+	if len(out) < 1000 {
+		t.Fatalf("Expected about 1600 samples, got %d", len(out))
+	}
+	// the first samples are skipped, the tone starts abruptly
+	for i, v := range out[100:] {
+		if math.Abs(float64(v)) > 0.005 {
+			t.Fatalf("Expected silence, got %f at %d", v, i+100)
+		}
+	}
+}
+
+func TestResamplePassthrough(t *testing.T) {
+	chunk := constantInt16(0x1000, 480, 2, 48000)
+	r := Resample(48000)(sliceReader([]wave.Audio{chunk}))
+	out, _, err := r.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != chunk {
+		t.Error("Expected the chunk at the target rate to be passed through")
+	}
+}
+
+func TestLinearResampler(t *testing.T) {
+	var r linearResampler
+	var out []float32
+	// a ramp keeps its slope across chunks
+	for c := 0; c < 4; c++ {
+		in := make([]float32, 100)
+		for i := range in {
+			in[i] = float32(c*100 + i)
+		}
+		out = r.process(out, in, 1, 16000, 48000)
+	}
+	if len(out) < 1190 {
+		t.Fatalf("Expected about 1200 samples, got %d", len(out))
+	}
+	for i, v := range out {
+		if expected := float32(i) / 3; math.Abs(float64(v-expected)) > 1e-3 {
+			t.Fatalf("Expected %f, got %f at %d", expected, v, i)
+		}
+	}
+}
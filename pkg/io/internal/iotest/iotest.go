@@ -0,0 +1,53 @@
+// Package iotest provides helpers to test the readers of the io packages.
+package iotest
+
+import (
+	"io"
+	"sync"
+)
+
+// Input is an input read in the background, e.g. by a mixer, receiving the frames sent by a
+// test one by one. Send returns once the frame is consumed, so that the test doesn't depend on
+// the scheduling of the background reader.
+type Input struct {
+	frames   chan interface{}
+	consumed chan struct{}
+	pending  bool
+	closed   sync.Once
+}
+
+// NewInput returns an Input without frames.
+func NewInput() *Input {
+	return &Input{
+		frames:   make(chan interface{}),
+		consumed: make(chan struct{}, 1),
+	}
+}
+
+// Read returns the next frame sent, or io.EOF once the input is closed. The previous frame is
+// consumed once Read is called again.
+func (in *Input) Read() (interface{}, error) {
+	if in.pending {
+		in.pending = false
+		in.consumed <- struct{}{}
+	}
+	frame, ok := <-in.frames
+	if !ok {
+		return nil, io.EOF
+	}
+	in.pending = true
+	return frame, nil
+}
+
+// Send sends frame, and waits until it's consumed.
+func (in *Input) Send(frame interface{}) {
+	in.frames <- frame
+	<-in.consumed
+}
+
+// Close ends the input. It can be called several times.
+func (in *Input) Close() {
+	in.closed.Do(func() {
+		close(in.frames)
+	})
+}
@@ -0,0 +1,449 @@
+package video
+
+import (
+	"image"
+	"image/color"
+	"io"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pion/mediadevices/pkg/clock"
+)
+
+// InputLayout is the placement of an input of a Compositor in the output frames.
+type InputLayout struct {
+	// Rect is the area of the output frames where the input is drawn. The input is hidden
+	// when it's empty.
+	Rect image.Rectangle
+	// Z is the z-order of the input, the inputs of higher Z are drawn over the others
+	Z int
+	// Fit defines how the aspect ratio of the input is kept in Rect
+	Fit FitMode
+	// Border is the width in pixels of the border drawn inside Rect
+	Border int
+	// BorderColor is the color of the border, white if nil
+	BorderColor color.Color
+}
+
+// GridLayout returns a layout of n inputs in a grid filling width x height, with gap pixels
+// between the cells. The grid has as many columns as rows, or one more.
+func GridLayout(n, width, height, gap int) []InputLayout {
+	if n <= 0 {
+		return nil
+	}
+	cols := int(math.Ceil(math.Sqrt(float64(n))))
+	rows := (n + cols - 1) / cols
+
+	layout := make([]InputLayout, n)
+	for i := range layout {
+		col, row := i%cols, i/cols
+		layout[i].Rect = image.Rect(
+			col*(width+gap)/cols, row*(height+gap)/rows,
+			(col+1)*(width+gap)/cols-gap, (row+1)*(height+gap)/rows-gap,
+		)
+	}
+	return layout
+}
+
+// PiPLayout returns a picture-in-picture layout of n inputs in width x height: the first
+// input fills the frames, and the others are drawn over it in insets of scale times the size
+// of the frames, in a row starting from the anchor corner, margin pixels apart.
+func PiPLayout(n, width, height int, scale float64, anchor OverlayAnchor, margin int) []InputLayout {
+	if n <= 0 {
+		return nil
+	}
+	layout := make([]InputLayout, n)
+	layout[0].Rect = image.Rect(0, 0, width, height)
+
+	size := image.Pt(int(float64(width)*scale), int(float64(height)*scale))
+	frame := image.Rect(0, 0, width, height)
+	for i := 1; i < n; i++ {
+		// the insets are positioned like overlays, moving away from the corner
+		o := overlayOptions{
+			anchor: anchor,
+			offset: image.Pt(margin+(i-1)*(size.X+margin), margin),
+		}
+		if anchor == AnchorCenter {
+			o.offset = image.Pt((i-1)*(size.X+margin), 0)
+		}
+		p := o.overlayPoint(frame, size)
+		layout[i] = InputLayout{
+			Rect: image.Rectangle{Min: p, Max: p.Add(size)},
+			Z:    1,
+		}
+	}
+	return layout
+}
+
+type compositorOptions struct {
+	layout       []InputLayout
+	background   color.Color
+	stallTimeout time.Duration
+	placeholder  image.Image
+	clock        clock.Clock
+}
+
+// CompositorOption configures a Compositor.
+type CompositorOption func(*compositorOptions)
+
+// WithLayout sets the initial layout of the inputs, a grid by default.
+func WithLayout(layout []InputLayout) CompositorOption {
+	return func(o *compositorOptions) {
+		o.layout = layout
+	}
+}
+
+// WithBackground sets the color of the areas without inputs, black by default.
+func WithBackground(c color.Color) CompositorOption {
+	return func(o *compositorOptions) {
+		o.background = c
+	}
+}
+
+// WithStallTimeout sets the time after which an input not sending frames is stalled, 1s by
+// default.
+func WithStallTimeout(d time.Duration) CompositorOption {
+	return func(o *compositorOptions) {
+		o.stallTimeout = d
+	}
+}
+
+// WithPlaceholder sets the image shown instead of the stalled and ended inputs. By default,
+// their last frame is held.
+func WithPlaceholder(img image.Image) CompositorOption {
+	return func(o *compositorOptions) {
+		o.placeholder = img
+	}
+}
+
+// WithCompositorClock sets the clock ticking at the output frame rate, against which the
+// inputs are checked for stalls. The default is the system clock.
+func WithCompositorClock(c clock.Clock) CompositorOption {
+	return func(o *compositorOptions) {
+		o.clock = c
+	}
+}
+
+// compositorInput holds the last frame of an input
+type compositorInput struct {
+	frame *image.YCbCr
+	// spare is the buffer the next frame is copied to
+	spare   *image.YCbCr
+	updated time.Time
+	ended   bool
+}
+
+// Compositor combines several video inputs in I420 frames of a fixed size, read at a fixed
+// frame rate. It can be used as the source of a video track.
+type Compositor struct {
+	id          string
+	rect        image.Rectangle
+	interval    time.Duration
+	options     compositorOptions
+	placeholder *image.YCbCr
+
+	mu     sync.Mutex
+	layout []InputLayout
+	inputs []*compositorInput
+	// readers is done once the inputs aren't read anymore
+	readers sync.WaitGroup
+
+	ticker    clock.Ticker
+	started   bool
+	done      chan struct{}
+	closeOnce sync.Once
+	output    *image.YCbCr
+}
+
+// NewCompositor returns a Compositor of the inputs, producing frames of width x height at
+// frameRate frames per second. The inputs are read in the background as fast as they produce
+// frames, and the last frame of each input is drawn in the output frames.
+func NewCompositor(inputs []Reader, width, height int, frameRate float64, opts ...CompositorOption) *Compositor {
+	if width <= 0 || height <= 0 {
+		panic("Both width and height must be positive!")
+	}
+	if frameRate <= 0 {
+		panic("The frame rate must be positive!")
+	}
+
+	options := compositorOptions{
+		background:   color.Black,
+		stallTimeout: time.Second,
+		clock:        clock.New(),
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if options.layout == nil {
+		options.layout = GridLayout(len(inputs), width, height, 0)
+	}
+
+	c := &Compositor{
+		id:       uuid.New().String(),
+		rect:     image.Rect(0, 0, width, height),
+		interval: time.Duration(float64(time.Second) / frameRate),
+		options:  options,
+		layout:   options.layout,
+		inputs:   make([]*compositorInput, len(inputs)),
+		done:     make(chan struct{}),
+	}
+	if options.placeholder != nil {
+		img, _, err := ToI420(ReaderFunc(func() (image.Image, func(), error) {
+			return options.placeholder, func() {}, nil
+		})).Read()
+		if err == nil {
+			c.placeholder = copyToI420(nil, img.(*image.YCbCr))
+		}
+	}
+
+	c.ticker = options.clock.NewTicker(c.interval)
+	for i, r := range inputs {
+		c.inputs[i] = &compositorInput{}
+		c.readers.Add(1)
+		go c.readInput(c.inputs[i], ToI420(r))
+	}
+	return c
+}
+
+// copyToI420 copies the I420 frame src in dst, which is reallocated if needed.
+func copyToI420(dst, src *image.YCbCr) *image.YCbCr {
+	dst = reuseYCbCr(dst, image.Rect(0, 0, src.Rect.Dx(), src.Rect.Dy()), image.YCbCrSubsampleRatio420)
+	copyYCbCr(dst, image.Point{}, src, src.Rect)
+	return dst
+}
+
+func (c *Compositor) readInput(in *compositorInput, r Reader) {
+	defer c.readers.Done()
+	for {
+		select {
+		case <-c.done:
+			return
+		default:
+		}
+
+		img, release, err := r.Read()
+		if err != nil {
+			c.mu.Lock()
+			in.ended = true
+			c.mu.Unlock()
+			return
+		}
+		// the frame is copied outside of the lock, which is only held to swap the buffers
+		spare := copyToI420(in.spare, img.(*image.YCbCr))
+		release()
+
+		c.mu.Lock()
+		in.spare, in.frame = in.frame, spare
+		in.updated = c.options.clock.Now()
+		c.mu.Unlock()
+	}
+}
+
+// ID returns the identifier of the compositor.
+func (c *Compositor) ID() string {
+	return c.id
+}
+
+// Close stops the compositor, and waits until the inputs aren't read anymore, i.e. after their
+// current frame. The inputs aren't closed.
+func (c *Compositor) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.done)
+		c.ticker.Stop()
+	})
+	c.readers.Wait()
+	return nil
+}
+
+// SetLayout changes the layout of the inputs, from the next frame. The layout is indexed like
+// the inputs, and the inputs without layout are hidden.
+func (c *Compositor) SetLayout(layout []InputLayout) {
+	c.mu.Lock()
+	c.layout = layout
+	c.mu.Unlock()
+}
+
+// Layout returns the current layout of the inputs.
+func (c *Compositor) Layout() []InputLayout {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.layout
+}
+
+// Read returns the next frame, waiting for the frame interval since the previous one. It
+// returns io.EOF once the compositor is closed, or all its inputs ended.
+func (c *Compositor) Read() (image.Image, func(), error) {
+	if c.started {
+		select {
+		case <-c.ticker.C():
+		case <-c.done:
+			return nil, func() {}, io.EOF
+		}
+	}
+	c.started = true
+
+	select {
+	case <-c.done:
+		return nil, func() {}, io.EOF
+	default:
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ended := len(c.inputs) > 0
+	for _, in := range c.inputs {
+		ended = ended && in.ended
+	}
+	if ended {
+		return nil, func() {}, io.EOF
+	}
+
+	c.output = reuseYCbCr(c.output, c.rect, image.YCbCrSubsampleRatio420)
+	c.compose(c.output)
+
+	cloned := *c.output // clone metadata
+	return &cloned, func() {}, nil
+}
+
+// compose draws the inputs in dst, in z-order
+func (c *Compositor) compose(dst *image.YCbCr) {
+	background := color.YCbCrModel.Convert(c.options.background).(color.YCbCr)
+	fillYCbCr(dst, dst.Rect, background)
+
+	order := make([]int, 0, len(c.layout))
+	for i := range c.layout {
+		if i < len(c.inputs) {
+			order = append(order, i)
+		}
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return c.layout[order[i]].Z < c.layout[order[j]].Z
+	})
+
+	now := c.options.clock.Now()
+	for _, i := range order {
+		l := c.layout[i]
+		rect := alignRect(l.Rect.Intersect(dst.Rect))
+		if rect.Empty() {
+			continue
+		}
+
+		if l.Border > 0 {
+			borderColor := l.BorderColor
+			if borderColor == nil {
+				borderColor = color.White
+			}
+			fillYCbCr(dst, rect, color.YCbCrModel.Convert(borderColor).(color.YCbCr))
+			rect = alignRect(rect.Inset(l.Border))
+			if rect.Empty() {
+				continue
+			}
+		}
+		fillYCbCr(dst, rect, background)
+
+		in := c.inputs[i]
+		frame := in.frame
+		stalled := in.ended || (frame != nil && now.Sub(in.updated) > c.options.stallTimeout)
+		if c.placeholder != nil && (stalled || frame == nil) {
+			frame = c.placeholder
+		}
+		if frame == nil {
+			continue
+		}
+
+		sr, dr := fitRects(frame.Rect, rect, l.Fit)
+		drawScaledI420(dst, dr, frame, sr)
+	}
+}
+
+// alignRect aligns r on the chroma samples of I420 frames
+func alignRect(r image.Rectangle) image.Rectangle {
+	return image.Rect(alignDown(r.Min.X, 2), alignDown(r.Min.Y, 2), alignDown(r.Max.X, 2), alignDown(r.Max.Y, 2))
+}
+
+// fitRects returns the area of a frame of bounds src and where it's drawn in dst, keeping its
+// aspect ratio according to mode.
+func fitRects(src, dst image.Rectangle, mode FitMode) (image.Rectangle, image.Rectangle) {
+	w, h := src.Dx(), src.Dy()
+	wider := w*dst.Dy() > h*dst.Dx()
+
+	if mode == FitCrop {
+		sr := src
+		if wider {
+			cw := h * dst.Dx() / dst.Dy()
+			sr.Min.X += (w - cw) / 2
+			sr.Max.X = sr.Min.X + cw
+		} else {
+			ch := w * dst.Dy() / dst.Dx()
+			sr.Min.Y += (h - ch) / 2
+			sr.Max.Y = sr.Min.Y + ch
+		}
+		return alignRect(sr), dst
+	}
+
+	size := dst.Size()
+	if wider {
+		size.Y = h * dst.Dx() / w
+	} else {
+		size.X = w * dst.Dy() / h
+	}
+	min := dst.Min.Add(dst.Size().Sub(size).Div(2))
+	return src, alignRect(image.Rectangle{Min: min, Max: min.Add(size)})
+}
+
+// drawScaledI420 scales the area sr of the I420 frame src to the area dr of dst. Both areas
+// must be aligned on the chroma samples.
+func drawScaledI420(dst *image.YCbCr, dr image.Rectangle, src *image.YCbCr, sr image.Rectangle) {
+	if dr.Empty() || sr.Empty() {
+		return
+	}
+	scalePlane(dst.Y, dst.YStride, dr, src.Y[src.YOffset(sr.Min.X, sr.Min.Y):], src.YStride, sr.Dx(), sr.Dy())
+
+	cdr := image.Rect(dr.Min.X/2, dr.Min.Y/2, dr.Max.X/2, dr.Max.Y/2)
+	ci := src.COffset(sr.Min.X, sr.Min.Y)
+	cw, ch := (sr.Dx()+1)/2, (sr.Dy()+1)/2
+	scalePlane(dst.Cb, dst.CStride, cdr, src.Cb[ci:], src.CStride, cw, ch)
+	scalePlane(dst.Cr, dst.CStride, cdr, src.Cr[ci:], src.CStride, cw, ch)
+}
+
+// scalePlane scales the plane src of sw x sh samples to the area dr of dst, with a bilinear
+// interpolation.
+func scalePlane(dst []uint8, dstStride int, dr image.Rectangle, src []uint8, srcStride, sw, sh int) {
+	dw, dh := dr.Dx(), dr.Dy()
+	if dw <= 0 || dh <= 0 || sw <= 0 || sh <= 0 {
+		return
+	}
+
+	// the sample positions in src in 24.8 fixed point, of the centers of the dst samples
+	position := func(i, dn, sn int) (int, int, int) {
+		p := int((int64(2*i+1)*int64(sn)*256)/int64(2*dn)) - 128
+		if p < 0 {
+			p = 0
+		}
+		i0 := p >> 8
+		if i0 >= sn-1 {
+			return sn - 1, sn - 1, 0
+		}
+		return i0, i0 + 1, p & 0xFF
+	}
+	x0s, x1s, wxs := make([]int, dw), make([]int, dw), make([]int, dw)
+	for x := range x0s {
+		x0s[x], x1s[x], wxs[x] = position(x, dw, sw)
+	}
+
+	for y := 0; y < dh; y++ {
+		y0, y1, wy := position(y, dh, sh)
+		row0, row1 := src[y0*srcStride:], src[y1*srcStride:]
+		d := dst[(dr.Min.Y+y)*dstStride+dr.Min.X:]
+		for x := 0; x < dw; x++ {
+			x0, x1, wx := x0s[x], x1s[x], wxs[x]
+			top := int(row0[x0])*(256-wx) + int(row0[x1])*wx
+			bottom := int(row1[x0])*(256-wx) + int(row1[x1])*wx
+			d[x] = uint8((top*(256-wy) + bottom*wy + 1<<15) >> 16)
+		}
+	}
+}
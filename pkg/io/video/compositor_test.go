@@ -0,0 +1,192 @@
+package video
+
+import (
+	"image"
+	"image/color"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/pion/mediadevices/pkg/clock"
+	"github.com/pion/mediadevices/pkg/io/internal/iotest"
+)
+
+// solidI420 returns an I420 frame of w x h filled with c
+func solidI420(w, h int, c color.Color) *image.YCbCr {
+	img := image.NewYCbCr(image.Rect(0, 0, w, h), image.YCbCrSubsampleRatio420)
+	fillYCbCr(img, img.Rect, color.YCbCrModel.Convert(c).(color.YCbCr))
+	return img
+}
+
+// solidRGBA returns an RGBA frame of w x h filled with c
+func solidRGBA(w, h int, c color.RGBA) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	fillRGBA(img, c)
+	return img
+}
+
+// newInput returns a reader of the frames sent to in.
+func newInput() (Reader, *iotest.Input) {
+	in := iotest.NewInput()
+	return ReaderFunc(func() (image.Image, func(), error) {
+		img, err := in.Read()
+		if err != nil {
+			return nil, func() {}, err
+		}
+		return img.(image.Image), func() {}, nil
+	}), in
+}
+
+func expectColor(t *testing.T, img *image.YCbCr, p image.Point, c color.Color) {
+	t.Helper()
+	expected := color.YCbCrModel.Convert(c).(color.YCbCr)
+	got := img.YCbCrAt(p.X, p.Y)
+	diff := func(a, b uint8) bool { return int(a) > int(b)+2 || int(b) > int(a)+2 }
+	if diff(got.Y, expected.Y) || diff(got.Cb, expected.Cb) || diff(got.Cr, expected.Cr) {
+		t.Errorf("Expected %v at %v, got %v", expected, p, got)
+	}
+}
+
+var (
+	red   = color.RGBA{R: 0xFF, A: 0xFF}
+	green = color.RGBA{G: 0xFF, A: 0xFF}
+	blue  = color.RGBA{B: 0xFF, A: 0xFF}
+	black = color.RGBA{A: 0xFF}
+	white = color.RGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}
+)
+
+func TestGridLayout(t *testing.T) {
+	testCases := map[string]struct {
+		n        int
+		gap      int
+		expected []image.Rectangle
+	}{
+		"One": {n: 1, expected: []image.Rectangle{image.Rect(0, 0, 64, 32)}},
+		"Three": {n: 3, expected: []image.Rectangle{
+			image.Rect(0, 0, 32, 16), image.Rect(32, 0, 64, 16), image.Rect(0, 16, 32, 32),
+		}},
+		"Gap": {n: 2, gap: 4, expected: []image.Rectangle{
+			image.Rect(0, 0, 30, 32), image.Rect(34, 0, 64, 32),
+		}},
+	}
+	for name, c := range testCases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			layout := GridLayout(c.n, 64, 32, c.gap)
+			if len(layout) != len(c.expected) {
+				t.Fatalf("Expected %d inputs, got %d", len(c.expected), len(layout))
+			}
+			for i, l := range layout {
+				if l.Rect != c.expected[i] {
+					t.Errorf("Input %d: expected %v, got %v", i, c.expected[i], l.Rect)
+				}
+			}
+		})
+	}
+}
+
+func TestCompositor(t *testing.T) {
+	m := clock.NewManual(time.Unix(0, 0))
+	r0, in0 := newInput()
+	r1, in1 := newInput()
+	c := NewCompositor([]Reader{r0, r1}, 64, 32, 10, WithCompositorClock(m))
+	defer c.Close()
+	defer in1.Close()
+	defer in0.Close()
+
+	read := func() *image.YCbCr {
+		t.Helper()
+		img, _, err := c.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return img.(*image.YCbCr)
+	}
+
+	// no frame yet
+	img := read()
+	if img.Rect != image.Rect(0, 0, 64, 32) || img.SubsampleRatio != image.YCbCrSubsampleRatio420 {
+		t.Fatalf("Unexpected output %v %v", img.Rect, img.SubsampleRatio)
+	}
+	expectColor(t, img, image.Pt(10, 10), black)
+
+	in0.Send(solidI420(32, 32, red))
+	in1.Send(solidRGBA(32, 32, green))
+	m.Add(100 * time.Millisecond)
+	img = read()
+	expectColor(t, img, image.Pt(16, 16), red)
+	expectColor(t, img, image.Pt(48, 16), green)
+
+	t.Run("PiP", func(t *testing.T) {
+		layout := PiPLayout(2, 64, 32, 0.25, AnchorBottomRight, 2)
+		layout[1].Border = 2
+		layout[1].BorderColor = blue
+		c.SetLayout(layout)
+		// the first input is letterboxed, the second is drawn over it in (46, 22)-(62, 30)
+		m.Add(100 * time.Millisecond)
+		img := read()
+		expectColor(t, img, image.Pt(32, 16), red)
+		expectColor(t, img, image.Pt(4, 16), black)
+		expectColor(t, img, image.Pt(54, 26), green)
+		expectColor(t, img, image.Pt(46, 22), blue)
+
+		// the z-order puts the first input over the second
+		layout[0].Z = 2
+		c.SetLayout(layout)
+		m.Add(100 * time.Millisecond)
+		expectColor(t, read(), image.Pt(46, 24), red)
+	})
+
+	t.Run("Stalled", func(t *testing.T) {
+		c.SetLayout(GridLayout(2, 64, 32, 0))
+		m.Add(2 * time.Second)
+		// the last frames are held
+		img := read()
+		expectColor(t, img, image.Pt(16, 16), red)
+		expectColor(t, img, image.Pt(48, 16), green)
+	})
+
+	in0.Close()
+	in1.Close()
+	// the compositor ends at the next frame once it saw the end of the inputs
+	c.readers.Wait()
+	m.Add(100 * time.Millisecond)
+	if _, _, err := c.Read(); err != io.EOF {
+		t.Errorf("Expected io.EOF once the inputs ended, got %v", err)
+	}
+}
+
+func TestCompositorPlaceholder(t *testing.T) {
+	m := clock.NewManual(time.Unix(0, 0))
+	r0, in0 := newInput()
+	r1, in1 := newInput()
+	c := NewCompositor([]Reader{r0, r1}, 64, 32, 10,
+		WithCompositorClock(m),
+		WithLayout(GridLayout(2, 64, 32, 0)),
+		WithPlaceholder(solidRGBA(8, 8, white)),
+		WithStallTimeout(time.Second),
+	)
+
+	in0.Send(solidI420(32, 32, red))
+	img, _, err := c.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectColor(t, img.(*image.YCbCr), image.Pt(16, 16), red)
+	// the input without frames shows the placeholder
+	expectColor(t, img.(*image.YCbCr), image.Pt(48, 16), white)
+
+	m.Add(1500 * time.Millisecond)
+	img, _, err = c.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectColor(t, img.(*image.YCbCr), image.Pt(16, 16), white)
+
+	in0.Close()
+	in1.Close()
+	c.Close()
+	if _, _, err := c.Read(); err != io.EOF {
+		t.Errorf("Expected %v, got %v", io.EOF, err)
+	}
+}
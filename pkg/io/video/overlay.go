@@ -0,0 +1,244 @@
+package video
+
+import (
+	"image"
+	"image/color"
+	"time"
+
+	"github.com/pion/mediadevices/pkg/clock"
+	"golang.org/x/image/draw"
+)
+
+// OverlayFunc returns the image drawn over the frame number frame, counted from 0, read at t.
+// Returning nil draws nothing on the frame. The image can be reused across frames.
+type OverlayFunc func(frame int, t time.Time) image.Image
+
+// OverlayImage returns an OverlayFunc drawing img, e.g. a logo, on every frame.
+func OverlayImage(img image.Image) OverlayFunc {
+	return func(int, time.Time) image.Image {
+		return img
+	}
+}
+
+// OverlayAnchor is the corner of the frames an overlay is positioned from.
+type OverlayAnchor int
+
+// List of overlay anchors
+const (
+	AnchorTopLeft OverlayAnchor = iota
+	AnchorTopRight
+	AnchorBottomLeft
+	AnchorBottomRight
+	AnchorCenter
+)
+
+type overlayOptions struct {
+	anchor OverlayAnchor
+	offset image.Point
+	alpha  uint8
+	clock  clock.Clock
+}
+
+// OverlayOption configures Overlay.
+type OverlayOption func(*overlayOptions)
+
+// WithOverlayPosition positions the overlays at offset from the anchor, towards the center of
+// the frames. For AnchorCenter, the overlays are centered and moved by offset. The default is
+// the top left corner.
+func WithOverlayPosition(anchor OverlayAnchor, offset image.Point) OverlayOption {
+	return func(o *overlayOptions) {
+		o.anchor = anchor
+		o.offset = offset
+	}
+}
+
+// WithOverlayAlpha sets the opacity of the overlays, from 0 (invisible) to 1 (opaque, the
+// default), which is multiplied by their own alpha.
+func WithOverlayAlpha(alpha float64) OverlayOption {
+	return func(o *overlayOptions) {
+		if alpha < 0 {
+			alpha = 0
+		} else if alpha > 1 {
+			alpha = 1
+		}
+		o.alpha = uint8(alpha*0xFF + 0.5)
+	}
+}
+
+// WithOverlayClock sets the clock read once per frame to give the OverlayFunc its time
+// argument. The default is the system clock.
+func WithOverlayClock(c clock.Clock) OverlayOption {
+	return func(o *overlayOptions) {
+		o.clock = c
+	}
+}
+
+// overlayPoint returns the position of the top left corner of an overlay of size in frame.
+func (o *overlayOptions) overlayPoint(frame image.Rectangle, size image.Point) image.Point {
+	switch o.anchor {
+	case AnchorTopRight:
+		return image.Pt(frame.Max.X-size.X-o.offset.X, frame.Min.Y+o.offset.Y)
+	case AnchorBottomLeft:
+		return image.Pt(frame.Min.X+o.offset.X, frame.Max.Y-size.Y-o.offset.Y)
+	case AnchorBottomRight:
+		return frame.Max.Sub(size).Sub(o.offset)
+	case AnchorCenter:
+		return frame.Min.Add(frame.Size().Sub(size).Div(2)).Add(o.offset)
+	default:
+		return frame.Min.Add(o.offset)
+	}
+}
+
+// Overlay returns a transform drawing the images returned by content over the frames, e.g.
+// logos with OverlayImage, or labels and clocks with OverlayText. Several overlays can be
+// drawn by merging several transforms.
+//
+// YCbCr frames of any subsample ratio and RGBA frames are supported. The frames are copied
+// before drawing, so the source buffers aren't modified.
+func Overlay(content OverlayFunc, opts ...OverlayOption) TransformFunc {
+	options := overlayOptions{
+		alpha: 0xFF,
+		clock: clock.New(),
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	return func(r Reader) Reader {
+		var ycbcr *image.YCbCr
+		var rgba *image.RGBA
+		var frame int
+		// buffers accumulating the overlay over the chroma samples
+		var sumA, sumCb, sumCr []uint32
+
+		return ReaderFunc(func() (image.Image, func(), error) {
+			img, _, err := r.Read()
+			if err != nil {
+				return nil, func() {}, err
+			}
+
+			ov := content(frame, options.clock.Now())
+			frame++
+			if ov == nil || options.alpha == 0 {
+				return img, func() {}, nil
+			}
+
+			switch v := img.(type) {
+			case *image.YCbCr:
+				rect := image.Rect(0, 0, v.Rect.Dx(), v.Rect.Dy())
+				ycbcr = reuseYCbCr(ycbcr, rect, v.SubsampleRatio)
+				copyYCbCr(ycbcr, image.Point{}, v, v.Rect)
+
+				p := options.overlayPoint(rect, ov.Bounds().Size())
+				sumA, sumCb, sumCr = blendYCbCr(ycbcr, ov, p, options.alpha, sumA, sumCb, sumCr)
+
+				cloned := *ycbcr // clone metadata
+				return &cloned, func() {}, nil
+
+			case *image.RGBA:
+				rect := image.Rect(0, 0, v.Rect.Dx(), v.Rect.Dy())
+				rgba = reuseRGBA(rgba, rect)
+				copyRGBA(rgba, image.Point{}, v, v.Rect)
+
+				bounds := ov.Bounds()
+				p := options.overlayPoint(rect, bounds.Size())
+				draw.DrawMask(rgba, bounds.Add(p.Sub(bounds.Min)), ov, bounds.Min,
+					image.NewUniform(color.Alpha{A: options.alpha}), image.Point{}, draw.Over)
+
+				cloned := *rgba // clone metadata
+				return &cloned, func() {}, nil
+
+			default:
+				return nil, func() {}, errUnsupportedImageType
+			}
+		})
+	}
+}
+
+// overlayPixel returns the non-premultiplied color of the pixel (x, y) of img.
+func overlayPixel(img image.Image, x, y int) color.NRGBA {
+	if rgba, ok := img.(*image.RGBA); ok {
+		i := rgba.PixOffset(x, y)
+		c := color.RGBA{R: rgba.Pix[i+0], G: rgba.Pix[i+1], B: rgba.Pix[i+2], A: rgba.Pix[i+3]}
+		switch c.A {
+		case 0:
+			return color.NRGBA{}
+		case 0xFF:
+			return color.NRGBA(c)
+		}
+	}
+	return color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+}
+
+// blendYCbCr draws ov with its top left corner at p over dst, with the opacity alpha. The
+// chroma samples are blended with the average of the overlay pixels they cover, accumulated
+// in the given buffers, which are returned to be reused.
+func blendYCbCr(dst *image.YCbCr, ov image.Image, p image.Point, alpha uint8, sumA, sumCb, sumCr []uint32) ([]uint32, []uint32, []uint32) {
+	bounds := ov.Bounds()
+	delta := bounds.Min.Sub(p)
+	r := bounds.Sub(delta).Intersect(dst.Rect)
+	if r.Empty() {
+		return sumA, sumCb, sumCr
+	}
+
+	hs, vs := subsampleFactors(dst.SubsampleRatio)
+	cx0, cy0 := r.Min.X/hs, r.Min.Y/vs
+	cw, ch := (r.Max.X-1)/hs-cx0+1, (r.Max.Y-1)/vs-cy0+1
+	n := cw * ch
+	if cap(sumA) < n {
+		sumA, sumCb, sumCr = make([]uint32, n), make([]uint32, n), make([]uint32, n)
+	}
+	sumA, sumCb, sumCr = sumA[:n], sumCb[:n], sumCr[:n]
+	for i := range sumA {
+		sumA[i], sumCb[i], sumCr[i] = 0, 0, 0
+	}
+
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		yi := dst.YOffset(r.Min.X, y)
+		ci := (y/vs - cy0) * cw
+		for x := r.Min.X; x < r.Max.X; x++ {
+			c := overlayPixel(ov, x+delta.X, y+delta.Y)
+			a := uint32(c.A) * uint32(alpha) / 0xFF
+			if a != 0 {
+				oy, ocb, ocr := color.RGBToYCbCr(c.R, c.G, c.B)
+				dst.Y[yi] = uint8((uint32(dst.Y[yi])*(0xFF-a) + uint32(oy)*a + 0x7F) / 0xFF)
+
+				k := ci + x/hs - cx0
+				sumA[k] += a
+				sumCb[k] += uint32(ocb) * a
+				sumCr[k] += uint32(ocr) * a
+			}
+			yi++
+		}
+	}
+
+	// the pixels of the chroma samples not covered by the overlay are transparent
+	for cy := 0; cy < ch; cy++ {
+		rows := subsamplePixels(cy0+cy, vs, dst.Rect.Min.Y, dst.Rect.Max.Y)
+		ci := dst.COffset((cx0)*hs, (cy0+cy)*vs)
+		for cx := 0; cx < cw; cx++ {
+			k := cy*cw + cx
+			if sumA[k] == 0 {
+				continue
+			}
+			// the chroma samples on the edges of odd-sized frames cover less pixels
+			full := uint32(0xFF * rows * subsamplePixels(cx0+cx, hs, dst.Rect.Min.X, dst.Rect.Max.X))
+			dst.Cb[ci+cx] = uint8((uint32(dst.Cb[ci+cx])*(full-sumA[k]) + sumCb[k] + full/2) / full)
+			dst.Cr[ci+cx] = uint8((uint32(dst.Cr[ci+cx])*(full-sumA[k]) + sumCr[k] + full/2) / full)
+		}
+	}
+	return sumA, sumCb, sumCr
+}
+
+// subsamplePixels returns how many pixels within [min, max) the chroma sample c covers in a
+// dimension subsampled by s.
+func subsamplePixels(c, s, min, max int) int {
+	lo, hi := c*s, (c+1)*s
+	if lo < min {
+		lo = min
+	}
+	if hi > max {
+		hi = max
+	}
+	return hi - lo
+}
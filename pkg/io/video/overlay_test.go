@@ -0,0 +1,194 @@
+package video
+
+import (
+	"image"
+	"image/color"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/pion/mediadevices/pkg/clock"
+)
+
+func TestFormatOverlayText(t *testing.T) {
+	at := time.Date(2021, time.March, 4, 17, 6, 7, 89*int(time.Millisecond), time.UTC)
+
+	testCases := map[string]struct {
+		template string
+		expected string
+	}{
+		"Plain":    {"Camera 1", "Camera 1"},
+		"Date":     {"%Y/%m/%d %y", "2021/03/04 21"},
+		"Time":     {"%H:%M:%S.%L %I%p", "17:06:07.089 05PM"},
+		"Short":    {"%F %T %Z", "2021-03-04 17:06:07 UTC"},
+		"Names":    {"%a %A %b %B %j", "Thu Thursday Mar March 063"},
+		"Frame":    {"#%f", "#42"},
+		"Unix":     {"%s", "1614877567"},
+		"Escaped":  {"100%% %q", "100% %q"},
+		"Trailing": {"%", "%"},
+	}
+	for name, c := range testCases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			if s := FormatOverlayText(c.template, 42, at); s != c.expected {
+				t.Errorf("Expected %q, got %q", c.expected, s)
+			}
+		})
+	}
+}
+
+func TestOverlay(t *testing.T) {
+	red := color.RGBA{R: 0xFF, A: 0xFF}
+	logo := image.NewRGBA(image.Rect(0, 0, 4, 2))
+	fillRGBA(logo, red)
+	redYCbCr := color.YCbCrModel.Convert(red).(color.YCbCr)
+
+	t.Run("YCbCr", func(t *testing.T) {
+		src := image.NewYCbCr(image.Rect(0, 0, 16, 8), image.YCbCrSubsampleRatio420)
+		fillYCbCr(src, src.Rect, color.YCbCr{Y: 0x10, Cb: 0x80, Cr: 0x80})
+
+		transform := Overlay(OverlayImage(logo), WithOverlayPosition(AnchorTopRight, image.Pt(2, 2)))
+		img := readOne(t, transform, src).(*image.YCbCr)
+
+		// the logo covers (10, 2)-(14, 4)
+		if c := img.YCbCrAt(10, 2); c != redYCbCr {
+			t.Errorf("Expected %v, got %v", redYCbCr, c)
+		}
+		for _, p := range []image.Point{{9, 2}, {14, 2}, {10, 4}, {0, 0}} {
+			if c := img.YCbCrAt(p.X, p.Y); c.Y != 0x10 || c.Cb != 0x80 {
+				t.Errorf("Unexpected color %v at %v", c, p)
+			}
+		}
+		if c := src.YCbCrAt(10, 2); c.Y != 0x10 {
+			t.Error("The source frame must not be modified")
+		}
+	})
+
+	t.Run("OddPosition", func(t *testing.T) {
+		src := image.NewYCbCr(image.Rect(0, 0, 8, 8), image.YCbCrSubsampleRatio420)
+		fillYCbCr(src, src.Rect, color.YCbCr{Y: 0x10, Cb: 0x80, Cr: 0x80})
+
+		transform := Overlay(OverlayImage(logo), WithOverlayPosition(AnchorTopLeft, image.Pt(1, 1)))
+		img := readOne(t, transform, src).(*image.YCbCr)
+
+		// the chroma sample at (0, 0) is covered by one pixel of the logo out of four
+		c := img.YCbCrAt(0, 0)
+		expected := uint8((0x80*3 + int(redYCbCr.Cr) + 2) / 4)
+		if c.Y != 0x10 || c.Cr != expected {
+			t.Errorf("Expected Cr %d, got %v", expected, c)
+		}
+	})
+
+	t.Run("OddSize", func(t *testing.T) {
+		src := image.NewYCbCr(image.Rect(0, 0, 7, 5), image.YCbCrSubsampleRatio420)
+		fillYCbCr(src, src.Rect, color.YCbCr{Y: 0x10, Cb: 0x80, Cr: 0x80})
+
+		transform := Overlay(OverlayImage(logo), WithOverlayPosition(AnchorBottomRight, image.Point{}))
+		img := readOne(t, transform, src).(*image.YCbCr)
+
+		// the chroma sample at (6, 4) only covers this pixel, which is covered by the logo
+		if c := img.YCbCrAt(6, 4); c != redYCbCr {
+			t.Errorf("Expected %v, got %v", redYCbCr, c)
+		}
+	})
+
+	t.Run("RGBAAlpha", func(t *testing.T) {
+		src := image.NewRGBA(image.Rect(0, 0, 8, 8))
+		fillRGBA(src, color.RGBA{A: 0xFF})
+
+		transform := Overlay(OverlayImage(logo), WithOverlayPosition(AnchorCenter, image.Point{}), WithOverlayAlpha(0.5))
+		img := readOne(t, transform, src).(*image.RGBA)
+
+		if c := img.RGBAAt(4, 3); c.R < 0x7E || c.R > 0x81 || c.A != 0xFF {
+			t.Errorf("Expected a half transparent overlay, got %v", c)
+		}
+		if c := img.RGBAAt(4, 2); c.R != 0 {
+			t.Errorf("Unexpected color %v", c)
+		}
+	})
+
+	t.Run("Dynamic", func(t *testing.T) {
+		start := time.Unix(1000, 0)
+		m := clock.NewManual(start)
+		var frames []int
+		transform := Overlay(func(frame int, at time.Time) image.Image {
+			if !at.Equal(start.Add(time.Duration(frame) * time.Second)) {
+				t.Errorf("Unexpected time %v for frame %d", at, frame)
+			}
+			frames = append(frames, frame)
+			return nil
+		}, WithOverlayClock(m))
+
+		src := image.NewRGBA(image.Rect(0, 0, 2, 2))
+		r := transform(ReaderFunc(func() (image.Image, func(), error) {
+			return src, func() {}, nil
+		}))
+		for i := 0; i < 3; i++ {
+			img, _, err := r.Read()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if img != src {
+				t.Error("Expected the frame to be passed through without overlay")
+			}
+			m.Add(time.Second)
+		}
+		if len(frames) != 3 || frames[2] != 2 {
+			t.Errorf("Unexpected frames %v", frames)
+		}
+	})
+}
+
+func TestOverlayText(t *testing.T) {
+	text := OverlayText("%f\nCamera", WithTextSize(16), WithTextBackground(color.Black, 2))
+
+	img := text(7, time.Now())
+	bounds := img.Bounds()
+	if bounds.Dx() < 6*8 || bounds.Dy() < 2*16 {
+		t.Fatalf("Unexpected text size %v", bounds)
+	}
+	var lit int
+	rgba := img.(*image.RGBA)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := rgba.RGBAAt(x, y)
+			if c.A != 0xFF {
+				t.Fatalf("Expected an opaque background, got %v at (%d, %d)", c, x, y)
+			}
+			if c.R > 0x80 {
+				lit++
+			}
+		}
+	}
+	if lit == 0 {
+		t.Error("Expected the text to be drawn")
+	}
+
+	if text(7, time.Now()) != img {
+		t.Error("Expected the same text not to be rendered again")
+	}
+	if OverlayText("")(0, time.Now()) != nil {
+		t.Error("Expected no overlay for an empty text")
+	}
+}
+
+func TestOverlayTextConcurrent(t *testing.T) {
+	text := OverlayTextFunc(func(frame int, _ time.Time) string {
+		return strconv.Itoa(frame % 3)
+	}, WithTextSize(8))
+
+	done := make(chan struct{})
+	for i := 0; i < 2; i++ {
+		go func() {
+			defer func() { done <- struct{}{} }()
+			for frame := 0; frame < 20; frame++ {
+				if text(frame, time.Now()) == nil {
+					t.Error("Expected the text to be drawn")
+					return
+				}
+			}
+		}()
+	}
+	<-done
+	<-done
+}
@@ -0,0 +1,227 @@
+package video
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gomono"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+var (
+	bundledFont     *opentype.Font
+	bundledFontOnce sync.Once
+)
+
+// newBundledFace returns a face of the bundled font, Go Mono, of size pixels.
+func newBundledFace(size float64) font.Face {
+	bundledFontOnce.Do(func() {
+		var err error
+		if bundledFont, err = opentype.Parse(gomono.TTF); err != nil {
+			panic(err)
+		}
+	})
+	face, err := opentype.NewFace(bundledFont, &opentype.FaceOptions{
+		Size:    size,
+		DPI:     72,
+		Hinting: font.HintingFull,
+	})
+	if err != nil {
+		panic(err)
+	}
+	return face
+}
+
+type textOptions struct {
+	face       font.Face
+	size       float64
+	color      color.Color
+	background color.Color
+	padding    int
+}
+
+// TextOption configures OverlayText.
+type TextOption func(*textOptions)
+
+// WithTextFace sets the font face of the text, instead of the bundled font.
+func WithTextFace(face font.Face) TextOption {
+	return func(o *textOptions) {
+		o.face = face
+	}
+}
+
+// WithTextSize sets the size in pixels of the bundled font, 24 by default.
+func WithTextSize(size float64) TextOption {
+	return func(o *textOptions) {
+		o.size = size
+	}
+}
+
+// WithTextColor sets the color of the text, white by default.
+func WithTextColor(c color.Color) TextOption {
+	return func(o *textOptions) {
+		o.color = c
+	}
+}
+
+// WithTextBackground fills the box around the text with c, which keeps the text readable
+// over any frame. The box is transparent by default.
+func WithTextBackground(c color.Color, padding int) TextOption {
+	return func(o *textOptions) {
+		o.background = c
+		o.padding = padding
+	}
+}
+
+// OverlayText returns an OverlayFunc drawing text formatted from template, which can contain
+// the following directives, like strftime:
+//
+//	%Y year            %y year without the century  %m month (01-12)
+//	%d day (01-31)     %j day of the year (001-366) %H hour (00-23)
+//	%I hour (01-12)    %p AM or PM                  %M minute (00-59)
+//	%S second (00-59)  %L millisecond (000-999)     %s Unix time in seconds
+//	%a weekday (Mon)   %A weekday (Monday)          %b month (Jan)
+//	%B month (January) %Z time zone (UTC)           %z time zone offset (+0000)
+//	%F %Y-%m-%d        %T %H:%M:%S                  %f frame number
+//	%% a '%'
+//
+// The text can have several lines. It's only rendered again when it changes.
+func OverlayText(template string, opts ...TextOption) OverlayFunc {
+	return OverlayTextFunc(func(frame int, t time.Time) string {
+		return FormatOverlayText(template, frame, t)
+	}, opts...)
+}
+
+// OverlayTextFunc returns an OverlayFunc drawing the text returned by fn for each frame, e.g.
+// for labels changing at runtime. The OverlayFunc can be shared by overlays of several
+// readers, fn is then called concurrently.
+func OverlayTextFunc(fn func(frame int, t time.Time) string, opts ...TextOption) OverlayFunc {
+	options := textOptions{
+		size:  24,
+		color: color.White,
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if options.face == nil {
+		options.face = newBundledFace(options.size)
+	}
+
+	// the last rendered text, shared by the readers
+	var mu sync.Mutex
+	var text string
+	var img *image.RGBA
+	return func(frame int, t time.Time) image.Image {
+		s := fn(frame, t)
+		if s == "" {
+			return nil
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if img == nil || s != text {
+			text = s
+			img = renderText(s, &options)
+		}
+		return img
+	}
+}
+
+func renderText(s string, o *textOptions) *image.RGBA {
+	lines := strings.Split(s, "\n")
+	metrics := o.face.Metrics()
+	lineHeight := metrics.Height.Ceil()
+
+	var width int
+	for _, line := range lines {
+		if w := font.MeasureString(o.face, line).Ceil(); w > width {
+			width = w
+		}
+	}
+	img := image.NewRGBA(image.Rect(0, 0, width+2*o.padding, lineHeight*len(lines)+2*o.padding))
+	if o.background != nil {
+		draw.Draw(img, img.Rect, image.NewUniform(o.background), image.Point{}, draw.Src)
+	}
+
+	d := font.Drawer{
+		Dst:  img,
+		Src:  image.NewUniform(o.color),
+		Face: o.face,
+	}
+	for i, line := range lines {
+		d.Dot = fixed.P(o.padding, o.padding+i*lineHeight+metrics.Ascent.Ceil())
+		d.DrawString(line)
+	}
+	return img
+}
+
+// FormatOverlayText formats template for the frame number frame at t, as described in
+// OverlayText.
+func FormatOverlayText(template string, frame int, t time.Time) string {
+	var b strings.Builder
+	for i := 0; i < len(template); i++ {
+		if template[i] != '%' || i+1 == len(template) {
+			b.WriteByte(template[i])
+			continue
+		}
+		i++
+		switch template[i] {
+		case 'Y':
+			b.WriteString(t.Format("2006"))
+		case 'y':
+			b.WriteString(t.Format("06"))
+		case 'm':
+			b.WriteString(t.Format("01"))
+		case 'd':
+			b.WriteString(t.Format("02"))
+		case 'j':
+			b.WriteString(fmt.Sprintf("%03d", t.YearDay()))
+		case 'H':
+			b.WriteString(t.Format("15"))
+		case 'I':
+			b.WriteString(t.Format("03"))
+		case 'p':
+			b.WriteString(t.Format("PM"))
+		case 'M':
+			b.WriteString(t.Format("04"))
+		case 'S':
+			b.WriteString(t.Format("05"))
+		case 'L':
+			b.WriteString(t.Format(".000")[1:])
+		case 's':
+			b.WriteString(strconv.FormatInt(t.Unix(), 10))
+		case 'a':
+			b.WriteString(t.Format("Mon"))
+		case 'A':
+			b.WriteString(t.Format("Monday"))
+		case 'b':
+			b.WriteString(t.Format("Jan"))
+		case 'B':
+			b.WriteString(t.Format("January"))
+		case 'Z':
+			b.WriteString(t.Format("MST"))
+		case 'z':
+			b.WriteString(t.Format("-0700"))
+		case 'F':
+			b.WriteString(t.Format("2006-01-02"))
+		case 'T':
+			b.WriteString(t.Format("15:04:05"))
+		case 'f':
+			b.WriteString(strconv.Itoa(frame))
+		case '%':
+			b.WriteByte('%')
+		default:
+			// unknown directives are kept as is
+			b.WriteByte('%')
+			b.WriteByte(template[i])
+		}
+	}
+	return b.String()
+}
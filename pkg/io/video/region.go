@@ -0,0 +1,329 @@
+package video
+
+import (
+	"errors"
+	"image"
+	"image/color"
+)
+
+var errEmptyRegion = errors.New("region: the region doesn't intersect the frame")
+
+// FitMode defines how Fit keeps the aspect ratio of the frames.
+type FitMode int
+
+const (
+	// FitLetterbox scales the frames to fit in the output, and fills the
+	// remaining area with black bars.
+	FitLetterbox FitMode = iota
+	// FitCrop scales the frames to fill the output, and crops what overflows.
+	FitCrop
+)
+
+// subsampleFactors returns the horizontal and vertical chroma subsampling
+// factors of ratio.
+func subsampleFactors(ratio image.YCbCrSubsampleRatio) (int, int) {
+	switch ratio {
+	case image.YCbCrSubsampleRatio422:
+		return 2, 1
+	case image.YCbCrSubsampleRatio420:
+		return 2, 2
+	case image.YCbCrSubsampleRatio440:
+		return 1, 2
+	case image.YCbCrSubsampleRatio411:
+		return 4, 1
+	case image.YCbCrSubsampleRatio410:
+		return 4, 2
+	default:
+		return 1, 1
+	}
+}
+
+// alignDown rounds v down to a multiple of n, which keeps the chroma samples
+// of YCbCr images aligned.
+func alignDown(v, n int) int {
+	if m := v % n; m != 0 {
+		if m < 0 {
+			m += n
+		}
+		v -= m
+	}
+	return v
+}
+
+// reuseYCbCr returns img if it has the given size and subsample ratio, or a
+// new image otherwise.
+func reuseYCbCr(img *image.YCbCr, rect image.Rectangle, ratio image.YCbCrSubsampleRatio) *image.YCbCr {
+	if img != nil && img.Rect == rect && img.SubsampleRatio == ratio {
+		return img
+	}
+	return image.NewYCbCr(rect, ratio)
+}
+
+// reuseRGBA returns img if it has the given size, or a new image otherwise.
+func reuseRGBA(img *image.RGBA, rect image.Rectangle) *image.RGBA {
+	if img != nil && img.Rect == rect {
+		return img
+	}
+	return image.NewRGBA(rect)
+}
+
+// copyYCbCr copies the area r of src to dst at dp. Both images must have the
+// same subsample ratio, and r.Min and dp must be aligned on chroma samples.
+func copyYCbCr(dst *image.YCbCr, dp image.Point, src *image.YCbCr, r image.Rectangle) {
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		di := dst.YOffset(dp.X, dp.Y+y-r.Min.Y)
+		si := src.YOffset(r.Min.X, y)
+		copy(dst.Y[di:di+r.Dx()], src.Y[si:si+r.Dx()])
+	}
+
+	hs, vs := subsampleFactors(src.SubsampleRatio)
+	n := (r.Dx() + hs - 1) / hs
+	for y := r.Min.Y; y < r.Max.Y; y += vs {
+		di := dst.COffset(dp.X, dp.Y+y-r.Min.Y)
+		si := src.COffset(r.Min.X, y)
+		copy(dst.Cb[di:di+n], src.Cb[si:si+n])
+		copy(dst.Cr[di:di+n], src.Cr[si:si+n])
+	}
+}
+
+// fillYCbCr fills the area r of dst with c, including the chroma samples
+// partially covered by r.
+func fillYCbCr(dst *image.YCbCr, r image.Rectangle, c color.YCbCr) {
+	r = r.Intersect(dst.Rect)
+	if r.Empty() {
+		return
+	}
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		i := dst.YOffset(r.Min.X, y)
+		row := dst.Y[i : i+r.Dx()]
+		for x := range row {
+			row[x] = c.Y
+		}
+	}
+
+	hs, vs := subsampleFactors(dst.SubsampleRatio)
+	x0, x1 := r.Min.X/hs, (r.Max.X-1)/hs
+	for y := alignDown(r.Min.Y, vs); y < r.Max.Y; y += vs {
+		i := dst.COffset(x0*hs, y)
+		for x := 0; x <= x1-x0; x++ {
+			dst.Cb[i+x] = c.Cb
+			dst.Cr[i+x] = c.Cr
+		}
+	}
+}
+
+// copyRGBA copies the area r of src to dst at dp.
+func copyRGBA(dst *image.RGBA, dp image.Point, src *image.RGBA, r image.Rectangle) {
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		di := dst.PixOffset(dp.X, dp.Y+y-r.Min.Y)
+		si := src.PixOffset(r.Min.X, y)
+		copy(dst.Pix[di:di+4*r.Dx()], src.Pix[si:si+4*r.Dx()])
+	}
+}
+
+// fillRGBA fills dst with c.
+func fillRGBA(dst *image.RGBA, c color.RGBA) {
+	for i := 0; i < len(dst.Pix); i += 4 {
+		dst.Pix[i+0] = c.R
+		dst.Pix[i+1] = c.G
+		dst.Pix[i+2] = c.B
+		dst.Pix[i+3] = c.A
+	}
+}
+
+// Crop returns a transform keeping the area rect of the frames, in the
+// coordinates of the frames. The output frames start at (0, 0).
+//
+// For subsampled YCbCr frames, rect is moved to the closest chroma sample on
+// its top left, keeping its size, so that the chroma doesn't need to be
+// resampled.
+func Crop(rect image.Rectangle) TransformFunc {
+	return func(r Reader) Reader {
+		var ycbcr *image.YCbCr
+		var rgba *image.RGBA
+
+		return ReaderFunc(func() (image.Image, func(), error) {
+			img, _, err := r.Read()
+			if err != nil {
+				return nil, func() {}, err
+			}
+
+			switch v := img.(type) {
+			case *image.YCbCr:
+				hs, vs := subsampleFactors(v.SubsampleRatio)
+				area := rect.Sub(rect.Min).Add(image.Pt(alignDown(rect.Min.X, hs), alignDown(rect.Min.Y, vs)))
+				area = area.Intersect(v.Rect)
+				if area.Empty() {
+					return nil, func() {}, errEmptyRegion
+				}
+				ycbcr = reuseYCbCr(ycbcr, image.Rect(0, 0, area.Dx(), area.Dy()), v.SubsampleRatio)
+				copyYCbCr(ycbcr, image.Point{}, v, area)
+
+				cloned := *ycbcr // clone metadata
+				return &cloned, func() {}, nil
+
+			case *image.RGBA:
+				area := rect.Intersect(v.Rect)
+				if area.Empty() {
+					return nil, func() {}, errEmptyRegion
+				}
+				rgba = reuseRGBA(rgba, image.Rect(0, 0, area.Dx(), area.Dy()))
+				copyRGBA(rgba, image.Point{}, v, area)
+
+				cloned := *rgba // clone metadata
+				return &cloned, func() {}, nil
+
+			default:
+				return nil, func() {}, errUnsupportedImageType
+			}
+		})
+	}
+}
+
+// padLayout returns the area of src copied in a frame of size, and where, so
+// that src is centered. src is cropped if it's larger than size. Positions
+// are rounded down to multiples of hs and vs.
+func padLayout(src image.Rectangle, size image.Point, hs, vs int) (image.Rectangle, image.Point) {
+	area := src
+	var dp image.Point
+	if d := src.Dx() - size.X; d > 0 {
+		area.Min.X = alignDown(src.Min.X+d/2, hs)
+		area.Max.X = area.Min.X + size.X
+		if area.Max.X > src.Max.X {
+			area.Max.X = src.Max.X
+		}
+	} else {
+		dp.X = alignDown(-d/2, hs)
+	}
+	if d := src.Dy() - size.Y; d > 0 {
+		area.Min.Y = alignDown(src.Min.Y+d/2, vs)
+		area.Max.Y = area.Min.Y + size.Y
+		if area.Max.Y > src.Max.Y {
+			area.Max.Y = src.Max.Y
+		}
+	} else {
+		dp.Y = alignDown(-d/2, vs)
+	}
+	return area, dp
+}
+
+// Pad returns a transform centering the frames in frames of width x height,
+// filled with c. Frames larger than the output are cropped.
+func Pad(width, height int, c color.Color) TransformFunc {
+	if width <= 0 || height <= 0 {
+		panic("Both width and height must be positive!")
+	}
+	ycbcrColor := color.YCbCrModel.Convert(c).(color.YCbCr)
+	rgbaColor := color.RGBAModel.Convert(c).(color.RGBA)
+	rect := image.Rect(0, 0, width, height)
+
+	return func(r Reader) Reader {
+		var ycbcr *image.YCbCr
+		var rgba *image.RGBA
+
+		return ReaderFunc(func() (image.Image, func(), error) {
+			img, _, err := r.Read()
+			if err != nil {
+				return nil, func() {}, err
+			}
+
+			switch v := img.(type) {
+			case *image.YCbCr:
+				hs, vs := subsampleFactors(v.SubsampleRatio)
+				ycbcr = reuseYCbCr(ycbcr, rect, v.SubsampleRatio)
+				area, dp := padLayout(v.Rect, rect.Max, hs, vs)
+				fillYCbCr(ycbcr, rect, ycbcrColor)
+				copyYCbCr(ycbcr, dp, v, area)
+
+				cloned := *ycbcr // clone metadata
+				return &cloned, func() {}, nil
+
+			case *image.RGBA:
+				rgba = reuseRGBA(rgba, rect)
+				area, dp := padLayout(v.Rect, rect.Max, 1, 1)
+				fillRGBA(rgba, rgbaColor)
+				copyRGBA(rgba, dp, v, area)
+
+				cloned := *rgba // clone metadata
+				return &cloned, func() {}, nil
+
+			default:
+				return nil, func() {}, errUnsupportedImageType
+			}
+		})
+	}
+}
+
+// Fit returns a transform scaling the frames to width x height, keeping their
+// aspect ratio according to mode. Setting scaler=nil to use default scaler.
+// (ScalerNearestNeighbor)
+func Fit(width, height int, mode FitMode, scaler Scaler) TransformFunc {
+	if width <= 0 || height <= 0 {
+		panic("Both width and height must be positive!")
+	}
+
+	return func(r Reader) Reader {
+		// the pipeline depends on the size of the frames, it's created again
+		// when it changes
+		var current image.Image
+		source := ReaderFunc(func() (image.Image, func(), error) {
+			return current, func() {}, nil
+		})
+		var pipeline Reader
+		var size image.Point
+
+		return ReaderFunc(func() (image.Image, func(), error) {
+			img, _, err := r.Read()
+			if err != nil {
+				return nil, func() {}, err
+			}
+
+			bounds := img.Bounds()
+			if pipeline == nil || bounds.Size() != size {
+				size = bounds.Size()
+				if size.X <= 0 || size.Y <= 0 {
+					return nil, func() {}, errEmptyRegion
+				}
+				pipeline = fitPipeline(bounds, width, height, mode, scaler)(source)
+			}
+
+			current = img
+			return pipeline.Read()
+		})
+	}
+}
+
+func fitPipeline(bounds image.Rectangle, width, height int, mode FitMode, scaler Scaler) TransformFunc {
+	w, h := bounds.Dx(), bounds.Dy()
+	wider := w*height > h*width
+
+	if mode == FitCrop {
+		area := bounds
+		if wider {
+			cw := h * width / height
+			area.Min.X += (w - cw) / 2
+			area.Max.X = area.Min.X + cw
+		} else {
+			ch := w * height / width
+			area.Min.Y += (h - ch) / 2
+			area.Max.Y = area.Min.Y + ch
+		}
+		return Merge(Crop(area), Scale(width, height, scaler))
+	}
+
+	sw, sh := width, height
+	if wider {
+		sh = h * width / w
+	} else {
+		sw = w * height / h
+	}
+	// even sizes keep the chroma of subsampled frames aligned
+	sw, sh = alignDown(sw, 2), alignDown(sh, 2)
+	if sw < 2 {
+		sw = 2
+	}
+	if sh < 2 {
+		sh = 2
+	}
+	return Merge(Scale(sw, sh, scaler), Pad(width, height, color.Black))
+}
@@ -0,0 +1,170 @@
+package video
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// newGradientYCbCr creates a YCbCr image whose Y is x + 16*y, and whose chroma
+// samples are numbered in order
+func newGradientYCbCr(w, h int, ratio image.YCbCrSubsampleRatio) *image.YCbCr {
+	img := image.NewYCbCr(image.Rect(0, 0, w, h), ratio)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Y[img.YOffset(x, y)] = uint8(x + 16*y)
+		}
+	}
+	for i := range img.Cb {
+		img.Cb[i] = uint8(i)
+		img.Cr[i] = uint8(255 - i)
+	}
+	return img
+}
+
+func readOne(t *testing.T, transform TransformFunc, src image.Image) image.Image {
+	t.Helper()
+	r := transform(ReaderFunc(func() (image.Image, func(), error) {
+		return src, func() {}, nil
+	}))
+	img, _, err := r.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return img
+}
+
+func TestCrop(t *testing.T) {
+	ratios := map[string]image.YCbCrSubsampleRatio{
+		"I444": image.YCbCrSubsampleRatio444,
+		"I422": image.YCbCrSubsampleRatio422,
+		"I420": image.YCbCrSubsampleRatio420,
+		"I440": image.YCbCrSubsampleRatio440,
+		"I411": image.YCbCrSubsampleRatio411,
+		"I410": image.YCbCrSubsampleRatio410,
+	}
+
+	for name, ratio := range ratios {
+		ratio := ratio
+		t.Run(name, func(t *testing.T) {
+			src := newGradientYCbCr(12, 8, ratio)
+			// the origin is moved to the chroma sample on its top left
+			rect := image.Rect(5, 3, 10, 6)
+			hs, vs := subsampleFactors(ratio)
+			origin := image.Pt(5/hs*hs, 3/vs*vs)
+
+			img := readOne(t, Crop(rect), src).(*image.YCbCr)
+			if img.Rect != image.Rect(0, 0, 5, 3) || img.SubsampleRatio != ratio {
+				t.Fatalf("Unexpected crop: %v %v", img.Rect, img.SubsampleRatio)
+			}
+			for y := 0; y < 3; y++ {
+				for x := 0; x < 5; x++ {
+					got := img.YCbCrAt(x, y)
+					expected := src.YCbCrAt(origin.X+x, origin.Y+y)
+					if got != expected {
+						t.Fatalf("Expected %v at (%d, %d), got %v", expected, x, y, got)
+					}
+				}
+			}
+		})
+	}
+
+	t.Run("RGBA", func(t *testing.T) {
+		src := image.NewRGBA(image.Rect(0, 0, 4, 4))
+		src.SetRGBA(3, 2, color.RGBA{R: 0xFF, A: 0xFF})
+		img := readOne(t, Crop(image.Rect(3, 2, 10, 10)), src).(*image.RGBA)
+		if img.Rect != image.Rect(0, 0, 1, 2) {
+			t.Fatalf("Expected the crop to be clipped to the frame, got %v", img.Rect)
+		}
+		if c := img.RGBAAt(0, 0); c.R != 0xFF {
+			t.Errorf("Unexpected pixel %v", c)
+		}
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		r := Crop(image.Rect(10, 10, 20, 20))(ReaderFunc(func() (image.Image, func(), error) {
+			return image.NewRGBA(image.Rect(0, 0, 4, 4)), func() {}, nil
+		}))
+		if _, _, err := r.Read(); err != errEmptyRegion {
+			t.Errorf("Expected %v, got %v", errEmptyRegion, err)
+		}
+	})
+}
+
+func TestPad(t *testing.T) {
+	red := color.RGBA{R: 0xFF, A: 0xFF}
+
+	t.Run("I420", func(t *testing.T) {
+		src := newGradientYCbCr(4, 2, image.YCbCrSubsampleRatio420)
+		img := readOne(t, Pad(8, 6, red), src).(*image.YCbCr)
+		if img.Rect != image.Rect(0, 0, 8, 6) {
+			t.Fatalf("Unexpected size %v", img.Rect)
+		}
+		// the frame is centered at (2, 2)
+		if got, expected := img.YCbCrAt(3, 3), src.YCbCrAt(1, 1); got != expected {
+			t.Errorf("Expected %v, got %v", expected, got)
+		}
+		expected := color.YCbCrModel.Convert(red).(color.YCbCr)
+		for _, p := range []image.Point{{0, 0}, {7, 5}, {1, 3}, {6, 2}} {
+			if got := img.YCbCrAt(p.X, p.Y); got != expected {
+				t.Errorf("Expected padding %v at %v, got %v", expected, p, got)
+			}
+		}
+	})
+
+	t.Run("RGBALarger", func(t *testing.T) {
+		src := image.NewRGBA(image.Rect(0, 0, 6, 2))
+		src.SetRGBA(3, 0, red)
+		img := readOne(t, Pad(2, 4, color.White), src).(*image.RGBA)
+		if img.Rect != image.Rect(0, 0, 2, 4) {
+			t.Fatalf("Unexpected size %v", img.Rect)
+		}
+		// the center of the frame is kept
+		if c := img.RGBAAt(1, 1); c != red {
+			t.Errorf("Expected %v, got %v", red, c)
+		}
+		if c := img.RGBAAt(0, 0); c != (color.RGBA{0xFF, 0xFF, 0xFF, 0xFF}) {
+			t.Errorf("Expected white padding, got %v", c)
+		}
+	})
+}
+
+func TestFit(t *testing.T) {
+	white := color.RGBA{0xFF, 0xFF, 0xFF, 0xFF}
+	src := image.NewRGBA(image.Rect(0, 0, 8, 4))
+	fillRGBA(src, white)
+
+	cases := map[string]struct {
+		mode FitMode
+		// top is the pixel at the middle of the first row
+		top color.RGBA
+	}{
+		"Letterbox": {mode: FitLetterbox, top: color.RGBA{A: 0xFF}},
+		"Crop":      {mode: FitCrop, top: white},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			r := Fit(8, 8, c.mode, nil)(ReaderFunc(func() (image.Image, func(), error) {
+				return src, func() {}, nil
+			}))
+
+			for i := 0; i < 2; i++ {
+				img, _, err := r.Read()
+				if err != nil {
+					t.Fatal(err)
+				}
+				rgba := img.(*image.RGBA)
+				if rgba.Rect != image.Rect(0, 0, 8, 8) {
+					t.Fatalf("Unexpected size %v", rgba.Rect)
+				}
+				if p := rgba.RGBAAt(4, 0); p != c.top {
+					t.Errorf("Expected %v at the top, got %v", c.top, p)
+				}
+				if p := rgba.RGBAAt(4, 4); p != white {
+					t.Errorf("Expected the frame at the center, got %v", p)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package video
+
+import (
+	"image"
+)
+
+// Orientation describes how frames are rotated and mirrored.
+type Orientation struct {
+	// Rotation is the clockwise rotation in degrees, a multiple of 90
+	Rotation int
+	// Flip mirrors the frames horizontally, before the rotation
+	Flip bool
+}
+
+// Transform returns a transform applying the orientation to the frame pixels.
+func (o Orientation) Transform() TransformFunc {
+	return orient(normalizeRotation(o.Rotation), o.Flip)
+}
+
+// CVO returns the orientation in the format of the video-orientation RTP header extension
+// (urn:3gpp:video-orientation, 3GPP TS 26.114). Like in WebRTC, the rotation is the one the
+// receiver applies to display the frames, and the camera bit is left to 0.
+func (o Orientation) CVO() byte {
+	b := byte(normalizeRotation(o.Rotation) / 90)
+	if o.Flip {
+		b |= 0x04
+	}
+	return b
+}
+
+// OrientationFromCVO parses the byte of the video-orientation RTP header extension.
+func OrientationFromCVO(b byte) Orientation {
+	return Orientation{
+		Rotation: int(b&0x03) * 90,
+		Flip:     b&0x04 != 0,
+	}
+}
+
+// normalizeRotation returns deg in [0, 360), and panics if it isn't a multiple of 90.
+func normalizeRotation(deg int) int {
+	if deg%90 != 0 {
+		panic("Rotation must be a multiple of 90 degrees!")
+	}
+	deg %= 360
+	if deg < 0 {
+		deg += 360
+	}
+	return deg
+}
+
+// Rotate returns a transform rotating the frames clockwise by deg degrees, which must be a
+// multiple of 90.
+//
+// I420, I422, I444 and RGBA frames are rotated natively. Rotating I422 frames by 90 or 270
+// degrees gives I440 frames. Other frames are converted to RGBA.
+func Rotate(deg int) TransformFunc {
+	return orient(normalizeRotation(deg), false)
+}
+
+// Flip returns a transform mirroring the frames horizontally (left to right) and/or
+// vertically (top to bottom).
+func Flip(horizontal, vertical bool) TransformFunc {
+	switch {
+	case horizontal && vertical:
+		return orient(180, false)
+	case vertical:
+		return orient(180, true)
+	default:
+		return orient(0, horizontal)
+	}
+}
+
+// orientedRatio returns the subsample ratio of frames of the given ratio rotated by rot, if it
+// exists.
+func orientedRatio(ratio image.YCbCrSubsampleRatio, rot int) (image.YCbCrSubsampleRatio, bool) {
+	if rot == 0 || rot == 180 {
+		return ratio, true
+	}
+	switch ratio {
+	case image.YCbCrSubsampleRatio444, image.YCbCrSubsampleRatio420:
+		return ratio, true
+	case image.YCbCrSubsampleRatio422:
+		return image.YCbCrSubsampleRatio440, true
+	case image.YCbCrSubsampleRatio440:
+		return image.YCbCrSubsampleRatio422, true
+	default:
+		return ratio, false
+	}
+}
+
+// orientedRect returns the bounds of frames of size r rotated by rot.
+func orientedRect(r image.Rectangle, rot int) image.Rectangle {
+	if rot == 90 || rot == 270 {
+		return image.Rect(0, 0, r.Dy(), r.Dx())
+	}
+	return image.Rect(0, 0, r.Dx(), r.Dy())
+}
+
+// orientPlane writes the plane src of w x h pixels of bpp bytes to dst, mirrored horizontally
+// if flip is set, then rotated clockwise by rot.
+func orientPlane(dst []uint8, dstStride int, src []uint8, srcStride, w, h, bpp, rot int, flip bool) {
+	// the position of the source pixel (x, y) in dst is start + x*xStep + y*yStep
+	var start, xStep, yStep int
+	switch rot {
+	case 90:
+		start, xStep, yStep = (h-1)*bpp, dstStride, -bpp
+	case 180:
+		start, xStep, yStep = (h-1)*dstStride+(w-1)*bpp, -bpp, -dstStride
+	case 270:
+		start, xStep, yStep = (w-1)*dstStride, -dstStride, bpp
+	default:
+		start, xStep, yStep = 0, bpp, dstStride
+	}
+	if flip {
+		start += (w - 1) * xStep
+		xStep = -xStep
+	}
+	remapPlane(dst, start, xStep, yStep, src, srcStride, w, h, bpp)
+}
+
+func orientYCbCr(dst, src *image.YCbCr, rot int, flip bool) {
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+	orientPlane(dst.Y, dst.YStride, src.Y[src.YOffset(src.Rect.Min.X, src.Rect.Min.Y):], src.YStride, w, h, 1, rot, flip)
+
+	hs, vs := subsampleFactors(src.SubsampleRatio)
+	cw, ch := (w+hs-1)/hs, (h+vs-1)/vs
+	ci := src.COffset(src.Rect.Min.X, src.Rect.Min.Y)
+	orientPlane(dst.Cb, dst.CStride, src.Cb[ci:], src.CStride, cw, ch, 1, rot, flip)
+	orientPlane(dst.Cr, dst.CStride, src.Cr[ci:], src.CStride, cw, ch, 1, rot, flip)
+}
+
+func orientRGBA(dst, src *image.RGBA, rot int, flip bool) {
+	orientPlane(dst.Pix, dst.Stride, src.Pix[src.PixOffset(src.Rect.Min.X, src.Rect.Min.Y):], src.Stride,
+		src.Rect.Dx(), src.Rect.Dy(), 4, rot, flip)
+}
+
+func orient(rot int, flip bool) TransformFunc {
+	return func(r Reader) Reader {
+		if rot == 0 && !flip {
+			return r
+		}
+
+		var ycbcr *image.YCbCr
+		var rgba *image.RGBA
+		var converted image.RGBA
+
+		return ReaderFunc(func() (image.Image, func(), error) {
+			img, _, err := r.Read()
+			if err != nil {
+				return nil, func() {}, err
+			}
+
+			switch v := img.(type) {
+			case *image.YCbCr:
+				if ratio, ok := orientedRatio(v.SubsampleRatio, rot); ok {
+					ycbcr = reuseYCbCr(ycbcr, orientedRect(v.Rect, rot), ratio)
+					orientYCbCr(ycbcr, v, rot, flip)
+
+					cloned := *ycbcr // clone metadata
+					return &cloned, func() {}, nil
+				}
+			case *image.RGBA:
+				rgba = reuseRGBA(rgba, orientedRect(v.Rect, rot))
+				orientRGBA(rgba, v, rot, flip)
+
+				cloned := *rgba // clone metadata
+				return &cloned, func() {}, nil
+			}
+
+			imageToRGBA(&converted, img)
+			rgba = reuseRGBA(rgba, orientedRect(converted.Rect, rot))
+			orientRGBA(rgba, &converted, rot, flip)
+
+			cloned := *rgba // clone metadata
+			return &cloned, func() {}, nil
+		})
+	}
+}
@@ -0,0 +1,37 @@
+#include <string.h>
+
+#include "rotate_cgo.h"
+
+void remapPlaneCGO(
+    unsigned char* dst,
+    const int start, const int x_step, const int y_step,
+    const unsigned char* src,
+    const int src_stride, const int w, const int h, const int bpp)
+{
+  int x, y;
+  for (y = 0; y < h; ++y)
+  {
+    const unsigned char* s = src + y * src_stride;
+    unsigned char* d = dst + start + y * y_step;
+    if (x_step == bpp)
+    {
+      memcpy(d, s, w * bpp);
+      continue;
+    }
+    if (bpp == 1)
+    {
+      for (x = 0; x < w; ++x)
+      {
+        *d = s[x];
+        d += x_step;
+      }
+      continue;
+    }
+    for (x = 0; x < w; ++x)
+    {
+      memcpy(d, s, bpp);
+      s += bpp;
+      d += x_step;
+    }
+  }
+}
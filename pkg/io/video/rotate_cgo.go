@@ -0,0 +1,28 @@
+//go:build cgo
+// +build cgo
+
+package video
+
+// #include "rotate_cgo.h"
+// #cgo CFLAGS: -std=c11
+import "C"
+
+// remapPlane copies the rows of w pixels of bpp bytes of src to dst, moving the source pixel
+// (x, y) to start + x*xStep + y*yStep.
+func remapPlane(dst []uint8, start, xStep, yStep int, src []uint8, srcStride, w, h, bpp int) {
+	if w <= 0 || h <= 0 {
+		return
+	}
+	// the bounds aren't checked in C, the corners of the plane are checked here
+	for _, i := range []int{start, start + (w-1)*xStep, start + (h-1)*yStep, start + (w-1)*xStep + (h-1)*yStep} {
+		_, _ = dst[i], dst[i+bpp-1]
+	}
+	_ = src[(h-1)*srcStride+w*bpp-1]
+
+	C.remapPlaneCGO(
+		(*C.uchar)(&dst[0]),
+		C.int(start), C.int(xStep), C.int(yStep),
+		(*C.uchar)(&src[0]),
+		C.int(srcStride), C.int(w), C.int(h), C.int(bpp),
+	)
+}
@@ -0,0 +1,5 @@
+void remapPlaneCGO(
+    unsigned char* dst,
+    const int start, const int x_step, const int y_step,
+    const unsigned char* src,
+    const int src_stride, const int w, const int h, const int bpp);
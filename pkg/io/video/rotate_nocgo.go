@@ -0,0 +1,39 @@
+//go:build !cgo
+// +build !cgo
+
+package video
+
+// remapPlane copies the rows of w pixels of bpp bytes of src to dst, moving the source pixel
+// (x, y) to start + x*xStep + y*yStep.
+func remapPlane(dst []uint8, start, xStep, yStep int, src []uint8, srcStride, w, h, bpp int) {
+	for y := 0; y < h; y++ {
+		s := y * srcStride
+		d := start + y*yStep
+		if xStep == bpp {
+			copy(dst[d:d+w*bpp], src[s:s+w*bpp])
+			continue
+		}
+		switch bpp {
+		case 1:
+			for x := 0; x < w; x++ {
+				dst[d] = src[s+x]
+				d += xStep
+			}
+		case 4:
+			for x := 0; x < w; x++ {
+				dst[d+0] = src[s+0]
+				dst[d+1] = src[s+1]
+				dst[d+2] = src[s+2]
+				dst[d+3] = src[s+3]
+				s += 4
+				d += xStep
+			}
+		default:
+			for x := 0; x < w; x++ {
+				copy(dst[d:d+bpp], src[s:s+bpp])
+				s += bpp
+				d += xStep
+			}
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package video
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// orientedPoint returns where the pixel (x, y) of a w x h frame is moved by the orientation
+func orientedPoint(x, y, w, h, rot int, flip bool) image.Point {
+	if flip {
+		x = w - 1 - x
+	}
+	switch rot {
+	case 90:
+		return image.Pt(h-1-y, x)
+	case 180:
+		return image.Pt(w-1-x, h-1-y)
+	case 270:
+		return image.Pt(y, w-1-x)
+	default:
+		return image.Pt(x, y)
+	}
+}
+
+func TestOrientation(t *testing.T) {
+	ratios := map[string]image.YCbCrSubsampleRatio{
+		"I444": image.YCbCrSubsampleRatio444,
+		"I422": image.YCbCrSubsampleRatio422,
+		"I420": image.YCbCrSubsampleRatio420,
+		"I440": image.YCbCrSubsampleRatio440,
+	}
+	rgba := image.NewRGBA(image.Rect(0, 0, 8, 4))
+	for i := range rgba.Pix {
+		rgba.Pix[i] = uint8(i)
+	}
+
+	for _, rot := range []int{0, 90, 180, 270} {
+		for _, flip := range []bool{false, true} {
+			o := Orientation{Rotation: rot, Flip: flip}
+
+			for name, ratio := range ratios {
+				src := newGradientYCbCr(8, 4, ratio)
+				img := readOne(t, o.Transform(), src).(*image.YCbCr)
+				if expected := orientedRect(src.Rect, rot); img.Rect != expected {
+					t.Fatalf("%s %+v: expected %v, got %v", name, o, expected, img.Rect)
+				}
+				for y := 0; y < 4; y++ {
+					for x := 0; x < 8; x++ {
+						p := orientedPoint(x, y, 8, 4, rot, flip)
+						if got, expected := img.YCbCrAt(p.X, p.Y), src.YCbCrAt(x, y); got != expected {
+							t.Fatalf("%s %+v: expected %v at %v, got %v", name, o, expected, p, got)
+						}
+					}
+				}
+			}
+
+			img := readOne(t, o.Transform(), rgba).(*image.RGBA)
+			for y := 0; y < 4; y++ {
+				for x := 0; x < 8; x++ {
+					p := orientedPoint(x, y, 8, 4, rot, flip)
+					if got, expected := img.RGBAAt(p.X, p.Y), rgba.RGBAAt(x, y); got != expected {
+						t.Fatalf("RGBA %+v: expected %v at %v, got %v", o, expected, p, got)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestRotate(t *testing.T) {
+	t.Run("OddSize", func(t *testing.T) {
+		src := newGradientYCbCr(5, 3, image.YCbCrSubsampleRatio420)
+		img := readOne(t, Rotate(-90), src).(*image.YCbCr)
+		if img.Rect != image.Rect(0, 0, 3, 5) {
+			t.Fatalf("Unexpected size %v", img.Rect)
+		}
+		if got, expected := img.Y[img.YOffset(0, 4)], src.Y[src.YOffset(0, 0)]; got != expected {
+			t.Errorf("Expected %d, got %d", expected, got)
+		}
+	})
+
+	t.Run("Fallback", func(t *testing.T) {
+		src := image.NewGray(image.Rect(0, 0, 3, 2))
+		src.SetGray(2, 0, color.Gray{Y: 0xFF})
+		img := readOne(t, Rotate(90), src).(*image.RGBA)
+		if img.Rect != image.Rect(0, 0, 2, 3) {
+			t.Fatalf("Unexpected size %v", img.Rect)
+		}
+		if c := img.RGBAAt(1, 2); c != (color.RGBA{0xFF, 0xFF, 0xFF, 0xFF}) {
+			t.Errorf("Unexpected pixel %v", c)
+		}
+	})
+
+	t.Run("InvalidAngle", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Error("Expected a panic")
+			}
+		}()
+		Rotate(45)
+	})
+}
+
+func TestFlip(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 3, 2))
+	src.SetRGBA(0, 0, color.RGBA{R: 0xFF, A: 0xFF})
+
+	testCases := map[string]struct {
+		horizontal, vertical bool
+		expected             image.Point
+	}{
+		"None":       {expected: image.Pt(0, 0)},
+		"Horizontal": {horizontal: true, expected: image.Pt(2, 0)},
+		"Vertical":   {vertical: true, expected: image.Pt(0, 1)},
+		"Both":       {horizontal: true, vertical: true, expected: image.Pt(2, 1)},
+	}
+	for name, c := range testCases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			img := readOne(t, Flip(c.horizontal, c.vertical), src).(*image.RGBA)
+			if p := img.RGBAAt(c.expected.X, c.expected.Y); p.R != 0xFF {
+				t.Errorf("Expected the red pixel at %v", c.expected)
+			}
+		})
+	}
+}
+
+func TestOrientationCVO(t *testing.T) {
+	testCases := map[string]struct {
+		orientation Orientation
+		cvo         byte
+	}{
+		"None":        {Orientation{}, 0x00},
+		"90":          {Orientation{Rotation: 90}, 0x01},
+		"180Flip":     {Orientation{Rotation: 180, Flip: true}, 0x06},
+		"Negative270": {Orientation{Rotation: -90}, 0x03},
+	}
+	for name, c := range testCases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			if cvo := c.orientation.CVO(); cvo != c.cvo {
+				t.Fatalf("Expected 0x%02x, got 0x%02x", c.cvo, cvo)
+			}
+			o := OrientationFromCVO(c.cvo)
+			if o.CVO() != c.cvo {
+				t.Errorf("Unexpected orientation %+v", o)
+			}
+		})
+	}
+}
+
+func BenchmarkRotate(b *testing.B) {
+	src := image.NewYCbCr(image.Rect(0, 0, 1280, 720), image.YCbCrSubsampleRatio420)
+	r := Rotate(90)(ReaderFunc(func() (image.Image, func(), error) {
+		return src, func() {}, nil
+	}))
+	for i := 0; i < b.N; i++ {
+		if _, _, err := r.Read(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -85,6 +85,13 @@ func Scale(width, height int, scaler Scaler) TransformFunc {
 			case image.YCbCrSubsampleRatio420:
 				rect.Max.X /= 2
 				rect.Max.Y /= 2
+			case image.YCbCrSubsampleRatio440:
+				rect.Max.Y /= 2
+			case image.YCbCrSubsampleRatio411:
+				rect.Max.X /= 4
+			case image.YCbCrSubsampleRatio410:
+				rect.Max.X /= 4
+				rect.Max.Y /= 2
 			}
 			return rect
 		}
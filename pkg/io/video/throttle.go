@@ -3,13 +3,20 @@ package video
 import (
 	"image"
 	"time"
+
+	"github.com/pion/mediadevices/pkg/clock"
 )
 
 // Throttle returns video throttling transform.
 // This transform drops some of the incoming frames to achieve given framerate in fps.
 func Throttle(rate float32) TransformFunc {
+	return ThrottleWithClock(rate, clock.New())
+}
+
+// ThrottleWithClock is Throttle, the frames being let through on the ticks of c.
+func ThrottleWithClock(rate float32, c clock.Clock) TransformFunc {
 	return func(r Reader) Reader {
-		ticker := time.NewTicker(time.Duration(int64(float64(time.Second) / float64(rate))))
+		ticker := c.NewTicker(time.Duration(int64(float64(time.Second) / float64(rate))))
 		return ReaderFunc(func() (image.Image, func(), error) {
 			for {
 				img, _, err := r.Read()
@@ -18,7 +25,7 @@ func Throttle(rate float32) TransformFunc {
 					return nil, func() {}, err
 				}
 				select {
-				case <-ticker.C:
+				case <-ticker.C():
 					return img, func() {}, nil
 				default:
 				}
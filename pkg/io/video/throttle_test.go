@@ -5,6 +5,8 @@ import (
 	"runtime"
 	"testing"
 	"time"
+
+	"github.com/pion/mediadevices/pkg/clock"
 )
 
 func TestThrottle(t *testing.T) {
@@ -37,3 +39,26 @@ func TestThrottle(t *testing.T) {
 	}
 	t.Log(cntPush)
 }
+
+func TestThrottleWithClock(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 1, 1))
+	c := clock.NewManual(time.Unix(0, 0))
+
+	// The source produces frames at 100 fps
+	var cntPush int
+	trans := ThrottleWithClock(25, c)
+	r := trans(ReaderFunc(func() (image.Image, func(), error) {
+		c.Add(10 * time.Millisecond)
+		cntPush++
+		return img, func() {}, nil
+	}))
+
+	for i := 0; i < 10; i++ {
+		if _, _, err := r.Read(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if cntPush != 40 {
+		t.Fatalf("Expected 40 pushed images, got %d", cntPush)
+	}
+}
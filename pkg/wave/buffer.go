@@ -142,6 +142,9 @@ func (buff *Buffer) StoreCopy(src Audio) {
 		clone.Data = buff.bufferInt16NonInterleaved
 		buff.tmp = clone
 
+	case interface{ copyTo(tmp Audio) Audio }:
+		buff.tmp = src.copyTo(buff.tmp)
+
 	default:
 		// TODO: Should have a routine to convert any format to one of the supported formats above
 		panic(errUnsupportedFormat)
@@ -94,6 +94,38 @@ func TestBufferStoreCopyAndLoad(t *testing.T) {
 				}
 			},
 		},
+		"Int24Interleaved": {
+			New: func() EditableAudio {
+				return NewInt24Interleaved(chunkInfo)
+			},
+			Update: func(src EditableAudio) {
+				src.Set(1, 1, Int24Sample(2))
+			},
+			Validate: func(t *testing.T, original Audio, clone Audio) {
+				ok := reflect.ValueOf(original.(*Int24Interleaved).Data).Pointer() != reflect.ValueOf(clone.(*Int24Interleaved).Data).Pointer()
+				if !ok {
+					t.Error(errIdenticalAddress)
+				}
+			},
+		},
+		"Int32NonInterleaved": {
+			New: func() EditableAudio {
+				return NewInt32NonInterleaved(chunkInfo)
+			},
+			Update: func(src EditableAudio) {
+				src.Set(1, 1, Int32Sample(2))
+			},
+			Validate: func(t *testing.T, original Audio, clone Audio) {
+				originalReal := original.(*Int32NonInterleaved)
+				cloneReal := clone.(*Int32NonInterleaved)
+				for i := range cloneReal.Data {
+					if reflect.ValueOf(originalReal.Data[i]).Pointer() == reflect.ValueOf(cloneReal.Data[i]).Pointer() {
+						err := fmt.Errorf("Channel %d memory address should be different", i)
+						t.Errorf("%v: %s", errIdenticalAddress, err)
+					}
+				}
+			},
+		},
 	}
 
 	buffer := NewBuffer()
@@ -11,6 +11,8 @@ import (
 // Format represents how audio is formatted in memory
 type Format fmt.Stringer
 
+// RawFormat describes raw audio samples. The integer samples of 1 byte are unsigned, the
+// samples of 3 bytes are packed 24-bits integers.
 type RawFormat struct {
 	SampleSize  int
 	IsFloat     bool
@@ -49,6 +51,12 @@ func init() {
 		newInt16NonInterleavedDecoder,
 		newFloat32InterleavedDecoder,
 		newFloat32NonInterleavedDecoder,
+		newUint8InterleavedDecoder,
+		newUint8NonInterleavedDecoder,
+		newInt24InterleavedDecoder,
+		newInt24NonInterleavedDecoder,
+		newInt32InterleavedDecoder,
+		newInt32NonInterleavedDecoder,
 	}
 
 	for _, decoderBuilder := range decoderBuilders {
@@ -303,3 +311,145 @@ func newFloat32NonInterleavedDecoder() (Decoder, Format) {
 
 	return decoder, format
 }
+
+// newPCMDecoder returns a decoder of format, calling decode with the size of the chunk
+func newPCMDecoder(format *RawFormat, decode func(endian binary.ByteOrder, chunkInfo ChunkInfo, chunk []byte) Audio) Decoder {
+	return DecoderFunc(func(endian binary.ByteOrder, chunk []byte, channels int) (Audio, error) {
+		chunkInfo, err := calculateChunkInfo(chunk, channels, format.SampleSize)
+		if err != nil {
+			return nil, err
+		}
+		return decode(endian, chunkInfo, chunk), nil
+	})
+}
+
+// decodeSamples calls set with the bytes of each sample of chunk
+func decodeSamples(chunk []byte, chunkInfo ChunkInfo, sampleSize int, interleaved bool, set func(i, ch int, sample []byte)) {
+	for ch := 0; ch < chunkInfo.Channels; ch++ {
+		for i := 0; i < chunkInfo.Len; i++ {
+			offset := (ch*chunkInfo.Len + i) * sampleSize
+			if interleaved {
+				offset = (i*chunkInfo.Channels + ch) * sampleSize
+			}
+			set(i, ch, chunk[offset:offset+sampleSize])
+		}
+	}
+}
+
+// readInt24 reads a packed 24-bits signed integer in endian byte order
+func readInt24(endian binary.ByteOrder, b []byte) Int24Sample {
+	var v uint32
+	if endian.Uint16([]byte{0x12, 0x34}) == 0x1234 {
+		v = uint32(b[0])<<16 | uint32(b[1])<<8 | uint32(b[2])
+	} else {
+		v = uint32(b[2])<<16 | uint32(b[1])<<8 | uint32(b[0])
+	}
+	// the sign bit is extended from the 24th bit
+	return Int24Sample(int32(v<<8) >> 8)
+}
+
+func newUint8InterleavedDecoder() (Decoder, Format) {
+	format := &RawFormat{
+		SampleSize:  1,
+		IsFloat:     false,
+		Interleaved: true,
+	}
+
+	decoder := newPCMDecoder(format, func(_ binary.ByteOrder, chunkInfo ChunkInfo, chunk []byte) Audio {
+		container := NewUint8Interleaved(chunkInfo)
+		copy(container.Data, chunk)
+		return container
+	})
+
+	return decoder, format
+}
+
+func newUint8NonInterleavedDecoder() (Decoder, Format) {
+	format := &RawFormat{
+		SampleSize:  1,
+		IsFloat:     false,
+		Interleaved: false,
+	}
+
+	decoder := newPCMDecoder(format, func(_ binary.ByteOrder, chunkInfo ChunkInfo, chunk []byte) Audio {
+		container := NewUint8NonInterleaved(chunkInfo)
+		for ch := range container.Data {
+			copy(container.Data[ch], chunk[ch*chunkInfo.Len:])
+		}
+		return container
+	})
+
+	return decoder, format
+}
+
+func newInt24InterleavedDecoder() (Decoder, Format) {
+	format := &RawFormat{
+		SampleSize:  3,
+		IsFloat:     false,
+		Interleaved: true,
+	}
+
+	decoder := newPCMDecoder(format, func(endian binary.ByteOrder, chunkInfo ChunkInfo, chunk []byte) Audio {
+		container := NewInt24Interleaved(chunkInfo)
+		decodeSamples(chunk, chunkInfo, format.SampleSize, true, func(i, ch int, sample []byte) {
+			container.SetInt24(i, ch, readInt24(endian, sample))
+		})
+		return container
+	})
+
+	return decoder, format
+}
+
+func newInt24NonInterleavedDecoder() (Decoder, Format) {
+	format := &RawFormat{
+		SampleSize:  3,
+		IsFloat:     false,
+		Interleaved: false,
+	}
+
+	decoder := newPCMDecoder(format, func(endian binary.ByteOrder, chunkInfo ChunkInfo, chunk []byte) Audio {
+		container := NewInt24NonInterleaved(chunkInfo)
+		decodeSamples(chunk, chunkInfo, format.SampleSize, false, func(i, ch int, sample []byte) {
+			container.SetInt24(i, ch, readInt24(endian, sample))
+		})
+		return container
+	})
+
+	return decoder, format
+}
+
+func newInt32InterleavedDecoder() (Decoder, Format) {
+	format := &RawFormat{
+		SampleSize:  4,
+		IsFloat:     false,
+		Interleaved: true,
+	}
+
+	decoder := newPCMDecoder(format, func(endian binary.ByteOrder, chunkInfo ChunkInfo, chunk []byte) Audio {
+		container := NewInt32Interleaved(chunkInfo)
+		decodeSamples(chunk, chunkInfo, format.SampleSize, true, func(i, ch int, sample []byte) {
+			container.SetInt32(i, ch, Int32Sample(endian.Uint32(sample)))
+		})
+		return container
+	})
+
+	return decoder, format
+}
+
+func newInt32NonInterleavedDecoder() (Decoder, Format) {
+	format := &RawFormat{
+		SampleSize:  4,
+		IsFloat:     false,
+		Interleaved: false,
+	}
+
+	decoder := newPCMDecoder(format, func(endian binary.ByteOrder, chunkInfo ChunkInfo, chunk []byte) Audio {
+		container := NewInt32NonInterleaved(chunkInfo)
+		decodeSamples(chunk, chunkInfo, format.SampleSize, false, func(i, ch int, sample []byte) {
+			container.SetInt32(i, ch, Int32Sample(endian.Uint32(sample)))
+		})
+		return container
+	})
+
+	return decoder, format
+}
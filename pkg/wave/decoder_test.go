@@ -117,6 +117,12 @@ func TestNewDecoder(t *testing.T) {
 		},
 	}
 
+	for _, sampleSize := range []int{1, 3} {
+		for _, interleaved := range []bool{true, false} {
+			rawFormats = append(rawFormats, RawFormat{SampleSize: sampleSize, Interleaved: interleaved})
+		}
+	}
+
 	for _, rawFormat := range rawFormats {
 		_, err := NewDecoder(&rawFormat)
 		if err != nil {
@@ -351,3 +357,90 @@ func TestDecodeFloat32NonInterleaved(t *testing.T) {
 		}
 	})
 }
+
+func TestDecodePCM(t *testing.T) {
+	testCases := map[string]struct {
+		builder  DecoderBuilderFunc
+		raw      []byte
+		endian   binary.ByteOrder
+		expected Audio
+	}{
+		"Uint8Interleaved": {
+			builder: newUint8InterleavedDecoder,
+			raw:     []byte{0x00, 0x80, 0xff, 0x01},
+			endian:  binary.BigEndian,
+			expected: &Uint8Interleaved{
+				Data: []uint8{0x00, 0x80, 0xff, 0x01},
+				Size: ChunkInfo{Len: 2, Channels: 2},
+			},
+		},
+		"Uint8NonInterleaved": {
+			builder: newUint8NonInterleavedDecoder,
+			raw:     []byte{0x00, 0x80, 0xff, 0x01},
+			endian:  binary.LittleEndian,
+			expected: &Uint8NonInterleaved{
+				Data: [][]uint8{{0x00, 0x80}, {0xff, 0x01}},
+				Size: ChunkInfo{Len: 2, Channels: 2},
+			},
+		},
+		"Int24InterleavedBigEndian": {
+			builder: newInt24InterleavedDecoder,
+			raw:     []byte{0x01, 0x02, 0x03, 0xff, 0xff, 0xfe},
+			endian:  binary.BigEndian,
+			expected: &Int24Interleaved{
+				Data: []int32{0x010203, -2},
+				Size: ChunkInfo{Len: 1, Channels: 2},
+			},
+		},
+		"Int24InterleavedLittleEndian": {
+			builder: newInt24InterleavedDecoder,
+			raw:     []byte{0x01, 0x02, 0x03, 0xfe, 0xff, 0xff},
+			endian:  binary.LittleEndian,
+			expected: &Int24Interleaved{
+				Data: []int32{0x030201, -2},
+				Size: ChunkInfo{Len: 1, Channels: 2},
+			},
+		},
+		"Int24NonInterleaved": {
+			builder: newInt24NonInterleavedDecoder,
+			raw:     []byte{0x80, 0x00, 0x00, 0x7f, 0xff, 0xff, 0x00, 0x00, 0x01, 0x00, 0x00, 0x02},
+			endian:  binary.BigEndian,
+			expected: &Int24NonInterleaved{
+				Data: [][]int32{{-0x800000, 0x7fffff}, {1, 2}},
+				Size: ChunkInfo{Len: 2, Channels: 2},
+			},
+		},
+		"Int32InterleavedBigEndian": {
+			builder: newInt32InterleavedDecoder,
+			raw:     []byte{0x01, 0x02, 0x03, 0x04, 0xff, 0xff, 0xff, 0xfe},
+			endian:  binary.BigEndian,
+			expected: &Int32Interleaved{
+				Data: []int32{0x01020304, -2},
+				Size: ChunkInfo{Len: 2, Channels: 1},
+			},
+		},
+		"Int32NonInterleavedLittleEndian": {
+			builder: newInt32NonInterleavedDecoder,
+			raw:     []byte{0x01, 0x02, 0x03, 0x04, 0xfe, 0xff, 0xff, 0xff},
+			endian:  binary.LittleEndian,
+			expected: &Int32NonInterleaved{
+				Data: [][]int32{{0x04030201}, {-2}},
+				Size: ChunkInfo{Len: 1, Channels: 2},
+			},
+		},
+	}
+	for name, c := range testCases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			decoder, _ := c.builder()
+			actual, err := decoder.Decode(c.endian, c.raw, c.expected.ChunkInfo().Channels)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(c.expected, actual) {
+				t.Errorf("Wrong decode result,\nexpected:\n%+v\ngot:\n%+v", c.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package wave
+
+// Int24Sample is a 24-bits signed integer audio sample, stored in the low bits of an int32.
+type Int24Sample int32
+
+func (s Int24Sample) Int() int64 {
+	return int64(s) << 8
+}
+
+// Int24Interleaved multi-channel interlaced Audio.
+type Int24Interleaved struct {
+	Data []int32
+	Size ChunkInfo
+}
+
+// ChunkInfo returns audio chunk size.
+func (a *Int24Interleaved) ChunkInfo() ChunkInfo {
+	return a.Size
+}
+
+func (a *Int24Interleaved) SampleFormat() SampleFormat {
+	return Int24SampleFormat
+}
+
+func (a *Int24Interleaved) At(i, ch int) Sample {
+	return Int24Sample(a.Data[i*a.Size.Channels+ch])
+}
+
+func (a *Int24Interleaved) Set(i, ch int, s Sample) {
+	a.Data[i*a.Size.Channels+ch] = int32(Int24SampleFormat.Convert(s).(Int24Sample))
+}
+
+func (a *Int24Interleaved) SetInt24(i, ch int, s Int24Sample) {
+	a.Data[i*a.Size.Channels+ch] = int32(s)
+}
+
+// SubAudio returns part of the original audio sharing the buffer.
+func (a *Int24Interleaved) SubAudio(offsetSamples, nSamples int) *Int24Interleaved {
+	ret := *a
+	offset := offsetSamples * a.Size.Channels
+	n := nSamples * a.Size.Channels
+	ret.Data = ret.Data[offset : offset+n]
+	ret.Size.Len = nSamples
+	return &ret
+}
+
+func (a *Int24Interleaved) copyTo(tmp Audio) Audio {
+	clone, ok := tmp.(*Int24Interleaved)
+	if !ok {
+		clone = &Int24Interleaved{}
+	}
+	clone.Size = a.Size
+	clone.Data = append(clone.Data[:0], a.Data...)
+	return clone
+}
+
+func NewInt24Interleaved(size ChunkInfo) *Int24Interleaved {
+	return &Int24Interleaved{
+		Data: make([]int32, size.Channels*size.Len),
+		Size: size,
+	}
+}
+
+// Int24NonInterleaved multi-channel interlaced Audio.
+type Int24NonInterleaved struct {
+	Data [][]int32
+	Size ChunkInfo
+}
+
+// ChunkInfo returns audio chunk size.
+func (a *Int24NonInterleaved) ChunkInfo() ChunkInfo {
+	return a.Size
+}
+
+func (a *Int24NonInterleaved) SampleFormat() SampleFormat {
+	return Int24SampleFormat
+}
+
+func (a *Int24NonInterleaved) At(i, ch int) Sample {
+	return Int24Sample(a.Data[ch][i])
+}
+
+func (a *Int24NonInterleaved) Set(i, ch int, s Sample) {
+	a.Data[ch][i] = int32(Int24SampleFormat.Convert(s).(Int24Sample))
+}
+
+func (a *Int24NonInterleaved) SetInt24(i, ch int, s Int24Sample) {
+	a.Data[ch][i] = int32(s)
+}
+
+// SubAudio returns part of the original audio sharing the buffer.
+func (a *Int24NonInterleaved) SubAudio(offsetSamples, nSamples int) *Int24NonInterleaved {
+	ret := *a
+	ret.Data = make([][]int32, len(a.Data))
+	for i := range a.Data {
+		ret.Data[i] = a.Data[i][offsetSamples : offsetSamples+nSamples]
+	}
+	ret.Size.Len = nSamples
+	return &ret
+}
+
+func (a *Int24NonInterleaved) copyTo(tmp Audio) Audio {
+	clone, ok := tmp.(*Int24NonInterleaved)
+	if !ok {
+		clone = &Int24NonInterleaved{}
+	}
+	clone.Size = a.Size
+	if cap(clone.Data) < len(a.Data) {
+		clone.Data = make([][]int32, len(a.Data))
+	}
+	clone.Data = clone.Data[:len(a.Data)]
+	for i := range a.Data {
+		clone.Data[i] = append(clone.Data[i][:0], a.Data[i]...)
+	}
+	return clone
+}
+
+func NewInt24NonInterleaved(size ChunkInfo) *Int24NonInterleaved {
+	d := make([][]int32, size.Channels)
+	for i := 0; i < size.Channels; i++ {
+		d[i] = make([]int32, size.Len)
+	}
+	return &Int24NonInterleaved{
+		Data: d,
+		Size: size,
+	}
+}
@@ -0,0 +1,77 @@
+package wave
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestInt24(t *testing.T) {
+	cases := map[string]struct {
+		in       Audio
+		expected [][]int64
+	}{
+		"Interleaved": {
+			in: &Int24Interleaved{
+				Data: []int32{
+					1, -0x800000, 0x7fffff, -1,
+				},
+				Size: ChunkInfo{2, 2, 48000},
+			},
+			expected: [][]int64{
+				{0x100, 0x7fffff00},
+				{-0x80000000, -0x100},
+			},
+		},
+		"NonInterleaved": {
+			in: &Int24NonInterleaved{
+				Data: [][]int32{
+					{1, 0x7fffff},
+					{-0x800000, -1},
+				},
+				Size: ChunkInfo{2, 2, 48000},
+			},
+			expected: [][]int64{
+				{0x100, 0x7fffff00},
+				{-0x80000000, -0x100},
+			},
+		},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			out := make([][]int64, c.in.ChunkInfo().Channels)
+			for i := 0; i < c.in.ChunkInfo().Channels; i++ {
+				for j := 0; j < c.in.ChunkInfo().Len; j++ {
+					out[i] = append(out[i], c.in.At(j, i).Int())
+				}
+			}
+			if !reflect.DeepEqual(c.expected, out) {
+				t.Errorf("Sample level differs, expected: %v, got: %v", c.expected, out)
+			}
+		})
+	}
+}
+
+func TestInt24SubAudio(t *testing.T) {
+	in := &Int24NonInterleaved{
+		Data: [][]int32{
+			{1, 2, 3, 4},
+			{-5, -6, -7, -8},
+		},
+		Size: ChunkInfo{4, 2, 48000},
+	}
+	expected := &Int24NonInterleaved{
+		Data: [][]int32{
+			{2, 3},
+			{-6, -7},
+		},
+		Size: ChunkInfo{2, 2, 48000},
+	}
+	out := in.SubAudio(1, 2)
+	if !reflect.DeepEqual(expected, out) {
+		t.Errorf("SubAudio differs, expected: %v, got: %v", expected, out)
+	}
+	if len(in.Data[0]) != 4 {
+		t.Error("SubAudio shouldn't modify the original audio")
+	}
+}
@@ -0,0 +1,143 @@
+package mixer
+
+import (
+	"errors"
+	"math"
+
+	"github.com/pion/mediadevices/pkg/wave"
+)
+
+var (
+	errSizeMismatch     = errors.New("buffer size mismatch")
+	errNotSettable      = errors.New("destination buffer is not settable")
+	errChannelMismatch  = errors.New("channel count doesn't match the matrix")
+	errInvalidChannelID = errors.New("invalid channel index")
+)
+
+// MatrixMixer mixes channels with a matrix of gains: the output channel i is the sum of the
+// input channels j weighted by Gains[i][j]. The input channels beyond the matrix columns
+// are dropped. Mixed samples out of range are clipped.
+type MatrixMixer struct {
+	Gains [][]float32
+}
+
+// NewMatrixMixer returns a MatrixMixer of gains, one row per output channel. The rows must
+// have the same length.
+func NewMatrixMixer(gains [][]float32) *MatrixMixer {
+	return &MatrixMixer{Gains: gains}
+}
+
+// MonoToStereo copies a monaural channel to the left and right channels.
+func MonoToStereo() *MatrixMixer {
+	return NewMatrixMixer([][]float32{{1}, {1}})
+}
+
+// StereoToMono averages the left and right channels.
+func StereoToMono() *MatrixMixer {
+	return NewMatrixMixer([][]float32{{0.5, 0.5}})
+}
+
+// SurroundToStereo downmixes 5.1 audio, in the L, R, C, LFE, Ls, Rs order, to stereo with the
+// ITU-R BS.775 coefficients. The center and surround channels are attenuated by 3dB, and the
+// LFE channel is dropped.
+func SurroundToStereo() *MatrixMixer {
+	const g = math.Sqrt2 / 2
+	return NewMatrixMixer([][]float32{
+		{1, 0, g, 0, g, 0},
+		{0, 1, g, 0, 0, g},
+	})
+}
+
+// SwapChannels swaps the left and right channels.
+func SwapChannels() *MatrixMixer {
+	m, _ := SelectChannels(1, 0)
+	return m
+}
+
+// SelectChannels copies the input channels chs, in order, e.g. SelectChannels(2) picks the
+// third channel of a multi-channel interface as monaural audio.
+func SelectChannels(chs ...int) (*MatrixMixer, error) {
+	var n int
+	for _, ch := range chs {
+		if ch < 0 {
+			return nil, errInvalidChannelID
+		}
+		if ch >= n {
+			n = ch + 1
+		}
+	}
+	gains := make([][]float32, len(chs))
+	for i, ch := range chs {
+		gains[i] = make([]float32, n)
+		gains[i][ch] = 1
+	}
+	return NewMatrixMixer(gains), nil
+}
+
+// Channels returns the minimum number of input channels, and the number of output channels.
+func (m *MatrixMixer) Channels() (src, dst int) {
+	if len(m.Gains) > 0 {
+		src = len(m.Gains[0])
+	}
+	return src, len(m.Gains)
+}
+
+func (m *MatrixMixer) Mix(dst wave.Audio, src wave.Audio) error {
+	srcInfo, dstInfo := src.ChunkInfo(), dst.ChunkInfo()
+	if dstInfo.Len != srcInfo.Len {
+		return errSizeMismatch
+	}
+	dstSetter, ok := dst.(wave.EditableAudio)
+	if !ok {
+		return errNotSettable
+	}
+	srcChannels, dstChannels := m.Channels()
+	if dstInfo.Channels != dstChannels || srcInfo.Channels < srcChannels {
+		return errChannelMismatch
+	}
+
+	frame := make([]float32, srcChannels)
+	for i := 0; i < srcInfo.Len; i++ {
+		for ch := range frame {
+			frame[ch] = toFloat32(src.At(i, ch))
+		}
+		for ch, gains := range m.Gains {
+			var v float32
+			for j, g := range gains {
+				v += g * frame[j]
+			}
+			setFloat32(dstSetter, i, ch, v)
+		}
+	}
+	return nil
+}
+
+// toFloat32 converts s to a value in [-1, 1]
+func toFloat32(s wave.Sample) float32 {
+	if f, ok := s.(wave.Float32Sample); ok {
+		return float32(f)
+	}
+	// the integer samples range within [-0x80000000, 0x7fffffff]
+	return float32(s.Int()) / 0x80000000
+}
+
+// setFloat32 sets the sample ch of dst at i from v in [-1, 1], clipping the values out of range
+func setFloat32(dst wave.EditableAudio, i, ch int, v float32) {
+	switch {
+	case v > 1:
+		v = 1
+	case v < -1:
+		v = -1
+	}
+	if f, ok := dst.(interface {
+		SetFloat32(i, ch int, s wave.Float32Sample)
+	}); ok {
+		f.SetFloat32(i, ch, wave.Float32Sample(v))
+		return
+	}
+	s := int64(float64(v) * 0x80000000)
+	if s > 0x7fffffff {
+		s = 0x7fffffff
+	}
+	dst.Set(i, ch, wave.Int64Sample(s))
+}
@@ -0,0 +1,118 @@
+package mixer
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/pion/mediadevices/pkg/wave"
+)
+
+func TestMatrixMixer(t *testing.T) {
+	selectThird, err := SelectChannels(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testCases := map[string]struct {
+		mixer    *MatrixMixer
+		src      wave.Audio
+		dst      wave.EditableAudio
+		expected wave.Audio
+	}{
+		"MonoToStereo": {
+			mixer: MonoToStereo(),
+			src: &wave.Int16Interleaved{
+				Size: wave.ChunkInfo{Len: 3, Channels: 1},
+				Data: []int16{0, 2, -4},
+			},
+			dst: wave.NewInt16Interleaved(wave.ChunkInfo{Len: 3, Channels: 2}),
+			expected: &wave.Int16Interleaved{
+				Size: wave.ChunkInfo{Len: 3, Channels: 2},
+				Data: []int16{0, 0, 2, 2, -4, -4},
+			},
+		},
+		"StereoToMono": {
+			mixer: StereoToMono(),
+			src: &wave.Float32NonInterleaved{
+				Size: wave.ChunkInfo{Len: 2, Channels: 2},
+				Data: [][]float32{{0.5, -0.25}, {0.25, -0.25}},
+			},
+			dst: wave.NewFloat32NonInterleaved(wave.ChunkInfo{Len: 2, Channels: 1}),
+			expected: &wave.Float32NonInterleaved{
+				Size: wave.ChunkInfo{Len: 2, Channels: 1},
+				Data: [][]float32{{0.375, -0.25}},
+			},
+		},
+		"Swap": {
+			mixer: SwapChannels(),
+			src: &wave.Int16Interleaved{
+				Size: wave.ChunkInfo{Len: 2, Channels: 2},
+				Data: []int16{1, 2, 3, 4},
+			},
+			dst: wave.NewInt16Interleaved(wave.ChunkInfo{Len: 2, Channels: 2}),
+			expected: &wave.Int16Interleaved{
+				Size: wave.ChunkInfo{Len: 2, Channels: 2},
+				Data: []int16{2, 1, 4, 3},
+			},
+		},
+		"Select": {
+			mixer: selectThird,
+			src: &wave.Int16Interleaved{
+				Size: wave.ChunkInfo{Len: 2, Channels: 4},
+				Data: []int16{1, 2, 3, 4, 5, 6, 7, 8},
+			},
+			dst: wave.NewInt16Interleaved(wave.ChunkInfo{Len: 2, Channels: 1}),
+			expected: &wave.Int16Interleaved{
+				Size: wave.ChunkInfo{Len: 2, Channels: 1},
+				Data: []int16{3, 7},
+			},
+		},
+		"SurroundToStereo": {
+			mixer: SurroundToStereo(),
+			src: &wave.Float32Interleaved{
+				Size: wave.ChunkInfo{Len: 1, Channels: 6},
+				Data: []float32{0.5, 0.25, 0, 1, 0.5, 0},
+			},
+			dst: wave.NewFloat32Interleaved(wave.ChunkInfo{Len: 1, Channels: 2}),
+			expected: &wave.Float32Interleaved{
+				Size: wave.ChunkInfo{Len: 1, Channels: 2},
+				Data: []float32{0.5 + 0.5*0.70710677, 0.25},
+			},
+		},
+		"Clip": {
+			mixer: NewMatrixMixer([][]float32{{2}}),
+			src: &wave.Int16Interleaved{
+				Size: wave.ChunkInfo{Len: 3, Channels: 1},
+				Data: []int16{0x1000, 0x7000, -0x7000},
+			},
+			dst: wave.NewInt16Interleaved(wave.ChunkInfo{Len: 3, Channels: 1}),
+			expected: &wave.Int16Interleaved{
+				Size: wave.ChunkInfo{Len: 3, Channels: 1},
+				Data: []int16{0x2000, 0x7FFF, -0x8000},
+			},
+		},
+	}
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			if err := testCase.mixer.Mix(testCase.dst, testCase.src); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(testCase.expected, testCase.dst) {
+				t.Errorf("Mix result is wrong\nexpected: %v\ngot: %v", testCase.expected, testCase.dst)
+			}
+		})
+	}
+}
+
+func TestMatrixMixerError(t *testing.T) {
+	src := wave.NewInt16Interleaved(wave.ChunkInfo{Len: 2, Channels: 2})
+	if err := SurroundToStereo().Mix(wave.NewInt16Interleaved(wave.ChunkInfo{Len: 2, Channels: 2}), src); err != errChannelMismatch {
+		t.Errorf("Expected %v, got %v", errChannelMismatch, err)
+	}
+	if err := SwapChannels().Mix(wave.NewInt16Interleaved(wave.ChunkInfo{Len: 3, Channels: 2}), src); err != errSizeMismatch {
+		t.Errorf("Expected %v, got %v", errSizeMismatch, err)
+	}
+	if _, err := SelectChannels(0, -1); err != errInvalidChannelID {
+		t.Errorf("Expected %v, got %v", errInvalidChannelID, err)
+	}
+}
@@ -0,0 +1,127 @@
+package wave
+
+// Uint8Sample is an 8-bits unsigned integer audio sample, centered on 0x80.
+type Uint8Sample uint8
+
+func (s Uint8Sample) Int() int64 {
+	return (int64(s) - 0x80) << 24
+}
+
+// Uint8Interleaved multi-channel interlaced Audio.
+type Uint8Interleaved struct {
+	Data []uint8
+	Size ChunkInfo
+}
+
+// ChunkInfo returns audio chunk size.
+func (a *Uint8Interleaved) ChunkInfo() ChunkInfo {
+	return a.Size
+}
+
+func (a *Uint8Interleaved) SampleFormat() SampleFormat {
+	return Uint8SampleFormat
+}
+
+func (a *Uint8Interleaved) At(i, ch int) Sample {
+	return Uint8Sample(a.Data[i*a.Size.Channels+ch])
+}
+
+func (a *Uint8Interleaved) Set(i, ch int, s Sample) {
+	a.Data[i*a.Size.Channels+ch] = uint8(Uint8SampleFormat.Convert(s).(Uint8Sample))
+}
+
+func (a *Uint8Interleaved) SetUint8(i, ch int, s Uint8Sample) {
+	a.Data[i*a.Size.Channels+ch] = uint8(s)
+}
+
+// SubAudio returns part of the original audio sharing the buffer.
+func (a *Uint8Interleaved) SubAudio(offsetSamples, nSamples int) *Uint8Interleaved {
+	ret := *a
+	offset := offsetSamples * a.Size.Channels
+	n := nSamples * a.Size.Channels
+	ret.Data = ret.Data[offset : offset+n]
+	ret.Size.Len = nSamples
+	return &ret
+}
+
+func (a *Uint8Interleaved) copyTo(tmp Audio) Audio {
+	clone, ok := tmp.(*Uint8Interleaved)
+	if !ok {
+		clone = &Uint8Interleaved{}
+	}
+	clone.Size = a.Size
+	clone.Data = append(clone.Data[:0], a.Data...)
+	return clone
+}
+
+func NewUint8Interleaved(size ChunkInfo) *Uint8Interleaved {
+	return &Uint8Interleaved{
+		Data: make([]uint8, size.Channels*size.Len),
+		Size: size,
+	}
+}
+
+// Uint8NonInterleaved multi-channel interlaced Audio.
+type Uint8NonInterleaved struct {
+	Data [][]uint8
+	Size ChunkInfo
+}
+
+// ChunkInfo returns audio chunk size.
+func (a *Uint8NonInterleaved) ChunkInfo() ChunkInfo {
+	return a.Size
+}
+
+func (a *Uint8NonInterleaved) SampleFormat() SampleFormat {
+	return Uint8SampleFormat
+}
+
+func (a *Uint8NonInterleaved) At(i, ch int) Sample {
+	return Uint8Sample(a.Data[ch][i])
+}
+
+func (a *Uint8NonInterleaved) Set(i, ch int, s Sample) {
+	a.Data[ch][i] = uint8(Uint8SampleFormat.Convert(s).(Uint8Sample))
+}
+
+func (a *Uint8NonInterleaved) SetUint8(i, ch int, s Uint8Sample) {
+	a.Data[ch][i] = uint8(s)
+}
+
+// SubAudio returns part of the original audio sharing the buffer.
+func (a *Uint8NonInterleaved) SubAudio(offsetSamples, nSamples int) *Uint8NonInterleaved {
+	ret := *a
+	ret.Data = make([][]uint8, len(a.Data))
+	for i := range a.Data {
+		ret.Data[i] = a.Data[i][offsetSamples : offsetSamples+nSamples]
+	}
+	ret.Size.Len = nSamples
+	return &ret
+}
+
+func (a *Uint8NonInterleaved) copyTo(tmp Audio) Audio {
+	clone, ok := tmp.(*Uint8NonInterleaved)
+	if !ok {
+		clone = &Uint8NonInterleaved{}
+	}
+	clone.Size = a.Size
+	if cap(clone.Data) < len(a.Data) {
+		clone.Data = make([][]uint8, len(a.Data))
+	}
+	clone.Data = clone.Data[:len(a.Data)]
+	for i := range a.Data {
+		clone.Data[i] = append(clone.Data[i][:0], a.Data[i]...)
+	}
+	return clone
+}
+
+func NewUint8NonInterleaved(size ChunkInfo) *Uint8NonInterleaved {
+	d := make([][]uint8, size.Channels)
+	for i := 0; i < size.Channels; i++ {
+		d[i] = make([]uint8, size.Len)
+	}
+	return &Uint8NonInterleaved{
+		Data: d,
+		Size: size,
+	}
+}
@@ -51,10 +51,61 @@ var (
 		if _, ok := s.(Float32Sample); ok {
 			return s
 		}
+		switch s.(type) {
+		case Uint8Sample, Int24Sample, Int32Sample:
+			return Float32Sample(float64(s.Int()) / 0x80000000)
+		}
 		return Float32Sample(float32(s.Int()) / 0x100000000)
 	})
+	Uint8SampleFormat = SampleFormatFunc(func(s Sample) Sample {
+		if _, ok := s.(Uint8Sample); ok {
+			return s
+		}
+		return Uint8Sample(level(s)>>24 + 0x80)
+	})
+	Int24SampleFormat = SampleFormatFunc(func(s Sample) Sample {
+		if _, ok := s.(Int24Sample); ok {
+			return s
+		}
+		return Int24Sample(level(s) >> 8)
+	})
+	Int32SampleFormat = SampleFormatFunc(func(s Sample) Sample {
+		if _, ok := s.(Int32Sample); ok {
+			return s
+		}
+		return Int32Sample(level(s))
+	})
 )
 
+// level returns the level of s within [-0x80000000, 0x7fffffff]. Float32Sample.Int is scaled
+// to 2^32, so a Float32Sample is converted from [-1, 1] directly.
+func level(s Sample) int64 {
+	f, ok := s.(Float32Sample)
+	if !ok {
+		return clampInt(s.Int(), -0x80000000, 0x7fffffff)
+	}
+	switch {
+	case f >= 1:
+		return 0x7fffffff
+	case f <= -1:
+		return -0x80000000
+	default:
+		return int64(float64(f) * 0x80000000)
+	}
+}
+
+// clampInt clamps v in [min, max]
+func clampInt(v, min, max int64) int64 {
+	switch {
+	case v < min:
+		return min
+	case v > max:
+		return max
+	default:
+		return v
+	}
+}
+
 // Sample can convert itself to 64-bits signed value.
 type Sample interface {
 	// Int returns the audio level value for the sample.
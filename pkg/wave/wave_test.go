@@ -46,6 +46,58 @@ func TestConvert(t *testing.T) {
 				Int16Sample(0x1000),
 			},
 		},
+		"Int16ToInt24": {
+			in: []Sample{
+				Int16Sample(-0x8000),
+				Int16Sample(-0x100),
+				Int16Sample(0x7fff),
+			},
+			typ: Int24SampleFormat,
+			expected: []Sample{
+				Int24Sample(-0x800000),
+				Int24Sample(-0x10000),
+				Int24Sample(0x7fff00),
+			},
+		},
+		"Int32ToUint8": {
+			in: []Sample{
+				Int32Sample(-0x80000000),
+				Int32Sample(0),
+				Int32Sample(0x7fffffff),
+			},
+			typ: Uint8SampleFormat,
+			expected: []Sample{
+				Uint8Sample(0),
+				Uint8Sample(0x80),
+				Uint8Sample(0xff),
+			},
+		},
+		"Float32ToInt32": {
+			in: []Sample{
+				Float32Sample(-1),
+				Float32Sample(math.Pow(2, -4)),
+				Float32Sample(1),
+			},
+			typ: Int32SampleFormat,
+			expected: []Sample{
+				Int32Sample(-0x80000000),
+				Int32Sample(0x08000000),
+				Int32Sample(0x7fffffff),
+			},
+		},
+		"Float32ToInt24": {
+			in: []Sample{
+				Float32Sample(-2),
+				Float32Sample(0.75),
+				Float32Sample(2),
+			},
+			typ: Int24SampleFormat,
+			expected: []Sample{
+				Int24Sample(-0x800000),
+				Int24Sample(0x600000),
+				Int24Sample(0x7fffff),
+			},
+		},
 	}
 	for name, c := range cases {
 		c := c
@@ -59,3 +111,23 @@ func TestConvert(t *testing.T) {
 		})
 	}
 }
+
+func TestConvertFloat32RoundTrip(t *testing.T) {
+	in := []Float32Sample{-1, -0.25, 0, 0.5, 0.75}
+	cases := map[string]SampleFormat{
+		"Uint8": Uint8SampleFormat,
+		"Int24": Int24SampleFormat,
+		"Int32": Int32SampleFormat,
+	}
+	for name, typ := range cases {
+		typ := typ
+		t.Run(name, func(t *testing.T) {
+			for _, s := range in {
+				got := Float32SampleFormat.Convert(typ.Convert(s))
+				if got != s {
+					t.Errorf("Round trip result differs, expected: %v, got: %v", s, got)
+				}
+			}
+		})
+	}
+}
@@ -3,18 +3,20 @@ package mediadevices
 import (
 	"math"
 	"time"
+
+	"github.com/pion/mediadevices/pkg/clock"
 )
 
 type samplerFunc func() uint32
 
 // newVideoSampler creates a video sampler that uses the actual video frame rate and
 // the codec's clock rate to come up with a duration for each sample.
-func newVideoSampler(clockRate uint32) samplerFunc {
+func newVideoSampler(clockRate uint32, c clock.Clock) samplerFunc {
 	clockRateFloat := float64(clockRate)
-	lastTimestamp := time.Now()
+	lastTimestamp := c.Now()
 
 	return samplerFunc(func() uint32 {
-		now := time.Now()
+		now := c.Now()
 		duration := now.Sub(lastTimestamp).Seconds()
 		samples := uint32(math.Round(clockRateFloat * duration))
 		lastTimestamp = now
@@ -0,0 +1,21 @@
+package mediadevices
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/mediadevices/pkg/clock"
+)
+
+func TestVideoSampler(t *testing.T) {
+	c := clock.NewManual(time.Unix(0, 0))
+	sample := newVideoSampler(90000, c)
+
+	for _, d := range []time.Duration{33 * time.Millisecond, 40 * time.Millisecond, 0} {
+		c.Add(d)
+		expected := uint32(d.Seconds() * 90000)
+		if samples := sample(); samples != expected {
+			t.Errorf("Expected %d samples after %v, got %d", expected, d, samples)
+		}
+	}
+}
@@ -5,17 +5,22 @@ import (
 	"fmt"
 	"image"
 	"io"
+	"math"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/pion/interceptor"
 	"github.com/pion/rtcp"
 
 	"github.com/google/uuid"
+	"github.com/pion/mediadevices/pkg/clock"
 	"github.com/pion/mediadevices/pkg/codec"
 	"github.com/pion/mediadevices/pkg/driver"
 	"github.com/pion/mediadevices/pkg/io/audio"
 	"github.com/pion/mediadevices/pkg/io/video"
+	"github.com/pion/mediadevices/pkg/prop"
 	"github.com/pion/mediadevices/pkg/wave"
 	"github.com/pion/rtp"
 	"github.com/pion/webrtc/v3"
@@ -26,6 +31,15 @@ const (
 	rtcpInboundMTU = 1500
 )
 
+// VideoOrientationURI is the URI of the video-orientation RTP header extension (3GPP TS 26.114),
+// which has to be registered in the webrtc.MediaEngine to send the orientation set by
+// VideoTrack.SetOrientation.
+const VideoOrientationURI = "urn:3gpp:video-orientation"
+
+// AudioLevelURI is the URI of the audio level RTP header extension (RFC 6464), which has to be
+// registered in the webrtc.MediaEngine to send the level measured by AudioTrack.Level.
+const AudioLevelURI = "urn:ietf:params:rtp-hdrext:ssrc-audio-level"
+
 var (
 	errInvalidDriverType      = errors.New("invalid driver type")
 	errNotFoundPeerConnection = errors.New("failed to find given peer connection")
@@ -89,6 +103,11 @@ type baseTrack struct {
 	kind                  MediaDeviceType
 	selector              *CodecSelector
 	activePeerConnections map[string]chan<- chan<- struct{}
+	// encodedSource is set when the driver can produce encoded frames natively
+	encodedSource driver.EncodedSource
+	encodedProp   prop.Media
+	// startSource starts the raw source of a track created from an EncodedSource
+	startSource func() error
 }
 
 func newBaseTrack(source Source, kind MediaDeviceType, selector *CodecSelector) *baseTrack {
@@ -188,6 +207,11 @@ func (track *baseTrack) bind(ctx webrtc.TrackLocalContext, specializedTrack Trac
 		return webrtc.RTPCodecParameters{}, errors.New(strings.Join(errReasons, "\n\n"))
 	}
 
+	extendHeaders := func([]*rtp.Packet) {}
+	if extender, ok := specializedTrack.(headerExtender); ok {
+		extendHeaders = extender.headerExtender(ctx.HeaderExtensions())
+	}
+
 	go func() {
 		var doneCh chan<- struct{}
 		writer := ctx.WriteStream()
@@ -216,6 +240,7 @@ func (track *baseTrack) bind(ctx webrtc.TrackLocalContext, specializedTrack Trac
 				return
 			}
 
+			extendHeaders(pkts)
 			for _, pkt := range pkts {
 				_, err = writer.WriteRTP(&pkt.Header, pkt.Payload)
 				if err != nil {
@@ -311,6 +336,88 @@ func (track *baseTrack) removeActivePeerConnection(id string) chan<- chan<- stru
 	return ch
 }
 
+// lazyStart returns a function calling open only once, and returning its result
+func lazyStart(open func() error) func() error {
+	var once sync.Once
+	var err error
+	return func() error {
+		once.Do(func() {
+			err = open()
+		})
+		return err
+	}
+}
+
+// startRawSource starts the raw source of the track if it hasn't been started yet
+func (track *baseTrack) startRawSource() error {
+	if track.startSource == nil {
+		return nil
+	}
+	return track.startSource()
+}
+
+// newPassthroughReader creates an encoded reader from the frames natively produced by the
+// driver, if their codec is one of codecNames. Otherwise, nil is returned, and the track
+// has to be encoded from the raw source.
+func (track *baseTrack) newPassthroughReader(codecNames ...string) (EncodedReadCloser, *codec.RTPCodec, error) {
+	if track.encodedSource == nil {
+		return nil, nil, nil
+	}
+
+	selectedCodec := track.encodedSource.EncodedCodec(track.encodedProp)
+	if selectedCodec == nil {
+		return nil, nil, nil
+	}
+
+	var matched bool
+	for _, codecName := range codecNames {
+		if strings.HasSuffix(strings.ToLower(selectedCodec.MimeType), strings.ToLower(codecName)) {
+			matched = true
+			break
+		}
+	}
+	if !matched {
+		return nil, nil, nil
+	}
+
+	reader, err := track.encodedSource.EncodedRecord(track.encodedProp)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	clockRate := uint64(selectedCodec.ClockRate)
+	var lastTicks uint64
+	var started bool
+
+	return &encodedReadCloserImpl{
+		readFn: func() (EncodedBuffer, func(), error) {
+			frame, release, err := reader.Read()
+			if err != nil {
+				return EncodedBuffer{}, func() {}, err
+			}
+
+			// Split the conversion to avoid overflowing after a few hours
+			seconds, rest := uint64(frame.Timestamp/time.Second), uint64(frame.Timestamp%time.Second)
+			ticks := seconds*clockRate + rest*clockRate/uint64(time.Second)
+
+			var samples uint32
+			if started && ticks > lastTicks {
+				samples = uint32(ticks - lastTicks)
+			}
+			started = true
+			lastTicks = ticks
+
+			return EncodedBuffer{Data: frame.Data, Samples: samples}, release, nil
+		},
+		closeFn: reader.Close,
+		controllerFn: func() codec.EncoderController {
+			// Drivers control the encoding through optional interfaces of the reader,
+			// e.g. codec.KeyFrameController
+			return reader
+		},
+	}, selectedCodec, nil
+}
+
 func newTrackFromDriver(d driver.Driver, constraints MediaTrackConstraints, selector *CodecSelector) (Track, error) {
 	if err := d.Open(); err != nil {
 		return nil, err
@@ -331,6 +438,9 @@ type VideoTrack struct {
 	*baseTrack
 	*video.Broadcaster
 	shouldCopyFrames bool
+	clock            clock.Clock
+	// orientation holds the video.Orientation sent in the video-orientation header extension
+	orientation atomic.Value
 }
 
 // NewVideoTrack constructs a new VideoTrack
@@ -348,6 +458,52 @@ func (track *VideoTrack) SetShouldCopyFrames(shouldCopyFrames bool) {
 	track.shouldCopyFrames = shouldCopyFrames
 }
 
+// SetClock sets the clock from which the RTP timestamps of the encoded frames are derived.
+// The readers created before keep the previous clock.
+func (track *VideoTrack) SetClock(c clock.Clock) {
+	track.clock = c
+}
+
+// SetOrientation sets the orientation sent to the peers in the video-orientation RTP header
+// extension, so that they rotate the frames when displaying them instead of rotating them
+// before encoding, see video.Orientation.Transform. It's only sent to the peers which
+// negotiated the extension, see VideoOrientationURI.
+func (track *VideoTrack) SetOrientation(o video.Orientation) {
+	track.orientation.Store(o)
+}
+
+// headerExtender is implemented by the tracks adding RTP header extensions to their packets
+type headerExtender interface {
+	// headerExtender returns the function adding the header extensions among the negotiated
+	// extensions to the packets of a frame
+	headerExtender(extensions []webrtc.RTPHeaderExtensionParameter) func(pkts []*rtp.Packet)
+}
+
+func (track *VideoTrack) headerExtender(extensions []webrtc.RTPHeaderExtensionParameter) func(pkts []*rtp.Packet) {
+	var id uint8
+	for _, ext := range extensions {
+		if ext.URI == VideoOrientationURI {
+			id = uint8(ext.ID)
+		}
+	}
+
+	return func(pkts []*rtp.Packet) {
+		o, ok := track.orientation.Load().(video.Orientation)
+		if id == 0 || !ok {
+			return
+		}
+		// like in WebRTC, the orientation is sent in the last packet of the frames
+		for _, pkt := range pkts {
+			if !pkt.Marker {
+				continue
+			}
+			if err := pkt.Header.SetExtension(id, []byte{o.CVO()}); err != nil {
+				logger.Warnf("failed to set the video orientation: %s", err)
+			}
+		}
+	}
+}
+
 func newVideoTrackFromReader(source Source, reader video.Reader, selector *CodecSelector) Track {
 	base := newBaseTrack(source, VideoInput, selector)
 	wrappedReader := video.ReaderFunc(func() (img image.Image, release func(), err error) {
@@ -364,11 +520,33 @@ func newVideoTrackFromReader(source Source, reader video.Reader, selector *Codec
 	return &VideoTrack{
 		baseTrack:   base,
 		Broadcaster: broadcaster,
+		clock:       clock.New(),
 	}
 }
 
 // newVideoTrackFromDriver is an internal video track creation from driver
 func newVideoTrackFromDriver(d driver.Driver, recorder driver.VideoRecorder, constraints MediaTrackConstraints, selector *CodecSelector) (Track, error) {
+	if source, ok := d.(driver.EncodedSource); ok && source.EncodedCodec(constraints.selectedMedia) != nil {
+		// The raw source is only started when the track is read in another codec
+		var reader video.Reader
+		start := lazyStart(func() (err error) {
+			reader, err = recorder.VideoRecord(constraints.selectedMedia)
+			return err
+		})
+		raw := video.ReaderFunc(func() (image.Image, func(), error) {
+			if err := start(); err != nil {
+				return nil, func() {}, err
+			}
+			return reader.Read()
+		})
+
+		track := newVideoTrackFromReader(d, raw, selector).(*VideoTrack)
+		track.encodedSource = source
+		track.encodedProp = constraints.selectedMedia
+		track.startSource = start
+		return track, nil
+	}
+
 	reader, err := recorder.VideoRecord(constraints.selectedMedia)
 	if err != nil {
 		return nil, err
@@ -392,6 +570,20 @@ func (track *VideoTrack) Unbind(ctx webrtc.TrackLocalContext) error {
 }
 
 func (track *VideoTrack) newEncodedReader(codecNames ...string) (EncodedReadCloser, *codec.RTPCodec, error) {
+	passthroughReader, passthroughCodec, err := track.newPassthroughReader(codecNames...)
+	if err != nil {
+		return nil, nil, err
+	}
+	if passthroughReader != nil {
+		return passthroughReader, passthroughCodec, nil
+	}
+
+	// The raw source is only started once the track is read in another codec than
+	// its native one. Failing to start it doesn't stop the passthrough readers.
+	if err := track.startRawSource(); err != nil {
+		return nil, nil, err
+	}
+
 	reader := track.NewReader(track.shouldCopyFrames)
 	inputProp, err := detectCurrentVideoProp(track.Broadcaster)
 	if err != nil {
@@ -403,7 +595,7 @@ func (track *VideoTrack) newEncodedReader(codecNames ...string) (EncodedReadClos
 		return nil, nil, err
 	}
 
-	sample := newVideoSampler(selectedCodec.ClockRate)
+	sample := newVideoSampler(selectedCodec.ClockRate, track.clock)
 
 	return &encodedReadCloserImpl{
 		readFn: func() (EncodedBuffer, func(), error) {
@@ -463,6 +655,9 @@ func (track *VideoTrack) NewRTPReader(codecName string, ssrc uint32, mtu int) (R
 type AudioTrack struct {
 	*baseTrack
 	*audio.Broadcaster
+	meter *audio.LevelMeter
+	// source is the source of the broadcaster, before its level is measured
+	source audio.Reader
 }
 
 // NewAudioTrack constructs a new AudioTrack
@@ -481,16 +676,40 @@ func newAudioTrackFromReader(source Source, reader audio.Reader, selector *Codec
 	})
 
 	// TODO: Allow users to configure broadcaster
-	broadcaster := audio.NewBroadcaster(wrappedReader, nil)
+	meter := audio.NewLevelMeter()
+	broadcaster := audio.NewBroadcaster(meter.Transform()(wrappedReader), nil)
 
 	return &AudioTrack{
 		baseTrack:   base,
 		Broadcaster: broadcaster,
+		meter:       meter,
+		source:      wrappedReader,
 	}
 }
 
 // newAudioTrackFromDriver is an internal audio track creation from driver
 func newAudioTrackFromDriver(d driver.Driver, recorder driver.AudioRecorder, constraints MediaTrackConstraints, selector *CodecSelector) (Track, error) {
+	if source, ok := d.(driver.EncodedSource); ok && source.EncodedCodec(constraints.selectedMedia) != nil {
+		// The raw source is only started when the track is read in another codec
+		var reader audio.Reader
+		start := lazyStart(func() (err error) {
+			reader, err = recorder.AudioRecord(constraints.selectedMedia)
+			return err
+		})
+		raw := audio.ReaderFunc(func() (wave.Audio, func(), error) {
+			if err := start(); err != nil {
+				return nil, func() {}, err
+			}
+			return reader.Read()
+		})
+
+		track := newAudioTrackFromReader(d, raw, selector).(*AudioTrack)
+		track.encodedSource = source
+		track.encodedProp = constraints.selectedMedia
+		track.startSource = start
+		return track, nil
+	}
+
 	reader, err := recorder.AudioRecord(constraints.selectedMedia)
 	if err != nil {
 		return nil, err
@@ -501,8 +720,55 @@ func newAudioTrackFromDriver(d driver.Driver, recorder driver.AudioRecorder, con
 
 // Transform transforms the underlying source by applying the given fns in serial order
 func (track *AudioTrack) Transform(fns ...audio.TransformFunc) {
-	src := track.Broadcaster.Source()
-	track.Broadcaster.ReplaceSource(audio.Merge(fns...)(src))
+	// the level is measured after the transforms
+	track.source = audio.Merge(fns...)(track.source)
+	track.Broadcaster.ReplaceSource(track.meter.Transform()(track.source))
+}
+
+// Level returns the level of the last chunk read from the track, after its transforms. It's
+// only measured while the track is read.
+func (track *AudioTrack) Level() audio.Level {
+	return track.meter.Level()
+}
+
+// OnVoiceActivity sets the handler called with true when someone starts speaking in the
+// track, and false when they stop, see audio.LevelMeter.
+func (track *AudioTrack) OnVoiceActivity(handler func(speaking bool)) {
+	track.meter.OnVoiceActivity(handler)
+}
+
+func (track *AudioTrack) headerExtender(extensions []webrtc.RTPHeaderExtensionParameter) func(pkts []*rtp.Packet) {
+	var id uint8
+	for _, ext := range extensions {
+		if ext.URI == AudioLevelURI {
+			id = uint8(ext.ID)
+		}
+	}
+
+	return func(pkts []*rtp.Packet) {
+		if id == 0 {
+			return
+		}
+		ext := []byte{audioLevelExtension(track.meter.Level())}
+		for _, pkt := range pkts {
+			if err := pkt.Header.SetExtension(id, ext); err != nil {
+				logger.Warnf("failed to set the audio level: %s", err)
+			}
+		}
+	}
+}
+
+// audioLevelExtension returns the payload of the audio level header extension: the voice
+// activity flag, and the level in -dBov.
+func audioLevelExtension(l audio.Level) byte {
+	level := byte(math.Round(-math.Min(0, l.RMS)))
+	if level > 127 {
+		level = 127
+	}
+	if l.Voice {
+		level |= 0x80
+	}
+	return level
 }
 
 func (track *AudioTrack) Bind(ctx webrtc.TrackLocalContext) (webrtc.RTPCodecParameters, error) {
@@ -514,16 +780,42 @@ func (track *AudioTrack) Unbind(ctx webrtc.TrackLocalContext) error {
 }
 
 func (track *AudioTrack) newEncodedReader(codecNames ...string) (EncodedReadCloser, *codec.RTPCodec, error) {
+	passthroughReader, passthroughCodec, err := track.newPassthroughReader(codecNames...)
+	if err != nil {
+		return nil, nil, err
+	}
+	if passthroughReader != nil {
+		return passthroughReader, passthroughCodec, nil
+	}
+
+	// The raw source is only started once the track is read in another codec than
+	// its native one. Failing to start it doesn't stop the passthrough readers.
+	if err := track.startRawSource(); err != nil {
+		return nil, nil, err
+	}
+
 	reader := track.NewReader(false)
 	inputProp, err := detectCurrentAudioProp(track.Broadcaster)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	encodedReader, selectedCodec, err := track.selector.selectAudioCodecByNames(reader, inputProp, codecNames...)
+	// the encoder is told whether someone speaks in each chunk it reads, e.g. for Opus DTX
+	var vad codec.VoiceActivityController
+	voiceReader := audio.ReaderFunc(func() (wave.Audio, func(), error) {
+		chunk, release, err := reader.Read()
+		if err == nil && vad != nil {
+			if err := vad.SetVoiceActivity(track.meter.Level().Voice); err != nil {
+				logger.Warnf("failed to set the voice activity: %s", err)
+			}
+		}
+		return chunk, release, err
+	})
+	encodedReader, selectedCodec, err := track.selector.selectAudioCodecByNames(voiceReader, inputProp, codecNames...)
 	if err != nil {
 		return nil, nil, err
 	}
+	vad, _ = encodedReader.Controller().(codec.VoiceActivityController)
 
 	sample := newAudioSampler(selectedCodec.ClockRate, selectedCodec.Latency)
 
@@ -572,6 +864,11 @@ func (track *AudioTrack) NewRTPReader(codecName string, ssrc uint32, mtu int) (R
 			}
 			defer release()
 
+			if len(encoded.Data) == 0 {
+				// nothing is transmitted while the encoder is discontinuous
+				packetizer.SkipSamples(encoded.Samples)
+				return nil, release, nil
+			}
 			pkts := packetizer.Packetize(encoded.Data, encoded.Samples)
 			return pkts, release, err
 		},
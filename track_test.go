@@ -2,12 +2,21 @@ package mediadevices
 
 import (
 	"errors"
+	"image"
 	"io"
+	"math"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/pion/interceptor"
+	"github.com/pion/mediadevices/pkg/codec"
+	"github.com/pion/mediadevices/pkg/driver"
+	"github.com/pion/mediadevices/pkg/io/audio"
+	"github.com/pion/mediadevices/pkg/io/video"
+	"github.com/pion/mediadevices/pkg/prop"
+	"github.com/pion/mediadevices/pkg/wave"
+	"github.com/pion/rtp"
 	"github.com/pion/webrtc/v3"
 )
 
@@ -246,3 +255,274 @@ func TestRtcpHandler(t *testing.T) {
 		}
 	})
 }
+
+type fakeEncodedReader struct {
+	frames []driver.EncodedFrame
+}
+
+func (r *fakeEncodedReader) Read() (driver.EncodedFrame, func(), error) {
+	if len(r.frames) == 0 {
+		return driver.EncodedFrame{}, func() {}, io.EOF
+	}
+	frame := r.frames[0]
+	r.frames = r.frames[1:]
+	return frame, func() {}, nil
+}
+
+func (r *fakeEncodedReader) Close() error { return nil }
+
+type fakeEncodedDriver struct {
+	frames []driver.EncodedFrame
+}
+
+func (d *fakeEncodedDriver) Open() error              { return nil }
+func (d *fakeEncodedDriver) Close() error             { return nil }
+func (d *fakeEncodedDriver) Properties() []prop.Media { return []prop.Media{{}} }
+func (d *fakeEncodedDriver) ID() string               { return "fake" }
+func (d *fakeEncodedDriver) Info() driver.Info        { return driver.Info{} }
+func (d *fakeEncodedDriver) Status() driver.State     { return driver.StateOpened }
+
+func (d *fakeEncodedDriver) VideoRecord(p prop.Media) (video.Reader, error) {
+	return nil, errExpected
+}
+
+func (d *fakeEncodedDriver) EncodedCodec(p prop.Media) *codec.RTPCodec {
+	return codec.NewRTPVP8Codec(90000)
+}
+
+func (d *fakeEncodedDriver) EncodedRecord(p prop.Media) (driver.EncodedReader, error) {
+	return &fakeEncodedReader{frames: d.frames}, nil
+}
+
+func TestEncodedPassthrough(t *testing.T) {
+	d := &fakeEncodedDriver{
+		frames: []driver.EncodedFrame{
+			{Data: []byte{0}, Timestamp: 0},
+			{Data: []byte{1}, Timestamp: 40 * time.Millisecond},
+			{Data: []byte{2}, Timestamp: 60 * time.Millisecond},
+		},
+	}
+	tr, err := newTrackFromDriver(d, MediaTrackConstraints{}, NewCodecSelector())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The raw source can't be started, but it doesn't end the track
+	if _, err := tr.NewEncodedReader(webrtc.MimeTypeH264); err != errExpected {
+		t.Errorf("Expected %v, got %v", errExpected, err)
+	}
+
+	r, err := tr.NewEncodedReader(webrtc.MimeTypeVP8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, samples := range []uint32{0, 3600, 1800} {
+		buffer, _, err := r.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if buffer.Data[0] != byte(i) || buffer.Samples != samples {
+			t.Errorf("Frame %d: expected samples %d, got %+v", i, samples, buffer)
+		}
+	}
+	if _, _, err := r.Read(); err != io.EOF {
+		t.Errorf("Expected %v, got %v", io.EOF, err)
+	}
+
+	ended := make(chan struct{})
+	tr.OnEnded(func(error) { close(ended) })
+	select {
+	case <-ended:
+		t.Error("Expected the track not to be ended")
+	case <-time.After(10 * time.Millisecond):
+	}
+}
+
+func TestVideoOrientationExtension(t *testing.T) {
+	track := &VideoTrack{}
+	newFrame := func() []*rtp.Packet {
+		return []*rtp.Packet{{}, {Header: rtp.Header{Marker: true}}}
+	}
+
+	extend := track.headerExtender([]webrtc.RTPHeaderExtensionParameter{
+		{URI: "urn:ietf:params:rtp-hdrext:sdes:mid", ID: 1},
+		{URI: VideoOrientationURI, ID: 3},
+	})
+
+	// nothing is sent until the orientation is set
+	pkts := newFrame()
+	extend(pkts)
+	if pkts[1].Extension {
+		t.Error("Unexpected header extension")
+	}
+
+	track.SetOrientation(video.Orientation{Rotation: 270, Flip: true})
+	pkts = newFrame()
+	extend(pkts)
+	if pkts[0].Extension {
+		t.Error("Expected the orientation only in the last packet of the frame")
+	}
+	if ext := pkts[1].GetExtension(3); len(ext) != 1 || ext[0] != 0x07 {
+		t.Errorf("Unexpected video orientation %v", ext)
+	}
+
+	// the extension isn't negotiated
+	pkts = newFrame()
+	track.headerExtender(nil)(pkts)
+	if pkts[1].Extension {
+		t.Error("Unexpected header extension")
+	}
+}
+
+func TestCompositorTrack(t *testing.T) {
+	frame := image.NewYCbCr(image.Rect(0, 0, 16, 16), image.YCbCrSubsampleRatio420)
+	input := video.ReaderFunc(func() (image.Image, func(), error) {
+		return frame, func() {}, nil
+	})
+	compositor := video.NewCompositor([]video.Reader{input}, 32, 16, 30)
+
+	track := NewVideoTrack(compositor, nil).(*VideoTrack)
+	defer track.Close()
+	img, _, err := track.NewReader(false).Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Bounds() != image.Rect(0, 0, 32, 16) {
+		t.Errorf("Unexpected frame size %v", img.Bounds())
+	}
+}
+
+type mockAudioSource struct {
+	audio.Reader
+}
+
+func (source *mockAudioSource) ID() string   { return "mock" }
+func (source *mockAudioSource) Close() error { return nil }
+
+func TestAudioTrackLevel(t *testing.T) {
+	chunk := wave.NewFloat32Interleaved(wave.ChunkInfo{Len: 480, Channels: 1, SamplingRate: 48000})
+	for i := range chunk.Data {
+		chunk.Data[i] = 0.5
+	}
+	track := NewAudioTrack(&mockAudioSource{audio.ReaderFunc(func() (wave.Audio, func(), error) {
+		return chunk, func() {}, nil
+	})}, nil).(*AudioTrack)
+	defer track.Close()
+
+	if level := track.Level(); level.RMS != audio.MinLevel {
+		t.Errorf("Expected %f before reading the track, got %f", audio.MinLevel, level.RMS)
+	}
+
+	// the level is measured after the transforms
+	track.Transform(func(r audio.Reader) audio.Reader {
+		return audio.ReaderFunc(func() (wave.Audio, func(), error) {
+			chunk, _, err := r.Read()
+			if err != nil {
+				return nil, func() {}, err
+			}
+			halved := wave.NewFloat32Interleaved(chunk.ChunkInfo())
+			for i, v := range chunk.(*wave.Float32Interleaved).Data {
+				halved.Data[i] = v / 2
+			}
+			return halved, func() {}, nil
+		})
+	})
+	if _, _, err := track.NewReader(false).Read(); err != nil {
+		t.Fatal(err)
+	}
+	if level := track.Level(); level.RMS > -12 || level.RMS < -12.1 {
+		t.Errorf("Expected -12.04 dBFS, got %f", level.RMS)
+	}
+
+	pkts := []*rtp.Packet{{}, {}}
+	track.headerExtender([]webrtc.RTPHeaderExtensionParameter{
+		{URI: AudioLevelURI, ID: 2},
+	})(pkts)
+	for _, pkt := range pkts {
+		if ext := pkt.GetExtension(2); len(ext) != 1 || ext[0] != 12 {
+			t.Errorf("Unexpected audio level %v", ext)
+		}
+	}
+}
+
+// mockVoiceEncoder returns a byte per chunk while someone speaks, and empty buffers otherwise.
+type mockVoiceEncoder struct {
+	r        audio.Reader
+	speaking bool
+}
+
+func (e *mockVoiceEncoder) RTPCodec() *codec.RTPCodec {
+	c := codec.NewRTPOpusCodec(48000)
+	c.Latency = 10 * time.Millisecond
+	return c
+}
+
+func (e *mockVoiceEncoder) BuildAudioEncoder(r audio.Reader, p prop.Media) (codec.ReadCloser, error) {
+	return &mockVoiceEncoder{r: r}, nil
+}
+
+func (e *mockVoiceEncoder) Read() ([]byte, func(), error) {
+	if _, _, err := e.r.Read(); err != nil {
+		return nil, func() {}, err
+	}
+	if !e.speaking {
+		return []byte{}, func() {}, nil
+	}
+	return []byte{1}, func() {}, nil
+}
+
+func (e *mockVoiceEncoder) SetVoiceActivity(speaking bool) error {
+	e.speaking = speaking
+	return nil
+}
+
+func (e *mockVoiceEncoder) Controller() codec.EncoderController { return e }
+func (e *mockVoiceEncoder) Close() error                        { return nil }
+
+func TestAudioTrackVoiceActivity(t *testing.T) {
+	// 10 chunks of silence, 20 chunks of a 200 Hz tone, 40 chunks of silence, and the tone again
+	var n int
+	source := &mockAudioSource{audio.ReaderFunc(func() (wave.Audio, func(), error) {
+		chunk := wave.NewFloat32Interleaved(wave.ChunkInfo{Len: 480, Channels: 1, SamplingRate: 48000})
+		if i := n / 480; i >= 10 && i < 30 || i >= 70 {
+			for j := range chunk.Data {
+				chunk.Data[j] = float32(0.5 * math.Sin(2*math.Pi*200*float64(n+j)/48000))
+			}
+		}
+		n += 480
+		return chunk, func() {}, nil
+	})}
+	track := NewAudioTrack(source, NewCodecSelector(WithAudioEncoders(&mockVoiceEncoder{}))).(*AudioTrack)
+	defer track.Close()
+
+	r, err := track.NewRTPReader(webrtc.MimeTypeOpus, 1, 1200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	var sent []int
+	var first uint32
+	for i := 0; i < 90; i++ {
+		pkts, _, err := r.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(pkts) == 0 {
+			continue
+		}
+		if len(sent) == 0 {
+			first = pkts[0].Timestamp
+		}
+		sent = append(sent, i)
+		// the samples of the chunks not transmitted are skipped
+		if expected := first + uint32(i-sent[0])*480; pkts[0].Timestamp != expected {
+			t.Errorf("Expected the timestamp %d for the chunk %d, got %d", expected, i, pkts[0].Timestamp)
+		}
+	}
+
+	// the voice is detected after 30ms, and held for 300ms
+	if len(sent) != 47+18 || sent[0] != 12 || sent[46] != 58 || sent[47] != 72 {
+		t.Errorf("Expected the chunks 12 to 58 and 72 to 89 to be sent, got %v", sent)
+	}
+}